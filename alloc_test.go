@@ -24,3 +24,23 @@ func TestRenderWrappedAllocations(t *testing.T) {
 		t.Fatalf("too many allocations per Render: got %.2f", allocs)
 	}
 }
+
+func TestRenderParagraphLinesAllocations(t *testing.T) {
+	src, err := os.ReadFile("testdata/OBAF.md")
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	allocs := testing.AllocsPerRun(20, func() {
+		var out bytes.Buffer
+		_ = Render(RenderRequest{
+			Reader: bytes.NewReader(src),
+			Writer: &out,
+			Width:  80,
+			Theme:  DefaultTheme(),
+		})
+	})
+	// Paragraph lines held for a setext underline are kept in reused buffers.
+	if allocs > 100 {
+		t.Fatalf("too many allocations per Render: got %.2f", allocs)
+	}
+}
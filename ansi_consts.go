@@ -1,3 +1,6 @@
 package mdf
 
 const ansiReset = "\x1b[0m"
+
+// imageMarker prefixes the alt text of image placeholders.
+const imageMarker = "🖼 "
@@ -2,11 +2,13 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"sort"
 	"strings"
@@ -15,7 +17,13 @@ import (
 	"github.com/spf13/pflag"
 	"golang.org/x/term"
 	"pkt.systems/mdf"
+	"pkt.systems/mdf/epub"
+	"pkt.systems/mdf/html"
+	"pkt.systems/mdf/latex"
+	"pkt.systems/mdf/man"
 	"pkt.systems/mdf/pdf"
+	"pkt.systems/mdf/png"
+	"pkt.systems/mdf/svg"
 	"pkt.systems/version"
 )
 
@@ -24,6 +32,9 @@ const (
 	defaultWidth     = 80
 	defaultChunkSize = 3
 	defaultDelay     = 20 * time.Millisecond
+	// backgroundQueryTimeout bounds the wait for the terminal to report its
+	// background colour for --theme auto.
+	backgroundQueryTimeout = 200 * time.Millisecond
 )
 
 func init() {
@@ -31,17 +42,34 @@ func init() {
 }
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "fmt" {
+		os.Exit(runFmt(os.Args[2:], os.Stdin, os.Stdout, os.Stderr))
+	}
 	var (
 		simulate          bool
 		simChunkSize      int
 		simDelay          time.Duration
 		themeName         string
+		themeFile         string
+		exportTheme       string
 		widthFlag         int
 		osc8Flag          string
+		colorFlag         string
+		htmlFlag          string
 		listThemes        bool
+		relayout          bool
+		speculative       bool
 		outPath           string
 		boring            bool
 		pdfMode           bool
+		formatFlag        string
+		svgWindow         bool
+		pngScale          float64
+		pngCellWidth      int
+		pngCellHeight     int
+		latexDocument     bool
+		manTitle          string
+		latexListings     bool
 		pdfPageSize       string
 		pdfMargin         float64
 		pdfLineHeight     float64
@@ -66,13 +94,27 @@ func main() {
 	flags.BoolVar(&simulate, "simulate", false, "Stream simulator (use default delay and chunk size)")
 	flags.IntVar(&simChunkSize, "simulate-chunk", defaultChunkSize, "Max bytes per stream chunk")
 	flags.DurationVar(&simDelay, "simulate-delay", defaultDelay, "Delay per stream chunk")
-	flags.StringVarP(&themeName, "theme", "t", defaultThemeName, "Theme name")
+	flags.StringVarP(&themeName, "theme", "t", defaultThemeName, "Theme name, or auto[:name] to pick the light or dark variant for the terminal")
+	flags.StringVar(&themeFile, "theme-file", "", "Theme file (TOML or JSON); overrides --theme")
+	flags.StringVar(&exportTheme, "export-theme", "", "Write the named built-in theme as a TOML theme file and exit")
 	flags.IntVarP(&widthFlag, "width", "w", 0, "Output width override (0 uses terminal width if available)")
 	flags.StringVarP(&osc8Flag, "osc8", "8", "auto", "OSC8 hyperlinks: auto|on|off")
+	flags.StringVar(&colorFlag, "color", "auto", "Colour depth: auto|always|never|16|256|truecolor")
+	flags.StringVar(&htmlFlag, "html", "interpret", "Raw HTML handling: text|strip|code|interpret")
 	flags.BoolVar(&listThemes, "list-themes", false, "List available themes")
+	flags.BoolVar(&relayout, "relayout", false, "Redraw the output at the new width when the terminal is resized")
+	flags.BoolVar(&speculative, "speculative", false, "Show code spans and link text before they close and repaint once resolved")
 	flags.StringVarP(&outPath, "output", "o", "", "Output file instead of stdout")
 	flags.BoolVarP(&boring, "boring", "b", false, "Generate non-ANSI output or boring PDF")
 	flags.BoolVar(&pdfMode, "pdf", false, "Generate a PDF instead of ANSI output")
+	flags.StringVar(&formatFlag, "format", "ansi", "Output format: ansi|pdf|html|svg|png|man|epub|latex (default from the output extension)")
+	flags.BoolVar(&svgWindow, "svg-window", false, "Draw a window frame around SVG output")
+	flags.Float64Var(&pngScale, "png-scale", 1, "Scale factor for PNG output (2 for high density displays)")
+	flags.IntVar(&pngCellWidth, "png-cell-width", 0, "PNG cell width in pixels (0 derives it from the font)")
+	flags.IntVar(&pngCellHeight, "png-cell-height", 0, "PNG cell height in pixels (0 derives it from the font)")
+	flags.StringVar(&manTitle, "title", "", "Man page title (default from the output or input file name)")
+	flags.BoolVar(&latexDocument, "latex-document", false, "Write a complete LaTeX article instead of a fragment to include")
+	flags.BoolVar(&latexListings, "latex-listings", false, "Write LaTeX code blocks as lstlisting instead of verbatim")
 	flags.StringVar(&pdfBoldFont, "pdf-bold-font", "", "TTF path for bold font")
 	flags.StringVar(&pdfItalicFont, "pdf-italic-font", "", "TTF path for italic font")
 	flags.StringVar(&pdfRegularFont, "pdf-regular-font", "", "TTF path for regular font")
@@ -94,7 +136,7 @@ func main() {
 	flags.SetInterspersed(true)
 	flags.Usage = func() {
 		fmt.Fprintln(os.Stderr, version.Module(), version.Current())
-		fmt.Fprintf(os.Stderr, "Usage: mdf [flags] [inputs...]\n")
+		fmt.Fprintf(os.Stderr, "Usage: mdf [flags] [inputs...]\n       mdf fmt [-w] [files...]\n")
 		fmt.Fprintln(os.Stderr, "\nIf no input is provided, Markdown is read from stdin.")
 		fmt.Fprintln(os.Stderr, "\nFlags:")
 		flags.PrintDefaults()
@@ -108,6 +150,19 @@ func main() {
 		printThemes()
 		return
 	}
+	if exportTheme != "" {
+		theme, ok := mdf.ThemeByName(exportTheme)
+		if !ok {
+			fmt.Fprintf(os.Stderr, "unknown theme %q\n\n", exportTheme)
+			printThemes()
+			os.Exit(2)
+		}
+		if err := mdf.ExportTheme(os.Stdout, theme); err != nil {
+			fmt.Fprintf(os.Stderr, "export theme: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
 
 	args := flags.Args()
 	reader, closer, err := openInputs(args)
@@ -123,9 +178,13 @@ func main() {
 		reader = &slowReader{r: reader, delay: simDelay, maxChunk: simChunkSize}
 	}
 
-	if !pdfMode && outPath != "" && strings.HasSuffix(strings.ToLower(outPath), ".pdf") {
-		fmt.Fprintf(os.Stderr, "warning: output %q ends with .pdf; enabling --pdf\n", outPath)
-		pdfMode = true
+	format, fromExt, err := resolveFormat(formatFlag, pdfMode, outPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid --format %q: %v\n", formatFlag, err)
+		os.Exit(2)
+	}
+	if fromExt {
+		fmt.Fprintf(os.Stderr, "warning: output %q ends with %s; enabling --format %s\n", outPath, filepath.Ext(outPath), format)
 	}
 
 	writer, closeOut, err := resolveOutput(outPath)
@@ -137,19 +196,42 @@ func main() {
 		defer func() { _ = closeOut.Close() }()
 	}
 
-	theme, ok := mdf.ThemeByName(themeName)
+	theme, ok := resolveTheme(themeName, func() mdf.Background {
+		return mdf.DetectBackground(backgroundQueryTimeout)
+	})
 	if !ok {
 		fmt.Fprintf(os.Stderr, "unknown theme %q\n\n", themeName)
 		printThemes()
 		os.Exit(2)
 	}
+	if themeFile != "" {
+		theme, err = loadThemeFile(themeFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "load theme: %v\n", err)
+			os.Exit(2)
+		}
+	}
 
-	if pdfMode {
+	htmlPolicy, err := resolveHTMLPolicy(htmlFlag)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid --html %q: %v\n", htmlFlag, err)
+		os.Exit(2)
+	}
+
+	// Ctrl-C stops the stream cleanly: the partial line is flushed and the
+	// terminal style reset before exiting. The first signal restores the
+	// default handling, so a second Ctrl-C kills the process.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	context.AfterFunc(ctx, stop)
+
+	switch format {
+	case "pdf":
 		if isTerminal(writer) {
 			fmt.Fprintln(os.Stderr, "refusing to write PDF to terminal; use -o/--output")
 			os.Exit(2)
 		}
-		if err := renderPDF(reader, writer, theme, boring, pdfConfig{
+		if err := renderPDF(ctx, reader, writer, theme, boring, pdfConfig{
 			pageSize:       pdfPageSize,
 			margin:         pdfMargin,
 			lineHeight:     pdfLineHeight,
@@ -167,11 +249,104 @@ func main() {
 			cornerMaxW:     pdfCornerMaxW,
 			cornerMaxH:     pdfCornerMaxH,
 			cornerPadding:  pdfCornerPadding,
+			htmlPolicy:     htmlPolicy,
+			imageBaseDir:   imageBaseDir(args),
 		}); err != nil {
+			if errors.Is(err, context.Canceled) {
+				os.Exit(130)
+			}
 			fmt.Fprintf(os.Stderr, "render pdf: %v\n", err)
 			os.Exit(1)
 		}
 		return
+	case "html":
+		if err := renderHTML(ctx, reader, writer, theme, boring, htmlPolicy, args); err != nil {
+			if errors.Is(err, context.Canceled) {
+				os.Exit(130)
+			}
+			fmt.Fprintf(os.Stderr, "render html: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	case "svg":
+		if boring {
+			theme = boringTheme()
+		}
+		cfg := svg.Config{Width: widthFlag, Window: svgWindow, HTMLPolicy: htmlPolicy, ImageBaseDir: imageBaseDir(args)}
+		if len(args) > 0 {
+			cfg.Title = filepath.Base(args[0])
+		}
+		if err := svg.RenderContext(ctx, svg.RenderRequest{Reader: reader, Writer: writer, Theme: theme, Config: cfg}); err != nil {
+			if errors.Is(err, context.Canceled) {
+				os.Exit(130)
+			}
+			fmt.Fprintf(os.Stderr, "render svg: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	case "png":
+		if isTerminal(writer) {
+			fmt.Fprintln(os.Stderr, "refusing to write PNG to terminal; use -o/--output")
+			os.Exit(2)
+		}
+		if boring {
+			theme = boringTheme()
+		}
+		cfg := png.Config{
+			Width:        widthFlag,
+			CellWidth:    pngCellWidth,
+			CellHeight:   pngCellHeight,
+			Scale:        pngScale,
+			HTMLPolicy:   htmlPolicy,
+			ImageBaseDir: imageBaseDir(args),
+		}
+		if err := png.RenderContext(ctx, png.RenderRequest{Reader: reader, Writer: writer, Theme: theme, Config: cfg}); err != nil {
+			if errors.Is(err, context.Canceled) {
+				os.Exit(130)
+			}
+			fmt.Fprintf(os.Stderr, "render png: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	case "epub":
+		if isTerminal(writer) {
+			fmt.Fprintln(os.Stderr, "refusing to write EPUB to terminal; use -o/--output")
+			os.Exit(2)
+		}
+		cfg := epub.Config{BackgroundEnabled: true, HTMLPolicy: htmlPolicy, ImageBaseDir: imageBaseDir(args)}
+		if boring {
+			theme = boringTheme()
+			cfg.BackgroundEnabled = false
+		}
+		if err := epub.RenderContext(ctx, epub.RenderRequest{Reader: reader, Writer: writer, Theme: theme, Config: cfg}); err != nil {
+			if errors.Is(err, context.Canceled) {
+				os.Exit(130)
+			}
+			fmt.Fprintf(os.Stderr, "render epub: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	case "man":
+		cfg := manConfig(outPath, args, manTitle)
+		cfg.HTMLPolicy = htmlPolicy
+		if err := man.RenderContext(ctx, man.RenderRequest{Reader: reader, Writer: writer, Config: cfg}); err != nil {
+			if errors.Is(err, context.Canceled) {
+				os.Exit(130)
+			}
+			fmt.Fprintf(os.Stderr, "render man: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	case "latex":
+		cfg := latex.Config{Document: latexDocument, Listings: latexListings, HTMLPolicy: htmlPolicy}
+		if err := latex.RenderContext(ctx, latex.RenderRequest{Reader: reader, Writer: writer, Config: cfg}); err != nil {
+			if errors.Is(err, context.Canceled) {
+				os.Exit(130)
+			}
+			fmt.Fprintf(os.Stderr, "render latex: %v\n", err)
+			os.Exit(1)
+		}
+		return
 	}
 
 	width := resolveWidth(widthFlag)
@@ -180,16 +355,36 @@ func main() {
 		fmt.Fprintf(os.Stderr, "invalid --osc8 %q: %v\n", osc8Flag, err)
 		os.Exit(2)
 	}
+	depth, err := resolveColor(colorFlag)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid --color %q: %v\n", colorFlag, err)
+		os.Exit(2)
+	}
+	theme = mdf.DownsampleTheme(theme, depth)
 	if boring {
 		theme = boringTheme()
 	}
-	if err := mdf.Render(mdf.RenderRequest{
-		Reader:  reader,
-		Writer:  writer,
-		Width:   width,
-		Theme:   theme,
-		Options: []mdf.RenderOption{mdf.WithOSC8(osc8)},
-	}); err != nil {
+	opts := []mdf.RenderOption{
+		mdf.WithOSC8(osc8),
+		mdf.WithHTMLPolicy(htmlPolicy),
+		mdf.WithImageBaseDir(imageBaseDir(args)),
+		mdf.WithSpeculativeInline(speculative && isTerminal(writer)),
+	}
+	if relayout && widthFlag == 0 && isTerminal(writer) {
+		err = renderRelayout(ctx, reader, writer.(*os.File), width, theme, opts)
+	} else {
+		err = mdf.RenderContext(ctx, mdf.RenderRequest{
+			Reader:  reader,
+			Writer:  writer,
+			Width:   width,
+			Theme:   theme,
+			Options: opts,
+		})
+	}
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			os.Exit(130)
+		}
 		fmt.Fprintf(os.Stderr, "render: %v\n", err)
 		os.Exit(1)
 	}
@@ -213,9 +408,11 @@ type pdfConfig struct {
 	cornerMaxW     float64
 	cornerMaxH     float64
 	cornerPadding  float64
+	htmlPolicy     mdf.HTMLPolicy
+	imageBaseDir   string
 }
 
-func renderPDF(r io.Reader, w io.Writer, theme mdf.Theme, boring bool, cfgIn pdfConfig) error {
+func renderPDF(ctx context.Context, r io.Reader, w io.Writer, theme mdf.Theme, boring bool, cfgIn pdfConfig) error {
 	cfg := pdf.DefaultConfig()
 	cfg.PageSize = defaultIf(cfgIn.pageSize, cfg.PageSize)
 	if cfgIn.margin > 0 {
@@ -250,6 +447,8 @@ func renderPDF(r io.Reader, w io.Writer, theme mdf.Theme, boring bool, cfgIn pdf
 		cfg.CornerImagePadding = cfgIn.cornerPadding
 	}
 	cfg.Boring = boring
+	cfg.HTMLPolicy = cfgIn.htmlPolicy
+	cfg.ImageBaseDir = cfgIn.imageBaseDir
 
 	reg, bold, italic := strings.TrimSpace(cfgIn.regularFont), strings.TrimSpace(cfgIn.boldFont), strings.TrimSpace(cfgIn.italicFont)
 	if reg != "" || bold != "" || italic != "" {
@@ -298,7 +497,7 @@ func renderPDF(r io.Reader, w io.Writer, theme mdf.Theme, boring bool, cfgIn pdf
 		cfg.HeadingFont = heading
 	}
 
-	return pdf.Render(pdf.RenderRequest{
+	return pdf.RenderContext(ctx, pdf.RenderRequest{
 		Reader: r,
 		Writer: w,
 		Theme:  theme,
@@ -306,6 +505,85 @@ func renderPDF(r io.Reader, w io.Writer, theme mdf.Theme, boring bool, cfgIn pdf
 	})
 }
 
+func renderHTML(ctx context.Context, r io.Reader, w io.Writer, theme mdf.Theme, boring bool, policy mdf.HTMLPolicy, args []string) error {
+	cfg := html.DefaultConfig()
+	cfg.Document = true
+	cfg.HTMLPolicy = policy
+	if len(args) > 0 {
+		cfg.Title = filepath.Base(args[0])
+	}
+	if boring {
+		theme = boringTheme()
+		cfg.BackgroundEnabled = false
+	}
+	return html.RenderContext(ctx, html.RenderRequest{
+		Reader: r,
+		Writer: w,
+		Theme:  theme,
+		Config: cfg,
+	})
+}
+
+// resolveFormat returns the output format named by --format or the --pdf
+// shorthand. Without either, a .pdf, .html, .svg, .png,
+// .epub, .tex or man page (.man, .1 to .9) output path picks the format,
+// and fromExt is set.
+func resolveFormat(format string, pdfMode bool, outPath string) (string, bool, error) {
+	format = strings.ToLower(strings.TrimSpace(format))
+	switch format {
+	case "", "ansi", "pdf", "html", "svg", "png", "man", "epub", "latex":
+	default:
+		return "", false, fmt.Errorf("expected ansi|pdf|html|svg|png|man|epub|latex")
+	}
+	switch {
+	case pdfMode:
+		return "pdf", false, nil
+	case format != "" && format != "ansi":
+		return format, false, nil
+	}
+	switch strings.ToLower(filepath.Ext(outPath)) {
+	case ".pdf":
+		return "pdf", true, nil
+	case ".html", ".htm":
+		return "html", true, nil
+	case ".svg":
+		return "svg", true, nil
+	case ".png":
+		return "png", true, nil
+	case ".epub":
+		return "epub", true, nil
+	case ".tex":
+		return "latex", true, nil
+	case ".man", ".1", ".2", ".3", ".4", ".5", ".6", ".7", ".8", ".9":
+		return "man", true, nil
+	}
+	return "ansi", false, nil
+}
+
+// manConfig returns the man page header fields implied by the flags and
+// file names: the page name from --title or else the output or first
+// input, and the section from an output extension such as ".1". Front
+// matter overrides both.
+func manConfig(outPath string, args []string, title string) man.Config {
+	cfg := man.Config{Title: strings.TrimSpace(title)}
+	name := strings.TrimSpace(outPath)
+	if name == "" && len(args) > 0 && args[0] != "-" {
+		name = args[0]
+	}
+	if name == "" {
+		return cfg
+	}
+	name = filepath.Base(name)
+	ext := filepath.Ext(name)
+	if len(ext) == 2 && ext[1] >= '1' && ext[1] <= '9' {
+		cfg.Section = ext[1:]
+	}
+	if cfg.Title == "" {
+		cfg.Title = strings.TrimSuffix(name, ext)
+	}
+	return cfg
+}
+
 func defaultIf(value, fallback string) string {
 	if value == "" {
 		return fallback
@@ -321,6 +599,30 @@ func printThemes() {
 	}
 }
 
+// resolveTheme returns the named built-in theme. "auto" picks the default
+// theme and "auto:name" the named one, or their counterpart when detect
+// reports the other kind of background.
+func resolveTheme(name string, detect func() mdf.Background) (mdf.Theme, bool) {
+	normalized := strings.ToLower(strings.TrimSpace(name))
+	if normalized != "auto" && !strings.HasPrefix(normalized, "auto:") {
+		return mdf.ThemeByName(name)
+	}
+	theme, ok := mdf.ThemeByName(strings.TrimPrefix(strings.TrimPrefix(normalized, "auto"), ":"))
+	if !ok {
+		return nil, false
+	}
+	return mdf.ThemeForBackground(theme, detect()), true
+}
+
+func loadThemeFile(path string) (mdf.Theme, error) {
+	f, err := os.Open(normalizePath(path))
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+	return mdf.LoadTheme(f)
+}
+
 func resolveWidth(width int) int {
 	if width > 0 {
 		return width
@@ -356,6 +658,38 @@ func resolveOSC8(mode string) (bool, error) {
 	}
 }
 
+// resolveColor returns the colour depth to render at. always keeps the
+// theme's colours as they are.
+func resolveColor(mode string) (mdf.ColorDepth, error) {
+	switch strings.ToLower(strings.TrimSpace(mode)) {
+	case "", "auto":
+		return mdf.DetectColorDepth(), nil
+	case "always":
+		return mdf.ColorTrue, nil
+	case "never":
+		return mdf.ColorNone, nil
+	}
+	if depth, ok := mdf.ParseColorDepth(mode); ok {
+		return depth, nil
+	}
+	return mdf.ColorNone, fmt.Errorf("expected auto|always|never|16|256|truecolor")
+}
+
+func resolveHTMLPolicy(mode string) (mdf.HTMLPolicy, error) {
+	switch strings.ToLower(strings.TrimSpace(mode)) {
+	case "text":
+		return mdf.HTMLAsText, nil
+	case "strip":
+		return mdf.HTMLStrip, nil
+	case "code":
+		return mdf.HTMLAsCode, nil
+	case "", "interpret":
+		return mdf.HTMLInterpret, nil
+	default:
+		return mdf.HTMLAsText, fmt.Errorf("expected text|strip|code|interpret")
+	}
+}
+
 func boringTheme() mdf.Theme {
 	return mdf.NewTheme("boring", mdf.Styles{})
 }
@@ -431,6 +765,34 @@ func openInputs(args []string) (io.Reader, io.Closer, error) {
 	return &multiInputReader{sources: sources}, nil, nil
 }
 
+// imageBaseDir returns the directory of the first local input so relative
+// image paths resolve next to the document.
+func imageBaseDir(args []string) string {
+	if len(args) == 0 {
+		return ""
+	}
+	path := strings.TrimSpace(args[0])
+	if u, err := url.Parse(path); err == nil && u.Scheme != "" {
+		switch strings.ToLower(u.Scheme) {
+		case "http", "https":
+			return ""
+		case "file":
+			path = u.Path
+			if path == "" {
+				path = u.Host
+			}
+			if unescaped, err := url.PathUnescape(path); err == nil {
+				path = unescaped
+			}
+		}
+	}
+	dir, err := filepath.Abs(filepath.Dir(path))
+	if err != nil {
+		return ""
+	}
+	return dir
+}
+
 func makeInputSource(raw string) (inputSource, error) {
 	raw = strings.TrimSpace(raw)
 	if raw == "" {
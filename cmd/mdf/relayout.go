@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+
+	"golang.org/x/term"
+	"pkt.systems/mdf"
+)
+
+type readResult struct {
+	data []byte
+	err  error
+}
+
+// renderRelayout renders r to the terminal out and lays the output out again
+// whenever the terminal is resized. Reads run in their own goroutine so a
+// stalled input does not keep cancellation from being noticed.
+func renderRelayout(ctx context.Context, r io.Reader, out *os.File, width int, theme mdf.Theme, opts []mdf.RenderOption) error {
+	w := mdf.NewRelayoutWriter(out, width, theme, opts...)
+	done := make(chan struct{})
+	defer close(done)
+
+	resized := make(chan os.Signal, 1)
+	notifyResize(resized)
+	defer signal.Stop(resized)
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case <-resized:
+				if cols, rows, err := term.GetSize(int(out.Fd())); err == nil {
+					_ = w.Resize(cols, rows)
+				}
+			}
+		}
+	}()
+
+	chunks := make(chan readResult)
+	go func() {
+		for {
+			buf := make([]byte, 4096)
+			n, err := r.Read(buf)
+			select {
+			case chunks <- readResult{data: buf[:n], err: err}:
+			case <-done:
+				return
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			_ = w.Close()
+			return ctx.Err()
+		case chunk := <-chunks:
+			if len(chunk.data) > 0 {
+				if _, err := w.Write(chunk.data); err != nil {
+					_ = w.Close()
+					return err
+				}
+			}
+			if chunk.err == io.EOF {
+				return w.Close()
+			}
+			if chunk.err != nil {
+				_ = w.Close()
+				return fmt.Errorf("read: %w", chunk.err)
+			}
+		}
+	}
+}
@@ -0,0 +1,8 @@
+//go:build !unix
+
+package main
+
+import "os"
+
+// notifyResize is a no-op where the terminal does not signal resizes.
+func notifyResize(chan<- os.Signal) {}
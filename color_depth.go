@@ -0,0 +1,290 @@
+package mdf
+
+import (
+	"os"
+	"strconv"
+	"strings"
+)
+
+// ColorDepth is the number of colours a terminal can show.
+type ColorDepth uint8
+
+const (
+	// ColorNone shows attributes such as bold and underline but no colour.
+	ColorNone ColorDepth = iota
+	// Color16 is the 8 standard and 8 bright ANSI colours.
+	Color16
+	// Color256 is the xterm 256-colour palette.
+	Color256
+	// ColorTrue is 24-bit RGB colour.
+	ColorTrue
+)
+
+// String returns the name ParseColorDepth accepts for d.
+func (d ColorDepth) String() string {
+	switch d {
+	case ColorNone:
+		return "none"
+	case Color16:
+		return "16"
+	case Color256:
+		return "256"
+	default:
+		return "truecolor"
+	}
+}
+
+// ParseColorDepth parses a colour depth name: none, 16, 256 or truecolor.
+func ParseColorDepth(name string) (ColorDepth, bool) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "none", "mono":
+		return ColorNone, true
+	case "16":
+		return Color16, true
+	case "256":
+		return Color256, true
+	case "truecolor", "24bit":
+		return ColorTrue, true
+	}
+	return ColorNone, false
+}
+
+// DetectColorDepth returns the colour depth the current environment likely
+// supports, from NO_COLOR, COLORTERM and TERM.
+func DetectColorDepth() ColorDepth {
+	if os.Getenv("NO_COLOR") != "" {
+		return ColorNone
+	}
+	switch strings.ToLower(os.Getenv("COLORTERM")) {
+	case "truecolor", "24bit":
+		return ColorTrue
+	}
+	if os.Getenv("WT_SESSION") != "" {
+		return ColorTrue
+	}
+	term := strings.ToLower(os.Getenv("TERM"))
+	switch {
+	case term == "" || term == "dumb":
+		return ColorNone
+	case strings.Contains(term, "direct") || strings.Contains(term, "truecolor"):
+		return ColorTrue
+	case strings.Contains(term, "256color") || strings.Contains(term, "kitty"):
+		return Color256
+	}
+	return Color16
+}
+
+// DownsampleTheme returns t with every colour in its styles replaced by the
+// nearest colour available at depth. Attributes are kept; at ColorNone only
+// attributes remain.
+func DownsampleTheme(t Theme, depth ColorDepth) Theme {
+	if t == nil {
+		t = DefaultTheme()
+	}
+	if depth == ColorTrue {
+		return t
+	}
+	styles := t.Styles()
+	for _, s := range styleFields(&styles) {
+		*s = ParseStyle(downsamplePrefix(s.Prefix, depth))
+	}
+	return NewTheme(t.Name(), styles)
+}
+
+// styleFields returns pointers to every style in s.
+func styleFields(s *Styles) []*Style {
+	named := namedStyles(s)
+	fields := make([]*Style, len(named))
+	for i, n := range named {
+		fields[i] = n.style
+	}
+	return fields
+}
+
+// downsamplePrefix rewrites the SGR sequences of a style prefix for depth.
+func downsamplePrefix(prefix string, depth ColorDepth) string {
+	var b strings.Builder
+	for prefix != "" {
+		start := strings.Index(prefix, "\x1b[")
+		if start < 0 {
+			b.WriteString(prefix)
+			break
+		}
+		end := strings.IndexByte(prefix[start:], 'm')
+		if end < 0 {
+			b.WriteString(prefix)
+			break
+		}
+		b.WriteString(prefix[:start])
+		params := downsampleParams(strings.Split(prefix[start+2:start+end], ";"), depth)
+		if len(params) > 0 {
+			b.WriteString("\x1b[")
+			b.WriteString(strings.Join(params, ";"))
+			b.WriteByte('m')
+		}
+		prefix = prefix[start+end+1:]
+	}
+	return b.String()
+}
+
+func downsampleParams(codes []string, depth ColorDepth) []string {
+	var out []string
+	for i := 0; i < len(codes); i++ {
+		n, err := strconv.Atoi(codes[i])
+		if err != nil {
+			out = append(out, codes[i])
+			continue
+		}
+		switch {
+		case n == 38 || n == 48:
+			rgb, index, used, ok := parseExtendedColor(codes[i+1:])
+			i += used
+			if !ok {
+				continue
+			}
+			out = append(out, extendedColor(n == 48, rgb, index, depth)...)
+		case n >= 30 && n <= 37, n >= 40 && n <= 47, n >= 90 && n <= 97, n >= 100 && n <= 107,
+			n == 39, n == 49:
+			if depth != ColorNone {
+				out = append(out, codes[i])
+			}
+		default:
+			out = append(out, codes[i])
+		}
+	}
+	return out
+}
+
+// parseExtendedColor parses the arguments of a 38 or 48 code: 5;n or
+// 2;r;g;b. index is -1 for an RGB colour.
+func parseExtendedColor(args []string) (rgb [3]int, index int, used int, ok bool) {
+	if len(args) == 0 {
+		return rgb, -1, 0, false
+	}
+	switch args[0] {
+	case "5":
+		if len(args) < 2 {
+			return rgb, -1, len(args), false
+		}
+		n, err := strconv.Atoi(args[1])
+		if err != nil || n < 0 || n > 255 {
+			return rgb, -1, 2, false
+		}
+		return xterm256RGB(n), n, 2, true
+	case "2":
+		if len(args) < 4 {
+			return rgb, -1, len(args), false
+		}
+		for i := range rgb {
+			v, err := strconv.Atoi(args[i+1])
+			if err != nil || v < 0 || v > 255 {
+				return rgb, -1, 4, false
+			}
+			rgb[i] = v
+		}
+		return rgb, -1, 4, true
+	}
+	return rgb, -1, 1, false
+}
+
+// extendedColor returns the SGR parameters for a colour at depth.
+func extendedColor(background bool, rgb [3]int, index int, depth ColorDepth) []string {
+	base := 38
+	if background {
+		base = 48
+	}
+	switch depth {
+	case ColorNone:
+		return nil
+	case Color16:
+		if index < 0 || index > 15 {
+			index = nearestANSI16(rgb)
+		}
+		code := 30 + index
+		if index >= 8 {
+			code = 90 + index - 8
+		}
+		if background {
+			code += 10
+		}
+		return []string{strconv.Itoa(code)}
+	case Color256:
+		if index < 0 {
+			index = nearestXterm256(rgb)
+		}
+		return []string{strconv.Itoa(base), "5", strconv.Itoa(index)}
+	}
+	return []string{strconv.Itoa(base), "2", strconv.Itoa(rgb[0]), strconv.Itoa(rgb[1]), strconv.Itoa(rgb[2])}
+}
+
+// ansi16RGB holds the xterm defaults for the 16 ANSI colours.
+var ansi16RGB = [16][3]int{
+	{0, 0, 0}, {205, 0, 0}, {0, 205, 0}, {205, 205, 0},
+	{0, 0, 238}, {205, 0, 205}, {0, 205, 205}, {229, 229, 229},
+	{127, 127, 127}, {255, 0, 0}, {0, 255, 0}, {255, 255, 0},
+	{92, 92, 255}, {255, 0, 255}, {0, 255, 255}, {255, 255, 255},
+}
+
+var cubeLevels = [6]int{0, 95, 135, 175, 215, 255}
+
+func xterm256RGB(n int) [3]int {
+	switch {
+	case n < 16:
+		return ansi16RGB[n]
+	case n < 232:
+		n -= 16
+		return [3]int{cubeLevels[n/36], cubeLevels[n/6%6], cubeLevels[n%6]}
+	}
+	v := 8 + (n-232)*10
+	return [3]int{v, v, v}
+}
+
+func nearestANSI16(rgb [3]int) int {
+	best, bestDist := 0, -1
+	for i, c := range ansi16RGB {
+		if d := colorDistance(rgb, c); bestDist < 0 || d < bestDist {
+			best, bestDist = i, d
+		}
+	}
+	return best
+}
+
+// nearestXterm256 picks the closer of the nearest colour cube entry and the
+// nearest grey ramp entry; the first 16 colours vary between terminals and
+// are not used.
+func nearestXterm256(rgb [3]int) int {
+	cube := 16
+	for i, v := range rgb {
+		level := 0
+		for l := range cubeLevels {
+			if abs(cubeLevels[l]-v) < abs(cubeLevels[level]-v) {
+				level = l
+			}
+		}
+		cube += level * [3]int{36, 6, 1}[i]
+	}
+	grey := 232
+	avg := (rgb[0] + rgb[1] + rgb[2]) / 3
+	if avg > 8 {
+		grey = min(232+(avg-8+5)/10, 255)
+	}
+	if colorDistance(rgb, xterm256RGB(grey)) < colorDistance(rgb, xterm256RGB(cube)) {
+		return grey
+	}
+	return cube
+}
+
+// colorDistance is a weighted squared RGB distance that tracks perceived
+// difference better than the plain Euclidean one.
+func colorDistance(a, b [3]int) int {
+	mean := (a[0] + b[0]) / 2
+	dr, dg, db := a[0]-b[0], a[1]-b[1], a[2]-b[2]
+	return ((512+mean)*dr*dr)>>8 + 4*dg*dg + ((767-mean)*db*db)>>8
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
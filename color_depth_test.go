@@ -0,0 +1,72 @@
+package mdf
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDetectColorDepth(t *testing.T) {
+	cases := []struct {
+		noColor, colorTerm, term string
+		want                     ColorDepth
+	}{
+		{"1", "truecolor", "xterm-256color", ColorNone},
+		{"", "truecolor", "xterm", ColorTrue},
+		{"", "24bit", "", ColorTrue},
+		{"", "", "xterm-256color", Color256},
+		{"", "", "xterm-direct", ColorTrue},
+		{"", "", "linux", Color16},
+		{"", "", "dumb", ColorNone},
+		{"", "", "", ColorNone},
+	}
+	for _, tc := range cases {
+		t.Setenv("NO_COLOR", tc.noColor)
+		t.Setenv("COLORTERM", tc.colorTerm)
+		t.Setenv("TERM", tc.term)
+		t.Setenv("WT_SESSION", "")
+		if got := DetectColorDepth(); got != tc.want {
+			t.Fatalf("NO_COLOR=%q COLORTERM=%q TERM=%q: got %v want %v", tc.noColor, tc.colorTerm, tc.term, got, tc.want)
+		}
+	}
+}
+
+func TestDownsamplePrefix(t *testing.T) {
+	cases := []struct {
+		prefix string
+		depth  ColorDepth
+		want   string
+	}{
+		{"\x1b[1;38;2;135;95;215m", Color256, "\x1b[1;38;5;98m"},
+		{"\x1b[38;2;255;0;0m", Color16, "\x1b[91m"},
+		{"\x1b[1;38;5;196m", Color16, "\x1b[1;91m"},
+		{"\x1b[48;5;4m", Color16, "\x1b[44m"},
+		{"\x1b[38;5;244m", Color256, "\x1b[38;5;244m"},
+		{"\x1b[38;2;128;128;128m", Color256, "\x1b[38;5;244m"},
+		{"\x1b[3m\x1b[1;38;5;219m", ColorNone, "\x1b[3m\x1b[1m"},
+		{"\x1b[90m", ColorNone, ""},
+		{"\x1b[1;32m", Color16, "\x1b[1;32m"},
+	}
+	for _, tc := range cases {
+		if got := downsamplePrefix(tc.prefix, tc.depth); got != tc.want {
+			t.Fatalf("downsamplePrefix(%q, %v) = %q, want %q", tc.prefix, tc.depth, got, tc.want)
+		}
+	}
+}
+
+func TestDownsampleTheme(t *testing.T) {
+	theme, _ := ThemeByName("synthwave-84")
+	if DownsampleTheme(theme, ColorTrue) != theme {
+		t.Fatalf("expected truecolor to keep the theme")
+	}
+	for _, depth := range []ColorDepth{ColorNone, Color16} {
+		styles := DownsampleTheme(theme, depth).Styles()
+		for _, s := range styleFields(&styles) {
+			if strings.Contains(s.Prefix, "38;") || strings.Contains(s.Prefix, "48;") {
+				t.Fatalf("%v: extended colour left in %q", depth, s.Prefix)
+			}
+		}
+	}
+	if got := DownsampleTheme(theme, ColorNone).Styles().Strong.Prefix; got == "" || strings.ReplaceAll(got, "\x1b[1m", "") != "" {
+		t.Fatalf("expected bold to survive without colour, got %q", got)
+	}
+}
@@ -9,7 +9,8 @@
 //   - Streaming-first parsing from io.Reader
 //   - Width-independent render tokens; wrap/reflow is last
 //   - Low allocations in hot paths
-//   - Theme-driven styling via ANSI prefixes
+//   - Theme-driven styling; each Style carries colours and attributes and
+//     the ANSI prefix generated from them
 //
 // Example:
 //
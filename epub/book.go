@@ -0,0 +1,264 @@
+package epub
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"pkt.systems/mdf"
+	"pkt.systems/mdf/html"
+	"pkt.systems/mdf/internal/role"
+)
+
+// book is an mdf.Stream that renders a document as XHTML chapters with
+// html.Stream, starting a chapter at each top-level heading of level 1. It
+// records the headings for the navigation document, the chapter of each
+// link target, and the local images to embed.
+type book struct {
+	chapters []*chapter
+	stream   *html.Stream
+	// depth counts the open lists and block quotes.
+	depth    int
+	headings []*heading
+	// heading is the open heading; started is set once its text starts.
+	heading *heading
+	started bool
+	// targets maps link target ids to chapter indexes.
+	targets map[string]int
+	images  []image
+	// imageNames maps image paths to their names in the book.
+	imageNames map[string]string
+	// skipLink drops the end of a link whose start was dropped.
+	skipLink bool
+}
+
+type chapter struct {
+	title string
+	buf   bytes.Buffer
+}
+
+type heading struct {
+	level   int
+	id      string
+	chapter int
+	text    strings.Builder
+}
+
+type image struct {
+	name      string
+	mediaType string
+	data      []byte
+}
+
+func newBook() *book {
+	b := &book{targets: make(map[string]int), imageNames: make(map[string]string)}
+	b.startChapter()
+	return b
+}
+
+func (b *book) startChapter() {
+	ch := &chapter{}
+	b.chapters = append(b.chapters, ch)
+	b.stream = html.NewXHTMLStream(&ch.buf)
+}
+
+func (b *book) current() int {
+	return len(b.chapters) - 1
+}
+
+// Width reports 0, as html.Stream does.
+func (b *book) Width() int { return 0 }
+
+// SetWidth is a no-op.
+func (b *book) SetWidth(int) {}
+
+// SetWrapIndent is a no-op.
+func (b *book) SetWrapIndent(string) {}
+
+// Flush closes the elements left open in the last chapter.
+func (b *book) Flush() error {
+	return b.stream.Flush()
+}
+
+// WriteToken passes tok on to the chapter being written.
+func (b *book) WriteToken(tok mdf.StreamToken) error {
+	switch tok.Kind {
+	case mdf.TokenListStart, mdf.TokenBlockquoteStart:
+		b.depth++
+	case mdf.TokenListEnd, mdf.TokenBlockquoteEnd:
+		b.depth--
+	case mdf.TokenHeadingStart:
+		return b.startHeading(tok)
+	case mdf.TokenHeadingEnd:
+		b.endHeading()
+	case mdf.TokenAnchor:
+		b.targets[tok.LinkURL] = b.current()
+	case mdf.TokenLinkStart:
+		if !bookURL(tok.LinkURL) {
+			b.skipLink = true
+			return nil
+		}
+	case mdf.TokenLinkEnd:
+		if b.skipLink {
+			b.skipLink = false
+			return nil
+		}
+	case mdf.TokenImage:
+		if name := b.embedImage(tok.LinkURL); name != "" {
+			tok.LinkURL = name
+		} else {
+			tok.Kind = mdf.TokenText
+			tok.Text = "[" + tok.Text + "]"
+		}
+	}
+	if h := b.heading; h != nil {
+		b.headingText(h, tok)
+	}
+	return b.stream.WriteToken(tok)
+}
+
+// startHeading starts a chapter for a heading of level 1 outside lists and
+// quotes, unless nothing has been written to the current one, and marks
+// the heading with an id for the navigation document.
+func (b *book) startHeading(tok mdf.StreamToken) error {
+	if tok.Block.Level == 1 && b.depth == 0 && b.chapters[b.current()].buf.Len() > 0 {
+		if err := b.stream.Flush(); err != nil {
+			return err
+		}
+		b.startChapter()
+	}
+	h := &heading{level: tok.Block.Level, id: "heading-" + strconv.Itoa(len(b.headings)+1), chapter: b.current()}
+	b.headings = append(b.headings, h)
+	b.heading = h
+	b.started = false
+	b.targets[h.id] = h.chapter
+	if err := b.stream.WriteToken(tok); err != nil {
+		return err
+	}
+	var anchor mdf.StreamToken
+	anchor.Kind = mdf.TokenAnchor
+	anchor.LinkURL = h.id
+	return b.stream.WriteToken(anchor)
+}
+
+func (b *book) headingText(h *heading, tok mdf.StreamToken) {
+	switch tok.Kind {
+	case mdf.TokenText, mdf.TokenCode, mdf.TokenURL:
+	default:
+		return
+	}
+	if !b.started {
+		// Quote bars, indentation and the "## " mark before the text.
+		set := role.Of(tok.Style)
+		if set.Has(role.Quote) || set.Has(role.ListMarker) || strings.TrimSpace(tok.Text) == "" ||
+			set.Has(role.Heading) && strings.Trim(tok.Text, "# ") == "" && strings.HasSuffix(tok.Text, " ") {
+			return
+		}
+		b.started = true
+	}
+	h.text.WriteString(tok.Text)
+}
+
+func (b *book) endHeading() {
+	h := b.heading
+	if h == nil {
+		return
+	}
+	b.heading = nil
+	if ch := b.chapters[h.chapter]; h.level == 1 && ch.title == "" {
+		ch.title = h.title()
+	}
+}
+
+func (h *heading) title() string {
+	return strings.Join(strings.Fields(h.text.String()), " ")
+}
+
+// embedImage adds the local image at path to the book and returns its name
+// there, or "" if it cannot be embedded.
+func (b *book) embedImage(path string) string {
+	if name, ok := b.imageNames[path]; ok {
+		return name
+	}
+	mediaType := imageMediaType(path)
+	if mediaType == "" || strings.Contains(path, ":") {
+		return ""
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	name := "images/image-" + strconv.Itoa(len(b.images)+1) + strings.ToLower(filepath.Ext(path))
+	b.images = append(b.images, image{name: name, mediaType: mediaType, data: data})
+	b.imageNames[path] = name
+	return name
+}
+
+func imageMediaType(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".png":
+		return "image/png"
+	case ".jpg", ".jpeg":
+		return "image/jpeg"
+	case ".gif":
+		return "image/gif"
+	case ".svg":
+		return "image/svg+xml"
+	case ".webp":
+		return "image/webp"
+	}
+	return ""
+}
+
+// bookURL reports whether a link to url works inside the book: a link to a
+// part of the document or an absolute URL. Links to local files do not.
+func bookURL(url string) bool {
+	if strings.HasPrefix(url, "#") {
+		return true
+	}
+	i := strings.IndexAny(url, ":/?# \t")
+	if i < 0 || url[i] != ':' {
+		return false
+	}
+	switch strings.ToLower(url[:i]) {
+	case "http", "https", "mailto", "ftp", "tel":
+		return true
+	}
+	return false
+}
+
+// resolveLinks points links to a part of the document at the chapter that
+// holds it.
+func (b *book) resolveLinks() {
+	const prefix = `href="#`
+	for _, ch := range b.chapters {
+		src := ch.buf.Bytes()
+		if !bytes.Contains(src, []byte(prefix)) {
+			continue
+		}
+		var out bytes.Buffer
+		for {
+			i := bytes.Index(src, []byte(prefix))
+			if i < 0 {
+				break
+			}
+			out.Write(src[:i+len(`href="`)])
+			src = src[i+len(`href="`):]
+			end := bytes.IndexByte(src, '"')
+			if end < 0 {
+				break
+			}
+			if n, ok := b.targets[string(src[1:end])]; ok {
+				out.WriteString(chapterName(n))
+			}
+		}
+		out.Write(src)
+		ch.buf = out
+	}
+}
+
+func chapterName(n int) string {
+	return "chapter-" + strconv.Itoa(n+1) + ".xhtml"
+}
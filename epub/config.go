@@ -0,0 +1,103 @@
+package epub
+
+import (
+	"time"
+
+	"pkt.systems/mdf"
+)
+
+// Config holds EPUB settings. The metadata fields are used where the front
+// matter of the document does not set them.
+type Config struct {
+	Title  string
+	Author string
+	// Language is a BCP 47 tag such as "en".
+	Language    string
+	Date        string
+	Publisher   string
+	Description string
+	// Identifier is the book's unique identifier, such as a "urn:isbn:" or
+	// "urn:uuid:" URN. By default one is derived from the input.
+	Identifier string
+	// Modified is the last modification time EPUB 3 requires; it defaults to
+	// the time of rendering.
+	Modified          time.Time
+	BackgroundEnabled bool
+	// BackgroundRGB and TextRGB default to colours suiting the theme: light
+	// for built-in light themes, dark otherwise.
+	BackgroundRGB [3]int
+	TextRGB       [3]int
+	HTMLPolicy    mdf.HTMLPolicy
+	// ImageBaseDir resolves relative image sources. Local images are
+	// embedded in the book; others are replaced by their alt text.
+	ImageBaseDir string
+}
+
+// DefaultConfig returns a baseline configuration.
+func DefaultConfig() Config {
+	return Config{Language: "en", BackgroundEnabled: true}
+}
+
+func applyConfig(dst *Config, src Config) {
+	if src.Title != "" {
+		dst.Title = src.Title
+	}
+	if src.Author != "" {
+		dst.Author = src.Author
+	}
+	if src.Language != "" {
+		dst.Language = src.Language
+	}
+	if src.Date != "" {
+		dst.Date = src.Date
+	}
+	if src.Publisher != "" {
+		dst.Publisher = src.Publisher
+	}
+	if src.Description != "" {
+		dst.Description = src.Description
+	}
+	if src.Identifier != "" {
+		dst.Identifier = src.Identifier
+	}
+	if !src.Modified.IsZero() {
+		dst.Modified = src.Modified
+	}
+	if !src.BackgroundEnabled && dst.BackgroundEnabled {
+		dst.BackgroundEnabled = false
+	}
+	if src.BackgroundRGB != [3]int{} {
+		dst.BackgroundRGB = src.BackgroundRGB
+	}
+	if src.TextRGB != [3]int{} {
+		dst.TextRGB = src.TextRGB
+	}
+	if src.HTMLPolicy != mdf.HTMLAsText {
+		dst.HTMLPolicy = src.HTMLPolicy
+	}
+	if src.ImageBaseDir != "" {
+		dst.ImageBaseDir = src.ImageBaseDir
+	}
+}
+
+// applyFrontMatter sets the metadata the front matter names. "lang" is
+// accepted for the language.
+func applyFrontMatter(dst *Config, fields map[string]string) {
+	for _, f := range []struct {
+		key   string
+		field *string
+	}{
+		{"title", &dst.Title},
+		{"author", &dst.Author},
+		{"lang", &dst.Language},
+		{"language", &dst.Language},
+		{"date", &dst.Date},
+		{"publisher", &dst.Publisher},
+		{"description", &dst.Description},
+		{"identifier", &dst.Identifier},
+	} {
+		if v := fields[f.key]; v != "" {
+			*f.field = v
+		}
+	}
+}
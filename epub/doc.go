@@ -0,0 +1,32 @@
+// Package epub renders Markdown to an EPUB 3 book using the mdf streaming
+// parser and the html package, so long documents can be read on e-readers.
+//
+// Each level 1 heading outside lists and quotes starts a chapter, written
+// as an XHTML content document. The navigation document lists the headings
+// of all levels, nested by level. The stylesheet is html.CSS for the theme,
+// with the embedded Hack fonts of the pdf package set for code. Local
+// images are embedded; other images are replaced by their alt text, and
+// links to local files by their text.
+//
+// The title, author, language (or lang), date, publisher, description and
+// identifier come from the document's front matter, falling back to the
+// Config:
+//
+//	---
+//	title: Handbook
+//	author: Platform Team
+//	lang: en
+//	---
+//
+// Example:
+//
+//	err := epub.Render(epub.RenderRequest{
+//		Reader: f,
+//		Writer: out,
+//		Theme:  mdf.DefaultTheme(),
+//		Config: epub.Config{ImageBaseDir: filepath.Dir(path)},
+//	})
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+package epub
@@ -0,0 +1,226 @@
+package epub
+
+import (
+	"archive/zip"
+	"hash/crc32"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"pkt.systems/mdf"
+	"pkt.systems/mdf/html"
+	"pkt.systems/mdf/pdf"
+)
+
+const containerXML = `<?xml version="1.0" encoding="utf-8"?>
+<container version="1.0" xmlns="urn:oasis:names:tc:opendocument:xmlns:container">
+<rootfiles>
+<rootfile full-path="EPUB/package.opf" media-type="application/oebps-package+xml"/>
+</rootfiles>
+</container>
+`
+
+// fonts are the embedded fonts the stylesheet declares, in the order of
+// their @font-face rules.
+var fonts = []struct {
+	name   string
+	weight string
+	style  string
+}{
+	{pdf.EmbeddedRegularFontName, "normal", "normal"},
+	{pdf.EmbeddedBoldFontName, "bold", "normal"},
+	{pdf.EmbeddedItalicFontName, "normal", "italic"},
+	{pdf.EmbeddedBoldItalicFontName, "bold", "italic"},
+}
+
+// item is a publication resource listed in the package document.
+type item struct {
+	id         string
+	href       string
+	mediaType  string
+	properties string
+}
+
+// writeBook writes the OCF container: the mimetype entry, which must come
+// first and be stored uncompressed, the container document, and the
+// publication resources under EPUB/.
+func writeBook(w io.Writer, b *book, theme mdf.Theme, cfg Config) error {
+	zw := zip.NewWriter(w)
+	mimetype := []byte("application/epub+zip")
+	fw, err := zw.CreateRaw(&zip.FileHeader{
+		Name:               "mimetype",
+		Method:             zip.Store,
+		CRC32:              crc32.ChecksumIEEE(mimetype),
+		CompressedSize64:   uint64(len(mimetype)),
+		UncompressedSize64: uint64(len(mimetype)),
+	})
+	if err != nil {
+		return err
+	}
+	if _, err := fw.Write(mimetype); err != nil {
+		return err
+	}
+	add := func(name string, data []byte) error {
+		fw, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: cfg.Modified})
+		if err != nil {
+			return err
+		}
+		_, err = fw.Write(data)
+		return err
+	}
+	if err := add("META-INF/container.xml", []byte(containerXML)); err != nil {
+		return err
+	}
+	items := []item{
+		{id: "nav", href: "nav.xhtml", mediaType: "application/xhtml+xml", properties: "nav"},
+		{id: "style", href: "style.css", mediaType: "text/css"},
+	}
+	if err := add("EPUB/nav.xhtml", []byte(navDocument(b, cfg))); err != nil {
+		return err
+	}
+	if err := add("EPUB/style.css", []byte(stylesheet(theme, cfg))); err != nil {
+		return err
+	}
+	for i, f := range fonts {
+		data, err := pdf.EmbeddedHackFont(f.name)
+		if err != nil {
+			return err
+		}
+		items = append(items, item{id: "font-" + strconv.Itoa(i+1), href: "fonts/" + f.name, mediaType: "font/ttf"})
+		if err := add("EPUB/fonts/"+f.name, data); err != nil {
+			return err
+		}
+	}
+	for i, img := range b.images {
+		items = append(items, item{id: "image-" + strconv.Itoa(i+1), href: img.name, mediaType: img.mediaType})
+		if err := add("EPUB/"+img.name, img.data); err != nil {
+			return err
+		}
+	}
+	for i, ch := range b.chapters {
+		name := chapterName(i)
+		items = append(items, item{id: strings.TrimSuffix(name, ".xhtml"), href: name, mediaType: "application/xhtml+xml"})
+		title := ch.title
+		if title == "" {
+			title = cfg.Title
+		}
+		page := xhtmlHead(title, cfg) + ch.buf.String() + "</body>\n</html>\n"
+		if err := add("EPUB/"+name, []byte(page)); err != nil {
+			return err
+		}
+	}
+	if err := add("EPUB/package.opf", []byte(packageDocument(items, len(b.chapters), cfg))); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// packageDocument returns the package document: the metadata, the manifest
+// of items and the spine of chapters.
+func packageDocument(items []item, chapters int, cfg Config) string {
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="utf-8"?>` + "\n")
+	b.WriteString(`<package xmlns="http://www.idpf.org/2007/opf" version="3.0" unique-identifier="book-id" xml:lang="` + escape(cfg.Language) + `">` + "\n")
+	b.WriteString(`<metadata xmlns:dc="http://purl.org/dc/elements/1.1/">` + "\n")
+	b.WriteString(`<dc:identifier id="book-id">` + escape(cfg.Identifier) + "</dc:identifier>\n")
+	b.WriteString("<dc:title>" + escape(cfg.Title) + "</dc:title>\n")
+	b.WriteString("<dc:language>" + escape(cfg.Language) + "</dc:language>\n")
+	for _, m := range []struct{ element, value string }{
+		{"dc:creator", cfg.Author},
+		{"dc:date", cfg.Date},
+		{"dc:publisher", cfg.Publisher},
+		{"dc:description", cfg.Description},
+	} {
+		if m.value != "" {
+			b.WriteString("<" + m.element + ">" + escape(m.value) + "</" + m.element + ">\n")
+		}
+	}
+	b.WriteString(`<meta property="dcterms:modified">` + cfg.Modified.UTC().Format(time.RFC3339) + "</meta>\n")
+	b.WriteString("</metadata>\n<manifest>\n")
+	for _, it := range items {
+		b.WriteString(`<item id="` + it.id + `" href="` + escape(it.href) + `" media-type="` + it.mediaType + `"`)
+		if it.properties != "" {
+			b.WriteString(` properties="` + it.properties + `"`)
+		}
+		b.WriteString("/>\n")
+	}
+	b.WriteString("</manifest>\n<spine>\n")
+	for i := range chapters {
+		b.WriteString(`<itemref idref="` + strings.TrimSuffix(chapterName(i), ".xhtml") + `"/>` + "\n")
+	}
+	b.WriteString("</spine>\n</package>\n")
+	return b.String()
+}
+
+// navDocument returns the navigation document, with a table of contents
+// nesting the headings of the book by level.
+func navDocument(b *book, cfg Config) string {
+	var s strings.Builder
+	s.WriteString(xhtmlHead(cfg.Title, cfg))
+	s.WriteString(`<nav epub:type="toc" id="toc">` + "\n<h1>" + escape(cfg.Title) + "</h1>\n<ol>\n")
+	var open []int
+	for _, h := range b.headings {
+		title := h.title()
+		if title == "" {
+			continue
+		}
+		switch {
+		case len(open) == 0:
+			open = append(open, h.level)
+		case h.level > open[len(open)-1]:
+			s.WriteString("\n<ol>\n")
+			open = append(open, h.level)
+		default:
+			s.WriteString("</li>\n")
+			for len(open) > 1 && h.level < open[len(open)-1] && h.level <= open[len(open)-2] {
+				s.WriteString("</ol>\n</li>\n")
+				open = open[:len(open)-1]
+			}
+			open[len(open)-1] = h.level
+		}
+		s.WriteString(`<li><a href="` + chapterName(h.chapter) + "#" + h.id + `">` + escape(title) + "</a>")
+	}
+	if len(open) == 0 {
+		s.WriteString(`<li><a href="` + chapterName(0) + `">` + escape(cfg.Title) + "</a>")
+		open = append(open, 1)
+	}
+	s.WriteString("</li>\n")
+	for range open[1:] {
+		s.WriteString("</ol>\n</li>\n")
+	}
+	s.WriteString("</ol>\n</nav>\n</body>\n</html>\n")
+	return s.String()
+}
+
+// xhtmlHead returns the start of an XHTML content document up to its body.
+func xhtmlHead(title string, cfg Config) string {
+	lang := escape(cfg.Language)
+	return `<?xml version="1.0" encoding="utf-8"?>` + "\n<!DOCTYPE html>\n" +
+		`<html xmlns="http://www.w3.org/1999/xhtml" xmlns:epub="http://www.idpf.org/2007/ops" lang="` + lang + `" xml:lang="` + lang + `">` + "\n" +
+		"<head>\n<meta charset=\"utf-8\"/>\n<title>" + escape(title) + "</title>\n" +
+		`<link rel="stylesheet" type="text/css" href="style.css"/>` + "\n</head>\n" +
+		`<body class="mdf">` + "\n"
+}
+
+// stylesheet returns the theme's HTML stylesheet with the embedded fonts
+// set for code.
+func stylesheet(theme mdf.Theme, cfg Config) string {
+	var b strings.Builder
+	for _, f := range fonts {
+		b.WriteString(`@font-face { font-family: "Hack"; src: url("fonts/` + f.name + `"); font-weight: ` + f.weight + "; font-style: " + f.style + " }\n")
+	}
+	b.WriteString(html.CSS(theme, html.Config{
+		BackgroundEnabled: cfg.BackgroundEnabled,
+		BackgroundRGB:     cfg.BackgroundRGB,
+		TextRGB:           cfg.TextRGB,
+	}))
+	b.WriteString(`.mdf code, .mdf pre { font-family: "Hack", monospace }` + "\n")
+	return b.String()
+}
+
+var escaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&#34;", "'", "&#39;")
+
+func escape(s string) string {
+	return escaper.Replace(s)
+}
@@ -0,0 +1,97 @@
+package epub
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"fmt"
+	"io"
+	"time"
+
+	"pkt.systems/mdf"
+	"pkt.systems/mdf/html"
+	"pkt.systems/mdf/internal/screen"
+)
+
+// RenderRequest contains inputs for EPUB rendering.
+type RenderRequest struct {
+	Reader io.Reader
+	Writer io.Writer
+	Theme  mdf.Theme
+	Config Config
+}
+
+// Render writes Markdown as an EPUB 3 book. The metadata comes from the
+// front matter, falling back to Config.
+func Render(req RenderRequest) error {
+	return RenderContext(context.Background(), req)
+}
+
+// RenderContext is Render with cancellation. The book is written once the
+// input has been read and parsed; once ctx is done it returns ctx.Err()
+// and writes nothing.
+func RenderContext(ctx context.Context, req RenderRequest) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if req.Reader == nil {
+		return fmt.Errorf("epub render: reader is nil")
+	}
+	if req.Writer == nil {
+		return fmt.Errorf("epub render: writer is nil")
+	}
+	src, err := io.ReadAll(req.Reader)
+	if err != nil {
+		return fmt.Errorf("epub render: %w", err)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	front, body := mdf.SplitFrontMatter(src)
+	cfg := DefaultConfig()
+	cfg.BackgroundRGB, cfg.TextRGB = screen.Colors(req.Theme)
+	applyConfig(&cfg, req.Config)
+	applyFrontMatter(&cfg, mdf.FrontMatterFields(front))
+	if cfg.Identifier == "" {
+		cfg.Identifier = sourceURN(src)
+	}
+	if cfg.Modified.IsZero() {
+		cfg.Modified = time.Now()
+	}
+	b := newBook()
+	if err := mdf.ParseContext(ctx, mdf.ParseRequest{
+		Reader: bytes.NewReader(body),
+		Stream: b,
+		Theme:  html.ParseTheme(),
+		Options: []mdf.RenderOption{
+			mdf.WithOSC8(true),
+			mdf.WithHTMLPolicy(cfg.HTMLPolicy),
+			mdf.WithImageBaseDir(cfg.ImageBaseDir),
+		},
+	}); err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil && err == ctxErr {
+			return err
+		}
+		return fmt.Errorf("epub render: %w", err)
+	}
+	b.resolveLinks()
+	if cfg.Title == "" {
+		cfg.Title = b.chapters[0].title
+	}
+	if cfg.Title == "" {
+		cfg.Title = "Untitled"
+	}
+	if err := writeBook(req.Writer, b, req.Theme, cfg); err != nil {
+		return fmt.Errorf("epub render: %w", err)
+	}
+	return nil
+}
+
+// sourceURN returns a name-based UUID URN for src, so that rendering the
+// same input twice gives the same identifier.
+func sourceURN(src []byte) string {
+	sum := sha1.Sum(src)
+	sum[6] = sum[6]&0x0f | 0x50
+	sum[8] = sum[8]&0x3f | 0x80
+	return fmt.Sprintf("urn:uuid:%x-%x-%x-%x-%x", sum[0:4], sum[4:6], sum[6:8], sum[8:10], sum[10:16])
+}
@@ -0,0 +1,186 @@
+package epub
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// renderBook renders src and returns the files of the book by name, and
+// their names in order.
+func renderBook(t *testing.T, src string, cfg Config) (map[string]string, []string) {
+	t.Helper()
+	var out bytes.Buffer
+	if err := Render(RenderRequest{Reader: strings.NewReader(src), Writer: &out, Config: cfg}); err != nil {
+		t.Fatalf("render: %v", err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(out.Bytes()), int64(out.Len()))
+	if err != nil {
+		t.Fatalf("read zip: %v", err)
+	}
+	files := make(map[string]string)
+	var names []string
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatalf("open %s: %v", f.Name, err)
+		}
+		data, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatalf("read %s: %v", f.Name, err)
+		}
+		files[f.Name] = string(data)
+		names = append(names, f.Name)
+	}
+	if names[0] != "mimetype" || zr.File[0].Method != zip.Store || files["mimetype"] != "application/epub+zip" {
+		t.Fatalf("first entry is %q (method %d), want a stored mimetype", names[0], zr.File[0].Method)
+	}
+	if !bytes.HasPrefix(out.Bytes()[30:], []byte("mimetypeapplication/epub+zip")) {
+		t.Fatalf("mimetype entry has extra fields")
+	}
+	return files, names
+}
+
+func TestRenderChaptersAndNav(t *testing.T) {
+	src := strings.Join([]string{
+		"Preface.",
+		"",
+		"# One",
+		"",
+		"## One *a*",
+		"",
+		"> # Quoted",
+		"",
+		"# Two",
+		"",
+		"### Deep",
+		"",
+		"## Two b",
+		"",
+	}, "\n")
+	files, _ := renderBook(t, src, Config{Title: "Book", Modified: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)})
+	for _, name := range []string{"chapter-1.xhtml", "chapter-2.xhtml", "chapter-3.xhtml"} {
+		if _, ok := files["EPUB/"+name]; !ok {
+			t.Fatalf("missing %s", name)
+		}
+	}
+	if _, ok := files["EPUB/chapter-4.xhtml"]; ok {
+		t.Fatalf("a heading in a quote started a chapter")
+	}
+	ch := files["EPUB/chapter-2.xhtml"]
+	for _, want := range []string{"<title>One</title>", `<h1><span id="heading-1"></span>One</h1>`, "<blockquote>\n<h1>"} {
+		if !strings.Contains(ch, want) {
+			t.Errorf("missing %q in:\n%s", want, ch)
+		}
+	}
+	nav := files["EPUB/nav.xhtml"]
+	want := `<ol>
+<li><a href="chapter-2.xhtml#heading-1">One</a>
+<ol>
+<li><a href="chapter-2.xhtml#heading-2">One a</a></li>
+</ol>
+</li>
+<li><a href="chapter-2.xhtml#heading-3">Quoted</a></li>
+<li><a href="chapter-3.xhtml#heading-4">Two</a>
+<ol>
+<li><a href="chapter-3.xhtml#heading-5">Deep</a></li>
+<li><a href="chapter-3.xhtml#heading-6">Two b</a></li>
+</ol>
+</li>
+</ol>
+</nav>`
+	if !strings.Contains(nav, want) {
+		t.Fatalf("nav:\n%s\nwant:\n%s", nav, want)
+	}
+	opf := files["EPUB/package.opf"]
+	for _, want := range []string{
+		`<meta property="dcterms:modified">2026-01-02T03:04:05Z</meta>`,
+		`<item id="nav" href="nav.xhtml" media-type="application/xhtml+xml" properties="nav"/>`,
+		`<item id="font-1" href="fonts/HackNerdFontMono-Regular.ttf" media-type="font/ttf"/>`,
+		"<spine>\n<itemref idref=\"chapter-1\"/>\n<itemref idref=\"chapter-2\"/>\n<itemref idref=\"chapter-3\"/>\n</spine>",
+	} {
+		if !strings.Contains(opf, want) {
+			t.Errorf("missing %q in:\n%s", want, opf)
+		}
+	}
+	if css := files["EPUB/style.css"]; !strings.Contains(css, `@font-face { font-family: "Hack"; src: url("fonts/HackNerdFontMono-Bold.ttf"); font-weight: bold`) ||
+		!strings.Contains(css, ".mdf h1 {") {
+		t.Errorf("stylesheet:\n%s", css)
+	}
+	if files["EPUB/fonts/"+fonts[0].name] == "" {
+		t.Errorf("font not embedded")
+	}
+}
+
+func TestRenderMetadataFromFrontMatter(t *testing.T) {
+	src := "---\ntitle: \"Handbook <1>\"\nauthor: Team\nlang: sv\ndate: 2024-05-01\n---\n# Start\n"
+	files, _ := renderBook(t, src, Config{Title: "ignored", Author: "ignored", Publisher: "Pub"})
+	opf := files["EPUB/package.opf"]
+	for _, want := range []string{
+		`xml:lang="sv"`,
+		"<dc:title>Handbook &lt;1&gt;</dc:title>",
+		"<dc:language>sv</dc:language>",
+		"<dc:creator>Team</dc:creator>",
+		"<dc:date>2024-05-01</dc:date>",
+		"<dc:publisher>Pub</dc:publisher>",
+		`<dc:identifier id="book-id">urn:uuid:`,
+	} {
+		if !strings.Contains(opf, want) {
+			t.Errorf("missing %q in:\n%s", want, opf)
+		}
+	}
+	again, _ := renderBook(t, src, Config{})
+	if id := func(opf string) string { return opf[strings.Index(opf, "urn:uuid:"):][:45] }; id(opf) != id(again["EPUB/package.opf"]) {
+		t.Errorf("identifier changed between renders")
+	}
+}
+
+func TestRenderLinksAndImages(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "pic.png"), []byte("png"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	src := "# A\n\nNote[^n], ![pic](pic.png), ![gone](missing.png), [file](other.md) and [web](https://x.y).\n\n# B\n\n[^n]: The note.\n"
+	files, _ := renderBook(t, src, Config{ImageBaseDir: dir})
+	ch := files["EPUB/chapter-1.xhtml"]
+	for _, want := range []string{
+		`<a href="chapter-2.xhtml#fn-1">`,
+		`<img src="images/image-1.png" alt="pic"/>`,
+		"[gone]",
+		", file and ",
+		`<a href="https://x.y">web</a>`,
+	} {
+		if !strings.Contains(ch, want) {
+			t.Errorf("missing %q in:\n%s", want, ch)
+		}
+	}
+	if !strings.Contains(files["EPUB/chapter-2.xhtml"], `href="chapter-1.xhtml#fnref-1"`) {
+		t.Errorf("back link not resolved:\n%s", files["EPUB/chapter-2.xhtml"])
+	}
+	if files["EPUB/images/image-1.png"] != "png" {
+		t.Errorf("image not embedded")
+	}
+	if !strings.Contains(files["EPUB/package.opf"], `<item id="image-1" href="images/image-1.png" media-type="image/png"/>`) {
+		t.Errorf("image missing from manifest")
+	}
+}
+
+func TestRenderContextCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	var out bytes.Buffer
+	err := RenderContext(ctx, RenderRequest{Reader: strings.NewReader("hi\n"), Writer: &out})
+	if err != context.Canceled {
+		t.Fatalf("err = %v, want context.Canceled", err)
+	}
+	if out.Len() != 0 {
+		t.Fatalf("wrote %d bytes", out.Len())
+	}
+}
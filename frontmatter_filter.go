@@ -1,6 +1,11 @@
 package mdf
 
-import "bytes"
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"strings"
+)
 
 const maxFrontMatterProbeBytes = 64 * 1024
 
@@ -81,6 +86,90 @@ func (f *frontMatterFilter) decide(eof bool) ([]byte, bool) {
 	return out, true
 }
 
+// SplitFrontMatter splits src into the front matter block the renderers
+// leave out, including its delimiter lines, and the Markdown after it.
+// front is empty when src does not start with front matter.
+func SplitFrontMatter(src []byte) (front, body []byte) {
+	var f frontMatterFilter
+	f.reset()
+	f.probe = append(f.probe, src...)
+	out, _ := f.decide(true)
+	return src[:len(src)-len(out)], src[len(src)-len(out):]
+}
+
+// FrontMatterFields returns the top-level scalar fields of a front matter
+// block as returned by SplitFrontMatter: "key: value" (YAML) and
+// "key = value" (TOML) lines, or the members of a JSON object. Nested
+// tables, lists and objects are left out.
+func FrontMatterFields(front []byte) map[string]string {
+	fields := make(map[string]string)
+	lines := bytes.Split(bytes.TrimSpace(trimBOM(front)), []byte("\n"))
+	if len(lines) < 2 {
+		return fields
+	}
+	inner := bytes.Join(lines[1:len(lines)-1], []byte("\n"))
+	if trimmed := bytes.TrimSpace(inner); len(trimmed) > 0 && trimmed[0] == '{' {
+		var doc map[string]any
+		if json.Unmarshal(trimmed, &doc) != nil {
+			return fields
+		}
+		for key, v := range doc {
+			switch v := v.(type) {
+			case string:
+				fields[key] = v
+			case float64:
+				fields[key] = strconv.FormatFloat(v, 'f', -1, 64)
+			case bool:
+				fields[key] = strconv.FormatBool(v)
+			}
+		}
+		return fields
+	}
+	for _, raw := range bytes.Split(inner, []byte("\n")) {
+		line := strings.TrimRight(string(raw), " \t\r")
+		if line == "" || line[0] == ' ' || line[0] == '\t' || line[0] == '#' || line[0] == '-' {
+			continue
+		}
+		if line[0] == '[' {
+			// A TOML table; the keys after it are not top-level.
+			break
+		}
+		i := strings.IndexAny(line, ":=")
+		if i <= 0 {
+			continue
+		}
+		key := strings.Trim(strings.TrimSpace(line[:i]), `"'`)
+		value := strings.TrimSpace(line[i+1:])
+		if key == "" || value == "" || value[0] == '[' || value[0] == '{' || value[0] == '|' || value[0] == '>' {
+			continue
+		}
+		fields[key] = frontMatterScalar(value)
+	}
+	return fields
+}
+
+// frontMatterScalar unquotes a front matter value or drops the comment
+// after an unquoted one.
+func frontMatterScalar(value string) string {
+	switch value[0] {
+	case '"':
+		if v, err := strconv.Unquote(value); err == nil {
+			return v
+		}
+		if end := strings.LastIndexByte(value, '"'); end > 0 {
+			return value[1:end]
+		}
+	case '\'':
+		if end := strings.LastIndexByte(value, '\''); end > 0 {
+			return strings.ReplaceAll(value[1:end], "''", "'")
+		}
+	}
+	if i := strings.Index(value, " #"); i >= 0 {
+		value = strings.TrimSpace(value[:i])
+	}
+	return value
+}
+
 func nextLine(src []byte, start int, eof bool) ([]byte, int, bool) {
 	if start > len(src) {
 		return nil, 0, false
@@ -112,3 +112,39 @@ func TestRenderAfterInitialFrontMatterStopsCheckingForMore(t *testing.T) {
 		}
 	}
 }
+
+func TestSplitFrontMatter(t *testing.T) {
+	t.Parallel()
+	front, body := SplitFrontMatter([]byte("---\ntitle: x\n---\n\n# Hi\n"))
+	if string(front) != "---\ntitle: x\n---\n" || string(body) != "\n# Hi\n" {
+		t.Fatalf("split = %q, %q", front, body)
+	}
+	front, body = SplitFrontMatter([]byte("---\n\nnot front matter\n"))
+	if len(front) != 0 || string(body) != "---\n\nnot front matter\n" {
+		t.Fatalf("split = %q, %q", front, body)
+	}
+}
+
+func TestFrontMatterFields(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		src  string
+		want map[string]string
+	}{
+		{"---\ntitle: \"A: B\"\nsection: 1 # comment\ntags:\n  - x\nauthor: 'O''Neil'\n---\n", map[string]string{"title": "A: B", "section": "1", "author": "O'Neil"}},
+		{"+++\ntitle = \"Hello\"\ndate = 2024-01-15\n[extra]\nname = \"no\"\n+++\n", map[string]string{"title": "Hello", "date": "2024-01-15"}},
+		{";;;\n{\"title\": \"J\", \"n\": 2, \"tags\": [\"a\"]}\n;;;\n", map[string]string{"title": "J", "n": "2"}},
+		{"", map[string]string{}},
+	}
+	for _, tc := range cases {
+		got := FrontMatterFields([]byte(tc.src))
+		if len(got) != len(tc.want) {
+			t.Fatalf("fields of %q = %v, want %v", tc.src, got, tc.want)
+		}
+		for k, v := range tc.want {
+			if got[k] != v {
+				t.Fatalf("fields of %q = %v, want %v", tc.src, got, tc.want)
+			}
+		}
+	}
+}
@@ -5,6 +5,7 @@ go 1.25
 require (
 	github.com/muesli/reflow v0.3.0
 	github.com/spf13/pflag v1.0.10
+	golang.org/x/image v0.25.0
 	golang.org/x/term v0.39.0
 	pkt.systems/mdf/pdf/testdata v0.0.3
 	pkt.systems/version v0.4.0
@@ -15,6 +16,7 @@ require (
 	github.com/clipperhouse/uax29/v2 v2.5.0 // indirect
 	github.com/mattn/go-runewidth v0.0.19 // indirect
 	golang.org/x/sys v0.40.0 // indirect
+	golang.org/x/text v0.23.0 // indirect
 )
 
 retract (
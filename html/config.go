@@ -0,0 +1,46 @@
+package html
+
+import "pkt.systems/mdf"
+
+// Config holds HTML rendering settings.
+type Config struct {
+	// Document writes a complete page with the stylesheet in its head
+	// instead of a fragment.
+	Document bool
+	// Title is the page title of a Document.
+	Title             string
+	BackgroundEnabled bool
+	BackgroundRGB     [3]int
+	TextRGB           [3]int
+	HTMLPolicy        mdf.HTMLPolicy
+}
+
+// DefaultConfig returns a baseline configuration.
+func DefaultConfig() Config {
+	return Config{
+		BackgroundEnabled: true,
+		BackgroundRGB:     [3]int{0, 0, 0},
+		TextRGB:           [3]int{220, 220, 220},
+	}
+}
+
+func applyConfig(dst *Config, src Config) {
+	if src.Document {
+		dst.Document = src.Document
+	}
+	if src.Title != "" {
+		dst.Title = src.Title
+	}
+	if !src.BackgroundEnabled && dst.BackgroundEnabled {
+		dst.BackgroundEnabled = false
+	}
+	if src.BackgroundRGB != [3]int{} {
+		dst.BackgroundRGB = src.BackgroundRGB
+	}
+	if src.TextRGB != [3]int{} {
+		dst.TextRGB = src.TextRGB
+	}
+	if src.HTMLPolicy != mdf.HTMLAsText {
+		dst.HTMLPolicy = src.HTMLPolicy
+	}
+}
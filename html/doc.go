@@ -0,0 +1,26 @@
+// Package html renders Markdown to HTML using the mdf streaming parser.
+//
+// The output is semantic HTML inside a <div class="mdf"> element. CSS builds
+// a stylesheet for it from an mdf.Theme, so a page uses the same colours as
+// the terminal renderer. HTML is written while the input is parsed, which
+// lets a web UI show partial output as model deltas arrive.
+//
+// Example:
+//
+//	cfg := html.DefaultConfig()
+//	cfg.Document = true
+//
+//	err := html.Render(html.RenderRequest{
+//		Reader: strings.NewReader("# Report\n\nHello HTML.\n"),
+//		Writer: outFile,
+//		Theme:  mdf.DefaultTheme(),
+//		Config: cfg,
+//	})
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//
+// NewWriter accepts pushed deltas instead of a reader. Stream is the
+// underlying mdf.Stream, for callers driving mdf.Parse themselves with
+// ParseTheme.
+package html
@@ -0,0 +1,217 @@
+package html
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"pkt.systems/mdf"
+	"pkt.systems/mdf/internal/role"
+)
+
+// RenderRequest contains inputs for HTML rendering.
+type RenderRequest struct {
+	Reader io.Reader
+	Writer io.Writer
+	Theme  mdf.Theme
+	Config Config
+}
+
+// Render converts Markdown to themed HTML.
+func Render(req RenderRequest) error {
+	return RenderContext(context.Background(), req)
+}
+
+// RenderContext is Render with cancellation. Output is written while the
+// reader is consumed; once ctx is done it stops reading and returns
+// ctx.Err(), leaving the output unfinished.
+func RenderContext(ctx context.Context, req RenderRequest) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if req.Reader == nil {
+		return fmt.Errorf("html render: reader is nil")
+	}
+	if req.Writer == nil {
+		return fmt.Errorf("html render: writer is nil")
+	}
+	cfg := DefaultConfig()
+	applyConfig(&cfg, req.Config)
+	if _, err := io.WriteString(req.Writer, header(req.Theme, cfg)); err != nil {
+		return fmt.Errorf("html render: %w", err)
+	}
+	reader := req.Reader
+	if flush := flushFunc(req.Writer); flush != nil {
+		reader = &flushReader{r: reader, flush: flush}
+	}
+	if err := mdf.ParseContext(ctx, mdf.ParseRequest{
+		Reader:  reader,
+		Stream:  NewStream(req.Writer),
+		Theme:   role.Theme(),
+		Options: parseOptions(cfg),
+	}); err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil && err == ctxErr {
+			return err
+		}
+		return fmt.Errorf("html render: %w", err)
+	}
+	if _, err := io.WriteString(req.Writer, footer(cfg)); err != nil {
+		return fmt.Errorf("html render: %w", err)
+	}
+	return nil
+}
+
+// NewWriter returns a renderer fed by writes, like mdf.NewWriter: each Write
+// parses its bytes and writes the HTML they complete to w, and Close closes
+// the open elements. If w has a Flush method, as http.ResponseWriter does,
+// it is called after every Write so a browser sees each delta as it comes.
+func NewWriter(w io.Writer, theme mdf.Theme, cfg Config) io.WriteCloser {
+	full := DefaultConfig()
+	applyConfig(&full, cfg)
+	hw := &writer{w: w, flush: flushFunc(w), header: header(theme, full), footer: footer(full)}
+	if w == nil {
+		hw.err = fmt.Errorf("html writer: writer is nil")
+		return hw
+	}
+	hw.inner = mdf.NewStreamWriter(NewStream(w), role.Theme(), parseOptions(full)...)
+	return hw
+}
+
+type writer struct {
+	w      io.Writer
+	inner  io.WriteCloser
+	flush  func() error
+	header string
+	footer string
+	err    error
+	closed bool
+}
+
+func (hw *writer) Write(p []byte) (int, error) {
+	if hw.err != nil {
+		return 0, hw.err
+	}
+	if err := hw.begin(); err != nil {
+		return 0, err
+	}
+	n, err := hw.inner.Write(p)
+	if err != nil {
+		hw.err = err
+		return n, err
+	}
+	return n, hw.flushOut()
+}
+
+// Close finishes the document. Closing twice is a no-op.
+func (hw *writer) Close() error {
+	if hw.closed {
+		return nil
+	}
+	hw.closed = true
+	if hw.inner == nil {
+		return hw.err
+	}
+	err := hw.begin()
+	if cerr := hw.inner.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		if _, werr := io.WriteString(hw.w, hw.footer); werr != nil {
+			err = fmt.Errorf("html writer: %w", werr)
+		}
+	}
+	if err == nil {
+		err = hw.flushOut()
+	}
+	return err
+}
+
+// begin writes the opening markup before the first output.
+func (hw *writer) begin() error {
+	if hw.header == "" {
+		return nil
+	}
+	if _, err := io.WriteString(hw.w, hw.header); err != nil {
+		hw.err = fmt.Errorf("html writer: %w", err)
+		return hw.err
+	}
+	hw.header = ""
+	return nil
+}
+
+func (hw *writer) flushOut() error {
+	if hw.flush == nil {
+		return nil
+	}
+	if err := hw.flush(); err != nil {
+		hw.err = fmt.Errorf("html writer: %w", err)
+	}
+	return hw.err
+}
+
+func parseOptions(cfg Config) []mdf.RenderOption {
+	return []mdf.RenderOption{
+		mdf.WithOSC8(true),
+		mdf.WithHTMLPolicy(cfg.HTMLPolicy),
+	}
+}
+
+// header returns the markup before the body: a page head with the
+// stylesheet for a Document, otherwise the opening wrapper element.
+func header(theme mdf.Theme, cfg Config) string {
+	if !cfg.Document {
+		return "<div class=\"mdf\">\n"
+	}
+	title := string(appendEscaped(nil, cfg.Title))
+	return "<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n" +
+		"<meta name=\"viewport\" content=\"width=device-width, initial-scale=1\">\n" +
+		"<title>" + title + "</title>\n<style>\n" + CSS(theme, cfg) + "</style>\n</head>\n" +
+		"<body class=\"mdf\">\n"
+}
+
+func footer(cfg Config) string {
+	if !cfg.Document {
+		return "</div>\n"
+	}
+	return "</body>\n</html>\n"
+}
+
+// flushReader flushes the output before each read, which is when the parser
+// has turned all input so far into HTML.
+type flushReader struct {
+	r     io.Reader
+	flush func() error
+}
+
+func (f *flushReader) Read(p []byte) (int, error) {
+	if f.flush != nil {
+		if err := f.flush(); err != nil {
+			return 0, err
+		}
+	}
+	return f.r.Read(p)
+}
+
+// SetReadDeadline passes deadlines on, so cancellation can still interrupt
+// a blocked read.
+func (f *flushReader) SetReadDeadline(t time.Time) error {
+	if d, ok := f.r.(interface{ SetReadDeadline(time.Time) error }); ok {
+		return d.SetReadDeadline(t)
+	}
+	return fmt.Errorf("html render: reader has no deadline")
+}
+
+// flushFunc returns the Flush method of w, or nil if it has none.
+func flushFunc(w io.Writer) func() error {
+	switch f := w.(type) {
+	case interface{ Flush() error }:
+		return f.Flush
+	case interface{ Flush() }:
+		return func() error {
+			f.Flush()
+			return nil
+		}
+	}
+	return nil
+}
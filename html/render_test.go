@@ -0,0 +1,199 @@
+package html
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"pkt.systems/mdf"
+)
+
+func renderString(t *testing.T, src string, cfg Config) string {
+	t.Helper()
+	var out bytes.Buffer
+	if err := Render(RenderRequest{Reader: strings.NewReader(src), Writer: &out, Config: cfg}); err != nil {
+		t.Fatalf("render: %v", err)
+	}
+	return out.String()
+}
+
+func TestRenderSemanticHTML(t *testing.T) {
+	src := strings.Join([]string{
+		"# Title *x*",
+		"",
+		"> quoted **bold**",
+		"",
+		"- [x] done",
+		"- item",
+		"",
+		"3. three",
+		"",
+		"| a | b |",
+		"|---|---|",
+		"| `1` |  |",
+		"",
+		"hard  ",
+		"break ~~gone~~ <tag> & [link](https://x.y \"T\") ![alt](p.png)",
+		"",
+		"```go",
+		"func main() {",
+		"",
+		"}",
+		"```",
+		"",
+		"---",
+		"",
+	}, "\n")
+	got := renderString(t, src, Config{})
+	for _, want := range []string{
+		"<div class=\"mdf\">\n<h1>Title <em>x</em></h1>\n",
+		"<blockquote>\n<p>quoted <strong>bold</strong></p>\n</blockquote>\n",
+		"<li class=\"task\"><p><input type=\"checkbox\" disabled checked> done</p>\n</li>\n<li><p>item</p>\n</li>\n</ul>\n",
+		"<ol start=\"3\">\n<li><p>three</p>\n",
+		"<tr><th>a</th><th>b</th></tr>\n</thead>\n<tbody>\n<tr><td><code>1</code></td><td></td></tr>\n</tbody>\n</table>\n",
+		"<p>hard<br>\nbreak <del>gone</del> &lt;tag&gt; &amp; <a href=\"https://x.y\" title=\"T\">link</a> <a href=\"p.png\"><img src=\"p.png\" alt=\"alt\"></a></p>\n",
+		"<pre><code class=\"language-go\"><span class=\"kw\">func</span> main<span class=\"pun\">()</span> <span class=\"pun\">{</span>\n\n<span class=\"pun\">}</span></code></pre>\n",
+		"<hr>\n</div>\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("missing %q in:\n%s", want, got)
+		}
+	}
+	if strings.ContainsRune(got, 0) {
+		t.Fatalf("role markers leaked into output: %q", got)
+	}
+}
+
+func TestRenderTableAlignment(t *testing.T) {
+	got := renderString(t, "| l | c | r | n |\n|:--|:-:|--:|---|\n| a \\| b | x │ y | 10 | full |\n", Config{})
+	want := "<table>\n<thead>\n" +
+		`<tr><th style="text-align: left">l</th><th style="text-align: center">c</th>` +
+		`<th style="text-align: right">r</th><th>n</th></tr>` + "\n</thead>\n<tbody>\n" +
+		`<tr><td style="text-align: left">a | b</td><td style="text-align: center">x │ y</td>` +
+		`<td style="text-align: right">10</td><td>full</td></tr>` + "\n</tbody>\n</table>\n"
+	if !strings.Contains(got, want) {
+		t.Fatalf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestRenderDropsScriptLinks(t *testing.T) {
+	got := renderString(t, "[x](javascript:alert(1)) [y](/rel) [z](mailto:a@b.c)\n", Config{})
+	for _, want := range []string{"<a>x</a>", `<a href="/rel">y</a>`, `<a href="mailto:a@b.c">z</a>`} {
+		if !strings.Contains(got, want) {
+			t.Errorf("missing %q in %q", want, got)
+		}
+	}
+}
+
+func TestXHTMLStreamClosesVoidElements(t *testing.T) {
+	var out bytes.Buffer
+	err := mdf.Parse(mdf.ParseRequest{
+		Reader:  strings.NewReader("- [x] a  \n  b ![i](p.png)\n\n---\n"),
+		Stream:  NewXHTMLStream(&out),
+		Theme:   ParseTheme(),
+		Options: []mdf.RenderOption{mdf.WithOSC8(true)},
+	})
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	for _, want := range []string{`<input type="checkbox" disabled="disabled" checked="checked"/> a`, "<br/>\n", `<img src="p.png" alt="i"/>`, "<hr/>\n"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("missing %q in %q", want, out.String())
+		}
+	}
+}
+
+func TestRenderDocument(t *testing.T) {
+	got := renderString(t, "hi\n", Config{Document: true, Title: "a<b", BackgroundEnabled: true})
+	for _, want := range []string{
+		"<!DOCTYPE html>\n",
+		"<title>a&lt;b</title>",
+		".mdf { color: #dcdcdc; background-color: #000000",
+		"<body class=\"mdf\">\n<p>hi</p>\n</body>\n</html>\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("missing %q in:\n%s", want, got)
+		}
+	}
+}
+
+func TestCSSFollowsTheme(t *testing.T) {
+	styles := mdf.Styles{
+		Heading:     [6]mdf.Style{{FG: mdf.RGBColor(0x12, 0x34, 0x56), Bold: true}},
+		CodeKeyword: mdf.Style{FG: mdf.IndexedColor(1), Italic: true},
+		Quote:       mdf.Style{FG: mdf.IndexedColor(244)},
+	}
+	css := CSS(mdf.NewTheme("t", styles), DefaultConfig())
+	for _, want := range []string{
+		".mdf h1 { color: #123456; font-weight: bold }\n",
+		".mdf pre .kw { color: #cd0000; font-style: italic }\n",
+		".mdf blockquote { margin-left: 0; padding-left: 1em; border-left: 0.25em solid #808080 }\n",
+	} {
+		if !strings.Contains(css, want) {
+			t.Errorf("missing %q in:\n%s", want, css)
+		}
+	}
+	if strings.Contains(CSS(mdf.NewTheme("t", styles), Config{}), "background-color: #000000") {
+		t.Fatal("page colours set with the background disabled")
+	}
+}
+
+func TestNewWriterStreamsDeltas(t *testing.T) {
+	src := "# Head\n\nSome *text* and `code`.\n\n- a\n- b\n\n```sh\necho hi\n```\n\n| a |\n|---|\n| 1 |\n"
+	want := renderString(t, src, Config{})
+
+	var out bytes.Buffer
+	w := NewWriter(&out, nil, Config{})
+	grew := false
+	for i := 0; i < len(src); i++ {
+		if _, err := w.Write([]byte{src[i]}); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+		if i < len(src)/2 && out.Len() > len("<div class=\"mdf\">\n") {
+			grew = true
+		}
+	}
+	if !grew {
+		t.Fatal("no output before the first half of the input was written")
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	if out.String() != want {
+		t.Fatalf("writer output differs from Render:\n got %q\nwant %q", out.String(), want)
+	}
+}
+
+type flushRecorder struct {
+	bytes.Buffer
+	flushes int
+}
+
+func (f *flushRecorder) Flush() { f.flushes++ }
+
+func TestNewWriterFlushes(t *testing.T) {
+	var out flushRecorder
+	w := NewWriter(&out, nil, Config{})
+	for _, delta := range []string{"Hello ", "world.\n", "\nNext\n"} {
+		if _, err := w.Write([]byte(delta)); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	if out.flushes != 4 {
+		t.Fatalf("flushes = %d, want 4", out.flushes)
+	}
+}
+
+func TestRenderContextCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	var out bytes.Buffer
+	err := RenderContext(ctx, RenderRequest{Reader: strings.NewReader("hi\n"), Writer: &out})
+	if err != context.Canceled {
+		t.Fatalf("err = %v, want context.Canceled", err)
+	}
+}
@@ -0,0 +1,554 @@
+package html
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"pkt.systems/mdf"
+	"pkt.systems/mdf/internal/role"
+)
+
+// Stream is an mdf.Stream that writes semantic HTML. Every token is written
+// to the underlying writer as it arrives, so partial documents can be sent
+// to a browser while the Markdown is still being parsed. The parser must run
+// with ParseTheme; the tokens of other themes lose their inline markup.
+//
+// Stream does not write the wrapper element or the stylesheet; Render and
+// NewWriter add them.
+type Stream struct {
+	w   io.Writer
+	buf []byte
+	err error
+
+	// closers holds the end tags of open block elements.
+	closers []string
+	// inline holds the open inline tags, outermost first.
+	inline []string
+	// lineStart is set at the start of each output line, where the parser
+	// writes quote bars, list markers and indentation that HTML leaves out.
+	lineStart bool
+	leaf      bool
+	pre       bool
+	// pendingBreak is a line break in a paragraph, written as <br> once more
+	// text follows.
+	pendingBreak bool
+	// task is the checkbox state of a task item until its "[x]" has been
+	// replaced by an input.
+	task  mdf.TaskState
+	table tableState
+	// held is whitespace written only if more text follows: spaces at the
+	// end of a table cell, or the line break after a line of code.
+	held string
+	// xhtml closes void elements and spells out boolean attributes.
+	xhtml bool
+}
+
+type tableState struct {
+	open bool
+	head bool
+	body bool
+	row  bool
+	cell bool
+	// header is set while the open row is a header row.
+	header bool
+}
+
+// NewStream returns a Stream writing to w.
+func NewStream(w io.Writer) *Stream {
+	return &Stream{w: w}
+}
+
+// NewXHTMLStream returns a Stream writing to w that keeps its output
+// well-formed XML, as XHTML documents such as EPUB chapters require.
+func NewXHTMLStream(w io.Writer) *Stream {
+	return &Stream{w: w, xhtml: true}
+}
+
+// Width reports 0: the browser wraps lines, and tables are not shrunk.
+func (s *Stream) Width() int { return 0 }
+
+// SetWidth is a no-op.
+func (s *Stream) SetWidth(int) {}
+
+// SetWrapIndent is a no-op.
+func (s *Stream) SetWrapIndent(string) {}
+
+// WriteToken writes the HTML for tok.
+func (s *Stream) WriteToken(tok mdf.StreamToken) error {
+	if s.err != nil {
+		return s.err
+	}
+	s.buf = s.buf[:0]
+	if mdf.IsBlockEvent(tok.Kind) {
+		s.block(tok)
+	} else {
+		switch tok.Kind {
+		case mdf.TokenLinkStart:
+			s.beginInline()
+			s.closeInline()
+			s.buf = append(s.buf, "<a"...)
+			href, title := splitLinkURL(tok.LinkURL)
+			if href = safeURL(href); href != "" {
+				s.attr("href", href)
+			}
+			if title != "" {
+				s.attr("title", title)
+			}
+			s.buf = append(s.buf, '>')
+		case mdf.TokenLinkEnd:
+			s.closeInline()
+			s.buf = append(s.buf, "</a>"...)
+		case mdf.TokenImage:
+			s.beginInline()
+			s.closeInline()
+			s.buf = append(s.buf, "<img"...)
+			s.attr("src", safeURL(tok.LinkURL))
+			s.attr("alt", tok.Text)
+			s.endVoid()
+		case mdf.TokenAnchor:
+			s.closeInline()
+			s.buf = append(s.buf, "<span"...)
+			s.attr("id", tok.LinkURL)
+			s.buf = append(s.buf, "></span>"...)
+		case mdf.TokenThematicBreak:
+			s.closeInline()
+			s.buf = append(s.buf, "<hr"...)
+			s.endVoid()
+			s.buf = append(s.buf, '\n')
+		default:
+			s.text(tok)
+		}
+	}
+	return s.write()
+}
+
+// Flush closes the elements left open and writes nothing else.
+func (s *Stream) Flush() error {
+	if s.err != nil {
+		return s.err
+	}
+	s.buf = s.buf[:0]
+	s.closeInline()
+	s.closeTable()
+	for len(s.closers) > 0 {
+		s.popBlock()
+	}
+	return s.write()
+}
+
+func (s *Stream) write() error {
+	if len(s.buf) == 0 {
+		return nil
+	}
+	if _, err := s.w.Write(s.buf); err != nil {
+		s.err = fmt.Errorf("html: %w", err)
+	}
+	return s.err
+}
+
+func (s *Stream) block(tok mdf.StreamToken) {
+	if s.tableEvent(tok) {
+		return
+	}
+	s.closeInline()
+	s.closeTable()
+	s.pendingBreak = false
+	s.lineStart = true
+	info := tok.Block
+	switch tok.Kind {
+	case mdf.TokenHeadingStart:
+		level := min(max(info.Level, 1), 6)
+		s.buf = fmt.Appendf(s.buf, "<h%d>", level)
+		s.pushBlock(fmt.Sprintf("</h%d>\n", level))
+		s.leaf = true
+	case mdf.TokenParagraphStart:
+		s.buf = append(s.buf, "<p>"...)
+		s.pushBlock("</p>\n")
+		s.leaf = true
+	case mdf.TokenListStart:
+		switch {
+		case !info.Ordered:
+			s.buf = append(s.buf, "<ul>\n"...)
+			s.pushBlock("</ul>\n")
+		case info.Start != 1:
+			s.buf = append(s.buf, "<ol"...)
+			s.attr("start", strconv.Itoa(info.Start))
+			s.buf = append(s.buf, ">\n"...)
+			s.pushBlock("</ol>\n")
+		default:
+			s.buf = append(s.buf, "<ol>\n"...)
+			s.pushBlock("</ol>\n")
+		}
+	case mdf.TokenListItemStart:
+		if info.Task != mdf.TaskNone {
+			s.buf = append(s.buf, `<li class="task">`...)
+		} else {
+			s.buf = append(s.buf, "<li>"...)
+		}
+		s.task = info.Task
+		s.pushBlock("</li>\n")
+	case mdf.TokenBlockquoteStart:
+		s.buf = append(s.buf, "<blockquote>\n"...)
+		s.pushBlock("</blockquote>\n")
+	case mdf.TokenCodeBlockStart:
+		s.buf = append(s.buf, "<pre><code"...)
+		if info.Lang != "" {
+			s.attr("class", "language-"+info.Lang)
+		}
+		s.buf = append(s.buf, '>')
+		s.pushBlock("</code></pre>\n")
+		s.pre = true
+	case mdf.TokenHeadingEnd, mdf.TokenParagraphEnd, mdf.TokenListEnd, mdf.TokenListItemEnd,
+		mdf.TokenBlockquoteEnd, mdf.TokenCodeBlockEnd:
+		s.popBlock()
+	}
+}
+
+func (s *Stream) pushBlock(closer string) {
+	s.closers = append(s.closers, closer)
+}
+
+func (s *Stream) popBlock() {
+	if len(s.closers) == 0 {
+		return
+	}
+	s.held = ""
+	s.buf = append(s.buf, s.closers[len(s.closers)-1]...)
+	s.closers = s.closers[:len(s.closers)-1]
+	s.leaf = false
+	s.pre = false
+	s.task = mdf.TaskNone
+}
+
+func (s *Stream) text(tok mdf.StreamToken) {
+	text := tok.Text
+	if text == "" {
+		return
+	}
+	if s.table.open && !s.table.cell {
+		// Rules, bars and padding the parser draws around the cells.
+		return
+	}
+	set := role.Of(tok.Style)
+	if strings.Trim(text, "\n") == "" {
+		s.newline(text)
+		return
+	}
+	if s.lineStart && s.decoration(tok, set) {
+		return
+	}
+	if s.task != mdf.TaskNone {
+		s.taskBox(text)
+		return
+	}
+	if s.table.cell && strings.TrimSpace(text) == "" {
+		s.held += text
+		return
+	}
+	s.beginInline()
+	if s.pre {
+		s.setInline(codeSpan(set))
+	} else {
+		s.setInline(inlineTags(tok, set, s.table.header))
+	}
+	s.buf = appendEscaped(s.buf, text)
+}
+
+// beginInline ends the line start and writes pending whitespace.
+func (s *Stream) beginInline() {
+	s.lineStart = false
+	if s.held != "" {
+		s.buf = append(s.buf, s.held...)
+		s.held = ""
+	}
+	if s.pendingBreak {
+		s.closeInline()
+		s.buf = append(s.buf, "<br"...)
+		s.endVoid()
+		s.buf = append(s.buf, '\n')
+		s.pendingBreak = false
+	}
+}
+
+func (s *Stream) newline(text string) {
+	s.lineStart = true
+	switch {
+	case s.pre:
+		s.setInline(nil)
+		s.held += text
+	case s.leaf:
+		s.pendingBreak = true
+	}
+}
+
+// decoration reports whether tok is part of what the parser draws at the
+// start of a line: quote bars, list markers, indentation and heading marks.
+func (s *Stream) decoration(tok mdf.StreamToken, set role.Set) bool {
+	if set.Has(role.Quote) || set.Has(role.ListMarker) {
+		return true
+	}
+	if tok.Kind == mdf.TokenCode {
+		return false
+	}
+	if s.pre || strings.TrimSpace(tok.Text) == "" {
+		return true
+	}
+	return set.Has(role.Heading) && strings.Trim(tok.Text, "# ") == "" && strings.HasSuffix(tok.Text, " ")
+}
+
+// taskBox drops the tokens of a task item's "[x]" and writes a checkbox in
+// their place. The space after it is dropped as part of the line start.
+func (s *Stream) taskBox(text string) {
+	if !strings.Contains(text, "]") {
+		return
+	}
+	s.buf = append(s.buf, `<input type="checkbox"`...)
+	s.flag("disabled")
+	if s.task == mdf.TaskChecked {
+		s.flag("checked")
+	}
+	s.endVoid()
+	s.buf = append(s.buf, ' ')
+	s.task = mdf.TaskNone
+}
+
+// tableEvent writes the elements of a table event and reports whether tok
+// was one.
+func (s *Stream) tableEvent(tok mdf.StreamToken) bool {
+	t := &s.table
+	info := tok.Block
+	switch tok.Kind {
+	case mdf.TokenTableStart:
+		s.closeInline()
+		s.closeTable()
+		s.buf = append(s.buf, "<table>\n"...)
+		*t = tableState{open: true}
+	case mdf.TokenTableRowStart:
+		switch {
+		case info.Header && !t.head:
+			s.buf = append(s.buf, "<thead>\n"...)
+			t.head = true
+		case !info.Header && !t.body:
+			if t.head {
+				s.buf = append(s.buf, "</thead>\n"...)
+			}
+			s.buf = append(s.buf, "<tbody>\n"...)
+			t.body = true
+		}
+		s.buf = append(s.buf, "<tr>"...)
+		t.row = true
+		t.header = info.Header
+	case mdf.TokenTableCellStart:
+		s.openCell(info.Align)
+	case mdf.TokenTableCellEnd:
+		if t.cell {
+			s.closeCell()
+		}
+	case mdf.TokenTableRowEnd:
+		if t.row {
+			s.buf = append(s.buf, "</tr>\n"...)
+			t.row = false
+		}
+	case mdf.TokenTableEnd:
+		s.closeTable()
+	default:
+		return false
+	}
+	return true
+}
+
+func (s *Stream) openCell(align mdf.CellAlign) {
+	if s.table.header {
+		s.buf = append(s.buf, "<th"...)
+	} else {
+		s.buf = append(s.buf, "<td"...)
+	}
+	switch align {
+	case mdf.AlignLeft:
+		s.attr("style", "text-align: left")
+	case mdf.AlignCenter:
+		s.attr("style", "text-align: center")
+	case mdf.AlignRight:
+		s.attr("style", "text-align: right")
+	}
+	s.buf = append(s.buf, '>')
+	s.table.cell = true
+	s.lineStart = false
+}
+
+func (s *Stream) closeCell() {
+	s.closeInline()
+	s.held = ""
+	if s.table.header {
+		s.buf = append(s.buf, "</th>"...)
+	} else {
+		s.buf = append(s.buf, "</td>"...)
+	}
+	s.table.cell = false
+}
+
+func (s *Stream) closeTable() {
+	t := &s.table
+	if !t.open {
+		return
+	}
+	if t.cell {
+		s.closeCell()
+	}
+	if t.row {
+		s.buf = append(s.buf, "</tr>\n"...)
+	}
+	switch {
+	case t.body:
+		s.buf = append(s.buf, "</tbody>\n"...)
+	case t.head:
+		s.buf = append(s.buf, "</thead>\n"...)
+	}
+	s.buf = append(s.buf, "</table>\n"...)
+	*t = tableState{}
+}
+
+// inlineTags returns the inline elements for a token, outermost first.
+// Header cells are bold already, so strong is left out of them.
+func inlineTags(tok mdf.StreamToken, set role.Set, header bool) []string {
+	var tags []string
+	if set.Has(role.Strikethrough) {
+		tags = append(tags, "del")
+	}
+	if (set.Has(role.Strong) || set.Has(role.EmphasisStrong)) && !header {
+		tags = append(tags, "strong")
+	}
+	if set.Has(role.Emphasis) || set.Has(role.EmphasisStrong) {
+		tags = append(tags, "em")
+	}
+	if tok.Kind == mdf.TokenCode && !tok.CodeBlock {
+		tags = append(tags, "code")
+	}
+	return tags
+}
+
+// codeSpan returns the highlight span for a token of a code block.
+func codeSpan(set role.Set) []string {
+	for _, c := range codeClasses {
+		if set.Has(c.role) {
+			return []string{`span class="` + c.class + `"`}
+		}
+	}
+	return nil
+}
+
+// setInline closes and opens inline tags so that exactly want is open.
+func (s *Stream) setInline(want []string) {
+	keep := 0
+	for keep < len(want) && keep < len(s.inline) && want[keep] == s.inline[keep] {
+		keep++
+	}
+	for len(s.inline) > keep {
+		s.popInline()
+	}
+	for _, tag := range want[keep:] {
+		s.buf = append(s.buf, '<')
+		s.buf = append(s.buf, tag...)
+		s.buf = append(s.buf, '>')
+		s.inline = append(s.inline, tag)
+	}
+}
+
+func (s *Stream) closeInline() {
+	for len(s.inline) > 0 {
+		s.popInline()
+	}
+}
+
+func (s *Stream) popInline() {
+	tag := s.inline[len(s.inline)-1]
+	if i := strings.IndexByte(tag, ' '); i >= 0 {
+		tag = tag[:i]
+	}
+	s.buf = append(s.buf, "</"...)
+	s.buf = append(s.buf, tag...)
+	s.buf = append(s.buf, '>')
+	s.inline = s.inline[:len(s.inline)-1]
+}
+
+// flag writes a boolean attribute.
+func (s *Stream) flag(name string) {
+	if s.xhtml {
+		s.attr(name, name)
+		return
+	}
+	s.buf = append(s.buf, ' ')
+	s.buf = append(s.buf, name...)
+}
+
+// endVoid ends the start tag of a void element.
+func (s *Stream) endVoid() {
+	if s.xhtml {
+		s.buf = append(s.buf, "/>"...)
+		return
+	}
+	s.buf = append(s.buf, '>')
+}
+
+func (s *Stream) attr(name, value string) {
+	s.buf = append(s.buf, ' ')
+	s.buf = append(s.buf, name...)
+	s.buf = append(s.buf, `="`...)
+	s.buf = appendEscaped(s.buf, value)
+	s.buf = append(s.buf, '"')
+}
+
+// splitLinkURL separates a link title the parser keeps after the
+// destination.
+func splitLinkURL(url string) (href, title string) {
+	i := strings.IndexAny(url, " \t")
+	if i < 0 {
+		return url, ""
+	}
+	title = strings.TrimSpace(url[i:])
+	if len(title) >= 2 {
+		switch title[0] {
+		case '"', '\'':
+			title = strings.Trim(title, title[:1])
+		case '(':
+			title = strings.TrimSuffix(title[1:], ")")
+		}
+	}
+	return url[:i], title
+}
+
+// safeURL returns url unless its scheme can run script, such as
+// javascript:, in which case it returns "".
+func safeURL(url string) string {
+	i := strings.IndexAny(url, ":/?#")
+	if i < 0 || url[i] != ':' {
+		return url
+	}
+	switch strings.ToLower(url[:i]) {
+	case "http", "https", "mailto", "ftp", "tel", "file":
+		return url
+	}
+	return ""
+}
+
+func appendEscaped(dst []byte, s string) []byte {
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '&':
+			dst = append(dst, "&amp;"...)
+		case '<':
+			dst = append(dst, "&lt;"...)
+		case '>':
+			dst = append(dst, "&gt;"...)
+		case '"':
+			dst = append(dst, "&#34;"...)
+		case '\'':
+			dst = append(dst, "&#39;"...)
+		default:
+			dst = append(dst, c)
+		}
+	}
+	return dst
+}
@@ -0,0 +1,157 @@
+package html
+
+import (
+	"fmt"
+	"strings"
+
+	"pkt.systems/mdf"
+	"pkt.systems/mdf/internal/role"
+)
+
+// ParseTheme returns the theme a Stream expects the parser to run with.
+// Pass it as the Theme of an mdf.ParseRequest that feeds a Stream; the
+// colours of the page come from CSS instead.
+func ParseTheme() mdf.Theme {
+	return role.Theme()
+}
+
+// codeClasses maps highlight roles to the classes of their spans inside
+// pre elements.
+var codeClasses = []struct {
+	role  role.Role
+	class string
+}{
+	{role.CodeKeyword, "kw"},
+	{role.CodeString, "str"},
+	{role.CodeComment, "com"},
+	{role.CodeNumber, "num"},
+	{role.CodeType, "typ"},
+	{role.CodePunctuation, "pun"},
+}
+
+// CSS returns a stylesheet that colours rendered HTML like theme colours
+// the terminal. Rules are scoped to the "mdf" class of the element Render
+// wraps its output in; cfg gives the page colours.
+func CSS(theme mdf.Theme, cfg Config) string {
+	if theme == nil {
+		theme = mdf.DefaultTheme()
+	}
+	def := DefaultConfig()
+	applyConfig(&def, cfg)
+	cfg = def
+	st := theme.Styles()
+	var b strings.Builder
+	// Without a background the page's own colours are kept.
+	var root []string
+	if cfg.BackgroundEnabled {
+		root = append(root, "color: "+rgbValue(cfg.TextRGB), "background-color: "+rgbValue(cfg.BackgroundRGB))
+	}
+	root = append(root, declarations(st.Text)...)
+	rule(&b, ".mdf", root...)
+	for i, h := range st.Heading {
+		rule(&b, fmt.Sprintf(".mdf h%d", i+1), declarations(h)...)
+	}
+	rule(&b, ".mdf em", declarations(st.Emphasis)...)
+	rule(&b, ".mdf strong", declarations(st.Strong)...)
+	rule(&b, ".mdf strong em", declarations(st.EmphasisStrong)...)
+	rule(&b, ".mdf del", declarations(st.Strikethrough)...)
+	rule(&b, ".mdf code", declarations(st.CodeInline)...)
+	rule(&b, ".mdf pre", append(declarations(st.CodeBlock), "overflow-x: auto")...)
+	rule(&b, ".mdf pre code", "color: inherit", "background-color: transparent")
+	for _, c := range codeClasses {
+		rule(&b, ".mdf pre ."+c.class, declarations(roleStyle(st, c.role))...)
+	}
+	quote := []string{"margin-left: 0", "padding-left: 1em"}
+	quote = append(quote, "border-left: 0.25em solid "+colorOr(st.Quote.FG, "currentColor"))
+	rule(&b, ".mdf blockquote", quote...)
+	rule(&b, ".mdf li::marker", declarations(st.ListMarker)...)
+	rule(&b, ".mdf li.task", "list-style: none")
+	rule(&b, ".mdf a", declarations(st.LinkText)...)
+	rule(&b, ".mdf img", "max-width: 100%")
+	rule(&b, ".mdf hr", "border: 0", "border-top: 1px solid "+colorOr(st.ThematicBreak.FG, "currentColor"))
+	rule(&b, ".mdf table", "border-collapse: collapse")
+	rule(&b, ".mdf th, .mdf td", "padding: 0.2em 0.6em", "border: 1px solid "+colorOr(st.ThematicBreak.FG, "currentColor"))
+	return b.String()
+}
+
+func roleStyle(st mdf.Styles, r role.Role) mdf.Style {
+	var s mdf.Style
+	switch r {
+	case role.CodeKeyword:
+		s = st.CodeKeyword
+	case role.CodeString:
+		s = st.CodeString
+	case role.CodeComment:
+		s = st.CodeComment
+	case role.CodeNumber:
+		s = st.CodeNumber
+	case role.CodeType:
+		s = st.CodeType
+	case role.CodePunctuation:
+		s = st.CodePunctuation
+	}
+	if s == (mdf.Style{}) {
+		return st.CodeBlock
+	}
+	return s
+}
+
+func rule(b *strings.Builder, selector string, decls ...string) {
+	if len(decls) == 0 {
+		return
+	}
+	b.WriteString(selector)
+	b.WriteString(" { ")
+	b.WriteString(strings.Join(decls, "; "))
+	b.WriteString(" }\n")
+}
+
+// declarations returns the CSS declarations for the colours and attributes
+// of s. Reverse video swaps the colours it sets.
+func declarations(s mdf.Style) []string {
+	fg, bg := s.FG, s.BG
+	if s.Reverse {
+		fg, bg = bg, fg
+	}
+	var out []string
+	if v := colorOr(fg, ""); v != "" {
+		out = append(out, "color: "+v)
+	}
+	if v := colorOr(bg, ""); v != "" {
+		out = append(out, "background-color: "+v)
+	}
+	if s.Bold {
+		out = append(out, "font-weight: bold")
+	}
+	if s.Italic {
+		out = append(out, "font-style: italic")
+	}
+	switch {
+	case s.Underline && s.Strikethrough:
+		out = append(out, "text-decoration: underline line-through")
+	case s.Underline:
+		out = append(out, "text-decoration: underline")
+	case s.Strikethrough:
+		out = append(out, "text-decoration: line-through")
+	}
+	if s.Dim {
+		out = append(out, "opacity: 0.7")
+	}
+	return out
+}
+
+func colorOr(c mdf.Color, fallback string) string {
+	r, g, b, ok := c.RGB()
+	if !ok {
+		return fallback
+	}
+	return fmt.Sprintf("#%02x%02x%02x", r, g, b)
+}
+
+func rgbValue(rgb [3]int) string {
+	return fmt.Sprintf("#%02x%02x%02x", clamp(rgb[0]), clamp(rgb[1]), clamp(rgb[2]))
+}
+
+func clamp(v int) int {
+	return min(max(v, 0), 255)
+}
@@ -55,7 +55,12 @@ func TestIntegrationRenderPlainAndANSI(t *testing.T) {
 		"1. ordered one",
 		"2. ordered two",
 		"",
-		"| Col A | Col B | | --- | --- | | A1 | B1 | | A2 | B2 |",
+		"┌───────┬───────┐",
+		"│ Col A │ Col B │",
+		"├───────┼───────┤",
+		"│ A1    │ B1    │",
+		"│ A2    │ B2    │",
+		"└───────┴───────┘",
 		"",
 		"site (https://example.com)",
 		"",
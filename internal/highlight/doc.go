@@ -0,0 +1,3 @@
+// Package highlight classifies source code, one line at a time, for syntax
+// highlighting of fenced code blocks.
+package highlight
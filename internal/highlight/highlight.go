@@ -0,0 +1,257 @@
+package highlight
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Class is the syntactic category of a span.
+type Class uint8
+
+// Span classes.
+const (
+	Plain Class = iota
+	Keyword
+	String
+	Comment
+	Number
+	Type
+	Punctuation
+)
+
+// Span is a classified run of text within a line.
+type Span struct {
+	Text  string
+	Class Class
+}
+
+// Highlighter classifies lines of a single code block. Strings and comments
+// left open at the end of a line carry over to the next one.
+type Highlighter struct {
+	lang *language
+	// comment is set inside an unterminated block comment.
+	comment bool
+	// quote holds the closing delimiter of an unterminated string.
+	quote string
+	// base, start and end track the last span appended by the current Line
+	// call so adjacent spans of one class are merged.
+	base  int
+	start int
+	end   int
+}
+
+// Reset selects the language named by a fence info string and clears any
+// carried state. It reports whether the language is supported; lines of an
+// unsupported language come back as a single Plain span.
+func (h *Highlighter) Reset(info string) bool {
+	h.lang = lookup(info)
+	h.comment = false
+	h.quote = ""
+	return h.lang != nil
+}
+
+// Line appends the spans of line to dst. The span texts concatenate back to
+// line.
+func (h *Highlighter) Line(line string, dst []Span) []Span {
+	if line == "" {
+		return dst
+	}
+	if h.lang == nil {
+		return append(dst, Span{Text: line, Class: Plain})
+	}
+	h.base = len(dst)
+	if h.lang.line != nil {
+		return h.lang.line(h, line, dst)
+	}
+	return h.scan(line, 0, len(line), dst)
+}
+
+// lookup returns the language for the first word of a fence info string.
+func lookup(info string) *language {
+	name := info
+	if i := strings.IndexAny(name, " \t{,"); i >= 0 {
+		name = name[:i]
+	}
+	name = strings.ToLower(strings.TrimPrefix(name, "."))
+	return languages[name]
+}
+
+// scan classifies line[start:end] with the generic lexer.
+func (h *Highlighter) scan(line string, start int, end int, dst []Span) []Span {
+	lang := h.lang
+	i := start
+	if h.comment {
+		close := strings.Index(line[i:end], lang.blockComment[1])
+		if close < 0 {
+			return h.add(dst, line, i, end, Comment)
+		}
+		h.comment = false
+		next := i + close + len(lang.blockComment[1])
+		dst = h.add(dst, line, i, next, Comment)
+		i = next
+	}
+	if h.quote != "" {
+		next, closed := scanString(line, i, end, h.quote, lang.escapes)
+		dst = h.add(dst, line, i, next, String)
+		if !closed {
+			return dst
+		}
+		h.quote = ""
+		i = next
+	}
+	for i < end {
+		c := line[i]
+		switch {
+		case c == ' ' || c == '\t':
+			j := i + 1
+			for j < end && (line[j] == ' ' || line[j] == '\t') {
+				j++
+			}
+			dst = h.add(dst, line, i, j, Plain)
+			i = j
+		case lang.isLineComment(line, i, end):
+			return h.add(dst, line, i, end, Comment)
+		case lang.blockComment[0] != "" && strings.HasPrefix(line[i:end], lang.blockComment[0]):
+			open := i + len(lang.blockComment[0])
+			close := strings.Index(line[open:end], lang.blockComment[1])
+			if close < 0 {
+				h.comment = true
+				return h.add(dst, line, i, end, Comment)
+			}
+			next := open + close + len(lang.blockComment[1])
+			dst = h.add(dst, line, i, next, Comment)
+			i = next
+		case strings.IndexByte(lang.quotes, c) >= 0:
+			delim := line[i : i+1]
+			if lang.tripleQuotes && strings.HasPrefix(line[i:end], strings.Repeat(delim, 3)) {
+				delim = line[i : i+3]
+			}
+			next, closed := scanString(line, i+len(delim), end, delim, lang.escapes && c != '`')
+			class := String
+			if lang.keys && lang.followedByColon(line, next, end) {
+				class = Type
+			}
+			dst = h.add(dst, line, i, next, class)
+			if !closed && (len(delim) == 3 || strings.IndexByte(lang.multiline, c) >= 0) {
+				// line may be reused for the next one.
+				h.quote = strings.Clone(delim)
+			}
+			i = next
+		case isDigit(c) || c == '.' && i+1 < end && isDigit(line[i+1]):
+			j := scanNumber(line, i, end)
+			dst = h.add(dst, line, i, j, Number)
+			i = j
+		case c == '$' && lang.variables:
+			j := scanVariable(line, i, end)
+			dst = h.add(dst, line, i, j, Type)
+			i = j
+		case isIdentStart(line, i, lang.identChars):
+			j := scanIdent(line, i, end, lang.identChars)
+			dst = h.add(dst, line, i, j, lang.classify(line[i:j], line, j, end))
+			i = j
+		case c < utf8.RuneSelf && strings.IndexByte(punctuation, c) >= 0:
+			dst = h.add(dst, line, i, i+1, Punctuation)
+			i++
+		default:
+			_, size := utf8.DecodeRuneInString(line[i:end])
+			dst = h.add(dst, line, i, i+size, Plain)
+			i += size
+		}
+	}
+	return dst
+}
+
+const punctuation = "!%&()*+,-./:;<=>?@[\\]^{|}~#$"
+
+// add appends line[i:j] as a span, extending the previous span when it has
+// the same class and ends at i.
+func (h *Highlighter) add(dst []Span, line string, i int, j int, class Class) []Span {
+	if i == j {
+		return dst
+	}
+	if n := len(dst); n > h.base && dst[n-1].Class == class && h.end == i {
+		dst[n-1].Text = line[h.start:j]
+		h.end = j
+		return dst
+	}
+	h.start, h.end = i, j
+	return append(dst, Span{Text: line[i:j], Class: class})
+}
+
+// scanString returns the offset just past the closing delimiter, or end when
+// the string runs to the end of the line.
+func scanString(line string, i int, end int, delim string, escapes bool) (int, bool) {
+	for i < end {
+		if escapes && line[i] == '\\' {
+			i += 2
+			continue
+		}
+		if strings.HasPrefix(line[i:end], delim) {
+			return i + len(delim), true
+		}
+		i++
+	}
+	return end, false
+}
+
+func scanNumber(line string, i int, end int) int {
+	hex := strings.HasPrefix(line[i:end], "0x") || strings.HasPrefix(line[i:end], "0X")
+	j := i + 1
+	for j < end {
+		c := line[j]
+		switch {
+		case isDigit(c) || c == '_' || c == '.' || isLetter(c):
+		case (c == '+' || c == '-') && !hex && (line[j-1] == 'e' || line[j-1] == 'E'):
+		default:
+			return j
+		}
+		j++
+	}
+	return j
+}
+
+// scanVariable scans a shell parameter such as $HOME, ${name} or $1.
+func scanVariable(line string, i int, end int) int {
+	j := i + 1
+	if j >= end {
+		return j
+	}
+	switch c := line[j]; {
+	case c == '{':
+		if close := strings.IndexByte(line[j:end], '}'); close >= 0 {
+			return j + close + 1
+		}
+		return end
+	case isLetter(c) || c == '_':
+		return scanIdent(line, j, end, "")
+	case isDigit(c) || strings.IndexByte("@*#?$!-", c) >= 0:
+		return j + 1
+	}
+	return j
+}
+
+func scanIdent(line string, i int, end int, extra string) int {
+	j := i
+	for j < end {
+		r, size := utf8.DecodeRuneInString(line[j:end])
+		if r != '_' && !unicode.IsLetter(r) && !unicode.IsDigit(r) && !strings.ContainsRune(extra, r) {
+			break
+		}
+		j += size
+	}
+	return j
+}
+
+func isIdentStart(line string, i int, extra string) bool {
+	r, _ := utf8.DecodeRuneInString(line[i:])
+	return r == '_' || unicode.IsLetter(r) || strings.ContainsRune(extra, r)
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isLetter(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
@@ -0,0 +1,128 @@
+package highlight
+
+import (
+	"strings"
+	"testing"
+	"unsafe"
+)
+
+// render marks every non-plain span as class:text for compact comparisons.
+func render(spans []Span) string {
+	names := [...]string{"", "kw", "str", "com", "num", "type", "p"}
+	var b strings.Builder
+	for _, s := range spans {
+		if s.Class == Plain {
+			b.WriteString(s.Text)
+			continue
+		}
+		b.WriteString("<" + names[s.Class] + ":" + s.Text + ">")
+	}
+	return b.String()
+}
+
+func TestHighlightLanguages(t *testing.T) {
+	cases := []struct {
+		info string
+		src  string
+		want string
+	}{
+		{"go", `func f(n int) error { return nil } // done`,
+			`<kw:func> f<p:(>n <type:int><p:)> <type:error> <p:{> <kw:return> <kw:nil> <p:}> <com:// done>`},
+		{"go", "s := `raw` + \"q\\\"\" + 'x' + 0x1F",
+			"s <p::=> <str:`raw`> <p:+> <str:\"q\\\"\"> <p:+> <str:'x'> <p:+> <num:0x1F>"},
+		{"bash", `if [ -n "$X" ]; then echo ${HOME}#x; fi # note`,
+			`<kw:if> <p:[> <p:->n <str:"$X"> <p:];> <kw:then> <kw:echo> <type:${HOME}><p:#>x<p:;> <kw:fi> <com:# note>`},
+		{"json", `{"name": "mdf", "n": -1.5e+3, "ok": true}`,
+			`<p:{><type:"name"><p::> <str:"mdf"><p:,> <type:"n"><p::> <p:-><num:1.5e+3><p:,> <type:"ok"><p::> <kw:true><p:}>`},
+		{"yaml", `  - name: mdf # tool`,
+			`  <p:- ><type:name><p::> <str:mdf> <com:# tool>`},
+		{"yml", `port: 8080`, `<type:port><p::> <num:8080>`},
+		{"python", `def f(x: int) -> None:  # hi`,
+			`<kw:def> f<p:(>x<p::> <type:int><p:)> <p:->> <kw:None><p::>  <com:# hi>`},
+		{"ts", `const $el: string = await f()`,
+			`<kw:const> $el<p::> <type:string> <p:=> <kw:await> f<p:()>`},
+		{"SQL", `SELECT id FROM t WHERE n > 2 -- x`,
+			`<kw:SELECT> id <kw:FROM> t <kw:WHERE> n <p:>> <num:2> <com:-- x>`},
+		{"diff", "+added", "<str:+added>"},
+		{"diff", "@@ -1 +1 @@", "<kw:@@ -1 +1 @@>"},
+		{"unknown", "func x", "func x"},
+	}
+	for _, tc := range cases {
+		var h Highlighter
+		h.Reset(tc.info)
+		spans := h.Line(tc.src, nil)
+		if got := render(spans); got != tc.want {
+			t.Fatalf("%s %q:\n got %s\nwant %s", tc.info, tc.src, got, tc.want)
+		}
+		var joined strings.Builder
+		for _, s := range spans {
+			joined.WriteString(s.Text)
+		}
+		if joined.String() != tc.src {
+			t.Fatalf("%s: spans do not cover the line: %q", tc.info, joined.String())
+		}
+	}
+}
+
+func TestHighlightCarriesStateAcrossLines(t *testing.T) {
+	cases := []struct {
+		info  string
+		lines []string
+		want  []string
+	}{
+		{"go", []string{"/* a", "b */ x", "s := `one", "two` + 1"},
+			[]string{"<com:/* a>", "<com:b */> x", "s <p::=> <str:`one>", "<str:two`> <p:+> <num:1>"}},
+		{"python", []string{`x = """doc`, `end""" # c`},
+			[]string{`x <p:=> <str:"""doc>`, `<str:end"""> <com:# c>`}},
+	}
+	for _, tc := range cases {
+		var h Highlighter
+		if !h.Reset(tc.info) {
+			t.Fatalf("%s: unsupported", tc.info)
+		}
+		for i, line := range tc.lines {
+			if got := render(h.Line(line, nil)); got != tc.want[i] {
+				t.Fatalf("%s line %d:\n got %s\nwant %s", tc.info, i, got, tc.want[i])
+			}
+		}
+	}
+}
+
+func TestHighlightCarriesStateOverReusedBuffer(t *testing.T) {
+	cases := []struct {
+		info  string
+		lines []string
+		want  []string
+	}{
+		{"go", []string{"x := `raw", "still doc`", "y"},
+			[]string{"x <p::=> <str:`raw>", "<str:still doc`>", "y"}},
+		{"python", []string{`x = """doc`, `more`, `end""" + y`},
+			[]string{`x <p:=> <str:"""doc>`, `<str:more>`, `<str:end"""> <p:+> y`}},
+	}
+	for _, tc := range cases {
+		var h Highlighter
+		h.Reset(tc.info)
+		buf := make([]byte, 0, 64)
+		for i, line := range tc.lines {
+			// The parser hands over each line in the buffer of the last.
+			buf = append(buf[:0], line...)
+			if got := render(h.Line(unsafe.String(&buf[0], len(buf)), nil)); got != tc.want[i] {
+				t.Fatalf("%s line %d:\n got %s\nwant %s", tc.info, i, got, tc.want[i])
+			}
+		}
+	}
+}
+
+func TestHighlightInfoString(t *testing.T) {
+	var h Highlighter
+	for _, info := range []string{"go", "Go", "go title=x", ".python", "js{1,3}", "shell,linenos"} {
+		if !h.Reset(info) {
+			t.Fatalf("expected %q to be supported", info)
+		}
+	}
+	for _, info := range []string{"", "text", "brainfuck"} {
+		if h.Reset(info) {
+			t.Fatalf("expected %q to be unsupported", info)
+		}
+	}
+}
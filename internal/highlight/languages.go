@@ -0,0 +1,271 @@
+package highlight
+
+import "strings"
+
+// language describes the lexical rules the generic lexer needs. Languages
+// that are line oriented rather than token oriented set line instead.
+type language struct {
+	keywords map[string]bool
+	types    map[string]bool
+	// foldCase matches keywords and types case-insensitively.
+	foldCase bool
+	// lineComments start a comment running to the end of the line.
+	lineComments []string
+	// wordComments only start a comment at the beginning of a word, as '#'
+	// in shell.
+	wordComments bool
+	blockComment [2]string
+	// quotes are the string delimiters; those also in multiline may span
+	// lines.
+	quotes       string
+	multiline    string
+	tripleQuotes bool
+	escapes      bool
+	// variables highlights shell parameters such as $HOME as types.
+	variables bool
+	// keys highlights object keys, a word or string followed by ':', as
+	// types.
+	keys bool
+	// identChars are extra runes allowed in identifiers.
+	identChars string
+	line       func(h *Highlighter, line string, dst []Span) []Span
+}
+
+func (l *language) isLineComment(line string, i int, end int) bool {
+	for _, prefix := range l.lineComments {
+		if !strings.HasPrefix(line[i:end], prefix) {
+			continue
+		}
+		if l.wordComments && i > 0 && line[i-1] != ' ' && line[i-1] != '\t' && line[i-1] != ';' {
+			continue
+		}
+		return true
+	}
+	return false
+}
+
+func (l *language) classify(word string, line string, next int, end int) Class {
+	if l.keys && l.followedByColon(line, next, end) {
+		return Type
+	}
+	if l.foldCase {
+		word = strings.ToLower(word)
+	}
+	switch {
+	case l.keywords[word]:
+		return Keyword
+	case l.types[word]:
+		return Type
+	}
+	return Plain
+}
+
+func (l *language) followedByColon(line string, i int, end int) bool {
+	for i < end && (line[i] == ' ' || line[i] == '\t') {
+		i++
+	}
+	return i < end && line[i] == ':'
+}
+
+func words(list string) map[string]bool {
+	set := make(map[string]bool)
+	for _, w := range strings.Fields(list) {
+		set[w] = true
+	}
+	return set
+}
+
+var (
+	langGo = &language{
+		keywords:     words("break case chan const continue default defer else fallthrough for func go goto if import interface map package range return select struct switch type var true false nil iota"),
+		types:        words("any bool byte comparable complex64 complex128 error float32 float64 int int8 int16 int32 int64 rune string uint uint8 uint16 uint32 uint64 uintptr"),
+		lineComments: []string{"//"},
+		blockComment: [2]string{"/*", "*/"},
+		quotes:       "\"'`",
+		multiline:    "`",
+		escapes:      true,
+	}
+
+	langShell = &language{
+		keywords: words("if then else elif fi case esac for select while until do done in function time return exit break continue " +
+			"export local readonly declare typeset unset set shift source alias eval exec trap read echo printf cd test"),
+		lineComments: []string{"#"},
+		wordComments: true,
+		quotes:       "\"'`",
+		multiline:    "\"'`",
+		escapes:      true,
+		variables:    true,
+	}
+
+	langJSON = &language{
+		keywords:     words("true false null"),
+		lineComments: []string{"//"},
+		blockComment: [2]string{"/*", "*/"},
+		quotes:       "\"",
+		escapes:      true,
+		keys:         true,
+	}
+
+	langPython = &language{
+		keywords: words("and as assert async await break class continue def del elif else except finally for from global if import in is lambda match case nonlocal not or pass raise return try while with yield True False None self"),
+		types: words("bool bytearray bytes complex dict float frozenset int list object range set str tuple type " +
+			"Exception ValueError TypeError KeyError IndexError RuntimeError"),
+		lineComments: []string{"#"},
+		quotes:       "\"'",
+		tripleQuotes: true,
+		escapes:      true,
+	}
+
+	langJS = &language{
+		keywords: words("async await break case catch class const continue debugger default delete do else export extends finally for from function get if import in instanceof let new of return set static super switch this throw try typeof var void while with yield true false null undefined " +
+			"abstract as declare enum implements interface keyof namespace private protected public readonly satisfies type"),
+		types:        words("any bigint boolean never number object string symbol unknown Array Map Set Promise Record Partial Error Date RegExp"),
+		lineComments: []string{"//"},
+		blockComment: [2]string{"/*", "*/"},
+		quotes:       "\"'`",
+		multiline:    "`",
+		escapes:      true,
+		identChars:   "$",
+	}
+
+	langSQL = &language{
+		keywords:     words("add all alter and any as asc begin between by case check column commit constraint create cross database default delete desc distinct drop else end exists foreign from full group having if in index inner insert into is join key left like limit not null offset on or order outer primary references returning right rollback select set table then transaction true false union unique update using values view when where with"),
+		types:        words("bigint binary bit blob boolean char date datetime decimal double float int integer interval json jsonb numeric real serial smallint text time timestamp uuid varchar"),
+		foldCase:     true,
+		lineComments: []string{"--"},
+		blockComment: [2]string{"/*", "*/"},
+		quotes:       "'\"`",
+		escapes:      true,
+	}
+
+	langYAML = &language{
+		keywords:     words("true false null yes no on off True False Null Yes No On Off TRUE FALSE NULL ~"),
+		lineComments: []string{"#"},
+		wordComments: true,
+		quotes:       "\"'",
+		escapes:      true,
+		keys:         true,
+		line:         yamlLine,
+	}
+
+	langDiff = &language{line: diffLine}
+)
+
+var languages = map[string]*language{
+	"go":         langGo,
+	"golang":     langGo,
+	"sh":         langShell,
+	"bash":       langShell,
+	"shell":      langShell,
+	"zsh":        langShell,
+	"ksh":        langShell,
+	"json":       langJSON,
+	"jsonc":      langJSON,
+	"yaml":       langYAML,
+	"yml":        langYAML,
+	"python":     langPython,
+	"py":         langPython,
+	"python3":    langPython,
+	"javascript": langJS,
+	"js":         langJS,
+	"jsx":        langJS,
+	"mjs":        langJS,
+	"typescript": langJS,
+	"ts":         langJS,
+	"tsx":        langJS,
+	"sql":        langSQL,
+	"diff":       langDiff,
+	"patch":      langDiff,
+}
+
+// diffLine classifies a whole line by its leading marker: file headers as
+// types, hunk headers as keywords, additions as strings and removals as
+// numbers, so each gets a distinct themed colour.
+func diffLine(h *Highlighter, line string, dst []Span) []Span {
+	class := Plain
+	switch {
+	case strings.HasPrefix(line, "+++"), strings.HasPrefix(line, "---"),
+		strings.HasPrefix(line, "diff "), strings.HasPrefix(line, "index "):
+		class = Type
+	case strings.HasPrefix(line, "@@"):
+		class = Keyword
+	case line[0] == '+' || line[0] == '>':
+		class = String
+	case line[0] == '-' || line[0] == '<':
+		class = Number
+	case line[0] == '\\':
+		class = Comment
+	}
+	return h.add(dst, line, 0, len(line), class)
+}
+
+// yamlLine classifies a mapping key and its plain scalar value, handing
+// quoted and flow values to the generic lexer.
+func yamlLine(h *Highlighter, line string, dst []Span) []Span {
+	if h.quote != "" {
+		return h.scan(line, 0, len(line), dst)
+	}
+	end := len(line)
+	i := 0
+	for i < end && (line[i] == ' ' || line[i] == '\t') {
+		i++
+	}
+	dst = h.add(dst, line, 0, i, Plain)
+	if rest := line[i:]; rest == "---" || rest == "..." {
+		return h.add(dst, line, i, end, Punctuation)
+	}
+	for i+1 < end && line[i] == '-' && line[i+1] == ' ' {
+		dst = h.add(dst, line, i, i+2, Punctuation)
+		i += 2
+	}
+	if i < end && line[i] != '#' && line[i] != '"' && line[i] != '\'' && line[i] != '[' && line[i] != '{' {
+		if colon := yamlKeyEnd(line, i); colon > 0 {
+			dst = h.add(dst, line, i, colon, Type)
+			dst = h.add(dst, line, colon, colon+1, Punctuation)
+			i = colon + 1
+			for i < end && (line[i] == ' ' || line[i] == '\t') {
+				i++
+			}
+			dst = h.add(dst, line, colon+1, i, Plain)
+		}
+	}
+	if i >= end {
+		return dst
+	}
+	switch line[i] {
+	case '"', '\'', '[', '{', '#', '|', '>', '&', '*', '!':
+		return h.scan(line, i, end, dst)
+	}
+	value := end
+	if c := strings.Index(line[i:], " #"); c >= 0 {
+		value = i + c
+	}
+	scalar := strings.TrimRight(line[i:value], " \t")
+	class := String
+	switch {
+	case h.lang.keywords[scalar]:
+		class = Keyword
+	case scalar != "" && (isDigit(scalar[0]) || len(scalar) > 1 && (scalar[0] == '-' || scalar[0] == '.') && isDigit(scalar[1])) && scanNumber(scalar, 0, len(scalar)) == len(scalar):
+		class = Number
+	}
+	dst = h.add(dst, line, i, i+len(scalar), class)
+	return h.scan(line, i+len(scalar), end, dst)
+}
+
+// yamlKeyEnd returns the offset of the ':' ending a plain mapping key that
+// starts at i, or -1.
+func yamlKeyEnd(line string, i int) int {
+	for j := i; j < len(line); j++ {
+		switch line[j] {
+		case ':':
+			if j+1 == len(line) || line[j+1] == ' ' || line[j+1] == '\t' {
+				return j
+			}
+		case '#':
+			if j > i && line[j-1] == ' ' {
+				return -1
+			}
+		}
+	}
+	return -1
+}
@@ -7,6 +7,7 @@ const (
 	Faint     = "\x1b[90m"
 	Italic    = "\x1b[3m"
 	Underline = "\x1b[4m"
+	Strike    = "\x1b[9m"
 )
 
 // Palette defines colors for Markdown elements.
@@ -28,647 +29,847 @@ type Palette struct {
 	LinkText       string
 	LinkURL        string
 	ThematicBreak  string
+	Strikethrough  string
+	// Syntax highlighting roles for fenced code blocks.
+	CodeKeyword     string
+	CodeString      string
+	CodeComment     string
+	CodeNumber      string
+	CodeType        string
+	CodePunctuation string
 }
 
 // Built-in palettes.
 var (
 	PaletteDefault = Palette{
-		Text:           "",
-		H1:             "\x1b[1;32m",
-		H2:             "\x1b[1;34m",
-		H3:             "\x1b[1;35m",
-		H4:             "\x1b[33m",
-		H5:             "\x1b[36m",
-		H6:             "\x1b[37m",
-		Emphasis:       "\x1b[34m",
-		Strong:         "\x1b[1;37m",
-		EmphasisStrong: "\x1b[1;35m",
-		CodeInline:     "\x1b[35m",
-		CodeBlock:      "\x1b[32m",
-		Quote:          Faint,
-		ListMarker:     "\x1b[36m",
-		LinkText:       "\x1b[1;34m",
-		LinkURL:        "\x1b[90m",
-		ThematicBreak:  Faint,
+		Text:            "",
+		H1:              "\x1b[1;32m",
+		H2:              "\x1b[1;34m",
+		H3:              "\x1b[1;35m",
+		H4:              "\x1b[33m",
+		H5:              "\x1b[36m",
+		H6:              "\x1b[37m",
+		Emphasis:        "\x1b[34m",
+		Strong:          "\x1b[1;37m",
+		EmphasisStrong:  "\x1b[1;35m",
+		CodeInline:      "\x1b[35m",
+		CodeBlock:       "\x1b[32m",
+		Quote:           Faint,
+		ListMarker:      "\x1b[36m",
+		LinkText:        "\x1b[1;34m",
+		LinkURL:         "\x1b[90m",
+		ThematicBreak:   Faint,
+		CodeKeyword:     "\x1b[35m",
+		CodeString:      "\x1b[33m",
+		CodeComment:     Faint,
+		CodeNumber:      "\x1b[36m",
+		CodeType:        "\x1b[34m",
+		CodePunctuation: "\x1b[32m",
 	}
 
 	PaletteOutrunElectric = Palette{
-		Text:           "",
-		H1:             "\x1b[1;38;5;219m",
-		H2:             "\x1b[1;38;5;81m",
-		H3:             "\x1b[1;38;5;201m",
-		H4:             "\x1b[38;5;69m",
-		H5:             "\x1b[38;5;99m",
-		H6:             "\x1b[38;5;117m",
-		Emphasis:       "\x1b[38;5;81m",
-		Strong:         "\x1b[1;38;5;45m",
-		EmphasisStrong: "\x1b[1;38;5;205m",
-		CodeInline:     "\x1b[38;5;39m",
-		CodeBlock:      "\x1b[38;5;33m",
-		Quote:          "\x1b[38;5;59m",
-		ListMarker:     "\x1b[38;5;201m",
-		LinkText:       "\x1b[1;38;5;81m",
-		LinkURL:        "\x1b[38;5;117m",
-		ThematicBreak:  "\x1b[38;5;60m",
+		Text:            "",
+		H1:              "\x1b[1;38;5;219m",
+		H2:              "\x1b[1;38;5;81m",
+		H3:              "\x1b[1;38;5;201m",
+		H4:              "\x1b[38;5;69m",
+		H5:              "\x1b[38;5;99m",
+		H6:              "\x1b[38;5;117m",
+		Emphasis:        "\x1b[38;5;81m",
+		Strong:          "\x1b[1;38;5;45m",
+		EmphasisStrong:  "\x1b[1;38;5;205m",
+		CodeInline:      "\x1b[38;5;39m",
+		CodeBlock:       "\x1b[38;5;33m",
+		Quote:           "\x1b[38;5;59m",
+		ListMarker:      "\x1b[38;5;201m",
+		LinkText:        "\x1b[1;38;5;81m",
+		LinkURL:         "\x1b[38;5;117m",
+		ThematicBreak:   "\x1b[38;5;60m",
+		CodeKeyword:     "\x1b[38;5;201m",
+		CodeString:      "\x1b[38;5;81m",
+		CodeComment:     "\x1b[38;5;59m",
+		CodeNumber:      "\x1b[38;5;219m",
+		CodeType:        "\x1b[38;5;99m",
+		CodePunctuation: "\x1b[38;5;33m",
 	}
 
 	PaletteDoomIosvkem = Palette{
-		Text:           "",
-		H1:             "\x1b[1;38;5;223m",
-		H2:             "\x1b[1;38;5;222m",
-		H3:             "\x1b[1;38;5;216m",
-		H4:             "\x1b[38;5;114m",
-		H5:             "\x1b[38;5;109m",
-		H6:             "\x1b[38;5;151m",
-		Emphasis:       "\x1b[38;5;109m",
-		Strong:         "\x1b[1;38;5;114m",
-		EmphasisStrong: "\x1b[1;38;5;208m",
-		CodeInline:     "\x1b[38;5;72m",
-		CodeBlock:      "\x1b[38;5;66m",
-		Quote:          "\x1b[38;5;240m",
-		ListMarker:     "\x1b[38;5;67m",
-		LinkText:       "\x1b[1;38;5;216m",
-		LinkURL:        "\x1b[38;5;242m",
-		ThematicBreak:  "\x1b[38;5;244m",
+		Text:            "",
+		H1:              "\x1b[1;38;5;223m",
+		H2:              "\x1b[1;38;5;222m",
+		H3:              "\x1b[1;38;5;216m",
+		H4:              "\x1b[38;5;114m",
+		H5:              "\x1b[38;5;109m",
+		H6:              "\x1b[38;5;151m",
+		Emphasis:        "\x1b[38;5;109m",
+		Strong:          "\x1b[1;38;5;114m",
+		EmphasisStrong:  "\x1b[1;38;5;208m",
+		CodeInline:      "\x1b[38;5;72m",
+		CodeBlock:       "\x1b[38;5;66m",
+		Quote:           "\x1b[38;5;240m",
+		ListMarker:      "\x1b[38;5;67m",
+		LinkText:        "\x1b[1;38;5;216m",
+		LinkURL:         "\x1b[38;5;242m",
+		ThematicBreak:   "\x1b[38;5;244m",
+		CodeKeyword:     "\x1b[38;5;216m",
+		CodeString:      "\x1b[38;5;114m",
+		CodeComment:     "\x1b[38;5;240m",
+		CodeNumber:      "\x1b[38;5;222m",
+		CodeType:        "\x1b[38;5;109m",
+		CodePunctuation: "\x1b[38;5;66m",
 	}
 
 	PaletteDoomGruvbox = Palette{
-		Text:           "",
-		H1:             "\x1b[1;38;5;221m",
-		H2:             "\x1b[1;38;5;214m",
-		H3:             "\x1b[1;38;5;178m",
-		H4:             "\x1b[38;5;108m",
-		H5:             "\x1b[38;5;142m",
-		H6:             "\x1b[38;5;208m",
-		Emphasis:       "\x1b[38;5;178m",
-		Strong:         "\x1b[1;38;5;214m",
-		EmphasisStrong: "\x1b[1;38;5;167m",
-		CodeInline:     "\x1b[38;5;72m",
-		CodeBlock:      "\x1b[38;5;66m",
-		Quote:          "\x1b[38;5;95m",
-		ListMarker:     "\x1b[38;5;172m",
-		LinkText:       "\x1b[1;38;5;178m",
-		LinkURL:        "\x1b[38;5;137m",
-		ThematicBreak:  "\x1b[38;5;101m",
+		Text:            "",
+		H1:              "\x1b[1;38;5;221m",
+		H2:              "\x1b[1;38;5;214m",
+		H3:              "\x1b[1;38;5;178m",
+		H4:              "\x1b[38;5;108m",
+		H5:              "\x1b[38;5;142m",
+		H6:              "\x1b[38;5;208m",
+		Emphasis:        "\x1b[38;5;178m",
+		Strong:          "\x1b[1;38;5;214m",
+		EmphasisStrong:  "\x1b[1;38;5;167m",
+		CodeInline:      "\x1b[38;5;72m",
+		CodeBlock:       "\x1b[38;5;66m",
+		Quote:           "\x1b[38;5;95m",
+		ListMarker:      "\x1b[38;5;172m",
+		LinkText:        "\x1b[1;38;5;178m",
+		LinkURL:         "\x1b[38;5;137m",
+		ThematicBreak:   "\x1b[38;5;101m",
+		CodeKeyword:     "\x1b[38;5;167m",
+		CodeString:      "\x1b[38;5;142m",
+		CodeComment:     "\x1b[38;5;95m",
+		CodeNumber:      "\x1b[38;5;175m",
+		CodeType:        "\x1b[38;5;214m",
+		CodePunctuation: "\x1b[38;5;66m",
 	}
 
 	PaletteDoomDracula = Palette{
-		Text:           "",
-		H1:             "\x1b[1;38;5;225m",
-		H2:             "\x1b[1;38;5;219m",
-		H3:             "\x1b[1;38;5;141m",
-		H4:             "\x1b[38;5;111m",
-		H5:             "\x1b[38;5;81m",
-		H6:             "\x1b[38;5;204m",
-		Emphasis:       "\x1b[38;5;141m",
-		Strong:         "\x1b[1;38;5;117m",
-		EmphasisStrong: "\x1b[1;38;5;204m",
-		CodeInline:     "\x1b[38;5;98m",
-		CodeBlock:      "\x1b[38;5;60m",
-		Quote:          "\x1b[38;5;59m",
-		ListMarker:     "\x1b[38;5;147m",
-		LinkText:       "\x1b[1;38;5;141m",
-		LinkURL:        "\x1b[38;5;95m",
-		ThematicBreak:  "\x1b[38;5;240m",
+		Text:            "",
+		H1:              "\x1b[1;38;5;225m",
+		H2:              "\x1b[1;38;5;219m",
+		H3:              "\x1b[1;38;5;141m",
+		H4:              "\x1b[38;5;111m",
+		H5:              "\x1b[38;5;81m",
+		H6:              "\x1b[38;5;204m",
+		Emphasis:        "\x1b[38;5;141m",
+		Strong:          "\x1b[1;38;5;117m",
+		EmphasisStrong:  "\x1b[1;38;5;204m",
+		CodeInline:      "\x1b[38;5;98m",
+		CodeBlock:       "\x1b[38;5;60m",
+		Quote:           "\x1b[38;5;59m",
+		ListMarker:      "\x1b[38;5;147m",
+		LinkText:        "\x1b[1;38;5;141m",
+		LinkURL:         "\x1b[38;5;95m",
+		ThematicBreak:   "\x1b[38;5;240m",
+		CodeKeyword:     "\x1b[38;5;212m",
+		CodeString:      "\x1b[38;5;228m",
+		CodeComment:     "\x1b[38;5;61m",
+		CodeNumber:      "\x1b[38;5;141m",
+		CodeType:        "\x1b[38;5;117m",
+		CodePunctuation: "\x1b[38;5;253m",
 	}
 
 	PaletteDoomNord = Palette{
-		Text:           "",
-		H1:             "\x1b[1;38;5;195m",
-		H2:             "\x1b[1;38;5;153m",
-		H3:             "\x1b[1;38;5;152m",
-		H4:             "\x1b[38;5;109m",
-		H5:             "\x1b[38;5;115m",
-		H6:             "\x1b[38;5;179m",
-		Emphasis:       "\x1b[38;5;152m",
-		Strong:         "\x1b[1;38;5;117m",
-		EmphasisStrong: "\x1b[1;38;5;210m",
-		CodeInline:     "\x1b[38;5;74m",
-		CodeBlock:      "\x1b[38;5;67m",
-		Quote:          "\x1b[38;5;103m",
-		ListMarker:     "\x1b[38;5;110m",
-		LinkText:       "\x1b[1;38;5;152m",
-		LinkURL:        "\x1b[38;5;109m",
-		ThematicBreak:  "\x1b[38;5;245m",
+		Text:            "",
+		H1:              "\x1b[1;38;5;195m",
+		H2:              "\x1b[1;38;5;153m",
+		H3:              "\x1b[1;38;5;152m",
+		H4:              "\x1b[38;5;109m",
+		H5:              "\x1b[38;5;115m",
+		H6:              "\x1b[38;5;179m",
+		Emphasis:        "\x1b[38;5;152m",
+		Strong:          "\x1b[1;38;5;117m",
+		EmphasisStrong:  "\x1b[1;38;5;210m",
+		CodeInline:      "\x1b[38;5;74m",
+		CodeBlock:       "\x1b[38;5;67m",
+		Quote:           "\x1b[38;5;103m",
+		ListMarker:      "\x1b[38;5;110m",
+		LinkText:        "\x1b[1;38;5;152m",
+		LinkURL:         "\x1b[38;5;109m",
+		ThematicBreak:   "\x1b[38;5;245m",
+		CodeKeyword:     "\x1b[38;5;110m",
+		CodeString:      "\x1b[38;5;150m",
+		CodeComment:     "\x1b[38;5;60m",
+		CodeNumber:      "\x1b[38;5;139m",
+		CodeType:        "\x1b[38;5;116m",
+		CodePunctuation: "\x1b[38;5;152m",
 	}
 
 	PaletteTokyoNight = Palette{
-		Text:           "",
-		H1:             "\x1b[1;38;5;218m",
-		H2:             "\x1b[1;38;5;111m",
-		H3:             "\x1b[1;38;5;110m",
-		H4:             "\x1b[38;5;176m",
-		H5:             "\x1b[38;5;117m",
-		H6:             "\x1b[38;5;173m",
-		Emphasis:       "\x1b[38;5;110m",
-		Strong:         "\x1b[1;38;5;111m",
-		EmphasisStrong: "\x1b[1;38;5;210m",
-		CodeInline:     "\x1b[38;5;67m",
-		CodeBlock:      "\x1b[38;5;63m",
-		Quote:          "\x1b[38;5;239m",
-		ListMarker:     "\x1b[38;5;74m",
-		LinkText:       "\x1b[1;38;5;110m",
-		LinkURL:        "\x1b[38;5;109m",
-		ThematicBreak:  "\x1b[38;5;244m",
+		Text:            "",
+		H1:              "\x1b[1;38;5;218m",
+		H2:              "\x1b[1;38;5;111m",
+		H3:              "\x1b[1;38;5;110m",
+		H4:              "\x1b[38;5;176m",
+		H5:              "\x1b[38;5;117m",
+		H6:              "\x1b[38;5;173m",
+		Emphasis:        "\x1b[38;5;110m",
+		Strong:          "\x1b[1;38;5;111m",
+		EmphasisStrong:  "\x1b[1;38;5;210m",
+		CodeInline:      "\x1b[38;5;67m",
+		CodeBlock:       "\x1b[38;5;63m",
+		Quote:           "\x1b[38;5;239m",
+		ListMarker:      "\x1b[38;5;74m",
+		LinkText:        "\x1b[1;38;5;110m",
+		LinkURL:         "\x1b[38;5;109m",
+		ThematicBreak:   "\x1b[38;5;244m",
+		CodeKeyword:     "\x1b[38;5;141m",
+		CodeString:      "\x1b[38;5;149m",
+		CodeComment:     "\x1b[38;5;60m",
+		CodeNumber:      "\x1b[38;5;215m",
+		CodeType:        "\x1b[38;5;117m",
+		CodePunctuation: "\x1b[38;5;110m",
 	}
 
 	PaletteSolarizedNightfall = Palette{
-		Text:           "",
-		H1:             "\x1b[1;38;5;230m",
-		H2:             "\x1b[1;38;5;86m",
-		H3:             "\x1b[1;38;5;37m",
-		H4:             "\x1b[38;5;61m",
-		H5:             "\x1b[38;5;65m",
-		H6:             "\x1b[38;5;136m",
-		Emphasis:       "\x1b[38;5;86m",
-		Strong:         "\x1b[1;38;5;36m",
-		EmphasisStrong: "\x1b[1;38;5;160m",
-		CodeInline:     "\x1b[38;5;30m",
-		CodeBlock:      "\x1b[38;5;24m",
-		Quote:          "\x1b[38;5;238m",
-		ListMarker:     "\x1b[38;5;33m",
-		LinkText:       "\x1b[1;38;5;86m",
-		LinkURL:        "\x1b[38;5;244m",
-		ThematicBreak:  "\x1b[38;5;239m",
+		Text:            "",
+		H1:              "\x1b[1;38;5;230m",
+		H2:              "\x1b[1;38;5;86m",
+		H3:              "\x1b[1;38;5;37m",
+		H4:              "\x1b[38;5;61m",
+		H5:              "\x1b[38;5;65m",
+		H6:              "\x1b[38;5;136m",
+		Emphasis:        "\x1b[38;5;86m",
+		Strong:          "\x1b[1;38;5;36m",
+		EmphasisStrong:  "\x1b[1;38;5;160m",
+		CodeInline:      "\x1b[38;5;30m",
+		CodeBlock:       "\x1b[38;5;24m",
+		Quote:           "\x1b[38;5;238m",
+		ListMarker:      "\x1b[38;5;33m",
+		LinkText:        "\x1b[1;38;5;86m",
+		LinkURL:         "\x1b[38;5;244m",
+		ThematicBreak:   "\x1b[38;5;239m",
+		CodeKeyword:     "\x1b[38;5;64m",
+		CodeString:      "\x1b[38;5;37m",
+		CodeComment:     "\x1b[38;5;240m",
+		CodeNumber:      "\x1b[38;5;125m",
+		CodeType:        "\x1b[38;5;136m",
+		CodePunctuation: "\x1b[38;5;244m",
 	}
 
 	PaletteCatppuccinMocha = Palette{
-		Text:           "",
-		H1:             "\x1b[1;38;5;223m",
-		H2:             "\x1b[1;38;5;217m",
-		H3:             "\x1b[1;38;5;183m",
-		H4:             "\x1b[38;5;147m",
-		H5:             "\x1b[38;5;152m",
-		H6:             "\x1b[38;5;216m",
-		Emphasis:       "\x1b[38;5;183m",
-		Strong:         "\x1b[1;38;5;150m",
-		EmphasisStrong: "\x1b[1;38;5;211m",
-		CodeInline:     "\x1b[38;5;109m",
-		CodeBlock:      "\x1b[38;5;104m",
-		Quote:          "\x1b[38;5;240m",
-		ListMarker:     "\x1b[38;5;182m",
-		LinkText:       "\x1b[1;38;5;183m",
-		LinkURL:        "\x1b[38;5;110m",
-		ThematicBreak:  "\x1b[38;5;244m",
+		Text:            "",
+		H1:              "\x1b[1;38;5;223m",
+		H2:              "\x1b[1;38;5;217m",
+		H3:              "\x1b[1;38;5;183m",
+		H4:              "\x1b[38;5;147m",
+		H5:              "\x1b[38;5;152m",
+		H6:              "\x1b[38;5;216m",
+		Emphasis:        "\x1b[38;5;183m",
+		Strong:          "\x1b[1;38;5;150m",
+		EmphasisStrong:  "\x1b[1;38;5;211m",
+		CodeInline:      "\x1b[38;5;109m",
+		CodeBlock:       "\x1b[38;5;104m",
+		Quote:           "\x1b[38;5;240m",
+		ListMarker:      "\x1b[38;5;182m",
+		LinkText:        "\x1b[1;38;5;183m",
+		LinkURL:         "\x1b[38;5;110m",
+		ThematicBreak:   "\x1b[38;5;244m",
+		CodeKeyword:     "\x1b[38;5;183m",
+		CodeString:      "\x1b[38;5;151m",
+		CodeComment:     "\x1b[38;5;243m",
+		CodeNumber:      "\x1b[38;5;216m",
+		CodeType:        "\x1b[38;5;229m",
+		CodePunctuation: "\x1b[38;5;117m",
 	}
 
 	PaletteGruvboxLight = Palette{
-		Text:           "",
-		H1:             "\x1b[1;38;5;223m",
-		H2:             "\x1b[1;38;5;130m",
-		H3:             "\x1b[1;38;5;108m",
-		H4:             "\x1b[38;5;66m",
-		H5:             "\x1b[38;5;142m",
-		H6:             "\x1b[38;5;173m",
-		Emphasis:       "\x1b[38;5;108m",
-		Strong:         "\x1b[1;38;5;73m",
-		EmphasisStrong: "\x1b[1;38;5;167m",
-		CodeInline:     "\x1b[38;5;114m",
-		CodeBlock:      "\x1b[38;5;109m",
-		Quote:          "\x1b[38;5;181m",
-		ListMarker:     "\x1b[38;5;136m",
-		LinkText:       "\x1b[1;38;5;108m",
-		LinkURL:        "\x1b[38;5;180m",
-		ThematicBreak:  "\x1b[38;5;180m",
+		Text:            "",
+		H1:              "\x1b[1;38;5;223m",
+		H2:              "\x1b[1;38;5;130m",
+		H3:              "\x1b[1;38;5;108m",
+		H4:              "\x1b[38;5;66m",
+		H5:              "\x1b[38;5;142m",
+		H6:              "\x1b[38;5;173m",
+		Emphasis:        "\x1b[38;5;108m",
+		Strong:          "\x1b[1;38;5;73m",
+		EmphasisStrong:  "\x1b[1;38;5;167m",
+		CodeInline:      "\x1b[38;5;114m",
+		CodeBlock:       "\x1b[38;5;109m",
+		Quote:           "\x1b[38;5;181m",
+		ListMarker:      "\x1b[38;5;136m",
+		LinkText:        "\x1b[1;38;5;108m",
+		LinkURL:         "\x1b[38;5;180m",
+		ThematicBreak:   "\x1b[38;5;180m",
+		CodeKeyword:     "\x1b[38;5;124m",
+		CodeString:      "\x1b[38;5;100m",
+		CodeComment:     "\x1b[38;5;245m",
+		CodeNumber:      "\x1b[38;5;96m",
+		CodeType:        "\x1b[38;5;136m",
+		CodePunctuation: "\x1b[38;5;66m",
 	}
 
 	PaletteMonokaiVibrant = Palette{
-		Text:           "",
-		H1:             "\x1b[1;38;5;229m",
-		H2:             "\x1b[1;38;5;121m",
-		H3:             "\x1b[1;38;5;198m",
-		H4:             "\x1b[38;5;118m",
-		H5:             "\x1b[38;5;215m",
-		H6:             "\x1b[38;5;141m",
-		Emphasis:       "\x1b[38;5;121m",
-		Strong:         "\x1b[1;38;5;229m",
-		EmphasisStrong: "\x1b[1;38;5;197m",
-		CodeInline:     "\x1b[38;5;114m",
-		CodeBlock:      "\x1b[38;5;104m",
-		Quote:          "\x1b[38;5;240m",
-		ListMarker:     "\x1b[38;5;141m",
-		LinkText:       "\x1b[1;38;5;121m",
-		LinkURL:        "\x1b[38;5;103m",
-		ThematicBreak:  "\x1b[38;5;59m",
+		Text:            "",
+		H1:              "\x1b[1;38;5;229m",
+		H2:              "\x1b[1;38;5;121m",
+		H3:              "\x1b[1;38;5;198m",
+		H4:              "\x1b[38;5;118m",
+		H5:              "\x1b[38;5;215m",
+		H6:              "\x1b[38;5;141m",
+		Emphasis:        "\x1b[38;5;121m",
+		Strong:          "\x1b[1;38;5;229m",
+		EmphasisStrong:  "\x1b[1;38;5;197m",
+		CodeInline:      "\x1b[38;5;114m",
+		CodeBlock:       "\x1b[38;5;104m",
+		Quote:           "\x1b[38;5;240m",
+		ListMarker:      "\x1b[38;5;141m",
+		LinkText:        "\x1b[1;38;5;121m",
+		LinkURL:         "\x1b[38;5;103m",
+		ThematicBreak:   "\x1b[38;5;59m",
+		CodeKeyword:     "\x1b[38;5;197m",
+		CodeString:      "\x1b[38;5;186m",
+		CodeComment:     "\x1b[38;5;242m",
+		CodeNumber:      "\x1b[38;5;141m",
+		CodeType:        "\x1b[38;5;81m",
+		CodePunctuation: "\x1b[38;5;252m",
 	}
 
 	PaletteOneDarkAurora = Palette{
-		Text:           "",
-		H1:             "\x1b[1;38;5;189m",
-		H2:             "\x1b[1;38;5;110m",
-		H3:             "\x1b[1;38;5;147m",
-		H4:             "\x1b[38;5;141m",
-		H5:             "\x1b[38;5;115m",
-		H6:             "\x1b[38;5;178m",
-		Emphasis:       "\x1b[38;5;147m",
-		Strong:         "\x1b[1;38;5;38m",
-		EmphasisStrong: "\x1b[1;38;5;203m",
-		CodeInline:     "\x1b[38;5;31m",
-		CodeBlock:      "\x1b[38;5;24m",
-		Quote:          "\x1b[38;5;240m",
-		ListMarker:     "\x1b[38;5;75m",
-		LinkText:       "\x1b[1;38;5;147m",
-		LinkURL:        "\x1b[38;5;109m",
-		ThematicBreak:  "\x1b[38;5;59m",
+		Text:            "",
+		H1:              "\x1b[1;38;5;189m",
+		H2:              "\x1b[1;38;5;110m",
+		H3:              "\x1b[1;38;5;147m",
+		H4:              "\x1b[38;5;141m",
+		H5:              "\x1b[38;5;115m",
+		H6:              "\x1b[38;5;178m",
+		Emphasis:        "\x1b[38;5;147m",
+		Strong:          "\x1b[1;38;5;38m",
+		EmphasisStrong:  "\x1b[1;38;5;203m",
+		CodeInline:      "\x1b[38;5;31m",
+		CodeBlock:       "\x1b[38;5;24m",
+		Quote:           "\x1b[38;5;240m",
+		ListMarker:      "\x1b[38;5;75m",
+		LinkText:        "\x1b[1;38;5;147m",
+		LinkURL:         "\x1b[38;5;109m",
+		ThematicBreak:   "\x1b[38;5;59m",
+		CodeKeyword:     "\x1b[38;5;176m",
+		CodeString:      "\x1b[38;5;114m",
+		CodeComment:     "\x1b[38;5;59m",
+		CodeNumber:      "\x1b[38;5;173m",
+		CodeType:        "\x1b[38;5;180m",
+		CodePunctuation: "\x1b[38;5;145m",
 	}
 
 	PaletteSynthwave84 = Palette{
-		Text:           "",
-		H1:             "\x1b[1;38;5;219m",
-		H2:             "\x1b[1;38;5;51m",
-		H3:             "\x1b[1;38;5;198m",
-		H4:             "\x1b[38;5;207m",
-		H5:             "\x1b[38;5;81m",
-		H6:             "\x1b[38;5;220m",
-		Emphasis:       "\x1b[38;5;51m",
-		Strong:         "\x1b[1;38;5;219m",
-		EmphasisStrong: "\x1b[1;38;5;205m",
-		CodeInline:     "\x1b[38;5;69m",
-		CodeBlock:      "\x1b[38;5;63m",
-		Quote:          "\x1b[38;5;60m",
-		ListMarker:     "\x1b[38;5;45m",
-		LinkText:       "\x1b[1;38;5;51m",
-		LinkURL:        "\x1b[38;5;69m",
-		ThematicBreak:  "\x1b[38;5;102m",
+		Text:            "",
+		H1:              "\x1b[1;38;5;219m",
+		H2:              "\x1b[1;38;5;51m",
+		H3:              "\x1b[1;38;5;198m",
+		H4:              "\x1b[38;5;207m",
+		H5:              "\x1b[38;5;81m",
+		H6:              "\x1b[38;5;220m",
+		Emphasis:        "\x1b[38;5;51m",
+		Strong:          "\x1b[1;38;5;219m",
+		EmphasisStrong:  "\x1b[1;38;5;205m",
+		CodeInline:      "\x1b[38;5;69m",
+		CodeBlock:       "\x1b[38;5;63m",
+		Quote:           "\x1b[38;5;60m",
+		ListMarker:      "\x1b[38;5;45m",
+		LinkText:        "\x1b[1;38;5;51m",
+		LinkURL:         "\x1b[38;5;69m",
+		ThematicBreak:   "\x1b[38;5;102m",
+		CodeKeyword:     "\x1b[38;5;222m",
+		CodeString:      "\x1b[38;5;209m",
+		CodeComment:     "\x1b[38;5;103m",
+		CodeNumber:      "\x1b[38;5;211m",
+		CodeType:        "\x1b[38;5;81m",
+		CodePunctuation: "\x1b[38;5;189m",
 	}
 
 	PaletteKanagawa = Palette{
-		Text:           "",
-		H1:             "\x1b[1;38;5;223m",
-		H2:             "\x1b[1;38;5;215m",
-		H3:             "\x1b[1;38;5;179m",
-		H4:             "\x1b[38;5;150m",
-		H5:             "\x1b[38;5;109m",
-		H6:             "\x1b[38;5;110m",
-		Emphasis:       "\x1b[38;5;150m",
-		Strong:         "\x1b[1;38;5;216m",
-		EmphasisStrong: "\x1b[1;38;5;181m",
-		CodeInline:     "\x1b[38;5;109m",
-		CodeBlock:      "\x1b[38;5;66m",
-		Quote:          "\x1b[38;5;239m",
-		ListMarker:     "\x1b[38;5;173m",
-		LinkText:       "\x1b[1;38;5;150m",
-		LinkURL:        "\x1b[38;5;109m",
-		ThematicBreak:  "\x1b[38;5;240m",
+		Text:            "",
+		H1:              "\x1b[1;38;5;223m",
+		H2:              "\x1b[1;38;5;215m",
+		H3:              "\x1b[1;38;5;179m",
+		H4:              "\x1b[38;5;150m",
+		H5:              "\x1b[38;5;109m",
+		H6:              "\x1b[38;5;110m",
+		Emphasis:        "\x1b[38;5;150m",
+		Strong:          "\x1b[1;38;5;216m",
+		EmphasisStrong:  "\x1b[1;38;5;181m",
+		CodeInline:      "\x1b[38;5;109m",
+		CodeBlock:       "\x1b[38;5;66m",
+		Quote:           "\x1b[38;5;239m",
+		ListMarker:      "\x1b[38;5;173m",
+		LinkText:        "\x1b[1;38;5;150m",
+		LinkURL:         "\x1b[38;5;109m",
+		ThematicBreak:   "\x1b[38;5;240m",
+		CodeKeyword:     "\x1b[38;5;98m",
+		CodeString:      "\x1b[38;5;107m",
+		CodeComment:     "\x1b[38;5;243m",
+		CodeNumber:      "\x1b[38;5;175m",
+		CodeType:        "\x1b[38;5;73m",
+		CodePunctuation: "\x1b[38;5;146m",
 	}
 
 	PaletteRosePine = Palette{
-		Text:           "",
-		H1:             "\x1b[1;38;5;223m",
-		H2:             "\x1b[1;38;5;217m",
-		H3:             "\x1b[1;38;5;180m",
-		H4:             "\x1b[38;5;146m",
-		H5:             "\x1b[38;5;110m",
-		H6:             "\x1b[38;5;117m",
-		Emphasis:       "\x1b[38;5;180m",
-		Strong:         "\x1b[1;38;5;217m",
-		EmphasisStrong: "\x1b[1;38;5;210m",
-		CodeInline:     "\x1b[38;5;110m",
-		CodeBlock:      "\x1b[38;5;104m",
-		Quote:          "\x1b[38;5;240m",
-		ListMarker:     "\x1b[38;5;146m",
-		LinkText:       "\x1b[1;38;5;180m",
-		LinkURL:        "\x1b[38;5;110m",
-		ThematicBreak:  "\x1b[38;5;244m",
+		Text:            "",
+		H1:              "\x1b[1;38;5;223m",
+		H2:              "\x1b[1;38;5;217m",
+		H3:              "\x1b[1;38;5;180m",
+		H4:              "\x1b[38;5;146m",
+		H5:              "\x1b[38;5;110m",
+		H6:              "\x1b[38;5;117m",
+		Emphasis:        "\x1b[38;5;180m",
+		Strong:          "\x1b[1;38;5;217m",
+		EmphasisStrong:  "\x1b[1;38;5;210m",
+		CodeInline:      "\x1b[38;5;110m",
+		CodeBlock:       "\x1b[38;5;104m",
+		Quote:           "\x1b[38;5;240m",
+		ListMarker:      "\x1b[38;5;146m",
+		LinkText:        "\x1b[1;38;5;180m",
+		LinkURL:         "\x1b[38;5;110m",
+		ThematicBreak:   "\x1b[38;5;244m",
+		CodeKeyword:     "\x1b[38;5;67m",
+		CodeString:      "\x1b[38;5;222m",
+		CodeComment:     "\x1b[38;5;60m",
+		CodeNumber:      "\x1b[38;5;181m",
+		CodeType:        "\x1b[38;5;152m",
+		CodePunctuation: "\x1b[38;5;103m",
 	}
 
 	PaletteRosePineDawn = Palette{
-		Text:           "",
-		H1:             "\x1b[1;38;5;180m",
-		H2:             "\x1b[1;38;5;173m",
-		H3:             "\x1b[1;38;5;137m",
-		H4:             "\x1b[38;5;108m",
-		H5:             "\x1b[38;5;66m",
-		H6:             "\x1b[38;5;109m",
-		Emphasis:       "\x1b[38;5;137m",
-		Strong:         "\x1b[1;38;5;173m",
-		EmphasisStrong: "\x1b[1;38;5;131m",
-		CodeInline:     "\x1b[38;5;109m",
-		CodeBlock:      "\x1b[38;5;66m",
-		Quote:          "\x1b[38;5;242m",
-		ListMarker:     "\x1b[38;5;137m",
-		LinkText:       "\x1b[1;38;5;137m",
-		LinkURL:        "\x1b[38;5;109m",
-		ThematicBreak:  "\x1b[38;5;244m",
+		Text:            "",
+		H1:              "\x1b[1;38;5;180m",
+		H2:              "\x1b[1;38;5;173m",
+		H3:              "\x1b[1;38;5;137m",
+		H4:              "\x1b[38;5;108m",
+		H5:              "\x1b[38;5;66m",
+		H6:              "\x1b[38;5;109m",
+		Emphasis:        "\x1b[38;5;137m",
+		Strong:          "\x1b[1;38;5;173m",
+		EmphasisStrong:  "\x1b[1;38;5;131m",
+		CodeInline:      "\x1b[38;5;109m",
+		CodeBlock:       "\x1b[38;5;66m",
+		Quote:           "\x1b[38;5;242m",
+		ListMarker:      "\x1b[38;5;137m",
+		LinkText:        "\x1b[1;38;5;137m",
+		LinkURL:         "\x1b[38;5;109m",
+		ThematicBreak:   "\x1b[38;5;244m",
+		CodeKeyword:     "\x1b[38;5;24m",
+		CodeString:      "\x1b[38;5;172m",
+		CodeComment:     "\x1b[38;5;247m",
+		CodeNumber:      "\x1b[38;5;174m",
+		CodeType:        "\x1b[38;5;66m",
+		CodePunctuation: "\x1b[38;5;102m",
 	}
 
 	PaletteEverforest = Palette{
-		Text:           "",
-		H1:             "\x1b[1;38;5;223m",
-		H2:             "\x1b[1;38;5;143m",
-		H3:             "\x1b[1;38;5;108m",
-		H4:             "\x1b[38;5;108m",
-		H5:             "\x1b[38;5;142m",
-		H6:             "\x1b[38;5;109m",
-		Emphasis:       "\x1b[38;5;108m",
-		Strong:         "\x1b[1;38;5;143m",
-		EmphasisStrong: "\x1b[1;38;5;179m",
-		CodeInline:     "\x1b[38;5;109m",
-		CodeBlock:      "\x1b[38;5;66m",
-		Quote:          "\x1b[38;5;240m",
-		ListMarker:     "\x1b[38;5;108m",
-		LinkText:       "\x1b[1;38;5;143m",
-		LinkURL:        "\x1b[38;5;109m",
-		ThematicBreak:  "\x1b[38;5;239m",
+		Text:            "",
+		H1:              "\x1b[1;38;5;223m",
+		H2:              "\x1b[1;38;5;143m",
+		H3:              "\x1b[1;38;5;108m",
+		H4:              "\x1b[38;5;108m",
+		H5:              "\x1b[38;5;142m",
+		H6:              "\x1b[38;5;109m",
+		Emphasis:        "\x1b[38;5;108m",
+		Strong:          "\x1b[1;38;5;143m",
+		EmphasisStrong:  "\x1b[1;38;5;179m",
+		CodeInline:      "\x1b[38;5;109m",
+		CodeBlock:       "\x1b[38;5;66m",
+		Quote:           "\x1b[38;5;240m",
+		ListMarker:      "\x1b[38;5;108m",
+		LinkText:        "\x1b[1;38;5;143m",
+		LinkURL:         "\x1b[38;5;109m",
+		ThematicBreak:   "\x1b[38;5;239m",
+		CodeKeyword:     "\x1b[38;5;174m",
+		CodeString:      "\x1b[38;5;144m",
+		CodeComment:     "\x1b[38;5;244m",
+		CodeNumber:      "\x1b[38;5;175m",
+		CodeType:        "\x1b[38;5;179m",
+		CodePunctuation: "\x1b[38;5;109m",
 	}
 
 	PaletteEverforestLight = Palette{
-		Text:           "",
-		H1:             "\x1b[1;38;5;130m",
-		H2:             "\x1b[1;38;5;136m",
-		H3:             "\x1b[1;38;5;108m",
-		H4:             "\x1b[38;5;66m",
-		H5:             "\x1b[38;5;109m",
-		H6:             "\x1b[38;5;137m",
-		Emphasis:       "\x1b[38;5;108m",
-		Strong:         "\x1b[1;38;5;136m",
-		EmphasisStrong: "\x1b[1;38;5;173m",
-		CodeInline:     "\x1b[38;5;109m",
-		CodeBlock:      "\x1b[38;5;66m",
-		Quote:          "\x1b[38;5;242m",
-		ListMarker:     "\x1b[38;5;108m",
-		LinkText:       "\x1b[1;38;5;136m",
-		LinkURL:        "\x1b[38;5;109m",
-		ThematicBreak:  "\x1b[38;5;243m",
+		Text:            "",
+		H1:              "\x1b[1;38;5;130m",
+		H2:              "\x1b[1;38;5;136m",
+		H3:              "\x1b[1;38;5;108m",
+		H4:              "\x1b[38;5;66m",
+		H5:              "\x1b[38;5;109m",
+		H6:              "\x1b[38;5;137m",
+		Emphasis:        "\x1b[38;5;108m",
+		Strong:          "\x1b[1;38;5;136m",
+		EmphasisStrong:  "\x1b[1;38;5;173m",
+		CodeInline:      "\x1b[38;5;109m",
+		CodeBlock:       "\x1b[38;5;66m",
+		Quote:           "\x1b[38;5;242m",
+		ListMarker:      "\x1b[38;5;108m",
+		LinkText:        "\x1b[1;38;5;136m",
+		LinkURL:         "\x1b[38;5;109m",
+		ThematicBreak:   "\x1b[38;5;243m",
+		CodeKeyword:     "\x1b[38;5;167m",
+		CodeString:      "\x1b[38;5;106m",
+		CodeComment:     "\x1b[38;5;246m",
+		CodeNumber:      "\x1b[38;5;133m",
+		CodeType:        "\x1b[38;5;136m",
+		CodePunctuation: "\x1b[38;5;67m",
 	}
 
 	PaletteNightOwl = Palette{
-		Text:           "",
-		H1:             "\x1b[1;38;5;117m",
-		H2:             "\x1b[1;38;5;111m",
-		H3:             "\x1b[1;38;5;75m",
-		H4:             "\x1b[38;5;110m",
-		H5:             "\x1b[38;5;81m",
-		H6:             "\x1b[38;5;179m",
-		Emphasis:       "\x1b[38;5;75m",
-		Strong:         "\x1b[1;38;5;117m",
-		EmphasisStrong: "\x1b[1;38;5;204m",
-		CodeInline:     "\x1b[38;5;74m",
-		CodeBlock:      "\x1b[38;5;67m",
-		Quote:          "\x1b[38;5;240m",
-		ListMarker:     "\x1b[38;5;75m",
-		LinkText:       "\x1b[1;38;5;75m",
-		LinkURL:        "\x1b[38;5;109m",
-		ThematicBreak:  "\x1b[38;5;244m",
+		Text:            "",
+		H1:              "\x1b[1;38;5;117m",
+		H2:              "\x1b[1;38;5;111m",
+		H3:              "\x1b[1;38;5;75m",
+		H4:              "\x1b[38;5;110m",
+		H5:              "\x1b[38;5;81m",
+		H6:              "\x1b[38;5;179m",
+		Emphasis:        "\x1b[38;5;75m",
+		Strong:          "\x1b[1;38;5;117m",
+		EmphasisStrong:  "\x1b[1;38;5;204m",
+		CodeInline:      "\x1b[38;5;74m",
+		CodeBlock:       "\x1b[38;5;67m",
+		Quote:           "\x1b[38;5;240m",
+		ListMarker:      "\x1b[38;5;75m",
+		LinkText:        "\x1b[1;38;5;75m",
+		LinkURL:         "\x1b[38;5;109m",
+		ThematicBreak:   "\x1b[38;5;244m",
+		CodeKeyword:     "\x1b[38;5;176m",
+		CodeString:      "\x1b[38;5;222m",
+		CodeComment:     "\x1b[38;5;66m",
+		CodeNumber:      "\x1b[38;5;209m",
+		CodeType:        "\x1b[38;5;149m",
+		CodePunctuation: "\x1b[38;5;116m",
 	}
 
 	PaletteAyuMirage = Palette{
-		Text:           "",
-		H1:             "\x1b[1;38;5;215m",
-		H2:             "\x1b[1;38;5;214m",
-		H3:             "\x1b[1;38;5;179m",
-		H4:             "\x1b[38;5;110m",
-		H5:             "\x1b[38;5;109m",
-		H6:             "\x1b[38;5;173m",
-		Emphasis:       "\x1b[38;5;179m",
-		Strong:         "\x1b[1;38;5;214m",
-		EmphasisStrong: "\x1b[1;38;5;173m",
-		CodeInline:     "\x1b[38;5;110m",
-		CodeBlock:      "\x1b[38;5;66m",
-		Quote:          "\x1b[38;5;240m",
-		ListMarker:     "\x1b[38;5;179m",
-		LinkText:       "\x1b[1;38;5;179m",
-		LinkURL:        "\x1b[38;5;109m",
-		ThematicBreak:  "\x1b[38;5;239m",
+		Text:            "",
+		H1:              "\x1b[1;38;5;215m",
+		H2:              "\x1b[1;38;5;214m",
+		H3:              "\x1b[1;38;5;179m",
+		H4:              "\x1b[38;5;110m",
+		H5:              "\x1b[38;5;109m",
+		H6:              "\x1b[38;5;173m",
+		Emphasis:        "\x1b[38;5;179m",
+		Strong:          "\x1b[1;38;5;214m",
+		EmphasisStrong:  "\x1b[1;38;5;173m",
+		CodeInline:      "\x1b[38;5;110m",
+		CodeBlock:       "\x1b[38;5;66m",
+		Quote:           "\x1b[38;5;240m",
+		ListMarker:      "\x1b[38;5;179m",
+		LinkText:        "\x1b[1;38;5;179m",
+		LinkURL:         "\x1b[38;5;109m",
+		ThematicBreak:   "\x1b[38;5;239m",
+		CodeKeyword:     "\x1b[38;5;215m",
+		CodeString:      "\x1b[38;5;150m",
+		CodeComment:     "\x1b[38;5;66m",
+		CodeNumber:      "\x1b[38;5;183m",
+		CodeType:        "\x1b[38;5;81m",
+		CodePunctuation: "\x1b[38;5;209m",
 	}
 
 	PaletteAyuLight = Palette{
-		Text:           "",
-		H1:             "\x1b[1;38;5;130m",
-		H2:             "\x1b[1;38;5;136m",
-		H3:             "\x1b[1;38;5;109m",
-		H4:             "\x1b[38;5;66m",
-		H5:             "\x1b[38;5;109m",
-		H6:             "\x1b[38;5;173m",
-		Emphasis:       "\x1b[38;5;109m",
-		Strong:         "\x1b[1;38;5;130m",
-		EmphasisStrong: "\x1b[1;38;5;173m",
-		CodeInline:     "\x1b[38;5;109m",
-		CodeBlock:      "\x1b[38;5;66m",
-		Quote:          "\x1b[38;5;242m",
-		ListMarker:     "\x1b[38;5;109m",
-		LinkText:       "\x1b[1;38;5;109m",
-		LinkURL:        "\x1b[38;5;109m",
-		ThematicBreak:  "\x1b[38;5;243m",
+		Text:            "",
+		H1:              "\x1b[1;38;5;130m",
+		H2:              "\x1b[1;38;5;136m",
+		H3:              "\x1b[1;38;5;109m",
+		H4:              "\x1b[38;5;66m",
+		H5:              "\x1b[38;5;109m",
+		H6:              "\x1b[38;5;173m",
+		Emphasis:        "\x1b[38;5;109m",
+		Strong:          "\x1b[1;38;5;130m",
+		EmphasisStrong:  "\x1b[1;38;5;173m",
+		CodeInline:      "\x1b[38;5;109m",
+		CodeBlock:       "\x1b[38;5;66m",
+		Quote:           "\x1b[38;5;242m",
+		ListMarker:      "\x1b[38;5;109m",
+		LinkText:        "\x1b[1;38;5;109m",
+		LinkURL:         "\x1b[38;5;109m",
+		ThematicBreak:   "\x1b[38;5;243m",
+		CodeKeyword:     "\x1b[38;5;208m",
+		CodeString:      "\x1b[38;5;106m",
+		CodeComment:     "\x1b[38;5;245m",
+		CodeNumber:      "\x1b[38;5;140m",
+		CodeType:        "\x1b[38;5;74m",
+		CodePunctuation: "\x1b[38;5;173m",
 	}
 
 	PaletteOneLight = Palette{
-		Text:           "",
-		H1:             "\x1b[1;38;5;130m",
-		H2:             "\x1b[1;38;5;33m",
-		H3:             "\x1b[1;38;5;97m",
-		H4:             "\x1b[38;5;67m",
-		H5:             "\x1b[38;5;109m",
-		H6:             "\x1b[38;5;137m",
-		Emphasis:       "\x1b[38;5;67m",
-		Strong:         "\x1b[1;38;5;130m",
-		EmphasisStrong: "\x1b[1;38;5;167m",
-		CodeInline:     "\x1b[38;5;109m",
-		CodeBlock:      "\x1b[38;5;66m",
-		Quote:          "\x1b[38;5;242m",
-		ListMarker:     "\x1b[38;5;67m",
-		LinkText:       "\x1b[1;38;5;67m",
-		LinkURL:        "\x1b[38;5;109m",
-		ThematicBreak:  "\x1b[38;5;243m",
+		Text:            "",
+		H1:              "\x1b[1;38;5;130m",
+		H2:              "\x1b[1;38;5;33m",
+		H3:              "\x1b[1;38;5;97m",
+		H4:              "\x1b[38;5;67m",
+		H5:              "\x1b[38;5;109m",
+		H6:              "\x1b[38;5;137m",
+		Emphasis:        "\x1b[38;5;67m",
+		Strong:          "\x1b[1;38;5;130m",
+		EmphasisStrong:  "\x1b[1;38;5;167m",
+		CodeInline:      "\x1b[38;5;109m",
+		CodeBlock:       "\x1b[38;5;66m",
+		Quote:           "\x1b[38;5;242m",
+		ListMarker:      "\x1b[38;5;67m",
+		LinkText:        "\x1b[1;38;5;67m",
+		LinkURL:         "\x1b[38;5;109m",
+		ThematicBreak:   "\x1b[38;5;243m",
+		CodeKeyword:     "\x1b[38;5;127m",
+		CodeString:      "\x1b[38;5;71m",
+		CodeComment:     "\x1b[38;5;247m",
+		CodeNumber:      "\x1b[38;5;130m",
+		CodeType:        "\x1b[38;5;136m",
+		CodePunctuation: "\x1b[38;5;240m",
 	}
 
 	PaletteOneDark = Palette{
-		Text:           "",
-		H1:             "\x1b[1;38;5;189m",
-		H2:             "\x1b[1;38;5;110m",
-		H3:             "\x1b[1;38;5;147m",
-		H4:             "\x1b[38;5;141m",
-		H5:             "\x1b[38;5;115m",
-		H6:             "\x1b[38;5;178m",
-		Emphasis:       "\x1b[38;5;147m",
-		Strong:         "\x1b[1;38;5;75m",
-		EmphasisStrong: "\x1b[1;38;5;203m",
-		CodeInline:     "\x1b[38;5;75m",
-		CodeBlock:      "\x1b[38;5;67m",
-		Quote:          "\x1b[38;5;240m",
-		ListMarker:     "\x1b[38;5;75m",
-		LinkText:       "\x1b[1;38;5;147m",
-		LinkURL:        "\x1b[38;5;109m",
-		ThematicBreak:  "\x1b[38;5;239m",
+		Text:            "",
+		H1:              "\x1b[1;38;5;189m",
+		H2:              "\x1b[1;38;5;110m",
+		H3:              "\x1b[1;38;5;147m",
+		H4:              "\x1b[38;5;141m",
+		H5:              "\x1b[38;5;115m",
+		H6:              "\x1b[38;5;178m",
+		Emphasis:        "\x1b[38;5;147m",
+		Strong:          "\x1b[1;38;5;75m",
+		EmphasisStrong:  "\x1b[1;38;5;203m",
+		CodeInline:      "\x1b[38;5;75m",
+		CodeBlock:       "\x1b[38;5;67m",
+		Quote:           "\x1b[38;5;240m",
+		ListMarker:      "\x1b[38;5;75m",
+		LinkText:        "\x1b[1;38;5;147m",
+		LinkURL:         "\x1b[38;5;109m",
+		ThematicBreak:   "\x1b[38;5;239m",
+		CodeKeyword:     "\x1b[38;5;176m",
+		CodeString:      "\x1b[38;5;114m",
+		CodeComment:     "\x1b[38;5;59m",
+		CodeNumber:      "\x1b[38;5;173m",
+		CodeType:        "\x1b[38;5;180m",
+		CodePunctuation: "\x1b[38;5;145m",
 	}
 
 	PaletteSolarizedLight = Palette{
-		Text:           "",
-		H1:             "\x1b[1;38;5;136m",
-		H2:             "\x1b[1;38;5;64m",
-		H3:             "\x1b[1;38;5;37m",
-		H4:             "\x1b[38;5;33m",
-		H5:             "\x1b[38;5;130m",
-		H6:             "\x1b[38;5;166m",
-		Emphasis:       "\x1b[38;5;64m",
-		Strong:         "\x1b[1;38;5;136m",
-		EmphasisStrong: "\x1b[1;38;5;166m",
-		CodeInline:     "\x1b[38;5;37m",
-		CodeBlock:      "\x1b[38;5;33m",
-		Quote:          "\x1b[38;5;244m",
-		ListMarker:     "\x1b[38;5;64m",
-		LinkText:       "\x1b[1;38;5;64m",
-		LinkURL:        "\x1b[38;5;109m",
-		ThematicBreak:  "\x1b[38;5;244m",
+		Text:            "",
+		H1:              "\x1b[1;38;5;136m",
+		H2:              "\x1b[1;38;5;64m",
+		H3:              "\x1b[1;38;5;37m",
+		H4:              "\x1b[38;5;33m",
+		H5:              "\x1b[38;5;130m",
+		H6:              "\x1b[38;5;166m",
+		Emphasis:        "\x1b[38;5;64m",
+		Strong:          "\x1b[1;38;5;136m",
+		EmphasisStrong:  "\x1b[1;38;5;166m",
+		CodeInline:      "\x1b[38;5;37m",
+		CodeBlock:       "\x1b[38;5;33m",
+		Quote:           "\x1b[38;5;244m",
+		ListMarker:      "\x1b[38;5;64m",
+		LinkText:        "\x1b[1;38;5;64m",
+		LinkURL:         "\x1b[38;5;109m",
+		ThematicBreak:   "\x1b[38;5;244m",
+		CodeKeyword:     "\x1b[38;5;64m",
+		CodeString:      "\x1b[38;5;37m",
+		CodeComment:     "\x1b[38;5;245m",
+		CodeNumber:      "\x1b[38;5;125m",
+		CodeType:        "\x1b[38;5;136m",
+		CodePunctuation: "\x1b[38;5;241m",
 	}
 
 	PaletteSolarizedDark = Palette{
-		Text:           "",
-		H1:             "\x1b[1;38;5;230m",
-		H2:             "\x1b[1;38;5;64m",
-		H3:             "\x1b[1;38;5;37m",
-		H4:             "\x1b[38;5;33m",
-		H5:             "\x1b[38;5;130m",
-		H6:             "\x1b[38;5;166m",
-		Emphasis:       "\x1b[38;5;64m",
-		Strong:         "\x1b[1;38;5;136m",
-		EmphasisStrong: "\x1b[1;38;5;166m",
-		CodeInline:     "\x1b[38;5;37m",
-		CodeBlock:      "\x1b[38;5;33m",
-		Quote:          "\x1b[38;5;240m",
-		ListMarker:     "\x1b[38;5;64m",
-		LinkText:       "\x1b[1;38;5;64m",
-		LinkURL:        "\x1b[38;5;109m",
-		ThematicBreak:  "\x1b[38;5;240m",
+		Text:            "",
+		H1:              "\x1b[1;38;5;230m",
+		H2:              "\x1b[1;38;5;64m",
+		H3:              "\x1b[1;38;5;37m",
+		H4:              "\x1b[38;5;33m",
+		H5:              "\x1b[38;5;130m",
+		H6:              "\x1b[38;5;166m",
+		Emphasis:        "\x1b[38;5;64m",
+		Strong:          "\x1b[1;38;5;136m",
+		EmphasisStrong:  "\x1b[1;38;5;166m",
+		CodeInline:      "\x1b[38;5;37m",
+		CodeBlock:       "\x1b[38;5;33m",
+		Quote:           "\x1b[38;5;240m",
+		ListMarker:      "\x1b[38;5;64m",
+		LinkText:        "\x1b[1;38;5;64m",
+		LinkURL:         "\x1b[38;5;109m",
+		ThematicBreak:   "\x1b[38;5;240m",
+		CodeKeyword:     "\x1b[38;5;64m",
+		CodeString:      "\x1b[38;5;37m",
+		CodeComment:     "\x1b[38;5;240m",
+		CodeNumber:      "\x1b[38;5;125m",
+		CodeType:        "\x1b[38;5;136m",
+		CodePunctuation: "\x1b[38;5;246m",
 	}
 
 	PaletteGithubLight = Palette{
-		Text:           "",
-		H1:             "\x1b[1;38;5;24m",
-		H2:             "\x1b[1;38;5;27m",
-		H3:             "\x1b[1;38;5;61m",
-		H4:             "\x1b[38;5;67m",
-		H5:             "\x1b[38;5;109m",
-		H6:             "\x1b[38;5;130m",
-		Emphasis:       "\x1b[38;5;61m",
-		Strong:         "\x1b[1;38;5;24m",
-		EmphasisStrong: "\x1b[1;38;5;130m",
-		CodeInline:     "\x1b[38;5;61m",
-		CodeBlock:      "\x1b[38;5;67m",
-		Quote:          "\x1b[38;5;244m",
-		ListMarker:     "\x1b[38;5;61m",
-		LinkText:       "\x1b[1;38;5;61m",
-		LinkURL:        "\x1b[38;5;109m",
-		ThematicBreak:  "\x1b[38;5;244m",
+		Text:            "",
+		H1:              "\x1b[1;38;5;24m",
+		H2:              "\x1b[1;38;5;27m",
+		H3:              "\x1b[1;38;5;61m",
+		H4:              "\x1b[38;5;67m",
+		H5:              "\x1b[38;5;109m",
+		H6:              "\x1b[38;5;130m",
+		Emphasis:        "\x1b[38;5;61m",
+		Strong:          "\x1b[1;38;5;24m",
+		EmphasisStrong:  "\x1b[1;38;5;130m",
+		CodeInline:      "\x1b[38;5;61m",
+		CodeBlock:       "\x1b[38;5;67m",
+		Quote:           "\x1b[38;5;244m",
+		ListMarker:      "\x1b[38;5;61m",
+		LinkText:        "\x1b[1;38;5;61m",
+		LinkURL:         "\x1b[38;5;109m",
+		ThematicBreak:   "\x1b[38;5;244m",
+		CodeKeyword:     "\x1b[38;5;167m",
+		CodeString:      "\x1b[38;5;24m",
+		CodeComment:     "\x1b[38;5;243m",
+		CodeNumber:      "\x1b[38;5;26m",
+		CodeType:        "\x1b[38;5;166m",
+		CodePunctuation: "\x1b[38;5;236m",
 	}
 
 	PaletteGithubDark = Palette{
-		Text:           "",
-		H1:             "\x1b[1;38;5;189m",
-		H2:             "\x1b[1;38;5;111m",
-		H3:             "\x1b[1;38;5;109m",
-		H4:             "\x1b[38;5;110m",
-		H5:             "\x1b[38;5;109m",
-		H6:             "\x1b[38;5;179m",
-		Emphasis:       "\x1b[38;5;109m",
-		Strong:         "\x1b[1;38;5;111m",
-		EmphasisStrong: "\x1b[1;38;5;203m",
-		CodeInline:     "\x1b[38;5;109m",
-		CodeBlock:      "\x1b[38;5;67m",
-		Quote:          "\x1b[38;5;240m",
-		ListMarker:     "\x1b[38;5;109m",
-		LinkText:       "\x1b[1;38;5;109m",
-		LinkURL:        "\x1b[38;5;109m",
-		ThematicBreak:  "\x1b[38;5;239m",
+		Text:            "",
+		H1:              "\x1b[1;38;5;189m",
+		H2:              "\x1b[1;38;5;111m",
+		H3:              "\x1b[1;38;5;109m",
+		H4:              "\x1b[38;5;110m",
+		H5:              "\x1b[38;5;109m",
+		H6:              "\x1b[38;5;179m",
+		Emphasis:        "\x1b[38;5;109m",
+		Strong:          "\x1b[1;38;5;111m",
+		EmphasisStrong:  "\x1b[1;38;5;203m",
+		CodeInline:      "\x1b[38;5;109m",
+		CodeBlock:       "\x1b[38;5;67m",
+		Quote:           "\x1b[38;5;240m",
+		ListMarker:      "\x1b[38;5;109m",
+		LinkText:        "\x1b[1;38;5;109m",
+		LinkURL:         "\x1b[38;5;109m",
+		ThematicBreak:   "\x1b[38;5;239m",
+		CodeKeyword:     "\x1b[38;5;203m",
+		CodeString:      "\x1b[38;5;153m",
+		CodeComment:     "\x1b[38;5;245m",
+		CodeNumber:      "\x1b[38;5;117m",
+		CodeType:        "\x1b[38;5;216m",
+		CodePunctuation: "\x1b[38;5;251m",
 	}
 
 	PalettePapercolorLight = Palette{
-		Text:           "",
-		H1:             "\x1b[1;38;5;130m",
-		H2:             "\x1b[1;38;5;33m",
-		H3:             "\x1b[1;38;5;64m",
-		H4:             "\x1b[38;5;67m",
-		H5:             "\x1b[38;5;109m",
-		H6:             "\x1b[38;5;136m",
-		Emphasis:       "\x1b[38;5;64m",
-		Strong:         "\x1b[1;38;5;130m",
-		EmphasisStrong: "\x1b[1;38;5;166m",
-		CodeInline:     "\x1b[38;5;61m",
-		CodeBlock:      "\x1b[38;5;67m",
-		Quote:          "\x1b[38;5;244m",
-		ListMarker:     "\x1b[38;5;64m",
-		LinkText:       "\x1b[1;38;5;64m",
-		LinkURL:        "\x1b[38;5;109m",
-		ThematicBreak:  "\x1b[38;5;244m",
+		Text:            "",
+		H1:              "\x1b[1;38;5;130m",
+		H2:              "\x1b[1;38;5;33m",
+		H3:              "\x1b[1;38;5;64m",
+		H4:              "\x1b[38;5;67m",
+		H5:              "\x1b[38;5;109m",
+		H6:              "\x1b[38;5;136m",
+		Emphasis:        "\x1b[38;5;64m",
+		Strong:          "\x1b[1;38;5;130m",
+		EmphasisStrong:  "\x1b[1;38;5;166m",
+		CodeInline:      "\x1b[38;5;61m",
+		CodeBlock:       "\x1b[38;5;67m",
+		Quote:           "\x1b[38;5;244m",
+		ListMarker:      "\x1b[38;5;64m",
+		LinkText:        "\x1b[1;38;5;64m",
+		LinkURL:         "\x1b[38;5;109m",
+		ThematicBreak:   "\x1b[38;5;244m",
+		CodeKeyword:     "\x1b[38;5;161m",
+		CodeString:      "\x1b[38;5;64m",
+		CodeComment:     "\x1b[38;5;245m",
+		CodeNumber:      "\x1b[38;5;166m",
+		CodeType:        "\x1b[38;5;25m",
+		CodePunctuation: "\x1b[38;5;31m",
 	}
 
 	PalettePapercolorDark = Palette{
-		Text:           "",
-		H1:             "\x1b[1;38;5;223m",
-		H2:             "\x1b[1;38;5;109m",
-		H3:             "\x1b[1;38;5;110m",
-		H4:             "\x1b[38;5;110m",
-		H5:             "\x1b[38;5;109m",
-		H6:             "\x1b[38;5;179m",
-		Emphasis:       "\x1b[38;5;110m",
-		Strong:         "\x1b[1;38;5;109m",
-		EmphasisStrong: "\x1b[1;38;5;203m",
-		CodeInline:     "\x1b[38;5;110m",
-		CodeBlock:      "\x1b[38;5;67m",
-		Quote:          "\x1b[38;5;240m",
-		ListMarker:     "\x1b[38;5;110m",
-		LinkText:       "\x1b[1;38;5;110m",
-		LinkURL:        "\x1b[38;5;109m",
-		ThematicBreak:  "\x1b[38;5;239m",
+		Text:            "",
+		H1:              "\x1b[1;38;5;223m",
+		H2:              "\x1b[1;38;5;109m",
+		H3:              "\x1b[1;38;5;110m",
+		H4:              "\x1b[38;5;110m",
+		H5:              "\x1b[38;5;109m",
+		H6:              "\x1b[38;5;179m",
+		Emphasis:        "\x1b[38;5;110m",
+		Strong:          "\x1b[1;38;5;109m",
+		EmphasisStrong:  "\x1b[1;38;5;203m",
+		CodeInline:      "\x1b[38;5;110m",
+		CodeBlock:       "\x1b[38;5;67m",
+		Quote:           "\x1b[38;5;240m",
+		ListMarker:      "\x1b[38;5;110m",
+		LinkText:        "\x1b[1;38;5;110m",
+		LinkURL:         "\x1b[38;5;109m",
+		ThematicBreak:   "\x1b[38;5;239m",
+		CodeKeyword:     "\x1b[38;5;205m",
+		CodeString:      "\x1b[38;5;148m",
+		CodeComment:     "\x1b[38;5;244m",
+		CodeNumber:      "\x1b[38;5;208m",
+		CodeType:        "\x1b[38;5;74m",
+		CodePunctuation: "\x1b[38;5;110m",
 	}
 
 	PaletteOceanicNext = Palette{
-		Text:           "",
-		H1:             "\x1b[1;38;5;189m",
-		H2:             "\x1b[1;38;5;110m",
-		H3:             "\x1b[1;38;5;75m",
-		H4:             "\x1b[38;5;109m",
-		H5:             "\x1b[38;5;110m",
-		H6:             "\x1b[38;5;179m",
-		Emphasis:       "\x1b[38;5;75m",
-		Strong:         "\x1b[1;38;5;110m",
-		EmphasisStrong: "\x1b[1;38;5;203m",
-		CodeInline:     "\x1b[38;5;75m",
-		CodeBlock:      "\x1b[38;5;67m",
-		Quote:          "\x1b[38;5;240m",
-		ListMarker:     "\x1b[38;5;75m",
-		LinkText:       "\x1b[1;38;5;75m",
-		LinkURL:        "\x1b[38;5;109m",
-		ThematicBreak:  "\x1b[38;5;239m",
+		Text:            "",
+		H1:              "\x1b[1;38;5;189m",
+		H2:              "\x1b[1;38;5;110m",
+		H3:              "\x1b[1;38;5;75m",
+		H4:              "\x1b[38;5;109m",
+		H5:              "\x1b[38;5;110m",
+		H6:              "\x1b[38;5;179m",
+		Emphasis:        "\x1b[38;5;75m",
+		Strong:          "\x1b[1;38;5;110m",
+		EmphasisStrong:  "\x1b[1;38;5;203m",
+		CodeInline:      "\x1b[38;5;75m",
+		CodeBlock:       "\x1b[38;5;67m",
+		Quote:           "\x1b[38;5;240m",
+		ListMarker:      "\x1b[38;5;75m",
+		LinkText:        "\x1b[1;38;5;75m",
+		LinkURL:         "\x1b[38;5;109m",
+		ThematicBreak:   "\x1b[38;5;239m",
+		CodeKeyword:     "\x1b[38;5;176m",
+		CodeString:      "\x1b[38;5;114m",
+		CodeComment:     "\x1b[38;5;242m",
+		CodeNumber:      "\x1b[38;5;209m",
+		CodeType:        "\x1b[38;5;221m",
+		CodePunctuation: "\x1b[38;5;73m",
 	}
 
 	PaletteHorizon = Palette{
-		Text:           "",
-		H1:             "\x1b[1;38;5;222m",
-		H2:             "\x1b[1;38;5;214m",
-		H3:             "\x1b[1;38;5;203m",
-		H4:             "\x1b[38;5;209m",
-		H5:             "\x1b[38;5;141m",
-		H6:             "\x1b[38;5;110m",
-		Emphasis:       "\x1b[38;5;203m",
-		Strong:         "\x1b[1;38;5;214m",
-		EmphasisStrong: "\x1b[1;38;5;203m",
-		CodeInline:     "\x1b[38;5;209m",
-		CodeBlock:      "\x1b[38;5;167m",
-		Quote:          "\x1b[38;5;240m",
-		ListMarker:     "\x1b[38;5;203m",
-		LinkText:       "\x1b[1;38;5;203m",
-		LinkURL:        "\x1b[38;5;110m",
-		ThematicBreak:  "\x1b[38;5;239m",
+		Text:            "",
+		H1:              "\x1b[1;38;5;222m",
+		H2:              "\x1b[1;38;5;214m",
+		H3:              "\x1b[1;38;5;203m",
+		H4:              "\x1b[38;5;209m",
+		H5:              "\x1b[38;5;141m",
+		H6:              "\x1b[38;5;110m",
+		Emphasis:        "\x1b[38;5;203m",
+		Strong:          "\x1b[1;38;5;214m",
+		EmphasisStrong:  "\x1b[1;38;5;203m",
+		CodeInline:      "\x1b[38;5;209m",
+		CodeBlock:       "\x1b[38;5;167m",
+		Quote:           "\x1b[38;5;240m",
+		ListMarker:      "\x1b[38;5;203m",
+		LinkText:        "\x1b[1;38;5;203m",
+		LinkURL:         "\x1b[38;5;110m",
+		ThematicBreak:   "\x1b[38;5;239m",
+		CodeKeyword:     "\x1b[38;5;140m",
+		CodeString:      "\x1b[38;5;216m",
+		CodeComment:     "\x1b[38;5;60m",
+		CodeNumber:      "\x1b[38;5;210m",
+		CodeType:        "\x1b[38;5;222m",
+		CodePunctuation: "\x1b[38;5;37m",
 	}
 
 	PalettePalenight = Palette{
-		Text:           "",
-		H1:             "\x1b[1;38;5;189m",
-		H2:             "\x1b[1;38;5;147m",
-		H3:             "\x1b[1;38;5;141m",
-		H4:             "\x1b[38;5;110m",
-		H5:             "\x1b[38;5;109m",
-		H6:             "\x1b[38;5;179m",
-		Emphasis:       "\x1b[38;5;141m",
-		Strong:         "\x1b[1;38;5;147m",
-		EmphasisStrong: "\x1b[1;38;5;203m",
-		CodeInline:     "\x1b[38;5;110m",
-		CodeBlock:      "\x1b[38;5;67m",
-		Quote:          "\x1b[38;5;240m",
-		ListMarker:     "\x1b[38;5;141m",
-		LinkText:       "\x1b[1;38;5;141m",
-		LinkURL:        "\x1b[38;5;109m",
-		ThematicBreak:  "\x1b[38;5;239m",
+		Text:            "",
+		H1:              "\x1b[1;38;5;189m",
+		H2:              "\x1b[1;38;5;147m",
+		H3:              "\x1b[1;38;5;141m",
+		H4:              "\x1b[38;5;110m",
+		H5:              "\x1b[38;5;109m",
+		H6:              "\x1b[38;5;179m",
+		Emphasis:        "\x1b[38;5;141m",
+		Strong:          "\x1b[1;38;5;147m",
+		EmphasisStrong:  "\x1b[1;38;5;203m",
+		CodeInline:      "\x1b[38;5;110m",
+		CodeBlock:       "\x1b[38;5;67m",
+		Quote:           "\x1b[38;5;240m",
+		ListMarker:      "\x1b[38;5;141m",
+		LinkText:        "\x1b[1;38;5;141m",
+		LinkURL:         "\x1b[38;5;109m",
+		ThematicBreak:   "\x1b[38;5;239m",
+		CodeKeyword:     "\x1b[38;5;176m",
+		CodeString:      "\x1b[38;5;150m",
+		CodeComment:     "\x1b[38;5;60m",
+		CodeNumber:      "\x1b[38;5;209m",
+		CodeType:        "\x1b[38;5;222m",
+		CodePunctuation: "\x1b[38;5;117m",
 	}
 )
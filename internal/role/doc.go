@@ -0,0 +1,3 @@
+// Package role recovers which theme styles the parser gave a token, for
+// renderers that write something other than ANSI.
+package role
@@ -0,0 +1,86 @@
+package role
+
+import "pkt.systems/mdf"
+
+// Role is a style of the mdf theme, recovered from the token styles the
+// parser emits when it runs with Theme.
+type Role uint8
+
+const (
+	Heading Role = iota + 1
+	Emphasis
+	Strong
+	EmphasisStrong
+	CodeInline
+	CodeBlock
+	Quote
+	ListMarker
+	LinkText
+	LinkURL
+	ThematicBreak
+	Strikethrough
+	Image
+	CodeKeyword
+	CodeString
+	CodeComment
+	CodeNumber
+	CodeType
+	CodePunctuation
+)
+
+// Set is a set of roles.
+type Set uint32
+
+// Has reports whether r is in the set.
+func (s Set) Has(r Role) bool { return s&(1<<r) != 0 }
+
+// marker is the private prefix of a role in the theme. The parser joins the
+// prefixes of nested styles, so a token prefix is a run of markers.
+func marker(r Role) string { return string([]byte{0, byte(r)}) }
+
+var theme = func() mdf.Theme {
+	st := mdf.Styles{
+		Emphasis:        mdf.Style{Prefix: marker(Emphasis)},
+		Strong:          mdf.Style{Prefix: marker(Strong)},
+		EmphasisStrong:  mdf.Style{Prefix: marker(EmphasisStrong)},
+		CodeInline:      mdf.Style{Prefix: marker(CodeInline)},
+		CodeBlock:       mdf.Style{Prefix: marker(CodeBlock)},
+		Quote:           mdf.Style{Prefix: marker(Quote)},
+		ListMarker:      mdf.Style{Prefix: marker(ListMarker)},
+		LinkText:        mdf.Style{Prefix: marker(LinkText)},
+		LinkURL:         mdf.Style{Prefix: marker(LinkURL)},
+		ThematicBreak:   mdf.Style{Prefix: marker(ThematicBreak)},
+		Strikethrough:   mdf.Style{Prefix: marker(Strikethrough)},
+		Image:           mdf.Style{Prefix: marker(Image)},
+		CodeKeyword:     mdf.Style{Prefix: marker(CodeKeyword)},
+		CodeString:      mdf.Style{Prefix: marker(CodeString)},
+		CodeComment:     mdf.Style{Prefix: marker(CodeComment)},
+		CodeNumber:      mdf.Style{Prefix: marker(CodeNumber)},
+		CodeType:        mdf.Style{Prefix: marker(CodeType)},
+		CodePunctuation: mdf.Style{Prefix: marker(CodePunctuation)},
+	}
+	for i := range st.Heading {
+		st.Heading[i] = mdf.Style{Prefix: marker(Heading)}
+	}
+	return mdf.NewTheme("roles", st)
+}()
+
+// Theme returns the theme to parse with. Its styles carry role markers
+// instead of ANSI sequences, so a stream sees which roles a token has
+// whatever theme its output is coloured with.
+func Theme() mdf.Theme {
+	return theme
+}
+
+// Of decodes the markers of a token style.
+func Of(st mdf.Style) Set {
+	var set Set
+	p := st.Prefix
+	for i := 0; i+1 < len(p); i++ {
+		if p[i] == 0 {
+			set |= 1 << p[i+1]
+			i++
+		}
+	}
+	return set
+}
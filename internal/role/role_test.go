@@ -0,0 +1,40 @@
+package role
+
+import (
+	"strings"
+	"testing"
+
+	"pkt.systems/mdf"
+)
+
+type collect struct{ sets map[string]Set }
+
+func (c *collect) Width() int           { return 0 }
+func (c *collect) SetWidth(int)         {}
+func (c *collect) SetWrapIndent(string) {}
+func (c *collect) Flush() error         { return nil }
+
+func (c *collect) WriteToken(tok mdf.StreamToken) error {
+	if strings.TrimSpace(tok.Text) != "" {
+		c.sets[tok.Text] |= Of(tok.Style)
+	}
+	return nil
+}
+
+func TestOfNestedStyles(t *testing.T) {
+	c := &collect{sets: make(map[string]Set)}
+	err := mdf.Parse(mdf.ParseRequest{
+		Reader: strings.NewReader("**b ~~s~~** `c`\n"),
+		Stream: c,
+		Theme:  Theme(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if set := c.sets["s"]; !set.Has(Strong) || !set.Has(Strikethrough) || set.Has(Emphasis) {
+		t.Fatalf("roles of s = %b", set)
+	}
+	if set := c.sets["c"]; !set.Has(CodeInline) || set.Has(Strong) {
+		t.Fatalf("roles of c = %b", set)
+	}
+}
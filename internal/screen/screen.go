@@ -0,0 +1,155 @@
+// Package screen lays out the output of the terminal renderer on a grid of
+// cells for the image renderers.
+package screen
+
+import (
+	"context"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/muesli/reflow/ansi"
+	"pkt.systems/mdf"
+)
+
+// Span is a run of text on one line drawn in one style.
+type Span struct {
+	Col   int
+	Width int
+	Text  string
+	Style mdf.Style
+	Link  string
+}
+
+// Screen is terminal output laid out on a grid of cells.
+type Screen struct {
+	Lines [][]Span
+	// Cols is the width of the widest line.
+	Cols int
+}
+
+// Render renders Markdown from r with the terminal renderer at width
+// columns, with OSC 8 links on, and lays out the output.
+func Render(ctx context.Context, r io.Reader, theme mdf.Theme, width int, opts ...mdf.RenderOption) (Screen, error) {
+	var out strings.Builder
+	err := mdf.RenderContext(ctx, mdf.RenderRequest{
+		Reader:  r,
+		Writer:  &out,
+		Width:   width,
+		Theme:   theme,
+		Options: append([]mdf.RenderOption{mdf.WithOSC8(true)}, opts...),
+	})
+	if err != nil {
+		return Screen{}, err
+	}
+	return Parse(out.String()), nil
+}
+
+// Parse lays out terminal output: SGR sequences set the style of the text
+// after them, OSC 8 sequences its link, and other escape sequences are
+// dropped.
+func Parse(out string) Screen {
+	var (
+		sc    Screen
+		line  []Span
+		text  strings.Builder
+		sgr   string
+		style mdf.Style
+		link  string
+		col   int
+		start int
+	)
+	flush := func() {
+		if text.Len() > 0 {
+			line = append(line, Span{Col: start, Width: col - start, Text: text.String(), Style: style, Link: link})
+			text.Reset()
+		}
+		start = col
+	}
+	for i := 0; i < len(out); {
+		c := out[i]
+		switch {
+		case c == '\n':
+			flush()
+			sc.Lines = append(sc.Lines, line)
+			sc.Cols = max(sc.Cols, col)
+			line, col, start = nil, 0, 0
+			i++
+		case c == '\x1b' && i+1 < len(out) && out[i+1] == '[':
+			end := i + 2
+			for end < len(out) && (out[end] < 0x40 || out[end] > 0x7e) {
+				end++
+			}
+			if end < len(out) && out[end] == 'm' {
+				flush()
+				// The style is parsed from every sequence since the last
+				// reset, so each one applies on top of the ones before.
+				sgr += out[i : end+1]
+				style = mdf.ParseStyle(sgr)
+				if style.Prefix = ""; style == (mdf.Style{}) {
+					sgr = ""
+				}
+			}
+			i = end + 1
+		case c == '\x1b' && i+1 < len(out) && out[i+1] == ']':
+			body, next := oscBody(out, i+2)
+			if rest, ok := strings.CutPrefix(body, "8;"); ok {
+				flush()
+				link = ""
+				if _, uri, ok := strings.Cut(rest, ";"); ok {
+					// Drop a link title kept after the destination.
+					link, _, _ = strings.Cut(uri, " ")
+				}
+			}
+			i = next
+		case c == '\x1b':
+			i += 2
+		case c == '\t':
+			n := 8 - col%8
+			text.WriteString(strings.Repeat(" ", n))
+			col += n
+			i++
+		case c < 0x20:
+			i++
+		default:
+			_, size := utf8.DecodeRuneInString(out[i:])
+			text.WriteString(out[i : i+size])
+			col += ansi.PrintableRuneWidth(out[i : i+size])
+			i += size
+		}
+	}
+	flush()
+	sc.Cols = max(sc.Cols, col)
+	if len(line) > 0 {
+		sc.Lines = append(sc.Lines, line)
+	}
+	for len(sc.Lines) > 0 && len(sc.Lines[len(sc.Lines)-1]) == 0 {
+		sc.Lines = sc.Lines[:len(sc.Lines)-1]
+	}
+	return sc
+}
+
+// oscBody returns the body of the OSC sequence starting at i, ended by BEL
+// or ST, and the index after it.
+func oscBody(out string, i int) (string, int) {
+	for j := i; j < len(out); j++ {
+		switch out[j] {
+		case '\a':
+			return out[i:j], j + 1
+		case '\x1b':
+			if j+1 < len(out) && out[j+1] == '\\' {
+				return out[i:j], j + 2
+			}
+		}
+	}
+	return out[i:], len(out)
+}
+
+// Colors returns the page colours for an image of output in theme: light
+// for built-in light themes, dark otherwise.
+func Colors(theme mdf.Theme) (background, text [3]int) {
+	if mdf.ThemeBackground(theme) == mdf.BackgroundLight {
+		return [3]int{250, 250, 250}, [3]int{56, 58, 66}
+	}
+	return [3]int{0, 0, 0}, [3]int{220, 220, 220}
+}
@@ -0,0 +1,40 @@
+package screen
+
+import (
+	"testing"
+
+	"pkt.systems/mdf"
+)
+
+func TestParse(t *testing.T) {
+	out := "\x1b[1;31mab\x1b[4mc\x1b[0m d\n" +
+		"\x1b]8;;https://x.y \"t\"\x1b\\link\x1b]8;;\x1b\\ 世界\tx\n\n\n"
+	sc := Parse(out)
+	if len(sc.Lines) != 2 {
+		t.Fatalf("lines = %d, want 2", len(sc.Lines))
+	}
+	first := sc.Lines[0]
+	if len(first) != 3 {
+		t.Fatalf("first line spans = %+v", first)
+	}
+	red := mdf.IndexedColor(1)
+	if s := first[0]; s.Text != "ab" || s.Col != 0 || s.Style.FG != red || !s.Style.Bold || s.Style.Underline {
+		t.Fatalf("span 0 = %+v", s)
+	}
+	if s := first[1]; s.Text != "c" || s.Col != 2 || s.Style.FG != red || !s.Style.Underline {
+		t.Fatalf("span 1 = %+v", s)
+	}
+	if s := first[2]; s.Text != " d" || s.Col != 3 || s.Style.FG != (mdf.Color{}) || s.Style.Bold {
+		t.Fatalf("span 2 = %+v", s)
+	}
+	second := sc.Lines[1]
+	if s := second[0]; s.Text != "link" || s.Link != "https://x.y" {
+		t.Fatalf("link span = %+v", s)
+	}
+	if s := second[1]; s.Text != " 世界       x" || s.Col != 4 || s.Width != 13 || s.Link != "" {
+		t.Fatalf("wide span = %+v", s)
+	}
+	if sc.Cols != 17 {
+		t.Fatalf("cols = %d, want 17", sc.Cols)
+	}
+}
@@ -0,0 +1,81 @@
+// Package table collects the tables the parser reports through table
+// events, for the renderers that write a table only once it is complete.
+package table
+
+import (
+	"strings"
+
+	"pkt.systems/mdf"
+)
+
+// Table is a table being collected.
+type Table struct {
+	// Open is set from the table's start event to its end event.
+	Open bool
+	// Header is the number of header rows.
+	Header int
+	// Aligns holds the alignment of each column.
+	Aligns []mdf.CellAlign
+	// Rows holds the tokens of each cell, row by row.
+	Rows [][][]mdf.StreamToken
+
+	cell bool
+	col  int
+}
+
+// Token adds tok and reports whether it ends the table. A table start event
+// opens a new table; other tokens are ignored until one does. Text the
+// parser draws outside the cells, such as rules, bars and padding, is
+// dropped.
+func (t *Table) Token(tok mdf.StreamToken) bool {
+	info := tok.Block
+	switch tok.Kind {
+	case mdf.TokenTableStart:
+		*t = Table{Open: true}
+	case mdf.TokenTableEnd:
+		t.Open = false
+		return true
+	case mdf.TokenTableRowStart:
+		if info.Header {
+			t.Header++
+		}
+		t.Rows = append(t.Rows, nil)
+	case mdf.TokenTableCellStart:
+		if len(t.Rows) == 0 {
+			break
+		}
+		row := &t.Rows[len(t.Rows)-1]
+		for len(*row) <= info.Column {
+			*row = append(*row, nil)
+		}
+		for len(t.Aligns) <= info.Column {
+			t.Aligns = append(t.Aligns, mdf.AlignNone)
+		}
+		t.Aligns[info.Column] = info.Align
+		if len((*row)[info.Column]) > 0 {
+			// The next line of a wrapped cell.
+			(*row)[info.Column] = append((*row)[info.Column], mdf.StreamToken{Token: mdf.Token{Text: " "}})
+		}
+		t.cell = true
+		t.col = info.Column
+	case mdf.TokenTableCellEnd:
+		t.cell = false
+	default:
+		if t.Open && t.cell {
+			row := t.Rows[len(t.Rows)-1]
+			tok.Text = strings.Clone(tok.Text)
+			tok.LinkURL = strings.Clone(tok.LinkURL)
+			row[t.col] = append(row[t.col], tok)
+		}
+	}
+	return false
+}
+
+// Columns returns the number of columns.
+func (t *Table) Columns() int {
+	n := len(t.Aligns)
+	for _, row := range t.Rows {
+		n = max(n, len(row))
+	}
+	return n
+}
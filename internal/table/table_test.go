@@ -0,0 +1,64 @@
+package table
+
+import (
+	"strings"
+	"testing"
+
+	"pkt.systems/mdf"
+)
+
+type collect struct {
+	table Table
+	done  int
+}
+
+func (c *collect) Width() int           { return 0 }
+func (c *collect) SetWidth(int)         {}
+func (c *collect) SetWrapIndent(string) {}
+func (c *collect) Flush() error         { return nil }
+
+func (c *collect) WriteToken(tok mdf.StreamToken) error {
+	if (c.table.Open || tok.Kind == mdf.TokenTableStart) && c.table.Token(tok) {
+		c.done++
+	}
+	return nil
+}
+
+func cellText(tokens []mdf.StreamToken) string {
+	var b strings.Builder
+	for _, tok := range tokens {
+		b.WriteString(tok.Text)
+	}
+	return b.String()
+}
+
+func TestTableFromEvents(t *testing.T) {
+	c := &collect{}
+	err := mdf.Parse(mdf.ParseRequest{
+		Reader: strings.NewReader("| a | b \\| c |\n|:-:|--:|\n| 1 | x │ y |\n\ntext\n"),
+		Stream: c,
+		Theme:  mdf.DefaultTheme(),
+	})
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	tbl := c.table
+	if c.done != 1 || tbl.Open {
+		t.Fatalf("expected one closed table, got %d (open %v)", c.done, tbl.Open)
+	}
+	if tbl.Header != 1 || tbl.Columns() != 2 {
+		t.Fatalf("header %d, columns %d", tbl.Header, tbl.Columns())
+	}
+	if tbl.Aligns[0] != mdf.AlignCenter || tbl.Aligns[1] != mdf.AlignRight {
+		t.Fatalf("aligns %v", tbl.Aligns)
+	}
+	var got []string
+	for _, row := range tbl.Rows {
+		for _, cell := range row {
+			got = append(got, cellText(cell))
+		}
+	}
+	if strings.Join(got, "/") != "a/b | c/1/x │ y" {
+		t.Fatalf("cells %q", got)
+	}
+}
@@ -0,0 +1,63 @@
+package latex
+
+import "pkt.systems/mdf"
+
+// Config holds LaTeX rendering settings.
+type Config struct {
+	// Document writes a complete article with a preamble instead of a
+	// fragment to include in a template.
+	Document bool
+	// Title, Author and Date are used by a Document where the front matter
+	// does not set them.
+	Title  string
+	Author string
+	Date   string
+	// Unnumbered uses the starred sectioning commands, such as \section*.
+	Unnumbered bool
+	// Listings writes code blocks as lstlisting environments, with the
+	// language of the fence where listings knows it, instead of verbatim.
+	Listings   bool
+	HTMLPolicy mdf.HTMLPolicy
+}
+
+// DefaultConfig returns a baseline configuration.
+func DefaultConfig() Config {
+	return Config{}
+}
+
+func applyConfig(dst *Config, src Config) {
+	if src.Document {
+		dst.Document = src.Document
+	}
+	if src.Title != "" {
+		dst.Title = src.Title
+	}
+	if src.Author != "" {
+		dst.Author = src.Author
+	}
+	if src.Date != "" {
+		dst.Date = src.Date
+	}
+	if src.Unnumbered {
+		dst.Unnumbered = src.Unnumbered
+	}
+	if src.Listings {
+		dst.Listings = src.Listings
+	}
+	if src.HTMLPolicy != mdf.HTMLAsText {
+		dst.HTMLPolicy = src.HTMLPolicy
+	}
+}
+
+// applyFrontMatter sets the document fields the front matter names.
+func applyFrontMatter(dst *Config, fields map[string]string) {
+	for key, field := range map[string]*string{
+		"title":  &dst.Title,
+		"author": &dst.Author,
+		"date":   &dst.Date,
+	} {
+		if v := fields[key]; v != "" {
+			*field = v
+		}
+	}
+}
@@ -0,0 +1,28 @@
+// Package latex renders Markdown to LaTeX using the mdf streaming parser,
+// so notes written in Markdown can go into a journal or report template.
+//
+// Headings of levels 1 to 5 become \section, \subsection, \subsubsection,
+// \paragraph and \subparagraph. Lists are itemize and enumerate
+// environments, block quotes quote environments, and code blocks verbatim,
+// or lstlisting with Config.Listings. Links are written with \href, or
+// \url when the text is the address itself; footnotes become \footnote at
+// their reference, and tables tabular environments. Text is escaped, so
+// characters such as $, % and _ come out as written.
+//
+// By default the output is a fragment for a template to \input. It uses
+// commands from the hyperref, graphicx and ulem packages, and listings for
+// Config.Listings, which the template must load. With Config.Document the
+// output is a complete article that loads them itself, with the title,
+// author and date taken from the front matter, falling back to the Config.
+//
+// Example:
+//
+//	err := latex.Render(latex.RenderRequest{
+//		Reader: f,
+//		Writer: out,
+//		Config: latex.Config{Listings: true},
+//	})
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+package latex
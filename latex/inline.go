@@ -0,0 +1,236 @@
+package latex
+
+import (
+	"strings"
+
+	"pkt.systems/mdf"
+	"pkt.systems/mdf/internal/role"
+)
+
+// notePrefix and noteSuffix enclose the number of a footnote reference
+// until the note's text is known. Text never contains them, since escape
+// drops NUL.
+const (
+	notePrefix = "\x00fn:"
+	noteSuffix = "\x00"
+)
+
+// inline collects the content of a paragraph, heading or table cell as
+// LaTeX.
+type inline struct {
+	buf []byte
+	// open holds the commands whose argument is open, outermost first.
+	open []string
+	// flat leaves links and line breaks out, as a heading needs.
+	flat bool
+	// brk is a hard line break, written once more text follows.
+	brk  bool
+	link linkKind
+	// linkAt is the offset in buf of the open link's text; raw collects
+	// the text itself.
+	linkAt  int
+	linkURL string
+	raw     strings.Builder
+	// linkImage is set when the link holds an image of its own target,
+	// which is how the parser writes every image.
+	linkImage bool
+}
+
+type linkKind uint8
+
+const (
+	linkNone linkKind = iota
+	linkHref
+	// linkPlain is an internal link, of which only the text is written.
+	linkPlain
+	// linkSkip drops the text of footnote references and back links.
+	linkSkip
+)
+
+// commands returns the commands for a token, outermost first.
+func commands(tok mdf.StreamToken, set role.Set) []string {
+	var cmds []string
+	if set.Has(role.Strikethrough) {
+		cmds = append(cmds, `\sout`)
+	}
+	if set.Has(role.Strong) || set.Has(role.EmphasisStrong) {
+		cmds = append(cmds, `\textbf`)
+	}
+	if set.Has(role.Emphasis) || set.Has(role.EmphasisStrong) {
+		cmds = append(cmds, `\emph`)
+	}
+	if tok.Kind == mdf.TokenCode {
+		cmds = append(cmds, `\texttt`)
+	}
+	return cmds
+}
+
+// token adds an inline token.
+func (in *inline) token(tok mdf.StreamToken, set role.Set) {
+	switch tok.Kind {
+	case mdf.TokenLinkStart:
+		in.startLink(tok.LinkURL)
+		return
+	case mdf.TokenLinkEnd:
+		in.endLink()
+		return
+	case mdf.TokenAnchor:
+		return
+	}
+	if in.link == linkSkip {
+		return
+	}
+	if in.brk {
+		in.brk = false
+		if in.flat {
+			in.buf = append(in.buf, ' ')
+		} else {
+			in.buf = append(in.buf, "\\\\\n"...)
+		}
+	}
+	if tok.Kind == mdf.TokenImage {
+		if in.link == linkHref && tok.LinkURL == in.linkURL {
+			in.linkImage = true
+		}
+		in.image(tok.Text, tok.LinkURL)
+		return
+	}
+	in.set(commands(tok, set))
+	if strings.HasPrefix(tok.Text, "-") && len(in.buf) > 0 && in.buf[len(in.buf)-1] == '-' {
+		// The parser hands text over a rune at a time; keep hyphens from
+		// joining across tokens too.
+		in.buf = append(in.buf, "{}"...)
+	}
+	in.buf = append(in.buf, escape(tok.Text)...)
+	if in.link != linkNone {
+		in.raw.WriteString(tok.Text)
+	}
+}
+
+// image writes a local image with \includegraphics, or its alt text in
+// brackets when the source is a URL or a path LaTeX cannot read.
+func (in *inline) image(alt, src string) {
+	in.set(nil)
+	if src == "" || strings.ContainsAny(src, `:%#{}\`) {
+		in.buf = append(in.buf, escape("["+alt+"]")...)
+		return
+	}
+	in.buf = append(in.buf, `\includegraphics[width=\linewidth]{`+src+`}`...)
+}
+
+// set closes and opens commands so that exactly want is open.
+func (in *inline) set(want []string) {
+	keep := 0
+	for keep < len(want) && keep < len(in.open) && want[keep] == in.open[keep] {
+		keep++
+	}
+	for len(in.open) > keep {
+		in.buf = append(in.buf, '}')
+		in.open = in.open[:len(in.open)-1]
+	}
+	for _, cmd := range want[keep:] {
+		in.buf = append(in.buf, cmd+"{"...)
+		in.open = append(in.open, cmd)
+	}
+}
+
+// empty reports whether nothing has been added.
+func (in *inline) empty() bool {
+	return len(in.buf) == 0 && len(in.open) == 0
+}
+
+// breakLine adds a hard line break.
+func (in *inline) breakLine() {
+	if len(in.buf) > 0 {
+		in.brk = true
+	}
+}
+
+func (in *inline) startLink(url string) {
+	in.set(nil)
+	switch {
+	case strings.HasPrefix(url, "#fnref-"):
+		in.link = linkSkip
+	case strings.HasPrefix(url, "#fn-"):
+		in.buf = append(in.buf, notePrefix+strings.TrimPrefix(url, "#fn-")+noteSuffix...)
+		in.link = linkSkip
+	case strings.HasPrefix(url, "#") || in.flat:
+		in.link = linkPlain
+	default:
+		if i := strings.IndexAny(url, " \t"); i >= 0 {
+			url = url[:i]
+		}
+		in.link = linkHref
+		in.linkURL = url
+		in.linkAt = len(in.buf)
+		in.raw.Reset()
+		in.linkImage = false
+	}
+}
+
+func (in *inline) endLink() {
+	in.set(nil)
+	if in.link == linkHref {
+		url := in.linkURL
+		text := string(in.buf[in.linkAt:])
+		in.buf = in.buf[:in.linkAt]
+		switch raw := in.raw.String(); {
+		case in.linkImage && raw == "":
+			in.buf = append(in.buf, text...)
+		case raw == url && (strings.HasPrefix(url, "http://") || strings.HasPrefix(url, "https://")):
+			in.buf = append(in.buf, `\url{`+escapeURL(url)+`}`...)
+		default:
+			in.buf = append(in.buf, `\href{`+escapeURL(url)+`}{`+text+`}`...)
+		}
+	}
+	in.link = linkNone
+}
+
+// finish returns the content, closing what is still open, and resets in.
+func (in *inline) finish() string {
+	if in.link != linkNone {
+		in.endLink()
+	}
+	in.set(nil)
+	out := strings.TrimSpace(string(in.buf))
+	flat := in.flat
+	*in = inline{buf: in.buf[:0], flat: flat}
+	return out
+}
+
+var escaper = strings.NewReplacer(
+	`\`, `\textbackslash{}`,
+	"{", `\{`,
+	"}", `\}`,
+	"$", `\$`,
+	"&", `\&`,
+	"%", `\%`,
+	"#", `\#`,
+	"_", `\_`,
+	"~", `\textasciitilde{}`,
+	"^", `\textasciicircum{}`,
+	"<", `\textless{}`,
+	">", `\textgreater{}`,
+	"|", `\textbar{}`,
+	"`", `\textasciigrave{}`,
+	"\u00a0", "~",
+	"\x00", "",
+)
+
+// escape escapes the characters LaTeX gives a meaning to, and splits runs
+// of hyphens, which it would join into dashes.
+func escape(text string) string {
+	text = escaper.Replace(text)
+	for strings.Contains(text, "--") {
+		text = strings.ReplaceAll(text, "--", "-{}-")
+	}
+	return text
+}
+
+var urlEscaper = strings.NewReplacer(`\`, "%5C", "#", `\#`, "%", `\%`, "{", `\{`, "}", `\}`)
+
+// escapeURL escapes a URL for \href and \url, which take most characters
+// as they are.
+func escapeURL(url string) string {
+	return urlEscaper.Replace(url)
+}
@@ -0,0 +1,107 @@
+package latex
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+
+	"pkt.systems/mdf"
+	"pkt.systems/mdf/internal/role"
+)
+
+// RenderRequest contains inputs for LaTeX rendering.
+type RenderRequest struct {
+	Reader io.Reader
+	Writer io.Writer
+	Config Config
+}
+
+// Render writes Markdown as LaTeX: a fragment for a template to \input, or
+// a complete article when Config.Document is set.
+func Render(req RenderRequest) error {
+	return RenderContext(context.Background(), req)
+}
+
+// RenderContext is Render with cancellation. The input is read in full
+// first; once ctx is done it returns ctx.Err() without writing anything.
+func RenderContext(ctx context.Context, req RenderRequest) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if req.Reader == nil {
+		return fmt.Errorf("latex render: reader is nil")
+	}
+	if req.Writer == nil {
+		return fmt.Errorf("latex render: writer is nil")
+	}
+	src, err := io.ReadAll(req.Reader)
+	if err != nil {
+		return fmt.Errorf("latex render: %w", err)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	front, body := mdf.SplitFrontMatter(src)
+	cfg := DefaultConfig()
+	applyConfig(&cfg, req.Config)
+	applyFrontMatter(&cfg, mdf.FrontMatterFields(front))
+	var out bytes.Buffer
+	if err := mdf.ParseContext(ctx, mdf.ParseRequest{
+		Reader:  bytes.NewReader(body),
+		Stream:  NewStream(&out, cfg),
+		Theme:   role.Theme(),
+		Options: []mdf.RenderOption{mdf.WithOSC8(true), mdf.WithHTMLPolicy(cfg.HTMLPolicy)},
+	}); err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil && err == ctxErr {
+			return err
+		}
+		return fmt.Errorf("latex render: %w", err)
+	}
+	text := out.Bytes()
+	if cfg.Document {
+		text = document(cfg, text)
+	}
+	if _, err := req.Writer.Write(text); err != nil {
+		return fmt.Errorf("latex render: %w", err)
+	}
+	return nil
+}
+
+// ParseTheme returns the theme a Stream expects the parser to run with.
+// Pass it as the Theme of an mdf.ParseRequest that feeds a Stream, with
+// mdf.WithOSC8(true) among its Options.
+func ParseTheme() mdf.Theme {
+	return role.Theme()
+}
+
+// document returns body as an article, with a preamble loading the
+// packages the body uses.
+func document(cfg Config, body []byte) []byte {
+	var b bytes.Buffer
+	b.WriteString("\\documentclass{article}\n")
+	b.WriteString("\\usepackage[T1]{fontenc}\n")
+	b.WriteString("\\usepackage[utf8]{inputenc}\n")
+	b.WriteString("\\usepackage{graphicx}\n")
+	b.WriteString("\\usepackage[normalem]{ulem}\n")
+	if cfg.Listings {
+		b.WriteString("\\usepackage{listings}\n")
+	}
+	b.WriteString("\\usepackage{hyperref}\n")
+	if cfg.Title != "" {
+		b.WriteString("\\title{" + escape(cfg.Title) + "}\n")
+		b.WriteString("\\author{" + escape(cfg.Author) + "}\n")
+		b.WriteString("\\date{" + escape(cfg.Date) + "}\n")
+	}
+	b.WriteString("\\begin{document}\n")
+	if cfg.Title != "" {
+		b.WriteString("\\maketitle\n")
+	}
+	if len(body) > 0 {
+		b.WriteByte('\n')
+		b.Write(body)
+		b.WriteByte('\n')
+	}
+	b.WriteString("\\end{document}\n")
+	return b.Bytes()
+}
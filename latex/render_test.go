@@ -0,0 +1,177 @@
+package latex
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+func renderString(t *testing.T, src string, cfg Config) string {
+	t.Helper()
+	var out bytes.Buffer
+	if err := Render(RenderRequest{Reader: strings.NewReader(src), Writer: &out, Config: cfg}); err != nil {
+		t.Fatalf("render: %v", err)
+	}
+	return out.String()
+}
+
+func TestRenderBlocks(t *testing.T) {
+	src := strings.Join([]string{
+		"# Intro",
+		"",
+		"Some *em*, **bold**, ~~old~~ and `co_de`.",
+		"",
+		"### Deep",
+		"",
+		"3. three",
+		"4. four",
+		"   - [x] done",
+		"   - plain",
+		"",
+		"> quoted",
+		"",
+		"```",
+		`\begin{x} $y$`,
+		"```",
+		"",
+		"---",
+		"",
+	}, "\n")
+	got := renderString(t, src, Config{})
+	want := strings.Join([]string{
+		`\section{Intro}`,
+		"",
+		`Some \emph{em}, \textbf{bold}, \sout{old} and \texttt{co\_de}.`,
+		"",
+		`\subsubsection{Deep}`,
+		"",
+		`\begin{enumerate}`,
+		`\setcounter{enumi}{2}`,
+		`\item three`,
+		`\item four`,
+		`\begin{itemize}`,
+		`\item[{[x]}] done`,
+		`\item plain`,
+		`\end{itemize}`,
+		`\end{enumerate}`,
+		"",
+		`\begin{quote}`,
+		"quoted",
+		`\end{quote}`,
+		"",
+		`\begin{verbatim}`,
+		`\begin{x} $y$`,
+		`\end{verbatim}`,
+		"",
+		`\begin{center}\rule{0.5\linewidth}{0.4pt}\end{center}`,
+		"",
+	}, "\n")
+	if got != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", got, want)
+	}
+	if got := renderString(t, "## A\n", Config{Unnumbered: true}); got != "\\subsection*{A}\n" {
+		t.Fatalf("unnumbered heading gave %q", got)
+	}
+}
+
+func TestRenderEscapesText(t *testing.T) {
+	got := renderString(t, "Cost: $5 & 10% of #1, {a} ~b^ <c> | d\\e.\n", Config{})
+	want := `Cost: \$5 \& 10\% of \#1, \{a\} \textasciitilde{}b\textasciicircum{} \textless{}c\textgreater{} \textbar{} d\textbackslash{}e.` + "\n"
+	if got != want {
+		t.Fatalf("got %q\nwant %q", got, want)
+	}
+}
+
+func TestRenderEscapesDashesAndBackticks(t *testing.T) {
+	got := renderString(t, "a -- b --- c `` d\n", Config{})
+	want := "a -{}- b -{}-{}- c \\textasciigrave{}\\textasciigrave{} d\n"
+	if got != want {
+		t.Fatalf("got %q\nwant %q", got, want)
+	}
+}
+
+func TestRenderCodeHoldingEnvironmentEnd(t *testing.T) {
+	src := "```\n\\end{verbatim}\n  x\n```\n"
+	got := renderString(t, src, Config{})
+	want := "\\texttt{\\textbackslash{}end\\{verbatim\\}}\\\\\n\\texttt{~~x}\n"
+	if got != want {
+		t.Fatalf("got %q\nwant %q", got, want)
+	}
+	if got := renderString(t, src, Config{Listings: true}); !strings.Contains(got, `\begin{lstlisting}`) {
+		t.Fatalf("listings fell back for code holding only \\end{verbatim}:\n%s", got)
+	}
+}
+
+func TestRenderLinksAndNotes(t *testing.T) {
+	src := "See [the docs](https://x.y/a_b#c%20d), <https://x.y>, [here](#intro) and ![pic](pic.png).\n\nA note[^n].\n\n[^n]: With *style* & more.\n"
+	got := renderString(t, src, Config{})
+	for _, want := range []string{
+		`\href{https://x.y/a_b\#c\%20d}{the docs}`,
+		`\url{https://x.y}`,
+		", here and ",
+		`\includegraphics[width=\linewidth]{pic.png}.`,
+		`A note\footnote{With \emph{style} \& more.}.`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("missing %q in:\n%s", want, got)
+		}
+	}
+	for _, unwanted := range []string{"Footnotes", "enumerate", `\href{pic.png}`} {
+		if strings.Contains(got, unwanted) {
+			t.Errorf("unexpected %q in:\n%s", unwanted, got)
+		}
+	}
+}
+
+func TestRenderTableAndListings(t *testing.T) {
+	src := "| A | Mid | C |\n|:--|:-:|--:|\n| 1 & 2 | x | 10% |\n\n```python\nprint(1)\n```\n"
+	got := renderString(t, src, Config{Listings: true})
+	want := strings.Join([]string{
+		`\begin{tabular}{lcr}`,
+		`\hline`,
+		`\textbf{A} & \textbf{Mid} & \textbf{C} \\`,
+		`\hline`,
+		`1 \& 2 & x & 10\% \\`,
+		`\hline`,
+		`\end{tabular}`,
+		"",
+		`\begin{lstlisting}[language=Python]`,
+		"print(1)",
+		`\end{lstlisting}`,
+		"",
+	}, "\n")
+	if got != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestRenderDocument(t *testing.T) {
+	src := "---\ntitle: Costs & 5%\nauthor: Team\n---\nBody.\n"
+	got := renderString(t, src, Config{Document: true, Title: "ignored", Date: "2026"})
+	for _, want := range []string{
+		"\\documentclass{article}\n",
+		"\\usepackage{hyperref}\n",
+		"\\title{Costs \\& 5\\%}\n\\author{Team}\n\\date{2026}\n\\begin{document}\n\\maketitle\n\nBody.\n\n\\end{document}\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("missing %q in:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "listings") {
+		t.Errorf("listings loaded without Listings:\n%s", got)
+	}
+}
+
+func TestRenderContextCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	var out bytes.Buffer
+	err := RenderContext(ctx, RenderRequest{Reader: strings.NewReader("hi\n"), Writer: &out})
+	if err != context.Canceled {
+		t.Fatalf("err = %v, want context.Canceled", err)
+	}
+	if out.Len() != 0 {
+		t.Fatalf("wrote %d bytes", out.Len())
+	}
+}
@@ -0,0 +1,605 @@
+package latex
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+
+	"pkt.systems/mdf"
+	"pkt.systems/mdf/internal/role"
+	"pkt.systems/mdf/internal/table"
+)
+
+// Stream is an mdf.Stream that writes LaTeX. The parser must run with
+// ParseTheme and with OSC 8 links on; otherwise emphasis and link
+// destinations are lost.
+//
+// Footnote definitions come at the end of the token stream, so Stream
+// holds its output until Flush, when it writes each note as a \footnote
+// where it is referenced. It does not write a preamble; Render adds one
+// for a Document.
+type Stream struct {
+	w   io.Writer
+	cfg Config
+	err error
+
+	doc []byte
+	// notes holds the text of each footnote by number; note is the one
+	// being read, whose content goes to noteBuf.
+	notes   map[string]string
+	note    string
+	noteBuf []byte
+	// sep is set after a block, which the next block is separated from by
+	// a blank line.
+	sep bool
+	// notesHeading is the offset of the "Footnotes" heading the parser
+	// writes before the definitions, while it is the last block; -1
+	// otherwise.
+	notesHeading int
+
+	containers []container
+	lists      []list
+	leaf       leafKind
+	level      int
+	// lineStart is set at the start of each line of a block, where the
+	// parser draws quote bars, list markers and indentation.
+	lineStart bool
+	// task is set until the parser's "[x]" of a task item has been dropped.
+	task  mdf.TaskState
+	in    inline
+	code  codeState
+	table table.Table
+}
+
+type leafKind uint8
+
+const (
+	leafNone leafKind = iota
+	leafParagraph
+	leafHeading
+	leafCode
+)
+
+// container is a block quote or a list item, with the offset of its start
+// in the output.
+type container struct {
+	quote  bool
+	offset int
+}
+
+type list struct {
+	ordered bool
+	// notes is set for the list of footnote definitions, which is not
+	// written as a list.
+	notes  bool
+	offset int
+	// cut is where the output is cut back to if the list holds footnotes.
+	cut int
+}
+
+type codeState struct {
+	lang  string
+	lines []string
+	line  strings.Builder
+}
+
+// NewStream returns a Stream writing to w. Of cfg, Unnumbered and Listings
+// apply.
+func NewStream(w io.Writer, cfg Config) *Stream {
+	return &Stream{w: w, cfg: cfg, notes: make(map[string]string), notesHeading: -1}
+}
+
+// Width reports 0: LaTeX fills lines, and tables are not shrunk.
+func (s *Stream) Width() int { return 0 }
+
+// SetWidth is a no-op.
+func (s *Stream) SetWidth(int) {}
+
+// SetWrapIndent is a no-op.
+func (s *Stream) SetWrapIndent(string) {}
+
+// WriteToken adds tok.
+func (s *Stream) WriteToken(tok mdf.StreamToken) error {
+	if s.err != nil {
+		return s.err
+	}
+	set := role.Of(tok.Style)
+	if s.table.Open || tok.Kind == mdf.TokenTableStart {
+		if s.table.Token(tok) {
+			s.endTable()
+		}
+		return nil
+	}
+	if mdf.IsBlockEvent(tok.Kind) {
+		s.block(tok)
+		return nil
+	}
+	switch s.leaf {
+	case leafCode:
+		s.codeToken(tok)
+	case leafParagraph, leafHeading:
+		s.inlineToken(tok, set)
+	default:
+		switch {
+		case tok.Kind == mdf.TokenAnchor:
+			s.anchor(tok.LinkURL)
+		case tok.Kind == mdf.TokenThematicBreak:
+			s.writeBlock(`\begin{center}\rule{0.5\linewidth}{0.4pt}\end{center}`)
+		}
+	}
+	return nil
+}
+
+// Flush writes the document, with the open blocks closed and each
+// footnote in place of its reference.
+func (s *Stream) Flush() error {
+	if s.err != nil {
+		return s.err
+	}
+	s.endLeaf()
+	if s.table.Open {
+		s.endTable()
+	}
+	for len(s.containers) > 0 {
+		s.popContainer()
+	}
+	for len(s.lists) > 0 {
+		s.endList()
+	}
+	out := s.resolveNotes(s.doc)
+	s.doc = s.doc[:0]
+	if _, err := s.w.Write(out); err != nil {
+		s.err = fmt.Errorf("latex: %w", err)
+	}
+	return s.err
+}
+
+// out returns the buffer blocks are written to.
+func (s *Stream) out() *[]byte {
+	if s.note != "" {
+		return &s.noteBuf
+	}
+	return &s.doc
+}
+
+func (s *Stream) write(text string) {
+	out := s.out()
+	*out = append(*out, text...)
+}
+
+// startBlock separates a block from the one before it.
+func (s *Stream) startBlock() {
+	out := s.out()
+	if s.sep {
+		*out = append(*out, '\n')
+	} else if n := len(*out); n > 0 && (*out)[n-1] != '\n' && (*out)[n-1] != ' ' {
+		*out = append(*out, '\n')
+	}
+	s.sep = false
+	s.notesHeading = -1
+}
+
+// writeBlock writes a block that is complete.
+func (s *Stream) writeBlock(text string) {
+	if text == "" {
+		return
+	}
+	s.startBlock()
+	s.write(text + "\n")
+	s.sep = true
+}
+
+// startEnv writes the start of an environment, with its options, on a line
+// of its own.
+func (s *Stream) startEnv(name, opts string) {
+	s.startBlock()
+	out := s.out()
+	if n := len(*out); n > 0 && (*out)[n-1] != '\n' {
+		*out = append(*out, '\n')
+	}
+	s.write(`\begin{` + name + "}" + opts + "\n")
+}
+
+func (s *Stream) endEnv(name string) {
+	s.write(`\end{` + name + "}\n")
+	s.sep = true
+}
+
+func (s *Stream) block(tok mdf.StreamToken) {
+	info := tok.Block
+	switch tok.Kind {
+	case mdf.TokenHeadingStart:
+		s.startLeaf(leafHeading)
+		s.level = info.Level
+		s.in.flat = true
+	case mdf.TokenParagraphStart:
+		s.startLeaf(leafParagraph)
+		s.in.flat = false
+	case mdf.TokenCodeBlockStart:
+		s.startLeaf(leafCode)
+		s.code.lang = info.Lang
+	case mdf.TokenHeadingEnd, mdf.TokenParagraphEnd, mdf.TokenCodeBlockEnd:
+		s.endLeaf()
+	case mdf.TokenListStart:
+		s.endLeaf()
+		l := list{ordered: info.Ordered, offset: len(*s.out()), cut: len(*s.out())}
+		if s.notesHeading >= 0 {
+			l.cut = s.notesHeading
+		}
+		s.lists = append(s.lists, l)
+		if n := len(s.containers); n > 0 && !s.containers[n-1].quote {
+			// A list nested in an item goes on without a paragraph break.
+			s.sep = false
+		}
+		env := "itemize"
+		if info.Ordered {
+			env = "enumerate"
+		}
+		s.startEnv(env, "")
+		if info.Ordered && info.Start != 1 {
+			s.write(`\setcounter{enum` + roman(s.orderedDepth()) + `}{` + strconv.Itoa(info.Start-1) + "}\n")
+		}
+	case mdf.TokenListEnd:
+		s.endLeaf()
+		s.endList()
+	case mdf.TokenListItemStart:
+		s.endLeaf()
+		s.startBlock()
+		s.containers = append(s.containers, container{offset: len(*s.out())})
+		switch info.Task {
+		case mdf.TaskChecked:
+			s.write(`\item[{[x]}] `)
+		case mdf.TaskUnchecked:
+			s.write(`\item[{[ ]}] `)
+		default:
+			s.write(`\item `)
+		}
+		s.task = info.Task
+	case mdf.TokenBlockquoteStart:
+		s.endLeaf()
+		s.containers = append(s.containers, container{quote: true})
+		s.startEnv("quote", "")
+	case mdf.TokenListItemEnd, mdf.TokenBlockquoteEnd:
+		s.endLeaf()
+		s.popContainer()
+	}
+}
+
+func (s *Stream) endList() {
+	n := len(s.lists)
+	if n == 0 {
+		return
+	}
+	l := s.lists[n-1]
+	s.lists = s.lists[:n-1]
+	switch {
+	case l.notes:
+		s.sep = false
+	case l.ordered:
+		s.endEnv("enumerate")
+	default:
+		s.endEnv("itemize")
+	}
+}
+
+func (s *Stream) popContainer() {
+	n := len(s.containers)
+	if n == 0 {
+		return
+	}
+	c := s.containers[n-1]
+	s.containers = s.containers[:n-1]
+	if c.quote {
+		s.endEnv("quote")
+		return
+	}
+	if s.note != "" && len(s.containers) == 0 {
+		s.notes[s.note] = strings.TrimSpace(string(s.noteBuf))
+		s.note = ""
+		s.noteBuf = s.noteBuf[:0]
+	}
+	s.sep = false
+}
+
+// orderedDepth returns the number of open ordered lists.
+func (s *Stream) orderedDepth() int {
+	n := 0
+	for _, l := range s.lists {
+		if l.ordered {
+			n++
+		}
+	}
+	return n
+}
+
+// anchor starts reading a footnote definition when the item of a list at
+// the top level starts with the anchor the parser writes for it. The list
+// and the heading before it are cut from the output.
+func (s *Stream) anchor(id string) {
+	num, ok := strings.CutPrefix(id, "fn-")
+	if !ok || s.note != "" || len(s.lists) != 1 || len(s.containers) != 1 || s.containers[0].quote {
+		return
+	}
+	l := &s.lists[0]
+	if !l.ordered {
+		return
+	}
+	if !l.notes {
+		l.notes = true
+		s.doc = s.doc[:l.cut]
+	} else {
+		s.doc = s.doc[:min(s.containers[0].offset, len(s.doc))]
+	}
+	s.note = num
+	s.noteBuf = s.noteBuf[:0]
+	s.sep = false
+}
+
+func (s *Stream) startLeaf(kind leafKind) {
+	s.endLeaf()
+	s.leaf = kind
+	s.lineStart = true
+}
+
+func (s *Stream) endLeaf() {
+	if s.leaf == leafNone {
+		return
+	}
+	switch s.leaf {
+	case leafParagraph:
+		s.writeBlock(s.in.finish())
+	case leafHeading:
+		text := s.in.finish()
+		offset := len(*s.out())
+		s.writeBlock(s.heading(text))
+		if text == "Footnotes" && s.note == "" {
+			s.notesHeading = offset
+		}
+	case leafCode:
+		if s.code.line.Len() > 0 {
+			s.code.lines = append(s.code.lines, s.code.line.String())
+		}
+		s.writeCode()
+		s.code = codeState{}
+	}
+	s.leaf = leafNone
+	s.task = mdf.TaskNone
+}
+
+// heading returns the sectioning command for a heading.
+func (s *Stream) heading(text string) string {
+	cmd := `\subparagraph`
+	switch s.level {
+	case 1:
+		cmd = `\section`
+	case 2:
+		cmd = `\subsection`
+	case 3:
+		cmd = `\subsubsection`
+	case 4:
+		cmd = `\paragraph`
+	}
+	if s.cfg.Unnumbered {
+		cmd += "*"
+	}
+	return cmd + "{" + text + "}"
+}
+
+// inlineToken adds a token of a paragraph or heading.
+func (s *Stream) inlineToken(tok mdf.StreamToken, set role.Set) {
+	if tok.Kind == mdf.TokenAnchor && s.leaf == leafParagraph && s.in.empty() {
+		s.anchor(tok.LinkURL)
+	}
+	if tok.Kind == mdf.TokenText && tok.Text != "" && strings.Trim(tok.Text, "\n") == "" {
+		s.in.breakLine()
+		s.lineStart = true
+		return
+	}
+	if s.lineStart && tok.Kind == mdf.TokenText {
+		if decoration(tok.Text, set) {
+			return
+		}
+		if s.task != mdf.TaskNone {
+			if strings.Contains(tok.Text, "]") {
+				s.task = mdf.TaskNone
+			}
+			return
+		}
+	}
+	if tok.Kind != mdf.TokenLinkStart && tok.Kind != mdf.TokenLinkEnd && tok.Kind != mdf.TokenAnchor {
+		s.lineStart = false
+	}
+	s.in.token(tok, set)
+}
+
+// decoration reports whether text is part of what the parser draws at the
+// start of a line: quote bars, list markers, indentation and heading marks.
+func decoration(text string, set role.Set) bool {
+	if set.Has(role.Quote) || set.Has(role.ListMarker) || strings.TrimSpace(text) == "" {
+		return true
+	}
+	return set.Has(role.Heading) && strings.Trim(text, "# ") == "" && strings.HasSuffix(text, " ")
+}
+
+// codeToken adds a token of a code block; the decoration the parser draws
+// before each line is dropped.
+func (s *Stream) codeToken(tok mdf.StreamToken) {
+	c := &s.code
+	switch {
+	case tok.Kind == mdf.TokenCode && tok.CodeBlock:
+		c.line.WriteString(tok.Text)
+	case tok.Kind == mdf.TokenText && strings.Trim(tok.Text, "\n") == "":
+		for range strings.Count(tok.Text, "\n") {
+			c.lines = append(c.lines, c.line.String())
+			c.line.Reset()
+		}
+	}
+}
+
+// endTable writes the table as a tabular with a rule under the header rows.
+func (s *Stream) endTable() {
+	t := s.table
+	s.table = table.Table{}
+	cols := t.Columns()
+	if cols == 0 {
+		return
+	}
+	var b strings.Builder
+	b.WriteString(`\begin{tabular}{`)
+	for i := range cols {
+		align := mdf.AlignNone
+		if i < len(t.Aligns) {
+			align = t.Aligns[i]
+		}
+		switch align {
+		case mdf.AlignCenter:
+			b.WriteByte('c')
+		case mdf.AlignRight:
+			b.WriteByte('r')
+		default:
+			b.WriteByte('l')
+		}
+	}
+	b.WriteString("}\n\\hline\n")
+	in := inline{flat: true}
+	for r, row := range t.Rows {
+		for i := range cols {
+			if i > 0 {
+				b.WriteString(" & ")
+			}
+			if i < len(row) {
+				for _, tok := range row[i] {
+					in.token(tok, role.Of(tok.Style))
+				}
+				b.WriteString(in.finish())
+			}
+		}
+		b.WriteString(" \\\\\n")
+		if r == t.Header-1 {
+			b.WriteString("\\hline\n")
+		}
+	}
+	b.WriteString("\\hline\n\\end{tabular}")
+	s.writeBlock(b.String())
+}
+
+// writeCode writes a code block as verbatim or lstlisting. Inside a
+// footnote, where neither works, and for code that holds the end of the
+// environment, which would close it early, the lines are typewriter text
+// with their spaces kept.
+func (s *Stream) writeCode() {
+	lines := s.code.lines
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	env, opts := "verbatim", ""
+	if s.cfg.Listings {
+		env = "lstlisting"
+		if lang := listingsLanguage(s.code.lang); lang != "" {
+			opts = "[language=" + lang + "]"
+		}
+	}
+	end := `\end{` + env + `}`
+	if s.note != "" || slices.ContainsFunc(lines, func(line string) bool { return strings.Contains(line, end) }) {
+		var b strings.Builder
+		for i, line := range lines {
+			if i > 0 {
+				b.WriteString("\\\\\n")
+			}
+			b.WriteString(`\texttt{` + strings.ReplaceAll(escape(line), " ", "~") + "}")
+		}
+		s.writeBlock(b.String())
+		return
+	}
+	s.startEnv(env, opts)
+	for _, line := range lines {
+		s.write(line + "\n")
+	}
+	s.endEnv(env)
+}
+
+// resolveNotes replaces the footnote references in src with \footnote and
+// the note's text, or the number alone for a note that was not defined.
+func (s *Stream) resolveNotes(src []byte) []byte {
+	if !bytes.Contains(src, []byte(notePrefix)) {
+		return src
+	}
+	var out []byte
+	for {
+		i := bytes.Index(src, []byte(notePrefix))
+		if i < 0 {
+			break
+		}
+		out = append(out, src[:i]...)
+		src = src[i+len(notePrefix):]
+		end := bytes.Index(src, []byte(noteSuffix))
+		if end < 0 {
+			end = len(src)
+		}
+		num := string(src[:end])
+		src = src[min(end+len(noteSuffix), len(src)):]
+		if text, ok := s.notes[num]; ok {
+			out = append(out, `\footnote{`+text+`}`...)
+		} else {
+			out = append(out, `\textsuperscript{`+escape(num)+`}`...)
+		}
+	}
+	return append(out, src...)
+}
+
+// listingsLanguage returns the listings name of a fence language, or "" if
+// listings does not know it.
+func listingsLanguage(lang string) string {
+	switch strings.ToLower(lang) {
+	case "c":
+		return "C"
+	case "cpp", "c++", "cxx":
+		return "C++"
+	case "java":
+		return "Java"
+	case "python", "py":
+		return "Python"
+	case "sh", "bash", "shell", "zsh":
+		return "bash"
+	case "sql":
+		return "SQL"
+	case "ruby", "rb":
+		return "Ruby"
+	case "perl":
+		return "Perl"
+	case "php":
+		return "PHP"
+	case "html":
+		return "HTML"
+	case "xml":
+		return "XML"
+	case "tex", "latex":
+		return "TeX"
+	case "haskell", "hs":
+		return "Haskell"
+	case "lisp":
+		return "Lisp"
+	case "make", "makefile":
+		return "make"
+	case "matlab":
+		return "Matlab"
+	}
+	return ""
+}
+
+// roman returns the lower-case roman numeral LaTeX counters use for the
+// levels of nested lists, such as "ii" for enumii.
+func roman(n int) string {
+	switch n {
+	case 1:
+		return "i"
+	case 2:
+		return "ii"
+	case 3:
+		return "iii"
+	}
+	return "iv"
+}
@@ -0,0 +1,174 @@
+package mdf
+
+import "strings"
+
+// blockFrame is an open block as reported to the stream.
+type blockFrame struct {
+	// kind is the block's start event; its end event is kind + 1.
+	kind tokenKind
+	info BlockInfo
+	// id tells successive blocks of one kind apart, such as two paragraphs
+	// or two items of a list. Blockquotes are identified by depth alone.
+	id int
+}
+
+// blockState tracks the block structure reported through start and end
+// events. Each line decision plans the blocks the line sits in; the events
+// that turn the open blocks into the planned ones are written around the
+// pending line breaks, so ends precede the blank lines between blocks and
+// starts follow them.
+type blockState struct {
+	open    []blockFrame
+	want    []blockFrame
+	ids     int
+	leaf    int
+	planned bool
+}
+
+func (b *blockState) reset() {
+	b.open = b.open[:0]
+	b.want = b.want[:0]
+	b.ids = 0
+	b.leaf = 0
+	b.planned = false
+}
+
+func (b *blockState) newID() int {
+	b.ids++
+	return b.ids
+}
+
+// planBlocks records the blocks the line being decided sits in: depth
+// blockquotes, the open lists and items, then leaf, if not zero. fresh
+// starts a new leaf instead of continuing the open one.
+func (p *liveParser) planBlocks(depth int, leaf tokenKind, info BlockInfo, fresh bool) {
+	b := &p.blocks
+	b.want = b.want[:0]
+	if p.quoteListPrefixFirst {
+		p.planLists()
+		p.planQuotes(depth)
+	} else {
+		p.planQuotes(depth)
+		p.planLists()
+	}
+	if leaf != 0 {
+		if n := len(b.open); fresh || n == 0 || b.open[n-1].kind != leaf || b.open[n-1].id != b.leaf {
+			b.leaf = b.newID()
+		}
+		b.want = append(b.want, blockFrame{kind: leaf, info: info, id: b.leaf})
+	}
+	b.planned = true
+}
+
+func (p *liveParser) planQuotes(depth int) {
+	for level := 1; level <= depth; level++ {
+		p.blocks.want = append(p.blocks.want, blockFrame{kind: tokenBlockquoteStart, info: BlockInfo{Level: level}})
+	}
+}
+
+func (p *liveParser) planLists() {
+	for _, state := range p.listStack {
+		p.blocks.want = append(p.blocks.want,
+			blockFrame{kind: tokenListStart, info: BlockInfo{Ordered: state.ordered, Start: state.start}, id: state.id},
+			blockFrame{kind: tokenListItemStart, info: BlockInfo{Task: state.task}, id: state.item})
+	}
+}
+
+// closeBlocks writes end events for the open blocks the plan leaves.
+func (p *liveParser) closeBlocks(stream Stream) error {
+	b := &p.blocks
+	keep := 0
+	for keep < len(b.open) && keep < len(b.want) && b.open[keep] == b.want[keep] {
+		keep++
+	}
+	return p.closeBlocksTo(stream, keep)
+}
+
+// openBlocks writes start events for the planned blocks not yet open.
+func (p *liveParser) openBlocks(stream Stream) error {
+	b := &p.blocks
+	b.planned = false
+	for len(b.open) < len(b.want) {
+		frame := b.want[len(b.open)]
+		b.open = append(b.open, frame)
+		if err := stream.WriteToken(StreamToken{Token: Token{Kind: frame.kind, Block: frame.info}}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *liveParser) closeBlocksTo(stream Stream, n int) error {
+	b := &p.blocks
+	for len(b.open) > n {
+		frame := b.open[len(b.open)-1]
+		if frame.kind == tokenParagraphStart || frame.kind == tokenHeadingStart {
+			// Inline markup left open ends with its block.
+			p.flushOpenInline(stream)
+			if err := p.flushEscapedBreak(stream); err != nil {
+				return err
+			}
+		}
+		b.open = b.open[:len(b.open)-1]
+		if err := stream.WriteToken(StreamToken{Token: Token{Kind: frame.kind + 1, Block: frame.info}}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// syncBlocks moves the open blocks to the plan without a line break.
+func (p *liveParser) syncBlocks(stream Stream) error {
+	if err := p.closeBlocks(stream); err != nil {
+		return err
+	}
+	return p.openBlocks(stream)
+}
+
+// applyBlockBreak is applyPendingBreak for a line that starts or continues
+// the planned blocks.
+func (p *liveParser) applyBlockBreak(stream Stream, mode breakMode) error {
+	if !p.blocks.planned {
+		return p.applyPendingBreak(stream, mode)
+	}
+	if err := p.closeBlocks(stream); err != nil {
+		return err
+	}
+	if err := p.applyPendingBreak(stream, mode); err != nil {
+		return err
+	}
+	return p.openBlocks(stream)
+}
+
+// taskState returns the checkbox state of list item content.
+func taskState(content string) TaskState {
+	if taskListExtraIndent(content) == 0 {
+		return TaskNone
+	}
+	if content[1] == ' ' {
+		return TaskUnchecked
+	}
+	return TaskChecked
+}
+
+// fenceLang returns the language word of a fence info string.
+func fenceLang(info string) string {
+	if i := strings.IndexAny(info, " \t{,"); i >= 0 {
+		info = info[:i]
+	}
+	return strings.Clone(strings.TrimPrefix(info, "."))
+}
+
+// closeLeaf writes the end event of an open paragraph, heading, code block
+// or table ahead of a blank quote line.
+func (p *liveParser) closeLeaf(stream Stream) error {
+	n := len(p.blocks.open)
+	if n == 0 {
+		return nil
+	}
+	switch p.blocks.open[n-1].kind {
+	case tokenHeadingStart, tokenParagraphStart, tokenCodeBlockStart, tokenTableStart:
+		return p.closeBlocksTo(stream, n-1)
+	}
+	return nil
+}
@@ -0,0 +1,149 @@
+package mdf
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+var blockNames = map[tokenKind]string{
+	tokenHeadingStart:    "h",
+	tokenParagraphStart:  "p",
+	tokenListStart:       "list",
+	tokenListItemStart:   "li",
+	tokenBlockquoteStart: "quote",
+	tokenCodeBlockStart:  "code",
+	tokenTableStart:      "table",
+	tokenTableRowStart:   "tr",
+	tokenTableCellStart:  "td",
+}
+
+// outline renders block events as bracketed tags around the text they
+// enclose, dropping the text of line prefixes and breaks.
+func outline(tokens []StreamToken) string {
+	var b strings.Builder
+	for _, tok := range tokens {
+		switch {
+		case tok.Kind == tokenThematicBreak:
+			b.WriteString("<hr>")
+		case IsBlockEvent(tok.Kind) && (tok.Kind-tokenHeadingStart)%2 == 0:
+			b.WriteString("<" + blockNames[tok.Kind])
+			info := tok.Block
+			if info.Level > 0 {
+				b.WriteString(" " + strconv.Itoa(info.Level))
+			}
+			if info.Ordered {
+				b.WriteString(" ol" + strconv.Itoa(info.Start))
+			}
+			if info.Task != TaskNone {
+				b.WriteString(" task" + strconv.Itoa(int(info.Task)))
+			}
+			if info.Lang != "" {
+				b.WriteString(" " + info.Lang)
+			}
+			if info.Header {
+				b.WriteString(" head")
+			}
+			if info.Align != AlignNone {
+				b.WriteString(" align" + strconv.Itoa(int(info.Align)))
+			}
+			b.WriteString(">")
+		case IsBlockEvent(tok.Kind):
+			b.WriteString("</" + blockNames[tok.Kind-1] + ">")
+		default:
+			b.WriteString(tok.Text)
+		}
+	}
+	return b.String()
+}
+
+// blockStream captures tokens, copying text that aliases parser buffers.
+type blockStream struct {
+	captureStream
+}
+
+func (s *blockStream) WriteToken(tok StreamToken) error {
+	tok.Text = strings.Clone(tok.Text)
+	return s.captureStream.WriteToken(tok)
+}
+
+func parseBlocks(t *testing.T, r io.Reader) []StreamToken {
+	t.Helper()
+	stream := &blockStream{}
+	if err := Parse(ParseRequest{Reader: r, Stream: stream, Theme: DefaultTheme()}); err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	return stream.tokens
+}
+
+func TestBlockEvents(t *testing.T) {
+	cases := []struct {
+		src  string
+		want string
+	}{
+		{"# Title\n\nOne\ntwo.\n\nThree.", "<h 1># Title</h>\n\n<p>One two.</p>\n\n<p>Three.</p>"},
+		{"Setext\n===\n", "<h 1># Setext</h>"},
+		{"- a\n- [x] b\n  more\n\n  second\n  - [ ] nested\n",
+			"<list><li><p>- a</p></li>\n<li task2><p>- [x] b more</p>\n\n<p>  second</p>\n\n" +
+				"<list><li task1><p>  - [ ] nested</p></li></list></li></list>"},
+		{"3. x\n4. y\n", "<list ol3><li><p>3. x</p></li>\n<li><p>4. y</p></li></list>"},
+		{"1. a\n\n   ```\n   code\n   ```\n2. b",
+			"<list ol1><li><p>1. a</p>\n\n<code>   code</code></li>\n\n<li><p>2. b</p></li></list>"},
+		{"> a\n>\n> b\n> > deep\n",
+			"<quote 1><p>> a</p>\n>\n<p>> b</p> <quote 2><p>deep</p></quote></quote>"},
+		{"- item\n\n  > inside\n", "<list><li><p>- item</p>\n\n<quote 1><p>  > inside</p></quote></li></list>"},
+		{"```go title=x\nx := 1\n```\n\n    indented\n", "<code go>x := 1</code>\n\n<code>indented\n</code>"},
+		{"one\n\n---\n\ntwo", "<p>one</p><hr>\n\n<p>two</p>"},
+		{"- a\n\n---\n", "<list><li><p>- a</p></li></list><hr>"},
+		{"* * *\n\n- - -\n", "<hr><hr>"},
+		{"- a\n\n```\nx\n```\n", "<list><li><p>- a</p></li></list>\n\n<code>x</code>"},
+		{"> | a | b |\n> |:-:|---|\n> | 1 | 2 |\n\nafter\n",
+			"<quote 1><table>> ┌───┬───┐\n> <tr head>│ <td head align2>a</td> │ <td head>b</td> │</tr>\n> ├───┼───┤\n" +
+				"> <tr>│ <td align2>1</td> │ <td>2</td> │</tr>\n> └───┴───┘</table></quote>\n\n<p>after</p>"},
+		{"text[^n]\n\n[^n]: note\n", "<p>text¹</p>\n\n<h 2>## Footnotes</h>\n\n<list ol1><li><p>1. note</p></li></list>"},
+	}
+	for _, tc := range cases {
+		if got := outline(parseBlocks(t, strings.NewReader(tc.src))); got != tc.want {
+			t.Fatalf("%q:\n got %q\nwant %q", tc.src, got, tc.want)
+		}
+	}
+}
+
+func TestBlockEventsBalancedWhenStreamed(t *testing.T) {
+	for _, name := range []string{"OBAF.md", "centaur.md", "lazyblockquote.md"} {
+		data, err := os.ReadFile(filepath.Join("testdata", name))
+		if err != nil {
+			t.Fatalf("read sample: %v", err)
+		}
+		want := parseBlocks(t, bytes.NewReader(data))
+		got := parseBlocks(t, &oneByteReader{data: data})
+		if outline(got) != outline(want) {
+			t.Fatalf("%s: byte-by-byte events differ from whole-input events", name)
+		}
+		var open []StreamToken
+		for _, tok := range got {
+			if !IsBlockEvent(tok.Kind) {
+				continue
+			}
+			if (tok.Kind-tokenHeadingStart)%2 == 0 {
+				open = append(open, tok)
+				continue
+			}
+			if len(open) == 0 {
+				t.Fatalf("%s: unmatched end event %d", name, tok.Kind)
+			}
+			start := open[len(open)-1]
+			open = open[:len(open)-1]
+			if start.Kind+1 != tok.Kind || start.Block != tok.Block {
+				t.Fatalf("%s: end event %d %+v closes start %d %+v", name, tok.Kind, tok.Block, start.Kind, start.Block)
+			}
+		}
+		if len(open) != 0 {
+			t.Fatalf("%s: %d blocks left open", name, len(open))
+		}
+	}
+}
@@ -0,0 +1,249 @@
+package mdf
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Private-use runes that mark where a note's anchor and back-link go while
+// the footnotes section is replayed through the parser.
+const (
+	footnoteAnchorRune = '\uE000'
+	footnoteBackRune   = '\uE001'
+)
+
+const footnoteBackText = "↩"
+
+type footnoteState struct {
+	defs     map[string]string
+	nums     map[string]int
+	order    []string
+	open     string
+	blank    bool
+	emitting int
+}
+
+func (f *footnoteState) reset() {
+	clear(f.defs)
+	clear(f.nums)
+	f.order = f.order[:0]
+	f.open = ""
+	f.blank = false
+	f.emitting = 0
+}
+
+// number returns the note number for a defined label, assigning the next one
+// on first reference, and reports whether this was the first reference.
+func (f *footnoteState) number(label string) (int, bool) {
+	if n, ok := f.nums[label]; ok {
+		return n, false
+	}
+	if f.nums == nil {
+		f.nums = make(map[string]int)
+	}
+	label = strings.Clone(label)
+	f.order = append(f.order, label)
+	f.nums[label] = len(f.order)
+	return len(f.order), true
+}
+
+// defineFootnote records a definition; the first definition of a label wins.
+// Following continuation lines are appended to it.
+func (p *liveParser) defineFootnote(label string, text string) error {
+	key := normalizeRefLabel(label)
+	if p.footnotes.defs == nil {
+		p.footnotes.defs = make(map[string]string)
+	}
+	p.footnotes.blank = false
+	if _, ok := p.footnotes.defs[key]; ok {
+		p.footnotes.open = ""
+		return nil
+	}
+	key = strings.Clone(key)
+	p.footnotes.defs[key] = strings.Clone(text)
+	p.footnotes.open = key
+	return p.releaseResolvedRefs()
+}
+
+// processFootnoteLine handles a complete line following a definition:
+// indented lines, and unindented lines directly after it, continue the note.
+// Anything else closes the note and is replayed normally.
+func (p *liveParser) processFootnoteLine(stream Stream, line string, full bool) error {
+	trimmed := strings.TrimSpace(line)
+	if trimmed == "" {
+		p.footnotes.blank = true
+		return nil
+	}
+	indent, _ := leadingIndentCount(line)
+	lazy := !p.footnotes.blank && !strings.HasPrefix(trimmed, "[^") && !isPotentialBlockStart([]rune(trimmed)[0])
+	if indent >= 4 || lazy {
+		key := p.footnotes.open
+		p.footnotes.defs[key] += " " + trimmed
+		p.footnotes.blank = false
+		return nil
+	}
+	blank := p.footnotes.blank
+	p.footnotes.open = ""
+	p.footnotes.blank = false
+	if blank {
+		if err := p.replayFullLine(stream, ""); err != nil {
+			return err
+		}
+	}
+	if full {
+		return p.replayFullLine(stream, line)
+	}
+	return p.replayLine(stream, line)
+}
+
+// emitFootnoteRef handles a "[^label]" reference. A defined note gets its
+// superscript number; an undefined one is deferred like a reference link
+// until its definition shows up, and stays literal text if it never does.
+func (p *liveParser) emitFootnoteRef(stream Stream) error {
+	raw := bytesToString(p.inline.linkText[1:])
+	label := normalizeRefLabel(raw)
+	literal := "[^" + raw + "]"
+	p.inline.inLink = false
+	p.inline.pendingClose = false
+	p.inline.linkText = p.inline.linkText[:0]
+	p.inline.linkURL = p.inline.linkURL[:0]
+	_, defined := p.footnotes.defs[label]
+	if defined && !p.refs.stream.active {
+		return p.writeFootnoteRef(stream, label)
+	}
+	if !defined && stream == Stream(&p.table.collector) {
+		return stream.WriteToken(StreamToken{Token: Token{Text: literal, Style: p.styles.Text}})
+	}
+	p.deferRef(stream, pendingRef{label: strings.Clone(label), literal: literal, footnote: true})
+	return nil
+}
+
+// writeFootnoteRef writes the superscript number for a defined note, linked
+// to it. The first reference also gets an anchor for the note's back-link.
+func (p *liveParser) writeFootnoteRef(stream Stream, label string) error {
+	n, first := p.footnotes.number(label)
+	id := strconv.Itoa(n)
+	if first {
+		if err := stream.WriteToken(StreamToken{Token: Token{Kind: tokenAnchor, LinkURL: "fnref-" + id}}); err != nil {
+			return err
+		}
+	}
+	if p.osc8 {
+		if err := stream.WriteToken(StreamToken{Token: Token{Kind: tokenLinkStart, LinkURL: "#fn-" + id}}); err != nil {
+			return err
+		}
+	}
+	if err := stream.WriteToken(StreamToken{Token: Token{Text: superscriptNumber(id), Style: p.styles.LinkText}}); err != nil {
+		return err
+	}
+	if p.osc8 {
+		return stream.WriteToken(StreamToken{Token: Token{Kind: tokenLinkEnd}})
+	}
+	return nil
+}
+
+// emitFootnotes writes the referenced definitions, in reference order, as a
+// numbered "Footnotes" section at the end of the document.
+func (p *liveParser) emitFootnotes(stream Stream) error {
+	if len(p.footnotes.order) == 0 {
+		return nil
+	}
+	if p.pendingBreaks == 0 && p.seenLine {
+		p.pendingBreaks = 1
+	}
+	if err := p.replayFullLine(stream, "## Footnotes"); err != nil {
+		return err
+	}
+	var line strings.Builder
+	for i, label := range p.footnotes.order {
+		text := p.footnotes.defs[label]
+		line.Reset()
+		line.WriteString(strconv.Itoa(i + 1))
+		line.WriteString(". ")
+		line.WriteRune(footnoteAnchorRune)
+		line.WriteString(text)
+		if p.osc8 {
+			line.WriteByte(' ')
+			line.WriteRune(footnoteBackRune)
+		}
+		p.footnotes.emitting = i + 1
+		err := p.replayFullLine(stream, line.String())
+		p.footnotes.emitting = 0
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// emitFootnoteMarker writes the anchor or back-link of the note being
+// emitted.
+func (p *liveParser) emitFootnoteMarker(stream Stream, r rune) error {
+	id := strconv.Itoa(p.footnotes.emitting)
+	if r == footnoteAnchorRune {
+		return stream.WriteToken(StreamToken{Token: Token{Kind: tokenAnchor, LinkURL: "fn-" + id}})
+	}
+	if err := stream.WriteToken(StreamToken{Token: Token{Kind: tokenLinkStart, LinkURL: "#fnref-" + id}}); err != nil {
+		return err
+	}
+	if err := stream.WriteToken(StreamToken{Token: Token{Text: footnoteBackText, Style: p.styles.LinkText}}); err != nil {
+		return err
+	}
+	return stream.WriteToken(StreamToken{Token: Token{Kind: tokenLinkEnd}})
+}
+
+func superscriptNumber(digits string) string {
+	var b strings.Builder
+	for _, r := range digits {
+		b.WriteRune(superscriptRunes[r])
+	}
+	return b.String()
+}
+
+// isFootnoteRef reports whether buffered link text is "^label".
+func isFootnoteRef(text []byte) bool {
+	return len(text) > 1 && text[0] == '^' && isFootnoteLabel(bytesToString(text[1:]))
+}
+
+func isFootnoteLabel(label string) bool {
+	if label == "" || len(label) > maxRefLabelLen {
+		return false
+	}
+	for _, r := range label {
+		if unicode.IsSpace(r) || r == '[' || r == ']' {
+			return false
+		}
+	}
+	return true
+}
+
+// parseFootnoteDefinition parses "[^label]: text".
+func parseFootnoteDefinition(line string) (string, string, bool) {
+	if !strings.HasPrefix(line, "[^") {
+		return "", "", false
+	}
+	end := strings.IndexByte(line, ']')
+	if end < 0 || end+1 >= len(line) || line[end+1] != ':' {
+		return "", "", false
+	}
+	label := line[2:end]
+	if !isFootnoteLabel(label) {
+		return "", "", false
+	}
+	return label, strings.TrimSpace(line[end+2:]), true
+}
+
+// maybeFootnoteDefinition reports whether a partial line could still become
+// a footnote definition.
+func maybeFootnoteDefinition(line string) bool {
+	if !strings.HasPrefix(line, "[^") {
+		return false
+	}
+	end := strings.IndexByte(line, ']')
+	if end < 0 {
+		rest := line[2:]
+		return rest == "" || isFootnoteLabel(rest)
+	}
+	return isFootnoteLabel(line[2:end]) && (end+1 == len(line) || line[end+1] == ':')
+}
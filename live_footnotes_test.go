@@ -0,0 +1,93 @@
+package mdf
+
+import (
+	"strings"
+	"testing"
+)
+
+const footnoteSample = "Text[^b] more[^a] again[^b].\n\n" +
+	"[^a]: Alpha note\n  continued.\n" +
+	"[^b]: Beta with *style*.\n\n    Second paragraph.\n" +
+	"[^unused]: Never referenced.\n\n" +
+	"Tail.\n"
+
+func TestFootnotesRenderSection(t *testing.T) {
+	out := stripANSI(renderStream(t, []byte(footnoteSample), 80))
+	want := "Text¹ more² again¹.\n\nTail.\n\n## Footnotes\n\n" +
+		"1. Beta with style. Second paragraph.\n" +
+		"2. Alpha note continued.\n"
+	if out != want {
+		t.Fatalf("got %q want %q", out, want)
+	}
+}
+
+func TestFootnotesWithoutDefinitions(t *testing.T) {
+	cases := []struct {
+		src  string
+		want string
+	}{
+		{src: "Only a ref[^x].\n", want: "Only a ref[^x].\n"},
+		{src: "[^x]: Only a definition.\n\nBody.\n", want: "Body.\n"},
+		{src: "Not notes: [^ x] [^] [a]^b\n", want: "Not notes: [^ x] [^] [a]^b\n"},
+		{src: "para\n\n    [^1]: note\n    code\n", want: "para\n\n[^1]: note\ncode\n"},
+	}
+	for _, tc := range cases {
+		out := stripANSI(renderStream(t, []byte(tc.src), 80))
+		if out != tc.want {
+			t.Fatalf("%q: got %q want %q", tc.src, out, tc.want)
+		}
+	}
+}
+
+func TestFootnotesNumberOnlyDefinedNotes(t *testing.T) {
+	src := "A[^x] b[^n] c[^y] d[^m].\n\n[^m]: Em.\n[^n]: En.\n"
+	out := stripANSI(renderStream(t, []byte(src), 80))
+	want := "A[^x] b¹ c[^y] d².\n\n## Footnotes\n\n1. En.\n2. Em.\n"
+	if out != want {
+		t.Fatalf("got %q want %q", out, want)
+	}
+}
+
+func TestFootnotesEmitLinks(t *testing.T) {
+	stream := &captureStream{}
+	err := Parse(ParseRequest{
+		Reader:  strings.NewReader("A[^n] b[^n].\n\n[^n]: Note.\n"),
+		Stream:  stream,
+		Theme:   DefaultTheme(),
+		Options: []RenderOption{WithOSC8(true)},
+	})
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	var got []string
+	for _, tok := range stream.tokens {
+		switch tok.Kind {
+		case tokenAnchor:
+			got = append(got, "@"+tok.LinkURL)
+		case tokenLinkStart:
+			got = append(got, tok.LinkURL)
+		}
+	}
+	want := "@fnref-1|#fn-1|#fn-1|@fn-1|#fnref-1"
+	if strings.Join(got, "|") != want {
+		t.Fatalf("got %q want %q", strings.Join(got, "|"), want)
+	}
+	var out strings.Builder
+	err = Render(RenderRequest{
+		Reader:  strings.NewReader("A[^n].\n\n[^n]: Note.\n"),
+		Writer:  &out,
+		Width:   80,
+		Theme:   DefaultTheme(),
+		Options: []RenderOption{WithOSC8(true)},
+	})
+	if err != nil {
+		t.Fatalf("render: %v", err)
+	}
+	if strings.Contains(out.String(), "8;;#") {
+		t.Fatalf("internal link leaked as OSC 8: %q", out.String())
+	}
+}
+
+func TestFootnotesStreamByteByByte(t *testing.T) {
+	assertByteByByteMatches(t, footnoteSample, 40)
+}
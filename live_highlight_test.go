@@ -0,0 +1,65 @@
+package mdf
+
+import (
+	"strings"
+	"testing"
+)
+
+const highlightSample = "```go\nfunc f() { return \"s\" } // c\n```\n\n" +
+	"```python\nx = \"\"\"doc\nend\"\"\"\n```\n\n" +
+	"```text\nfunc plain\n```\n"
+
+// codeStyleStream records the style of each code block token. Code token
+// text is only valid during WriteToken, so it is copied there.
+type codeStyleStream struct {
+	captureStream
+	styles map[string]Style
+}
+
+func (c *codeStyleStream) WriteToken(tok StreamToken) error {
+	if tok.Kind == tokenCode && tok.CodeBlock {
+		c.styles[strings.Clone(tok.Text)] = tok.Style
+	}
+	return nil
+}
+
+func TestCodeFenceHighlightStyles(t *testing.T) {
+	stream := &codeStyleStream{styles: make(map[string]Style)}
+	err := Parse(ParseRequest{
+		Reader: strings.NewReader(highlightSample),
+		Stream: stream,
+		Theme:  DefaultTheme(),
+	})
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	styles := DefaultTheme().Styles()
+	got := stream.styles
+	want := map[string]Style{
+		"func":       styles.CodeKeyword,
+		" f":         styles.CodeBlock,
+		"\"s\"":      styles.CodeString,
+		"// c":       styles.CodeComment,
+		"{":          styles.CodePunctuation,
+		"\"\"\"doc":  styles.CodeString,
+		"end\"\"\"":  styles.CodeString,
+		"func plain": styles.CodeBlock,
+	}
+	for text, style := range want {
+		if got[text] != style {
+			t.Fatalf("%q: got style %q want %q", text, got[text].Prefix, style.Prefix)
+		}
+	}
+}
+
+func TestCodeFenceHighlightKeepsText(t *testing.T) {
+	out := stripANSI(renderStream(t, []byte(highlightSample), 80))
+	want := "func f() { return \"s\" } // c\n\nx = \"\"\"doc\nend\"\"\"\n\nfunc plain\n"
+	if out != want {
+		t.Fatalf("got %q want %q", out, want)
+	}
+}
+
+func TestCodeFenceHighlightStreamsByteByByte(t *testing.T) {
+	assertByteByByteMatches(t, highlightSample, 20)
+}
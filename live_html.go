@@ -0,0 +1,507 @@
+package mdf
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// maxHTMLTagLen bounds how much of an inline tag is buffered before it is
+// given up on and emitted literally.
+const maxHTMLTagLen = 512
+
+type htmlState struct {
+	policy    HTMLPolicy
+	inBlock   bool
+	blockCode bool
+	blockEnd  string
+}
+
+func (h *htmlState) reset(policy HTMLPolicy) {
+	h.policy = policy
+	h.inBlock = false
+	h.blockCode = false
+	h.blockEnd = ""
+}
+
+// htmlBlockNames are the CommonMark tag names that start an HTML block.
+var htmlBlockNames = map[string]bool{
+	"address": true, "article": true, "aside": true, "blockquote": true,
+	"body": true, "center": true, "details": true, "dialog": true, "dd": true,
+	"div": true, "dl": true, "dt": true, "fieldset": true, "figcaption": true,
+	"figure": true, "footer": true, "form": true, "h1": true, "h2": true,
+	"h3": true, "h4": true, "h5": true, "h6": true, "head": true,
+	"header": true, "hr": true, "html": true, "iframe": true, "legend": true,
+	"li": true, "main": true, "menu": true, "nav": true, "ol": true, "p": true,
+	"section": true, "summary": true, "table": true, "tbody": true, "td": true,
+	"tfoot": true, "th": true, "thead": true, "tr": true, "ul": true,
+}
+
+// htmlRawTextNames are the tags whose content is not text to show. They
+// start an HTML block that runs to the closing tag.
+var htmlRawTextNames = []string{"script", "style"}
+
+// htmlRawTextEnd returns the closing tag of the raw text block line opens,
+// or "".
+func htmlRawTextEnd(line string) string {
+	lower := strings.ToLower(line)
+	for _, name := range htmlRawTextNames {
+		rest, ok := strings.CutPrefix(lower, "<"+name)
+		if ok && (rest == "" || rest[0] == '>' || rest[0] == ' ' || rest[0] == '\t') {
+			return "</" + name + ">"
+		}
+	}
+	return ""
+}
+
+// beginHTMLBlock handles a line that starts with '<' outside a paragraph. It
+// reports whether the line was consumed as (part of) an HTML block.
+func (p *liveParser) beginHTMLBlock(stream Stream, line string, trimmed string) (bool, error) {
+	comment := strings.HasPrefix(trimmed, "<!--")
+	end := htmlRawTextEnd(trimmed)
+	rawText := end != ""
+	if !comment && !rawText && !isHTMLBlockStart(trimmed) {
+		return false, nil
+	}
+	if p.inParagraph && !comment && !rawText {
+		if name, _, _, _ := parseHTMLTag(firstHTMLTag(trimmed)); !htmlBlockNames[name] {
+			return false, nil
+		}
+	}
+	closed := false
+	switch {
+	case comment:
+		end = "-->"
+		closed = strings.Contains(trimmed[4:], end)
+	case rawText:
+		closed = strings.Contains(strings.ToLower(trimmed), end)
+	}
+	switch p.html.policy {
+	case HTMLInterpret:
+		if !comment && !rawText {
+			if !htmlLineInvisible(trimmed) {
+				return false, nil
+			}
+			p.hideLine()
+			return true, nil
+		}
+		fallthrough
+	case HTMLStrip:
+		p.hideLine()
+		p.inParagraph = false
+		if !closed {
+			p.html.inBlock = true
+			p.html.blockCode = false
+			p.html.blockEnd = end
+		}
+		return true, nil
+	case HTMLAsCode:
+		p.listLazy = false
+		p.listItemFirstLine = false
+		p.clearListIfOutdented(leadingIndentCountBytes(line))
+		p.inParagraph = false
+		p.planBlocks(0, tokenCodeBlockStart, BlockInfo{Lang: "html", HTML: true}, true)
+		if err := p.applyBlockBreak(stream, breakDouble); err != nil {
+			return true, err
+		}
+		p.enterCodeNoWrap(stream)
+		p.pendingCodeNL = false
+		p.html.inBlock = true
+		p.html.blockCode = true
+		p.html.blockEnd = end
+		p.lineDecided = true
+		p.lineIgnoreRest = true
+		p.lineSkipBreak = true
+		if err := p.emitCodeLine(stream, p.htmlCodeLine(line)); err != nil {
+			return true, err
+		}
+		if closed {
+			p.endHTMLBlock(stream)
+		}
+		return true, nil
+	}
+	return false, nil
+}
+
+// hideLine consumes the current line without producing output or counting
+// it as content.
+func (p *liveParser) hideLine() {
+	p.lineDecided = true
+	p.lineIgnoreRest = true
+	p.lineSkipBreak = true
+	p.lineHidden = true
+}
+
+// processHTMLBlockLine handles a complete line inside an HTML block.
+func (p *liveParser) processHTMLBlockLine(stream Stream, line string) error {
+	if p.html.blockEnd == "" && strings.TrimSpace(line) == "" {
+		p.endHTMLBlock(stream)
+		return p.replayFullLine(stream, line)
+	}
+	if p.html.blockCode {
+		p.seenLine = true
+		if err := p.emitCodeLine(stream, p.htmlCodeLine(line)); err != nil {
+			return err
+		}
+	}
+	if p.html.blockEnd != "" && strings.Contains(strings.ToLower(line), p.html.blockEnd) {
+		p.endHTMLBlock(stream)
+	}
+	return nil
+}
+
+func (p *liveParser) endHTMLBlock(stream Stream) {
+	if p.html.blockCode {
+		p.pendingCodeNL = false
+		p.pendingBreaks++
+		p.exitCodeNoWrap(stream)
+	}
+	p.html.inBlock = false
+	p.html.blockCode = false
+	p.html.blockEnd = ""
+}
+
+// htmlCodeLine returns a line of an HTML block shown as code, without the
+// indentation of the list item it is in.
+func (p *liveParser) htmlCodeLine(line string) string {
+	line = strings.TrimRight(line, "\r")
+	if len(p.listStack) > 0 {
+		line = trimIndent(line, p.listStack[len(p.listStack)-1].contentIndent)
+	}
+	return line
+}
+
+// isHTMLBlockStart reports whether a line opens an HTML block: a known block
+// tag, or a line made only of complete tags.
+func isHTMLBlockStart(line string) bool {
+	if name, _, _, ok := parseHTMLTag(firstHTMLTag(line)); ok && htmlBlockNames[name] {
+		return true
+	}
+	return htmlTagsOnly(line)
+}
+
+func firstHTMLTag(line string) string {
+	if end := htmlTagEnd(line); end > 0 {
+		return line[:end]
+	}
+	return ""
+}
+
+// htmlTagEnd returns the index after the '>' closing the tag at the start of
+// s, honouring quoted attribute values, or -1.
+func htmlTagEnd(s string) int {
+	if !strings.HasPrefix(s, "<") {
+		return -1
+	}
+	if strings.HasPrefix(s, "<!--") {
+		if end := strings.Index(s[4:], "-->"); end >= 0 {
+			return end + 7
+		}
+		return -1
+	}
+	var quote byte
+	for i := 1; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '>':
+			return i + 1
+		}
+	}
+	return -1
+}
+
+// htmlTagsOnly reports whether line consists of complete tags and spaces.
+func htmlTagsOnly(line string) bool {
+	found := false
+	for {
+		line = strings.TrimLeft(line, " \t\r")
+		if line == "" {
+			return found
+		}
+		end := htmlTagEnd(line)
+		if end < 0 {
+			return false
+		}
+		if _, _, _, ok := parseHTMLTag(line[:end]); !ok {
+			return false
+		}
+		found = true
+		line = line[end:]
+	}
+}
+
+// htmlLineInvisible reports whether an interpreted line would render
+// nothing: only tags that produce no text of their own.
+func htmlLineInvisible(line string) bool {
+	if !htmlTagsOnly(line) {
+		return false
+	}
+	for line = strings.TrimSpace(line); line != ""; line = strings.TrimLeft(line, " \t\r") {
+		end := htmlTagEnd(line)
+		name, closing, _, _ := parseHTMLTag(line[:end])
+		if name == "br" || (name == "summary" && !closing) {
+			return false
+		}
+		line = line[end:]
+	}
+	return true
+}
+
+// parseHTMLTag parses a complete "<...>" tag or comment. Comments report an
+// empty name.
+func parseHTMLTag(raw string) (name string, closing bool, attrs string, ok bool) {
+	if len(raw) < 3 || raw[0] != '<' || raw[len(raw)-1] != '>' {
+		return "", false, "", false
+	}
+	body := raw[1 : len(raw)-1]
+	if strings.HasPrefix(body, "!--") {
+		return "", false, "", strings.HasSuffix(body, "--") && len(body) >= 5
+	}
+	if strings.HasPrefix(body, "/") {
+		closing = true
+		body = body[1:]
+	}
+	n := 0
+	for n < len(body) && isHTMLNameByte(body[n], n == 0) {
+		n++
+	}
+	if n == 0 {
+		return "", false, "", false
+	}
+	rest := body[n:]
+	if rest != "" && rest[0] != ' ' && rest[0] != '\t' && rest != "/" {
+		return "", false, "", false
+	}
+	attrs = strings.TrimSuffix(strings.TrimSpace(rest), "/")
+	if closing && strings.TrimSpace(attrs) != "" {
+		return "", false, "", false
+	}
+	return strings.ToLower(body[:n]), closing, attrs, true
+}
+
+func isHTMLNameByte(c byte, first bool) bool {
+	if c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' {
+		return true
+	}
+	return !first && (c >= '0' && c <= '9' || c == '-')
+}
+
+// isHTMLTagPrefix reports whether the text buffered after '<' can still be
+// the start of a tag with attributes or a comment.
+func isHTMLTagPrefix(buf []byte) bool {
+	if len(buf) >= 3 && buf[0] == '!' && buf[1] == '-' && buf[2] == '-' {
+		return true
+	}
+	if len(buf) > 0 && buf[0] == '/' {
+		buf = buf[1:]
+	}
+	if len(buf) == 0 {
+		return false
+	}
+	for i := 0; i < len(buf); i++ {
+		if !isHTMLNameByte(buf[i], i == 0) {
+			return false
+		}
+	}
+	return true
+}
+
+// htmlAttr returns the value of attribute key from a tag's attribute text.
+func htmlAttr(attrs string, key string) string {
+	for attrs != "" {
+		attrs = strings.TrimLeft(attrs, " \t")
+		n := 0
+		for n < len(attrs) && attrs[n] != '=' && attrs[n] != ' ' && attrs[n] != '\t' {
+			n++
+		}
+		name := attrs[:n]
+		attrs = attrs[n:]
+		value := ""
+		if strings.HasPrefix(attrs, "=") {
+			attrs = attrs[1:]
+			if attrs != "" && (attrs[0] == '"' || attrs[0] == '\'') {
+				quote := attrs[0]
+				end := strings.IndexByte(attrs[1:], quote)
+				if end < 0 {
+					return ""
+				}
+				value = attrs[1 : end+1]
+				attrs = attrs[end+2:]
+			} else {
+				end := strings.IndexAny(attrs, " \t")
+				if end < 0 {
+					end = len(attrs)
+				}
+				value = attrs[:end]
+				attrs = attrs[end:]
+			}
+		}
+		if strings.EqualFold(name, key) {
+			return value
+		}
+		if n == 0 && value == "" {
+			return ""
+		}
+	}
+	return ""
+}
+
+// scanHTMLTag buffers an inline tag that contains attributes or spaces until
+// its closing '>'.
+func (p *liveParser) scanHTMLTag(stream Stream, r rune) error {
+	p.inline.autoLink = utf8.AppendRune(p.inline.autoLink, r)
+	buf := p.inline.autoLink
+	if p.inline.htmlQuote != 0 {
+		if r == p.inline.htmlQuote {
+			p.inline.htmlQuote = 0
+		}
+	} else if buf[0] == '!' {
+		if r == '>' && len(buf) >= 6 && buf[len(buf)-2] == '-' && buf[len(buf)-3] == '-' {
+			return p.finishHTMLTag(stream)
+		}
+	} else {
+		switch r {
+		case '"', '\'':
+			p.inline.htmlQuote = r
+		case '>':
+			return p.finishHTMLTag(stream)
+		case '<', '\n':
+			return p.flushPendingHTMLTag(stream)
+		}
+	}
+	if len(buf) > maxHTMLTagLen {
+		return p.flushPendingHTMLTag(stream)
+	}
+	return nil
+}
+
+func (p *liveParser) finishHTMLTag(stream Stream) error {
+	start := len(p.textArena)
+	p.textArena = append(p.textArena, '<')
+	p.textArena = append(p.textArena, p.inline.autoLink...)
+	raw := bytesToString(p.textArena[start:len(p.textArena)])
+	p.inline.inHTMLTag = false
+	p.inline.htmlQuote = 0
+	p.inline.autoLink = p.inline.autoLink[:0]
+	if _, _, _, ok := parseHTMLTag(raw); !ok {
+		return p.emitStyledText(stream, raw)
+	}
+	return p.emitHTMLTag(stream, raw)
+}
+
+// flushPendingHTMLTag emits an unterminated inline tag literally.
+func (p *liveParser) flushPendingHTMLTag(stream Stream) error {
+	if !p.inline.inHTMLTag {
+		return nil
+	}
+	start := len(p.textArena)
+	p.textArena = append(p.textArena, '<')
+	p.textArena = append(p.textArena, p.inline.autoLink...)
+	text := bytesToString(p.textArena[start:len(p.textArena)])
+	p.inline.inHTMLTag = false
+	p.inline.htmlQuote = 0
+	p.inline.autoLink = p.inline.autoLink[:0]
+	return p.emitStyledText(stream, text)
+}
+
+// emitHTMLTag renders a complete inline tag or comment according to the
+// HTML policy.
+func (p *liveParser) emitHTMLTag(stream Stream, raw string) error {
+	switch p.html.policy {
+	case HTMLStrip:
+		return nil
+	case HTMLAsCode:
+		return stream.WriteToken(StreamToken{Token: Token{Text: raw, Style: p.styles.CodeInline, Kind: tokenCode, HTML: true}})
+	case HTMLInterpret:
+		return p.interpretHTMLTag(stream, raw)
+	}
+	return p.emitStyledText(stream, raw)
+}
+
+func (p *liveParser) interpretHTMLTag(stream Stream, raw string) error {
+	name, closing, attrs, _ := parseHTMLTag(raw)
+	switch name {
+	case "br":
+		if err := stream.WriteToken(StreamToken{Token: Token{Text: "\n", Style: Style{}, Kind: tokenText}}); err != nil {
+			return err
+		}
+		return p.emitPrefix(stream, p.quoteDepth, p.listPrefixLen)
+	case "b", "strong":
+		p.inline.inStrong = !closing
+	case "i", "em":
+		p.inline.inEm = !closing
+	case "s", "del", "strike":
+		p.inline.inStrike = !closing
+	case "code", "kbd", "samp":
+		if closing {
+			if p.inline.htmlCode > 0 {
+				p.inline.htmlCode--
+			}
+		} else {
+			p.inline.htmlCode++
+		}
+	case "sup":
+		p.inline.htmlSup = !closing
+	case "sub":
+		p.inline.htmlSub = !closing
+	case "summary":
+		p.inline.inStrong = !closing
+		if !closing {
+			return stream.WriteToken(StreamToken{Token: Token{Text: "▸ ", Style: p.styles.ListMarker}})
+		}
+	case "a":
+		if closing {
+			url := p.inline.htmlLink
+			if url == "" {
+				return nil
+			}
+			p.inline.htmlLink = ""
+			if p.osc8 {
+				return stream.WriteToken(StreamToken{Token: Token{Kind: tokenLinkEnd}})
+			}
+			return p.emitLinkURLSuffix(stream, url)
+		}
+		url := htmlAttr(attrs, "href")
+		if url == "" || p.inline.htmlLink != "" {
+			return nil
+		}
+		p.inline.htmlLink = url
+		if p.osc8 {
+			return stream.WriteToken(StreamToken{Token: Token{Kind: tokenLinkStart, LinkURL: url}})
+		}
+	}
+	return nil
+}
+
+var superscriptRunes = map[rune]rune{
+	'0': '⁰', '1': '¹', '2': '²', '3': '³', '4': '⁴', '5': '⁵', '6': '⁶',
+	'7': '⁷', '8': '⁸', '9': '⁹', '+': '⁺', '-': '⁻', '=': '⁼', '(': '⁽',
+	')': '⁾', 'n': 'ⁿ', 'i': 'ⁱ',
+}
+
+var subscriptRunes = map[rune]rune{
+	'0': '₀', '1': '₁', '2': '₂', '3': '₃', '4': '₄', '5': '₅', '6': '₆',
+	'7': '₇', '8': '₈', '9': '₉', '+': '₊', '-': '₋', '=': '₌', '(': '₍',
+	')': '₎', 'a': 'ₐ', 'e': 'ₑ', 'o': 'ₒ', 'x': 'ₓ', 'h': 'ₕ', 'k': 'ₖ',
+	'l': 'ₗ', 'm': 'ₘ', 'n': 'ₙ', 'p': 'ₚ', 's': 'ₛ', 't': 'ₜ',
+}
+
+// scriptRune maps r to its Unicode superscript or subscript form inside
+// <sup> and <sub>, leaving runes without one unchanged.
+func (p *liveParser) scriptRune(r rune) rune {
+	if p.inline.htmlSup {
+		if m, ok := superscriptRunes[r]; ok {
+			return m
+		}
+	} else if p.inline.htmlSub {
+		if m, ok := subscriptRunes[r]; ok {
+			return m
+		}
+	}
+	return r
+}
@@ -0,0 +1,118 @@
+package mdf
+
+import (
+	"strings"
+	"testing"
+)
+
+const htmlPolicySample = "Inline <span class=\"note\">note</span> and <b>bold</b>.\n\n" +
+	"<!-- hidden\ncomment -->\n\n" +
+	"<div class=\"block\">\n  <p>Block text.</p>\n</div>\n\n" +
+	"Tail.\n"
+
+func TestHTMLPolicies(t *testing.T) {
+	cases := []struct {
+		policy HTMLPolicy
+		want   string
+	}{
+		{
+			policy: HTMLAsText,
+			want: "Inline <span class=\"note\">note</span> and <b>bold</b>.\n\n" +
+				"<!-- hidden comment -->\n\n" +
+				"<div class=\"block\"> <p>Block text.</p> </div>\n\n" +
+				"Tail.\n",
+		},
+		{
+			policy: HTMLStrip,
+			want:   "Inline note and bold.\n\nTail.\n",
+		},
+		{
+			policy: HTMLAsCode,
+			want: "Inline <span class=\"note\">note</span> and <b>bold</b>.\n\n" +
+				"<!-- hidden\ncomment -->\n\n" +
+				"<div class=\"block\">\n  <p>Block text.</p>\n</div>\n\n" +
+				"Tail.\n",
+		},
+		{
+			policy: HTMLInterpret,
+			want:   "Inline note and bold.\n\nBlock text.\n\nTail.\n",
+		},
+	}
+	for _, tc := range cases {
+		out := stripANSI(renderStreamWithOptions(t, []byte(htmlPolicySample), 80, WithHTMLPolicy(tc.policy)))
+		if out != tc.want {
+			t.Fatalf("policy %d: got %q want %q", tc.policy, out, tc.want)
+		}
+	}
+}
+
+func TestHTMLInterpretSafeSubset(t *testing.T) {
+	src := "<details>\n<summary>More</summary>\n\nH<sub>2</sub>O and x<sup>2</sup>, press <kbd>Ctrl</kbd>+<kbd>C</kbd>.<br>Next <a href=\"https://example.com\">site</a>.\n\n</details>\n"
+	out := stripANSI(renderStreamWithOptions(t, []byte(src), 80, WithHTMLPolicy(HTMLInterpret)))
+	want := "▸ More\n\nH₂O and x², press Ctrl+C.\nNext site (https://example.com).\n"
+	if out != want {
+		t.Fatalf("got %q want %q", out, want)
+	}
+}
+
+func TestHTMLInIndentedCode(t *testing.T) {
+	src := "para\n\n    <div>x</div>\n    code\n"
+	for _, policy := range []HTMLPolicy{HTMLStrip, HTMLAsCode, HTMLInterpret} {
+		out := stripANSI(renderStreamWithOptions(t, []byte(src), 80, WithHTMLPolicy(policy)))
+		if out != "para\n\n<div>x</div>\ncode\n" {
+			t.Fatalf("policy %d: got %q", policy, out)
+		}
+	}
+}
+
+func TestHTMLScriptAndStyleContentHidden(t *testing.T) {
+	src := "Before.\n\n<script type=\"text/javascript\">\nalert(\"x\");\n</script>\n<STYLE>\np { color: red }\n</STYLE>\n<style>b { x }</style>\n\nAfter.\n"
+	for _, policy := range []HTMLPolicy{HTMLStrip, HTMLInterpret} {
+		out := stripANSI(renderStreamWithOptions(t, []byte(src), 80, WithHTMLPolicy(policy)))
+		if out != "Before.\n\nAfter.\n" {
+			t.Fatalf("policy %d: got %q", policy, out)
+		}
+	}
+	out := stripANSI(renderStreamWithOptions(t, []byte(src), 80, WithHTMLPolicy(HTMLAsCode)))
+	if !strings.Contains(out, "alert(\"x\");\n</script>\n") {
+		t.Fatalf("code policy: got %q", out)
+	}
+}
+
+func TestHTMLInterpretStyles(t *testing.T) {
+	src := "<b>bold</b> <code>x</code> <a href=\"https://example.com\">site</a>"
+	stream := &captureStream{}
+	err := Parse(ParseRequest{
+		Reader:  strings.NewReader(src + "\n"),
+		Stream:  stream,
+		Theme:   DefaultTheme(),
+		Options: []RenderOption{WithOSC8(true), WithHTMLPolicy(HTMLInterpret)},
+	})
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	var sawBold, sawCode, sawLink bool
+	for _, tok := range stream.tokens {
+		switch {
+		case tok.Kind == tokenLinkStart:
+			sawLink = tok.LinkURL == "https://example.com"
+		case tok.Text == "o":
+			sawBold = tok.Style == DefaultTheme().Styles().Strong
+		case tok.Text == "x":
+			sawCode = tok.Kind == tokenCode
+		}
+		if strings.Contains(tok.Text, "<") {
+			t.Fatalf("markup leaked: %q", tok.Text)
+		}
+	}
+	if !sawBold || !sawCode || !sawLink {
+		t.Fatalf("bold=%v code=%v link=%v", sawBold, sawCode, sawLink)
+	}
+}
+
+func TestHTMLPolicyStreamsByteByByte(t *testing.T) {
+	src := htmlPolicySample + "\nA<br>B <kbd>K</kbd> <a href=\"x\">y</a>\n"
+	for _, policy := range []HTMLPolicy{HTMLStrip, HTMLAsCode, HTMLInterpret} {
+		assertByteByByteMatches(t, src, 40, WithHTMLPolicy(policy))
+	}
+}
@@ -0,0 +1,136 @@
+package mdf
+
+import (
+	"net/url"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// flushPendingBang emits a held '!' that did not turn out to open an image.
+func (p *liveParser) flushPendingBang(stream Stream) error {
+	if !p.inline.pendingBang {
+		return nil
+	}
+	p.inline.pendingBang = false
+	style, kind := p.inlineStyle()
+	return stream.WriteToken(StreamToken{Token: Token{Text: "!", Style: style, Kind: kind}})
+}
+
+// literalLinkOpen returns the opener of an abandoned link or image so it can
+// be emitted as text, and clears the image state.
+func (p *liveParser) literalLinkOpen() string {
+	image := p.inline.inImage
+	p.inline.inImage = false
+	p.inline.linkDepth = 0
+	if image {
+		return "!["
+	}
+	return "["
+}
+
+// emitImage writes an image token, hyperlinked to its own source. The
+// link carries the image's title, as links carry theirs, and ref, the label
+// of a reference image.
+func (p *liveParser) emitImage(stream Stream, alt string, dest string, ref string) error {
+	src := p.resolveImageSource(imageSource(dest))
+	target := src
+	if filepath.IsAbs(src) {
+		target = (&url.URL{Scheme: "file", Path: filepath.ToSlash(src)}).String()
+	}
+	if title := imageTitle(dest); title != "" && target != "" {
+		target += " " + title
+	}
+	return p.emitLinkedImage(stream, alt, src, target, ref)
+}
+
+// emitLinkedImage writes an image token carrying the alt text and source,
+// wrapped in a hyperlink to target when OSC 8 is enabled or followed by the
+// target otherwise.
+func (p *liveParser) emitLinkedImage(stream Stream, alt string, src string, target string, ref string) error {
+	if alt == "" && src != "" {
+		alt = path.Base(src)
+	}
+	tok := StreamToken{Token: Token{Text: alt, Style: p.styles.Image, Kind: tokenImage, LinkURL: src}}
+	if p.osc8 && target != "" {
+		if err := stream.WriteToken(StreamToken{Token: Token{Kind: tokenLinkStart, LinkURL: target, LinkRef: ref}}); err != nil {
+			return err
+		}
+		if err := stream.WriteToken(tok); err != nil {
+			return err
+		}
+		return stream.WriteToken(StreamToken{Token: Token{Kind: tokenLinkEnd}})
+	}
+	if err := stream.WriteToken(tok); err != nil {
+		return err
+	}
+	return p.emitLinkURLSuffix(stream, target)
+}
+
+// parseInlineImage reports whether link text is exactly one inline image, as
+// in a badge wrapped in a link.
+func parseInlineImage(text string) (alt string, src string, ok bool) {
+	if !strings.HasPrefix(text, "![") || !strings.HasSuffix(text, ")") {
+		return "", "", false
+	}
+	mid := strings.Index(text, "](")
+	if mid < 0 {
+		return "", "", false
+	}
+	return text[2:mid], imageSource(text[mid+2 : len(text)-1]), true
+}
+
+// resolveImageSource joins a relative local source onto the configured base
+// directory. URLs and absolute paths are returned unchanged.
+func (p *liveParser) resolveImageSource(src string) string {
+	if src == "" || p.imageBaseDir == "" || filepath.IsAbs(src) || hasURLScheme(src) {
+		return src
+	}
+	return filepath.Join(p.imageBaseDir, filepath.FromSlash(src))
+}
+
+// hasURLScheme reports whether s starts with a URI scheme such as "https:".
+func hasURLScheme(s string) bool {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z':
+		case i > 0 && (c >= '0' && c <= '9' || c == '+' || c == '-' || c == '.'):
+		case c == ':':
+			return i > 1
+		default:
+			return false
+		}
+	}
+	return false
+}
+
+// imageSource drops angle brackets and an optional title from an image
+// destination.
+func imageSource(raw string) string {
+	raw = strings.TrimSpace(raw)
+	if strings.HasPrefix(raw, "<") {
+		if end := strings.IndexByte(raw, '>'); end > 0 {
+			return raw[1:end]
+		}
+	}
+	if end := strings.IndexAny(raw, " \t"); end >= 0 {
+		raw = raw[:end]
+	}
+	return raw
+}
+
+// imageTitle returns the title of an image destination, quotes included,
+// or "".
+func imageTitle(raw string) string {
+	raw = strings.TrimSpace(raw)
+	if strings.HasPrefix(raw, "<") {
+		if end := strings.IndexByte(raw, '>'); end > 0 {
+			return strings.TrimSpace(raw[end+1:])
+		}
+	}
+	if end := strings.IndexAny(raw, " \t"); end >= 0 {
+		return strings.TrimSpace(raw[end:])
+	}
+	return ""
+}
@@ -0,0 +1,66 @@
+package mdf
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestImagesRenderPlaceholders(t *testing.T) {
+	cases := []struct {
+		src  string
+		want string
+	}{
+		{src: "See ![a cat](img/cat.png \"Cat\") here!\n", want: "See 🖼 a cat (img/cat.png) here!\n"},
+		{src: "![](dir/pic.jpg)\n", want: "🖼 pic.jpg (dir/pic.jpg)\n"},
+		{src: "[![build](https://ci.example/b.svg)](https://ci.example/)\n", want: "🖼 build (https://ci.example/)\n"},
+		{src: "![logo][l]\n\n[l]: logo.png\n", want: "🖼 logo (logo.png)\n"},
+		{src: "Wow! !not ![open\n", want: "Wow! !not ![open\n"},
+	}
+	for _, tc := range cases {
+		out := stripANSI(renderStream(t, []byte(tc.src), 80))
+		if out != tc.want {
+			t.Fatalf("%q: got %q want %q", tc.src, out, tc.want)
+		}
+	}
+}
+
+func TestImagesEmitImageTokens(t *testing.T) {
+	base := filepath.Join(t.TempDir(), "docs")
+	src := "![diagram](img/d.png) and ![remote](https://example.com/r.png \"R\")\n"
+	stream := &captureStream{}
+	err := Parse(ParseRequest{
+		Reader:  strings.NewReader(src),
+		Stream:  stream,
+		Theme:   DefaultTheme(),
+		Options: []RenderOption{WithOSC8(true), WithImageBaseDir(base)},
+	})
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	var images, links []string
+	for _, tok := range stream.tokens {
+		switch tok.Kind {
+		case tokenImage:
+			images = append(images, tok.Text+"="+tok.LinkURL)
+		case tokenLinkStart:
+			links = append(links, tok.LinkURL)
+		}
+	}
+	local := filepath.Join(base, "img", "d.png")
+	wantImages := []string{"diagram=" + local, "remote=https://example.com/r.png"}
+	if strings.Join(images, "|") != strings.Join(wantImages, "|") {
+		t.Fatalf("images %q want %q", images, wantImages)
+	}
+	if len(links) != 2 || !strings.HasPrefix(links[0], "file://") || links[1] != `https://example.com/r.png "R"` {
+		t.Fatalf("unexpected link targets %q", links)
+	}
+}
+
+func TestImagesStreamByteByByte(t *testing.T) {
+	src := "A ![x](x.png) b!\n\n| c |\n| - |\n| ![y](y.png) |\n\n[![z](z.png)](https://e.com)\n"
+	want := assertByteByByteMatches(t, src, 40)
+	if !strings.Contains(stripANSI(want), "🖼 y (y.png)") {
+		t.Fatalf("expected image placeholder in table cell, got %q", stripANSI(want))
+	}
+}
@@ -0,0 +1,192 @@
+package mdf
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// maxBareLinkLen bounds how much of a bare URL is buffered before it is
+// emitted.
+const maxBareLinkLen = 2048
+
+var bareLinkPrefixes = [...]string{"http://", "https://", "www."}
+
+// isBareLinkBoundary reports whether a GFM extended autolink may start after
+// prev. A zero rune marks the start of a line.
+func isBareLinkBoundary(prev rune) bool {
+	switch prev {
+	case 0, '*', '_', '~', '(':
+		return true
+	}
+	return unicode.IsSpace(prev)
+}
+
+// bareLinkPrefixState reports whether buf is a prefix of a bare link opener
+// and whether an opener has been fully matched.
+func bareLinkPrefixState(buf []byte) (candidate bool, matched bool) {
+	for _, prefix := range bareLinkPrefixes {
+		if len(buf) >= len(prefix) {
+			if strings.EqualFold(bytesToString(buf[:len(prefix)]), prefix) {
+				return true, true
+			}
+			continue
+		}
+		if strings.EqualFold(bytesToString(buf), prefix[:len(buf)]) {
+			candidate = true
+		}
+	}
+	return candidate, false
+}
+
+// scanBareLink consumes r while a bare link is being buffered. It reports
+// whether the rune was consumed; otherwise the caller handles it normally.
+func (p *liveParser) scanBareLink(stream Stream, r rune) (bool, error) {
+	if r == ' ' || r == '\t' || r == '\n' || r == '<' || len(p.inline.bareLink) >= maxBareLinkLen {
+		return false, p.flushPendingBareLink(stream)
+	}
+	p.inline.bareLink = utf8.AppendRune(p.inline.bareLink, r)
+	if p.inline.bareLinkOpen {
+		return true, nil
+	}
+	candidate, matched := bareLinkPrefixState(p.inline.bareLink)
+	if matched {
+		p.inline.bareLinkOpen = true
+		return true, nil
+	}
+	if candidate {
+		return true, nil
+	}
+	held := p.inline.bareLink[:len(p.inline.bareLink)-utf8.RuneLen(r)]
+	text := p.bytesTokenText(held)
+	p.inline.inBareLink = false
+	p.inline.bareLink = p.inline.bareLink[:0]
+	return false, p.emitStyledText(stream, text)
+}
+
+// flushPendingBareLink emits a buffered bare link, trimming trailing
+// punctuation the GFM way and replaying the trimmed suffix as inline text.
+func (p *liveParser) flushPendingBareLink(stream Stream) error {
+	if !p.inline.inBareLink {
+		return nil
+	}
+	text := p.bytesTokenText(p.inline.bareLink)
+	open := p.inline.bareLinkOpen
+	p.inline.inBareLink = false
+	p.inline.bareLinkOpen = false
+	p.inline.bareLink = p.inline.bareLink[:0]
+	if !open {
+		return p.emitStyledText(stream, text)
+	}
+	link, suffix := trimBareLink(text)
+	if !validBareLink(link) {
+		link, suffix = "", text
+	}
+	if link != "" {
+		target := link
+		if len(link) >= 4 && strings.EqualFold(link[:4], "www.") {
+			target = "http://" + link
+		}
+		if err := p.emitAutoLinkTokens(stream, link, target); err != nil {
+			return err
+		}
+	}
+	p.inline.skipBareLink = true
+	var err error
+	for _, r := range suffix {
+		if opensInline(r) && !p.closesInline(r) {
+			// A delimiter trimmed off the link that closes nothing is text.
+			p.inline.prevRune = r
+			err = p.emitStyledText(stream, p.runeTokenText(r))
+		} else {
+			err = p.emitInline(stream, r)
+		}
+		if err != nil {
+			break
+		}
+	}
+	p.inline.skipBareLink = false
+	return err
+}
+
+// opensInline reports whether r is an emphasis or strikethrough delimiter.
+func opensInline(r rune) bool {
+	return r == '*' || r == '_' || r == '~'
+}
+
+// closesInline reports whether the delimiter r may close open emphasis or
+// strikethrough.
+func (p *liveParser) closesInline(r rune) bool {
+	if r == '~' {
+		return p.inline.inStrike
+	}
+	return p.inline.inEm || p.inline.inStrong
+}
+
+// trimBareLink splits trailing punctuation, unbalanced closing parentheses
+// and trailing entity references off a bare link.
+func trimBareLink(text string) (string, string) {
+	end := len(text)
+	for end > 0 {
+		c := text[end-1]
+		switch c {
+		case '?', '!', '.', ',', ':', '*', '_', '~':
+			end--
+			continue
+		case ')':
+			if strings.Count(text[:end], ")") > strings.Count(text[:end], "(") {
+				end--
+				continue
+			}
+		case ';':
+			amp := strings.LastIndexByte(text[:end], '&')
+			if amp >= 0 && isEntityName(text[amp+1:end-1]) {
+				end = amp
+				continue
+			}
+		}
+		break
+	}
+	return text[:end], text[end:]
+}
+
+func isEntityName(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// validBareLink requires a domain after the opener with no underscores in
+// its last two labels.
+func validBareLink(link string) bool {
+	rest := ""
+	for _, prefix := range bareLinkPrefixes {
+		if len(link) > len(prefix) && strings.EqualFold(link[:len(prefix)], prefix) {
+			rest = link[len(prefix):]
+			break
+		}
+	}
+	if end := strings.IndexAny(rest, "/?#"); end >= 0 {
+		rest = rest[:end]
+	}
+	if rest == "" || strings.HasSuffix(rest, ".") {
+		return false
+	}
+	labels := strings.Split(rest, ".")
+	for i, label := range labels {
+		if label == "" {
+			return false
+		}
+		if i >= len(labels)-2 && strings.ContainsRune(label, '_') {
+			return false
+		}
+	}
+	return true
+}
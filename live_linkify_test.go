@@ -0,0 +1,99 @@
+package mdf
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBareLinksTrimTrailingPunctuation(t *testing.T) {
+	cases := []struct {
+		src  string
+		link string
+		rest string
+	}{
+		{src: "Visit https://example.com.", link: "https://example.com", rest: "."},
+		{src: "(see www.example.com/a_(b))", link: "www.example.com/a_(b)", rest: ")"},
+		{src: "go to http://example.com/x?y=1!", link: "http://example.com/x?y=1", rest: "!"},
+		{src: "**https://example.com**", link: "https://example.com", rest: ""},
+		{src: "www.example.com/&amp;", link: "www.example.com/", rest: ""},
+		{src: "https://example.com/path*emph* x", link: "https://example.com/path*emph", rest: "* x"},
+		{src: "see www.example.com_ and more", link: "www.example.com", rest: "_ and more"},
+	}
+	for _, tc := range cases {
+		stream := &captureStream{}
+		err := Parse(ParseRequest{
+			Reader:  strings.NewReader(tc.src),
+			Stream:  stream,
+			Theme:   DefaultTheme(),
+			Options: []RenderOption{WithOSC8(true)},
+		})
+		if err != nil {
+			t.Fatalf("parse %q: %v", tc.src, err)
+		}
+		var link, text strings.Builder
+		target := ""
+		inLink := false
+		for _, tok := range stream.tokens {
+			switch tok.Kind {
+			case tokenLinkStart:
+				inLink = true
+				target = tok.LinkURL
+			case tokenLinkEnd:
+				inLink = false
+			default:
+				if inLink {
+					link.WriteString(tok.Text)
+				} else {
+					text.WriteString(tok.Text)
+				}
+			}
+		}
+		if link.String() != tc.link {
+			t.Fatalf("%q: link text %q want %q", tc.src, link.String(), tc.link)
+		}
+		if strings.HasPrefix(tc.link, "www.") && target != "http://"+tc.link {
+			t.Fatalf("%q: unexpected target %q", tc.src, target)
+		}
+		if !strings.HasSuffix(text.String(), tc.rest) {
+			t.Fatalf("%q: trailing text %q want suffix %q", tc.src, text.String(), tc.rest)
+		}
+	}
+}
+
+func TestBareLinksRequireBoundary(t *testing.T) {
+	cases := []string{
+		"xhttps://example.com",
+		"`https://example.com` ",
+		"https://",
+		"www. example",
+		"hello world",
+	}
+	for _, src := range cases {
+		stream := &captureStream{}
+		err := Parse(ParseRequest{
+			Reader:  strings.NewReader(src),
+			Stream:  stream,
+			Theme:   DefaultTheme(),
+			Options: []RenderOption{WithOSC8(true)},
+		})
+		if err != nil {
+			t.Fatalf("parse %q: %v", src, err)
+		}
+		var text strings.Builder
+		for _, tok := range stream.tokens {
+			if tok.Kind == tokenLinkStart {
+				t.Fatalf("%q: unexpected link to %q", src, tok.LinkURL)
+			}
+			text.WriteString(tok.Text)
+		}
+		want := strings.ReplaceAll(src, "`", "")
+		if text.String() != want {
+			t.Fatalf("%q: got %q want %q", src, text.String(), want)
+		}
+	}
+}
+
+func TestBareLinksStreamByteByByte(t *testing.T) {
+	src := "See https://example.com/a, then www.example.org.\n\n- item http://x.io\n"
+	assertByteByByteMatches(t, src, 40)
+}
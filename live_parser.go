@@ -4,8 +4,11 @@ import (
 	"bytes"
 	"strconv"
 	"strings"
+	"unicode"
 	"unicode/utf8"
 	"unsafe"
+
+	"pkt.systems/mdf/internal/highlight"
 )
 
 var hashStringsWithSpace = [...]string{
@@ -43,19 +46,28 @@ type liveParser struct {
 	styles Styles
 	osc8   bool
 
+	imageBaseDir string
+	// speculative is set when open code spans and links are shown as they
+	// arrive.
+	speculative bool
+
 	frontMatter frontMatterFilter
 
-	lineBuf                   []rune
-	lineBytes                 []byte
-	textArena                 []byte
-	lineDecided               bool
-	lineEmitIdx               int
-	lineIgnoreRest            bool
-	lineSkipBreak             bool
-	lineStyle                 Style
-	lineStyled                bool
-	pendingBreaks             int
-	hardBreakPending          bool
+	lineBuf          []rune
+	lineBytes        []byte
+	textArena        []byte
+	lineDecided      bool
+	lineEmitIdx      int
+	lineIgnoreRest   bool
+	lineSkipBreak    bool
+	lineHidden       bool
+	lineStyle        Style
+	lineStyled       bool
+	pendingBreaks    int
+	hardBreakPending bool
+	// escapedBreak is set when a backslash asked for the pending hard
+	// break. If the paragraph ends instead, the backslash is literal.
+	escapedBreak              bool
 	immediateSpaces           []rune
 	inParagraph               bool
 	quoteDepth                int
@@ -79,6 +91,9 @@ type liveParser struct {
 
 	inCodeFence         bool
 	fenceMarker         string
+	codeHighlight       bool
+	highlighter         highlight.Highlighter
+	codeSpans           []highlight.Span
 	pendingCodeNL       bool
 	inIndentCode        bool
 	indentCode          int
@@ -88,6 +103,13 @@ type liveParser struct {
 	postCodeBreakSingle bool
 
 	inline inlineState
+	table  tableState
+	setext setextState
+	refs   refState
+	html   htmlState
+
+	footnotes footnoteState
+	blocks    blockState
 
 	lineBufArr         [1024]rune
 	lineBytesArr       [4096]byte
@@ -99,6 +121,7 @@ type liveParser struct {
 	inlineLinkTextArr  [128]byte
 	inlineLinkURLArr   [128]byte
 	inlineAutoLinkArr  [128]byte
+	inlineBareLinkArr  [128]byte
 	inlineEntityArr    [32]byte
 }
 
@@ -109,20 +132,41 @@ type inlineState struct {
 	pendingBackticks int
 	inEm             bool
 	inStrong         bool
+	inStrike         bool
 	inLink           bool
 	inLinkURL        bool
+	inLinkRef        bool
+	inImage          bool
+	pendingBang      bool
+	linkDepth        int
 	inAutoLink       bool
+	inHTMLTag        bool
+	htmlQuote        rune
+	htmlCode         int
+	htmlSup          bool
+	htmlSub          bool
+	htmlLink         string
+	inBareLink       bool
+	bareLinkOpen     bool
+	skipBareLink     bool
 	inEntity         bool
 	pendingNumUS     bool
+	pendingEscape    bool
 	lastWasDigit     bool
+	prevRune         rune
 
-	pendingDelim rune
-	pendingCount int
-	pendingClose bool
+	pendingDelim  rune
+	pendingCount  int
+	pendingClose  bool
+	pendingTildes int
+	// delimAfterWord is set when the pending delimiter run follows a letter
+	// or digit; an underscore run between two of them stays literal.
+	delimAfterWord bool
 
 	linkText []byte
 	linkURL  []byte
 	autoLink []byte
+	bareLink []byte
 	entity   []byte
 }
 
@@ -134,6 +178,11 @@ type listState struct {
 	contentIndent   int
 	prefixLen       int
 	itemIndentExtra int
+	// id and item identify the list and its current item in block events.
+	id    int
+	item  int
+	start int
+	task  TaskState
 }
 
 func newLiveParser(theme Theme, osc8 bool) *liveParser {
@@ -150,7 +199,9 @@ func newLiveParser(theme Theme, osc8 bool) *liveParser {
 	p.inline.linkText = p.inlineLinkTextArr[:0]
 	p.inline.linkURL = p.inlineLinkURLArr[:0]
 	p.inline.autoLink = p.inlineAutoLinkArr[:0]
+	p.inline.bareLink = p.inlineBareLinkArr[:0]
 	p.inline.entity = p.inlineEntityArr[:0]
+	p.refs.stream.p = p
 	return p
 }
 
@@ -168,10 +219,12 @@ func (p *liveParser) Reset(theme Theme, osc8 bool) {
 	p.lineEmitIdx = 0
 	p.lineIgnoreRest = false
 	p.lineSkipBreak = false
+	p.lineHidden = false
 	p.lineStyle = Style{}
 	p.lineStyled = false
 	p.pendingBreaks = 0
 	p.hardBreakPending = false
+	p.escapedBreak = false
 	p.immediateSpaces = p.immediateSpacesArr[:0]
 	p.inParagraph = false
 	p.quoteDepth = 0
@@ -191,6 +244,7 @@ func (p *liveParser) Reset(theme Theme, osc8 bool) {
 	p.lineHasNonSpace = false
 	p.inCodeFence = false
 	p.fenceMarker = ""
+	p.codeHighlight = false
 	p.pendingCodeNL = false
 	p.inIndentCode = false
 	p.indentCode = 0
@@ -202,17 +256,31 @@ func (p *liveParser) Reset(theme Theme, osc8 bool) {
 	p.inline.linkText = p.inlineLinkTextArr[:0]
 	p.inline.linkURL = p.inlineLinkURLArr[:0]
 	p.inline.autoLink = p.inlineAutoLinkArr[:0]
+	p.inline.bareLink = p.inlineBareLinkArr[:0]
 	p.inline.entity = p.inlineEntityArr[:0]
 	p.resetInline()
+	p.table.reset()
+	p.setext.reset()
+	p.refs.reset()
+	p.refs.stream.p = p
+	p.html.reset(HTMLAsText)
+	p.footnotes.reset()
+	p.blocks.reset()
+	p.imageBaseDir = ""
+	p.speculative = false
 }
 
 func (p *liveParser) feedRune(stream Stream, r rune) error {
+	stream = p.refTarget(stream)
 	if p.pendingQuoteBlank && !p.lineHasNonSpace && !p.pendingQuoteExplicit {
 		if r != ' ' && r != '\t' && r != '\r' {
 			if r == '>' {
 				if p.pendingBreaks == 0 {
 					p.pendingBreaks = 1
 				}
+				if err := p.closeLeaf(stream); err != nil {
+					return err
+				}
 				if err := p.applyPendingBreak(stream, breakSingle); err != nil {
 					return err
 				}
@@ -259,6 +327,51 @@ func (p *liveParser) feedRune(stream Stream, r rune) error {
 		p.lineBytes = utf8.AppendRune(p.lineBytes, r)
 		return nil
 	}
+	if p.table.pending || p.table.active {
+		if r == '\n' {
+			line := strings.Clone(bytesToString(p.lineBytes))
+			p.resetLine()
+			p.seenLine = true
+			return p.processTableLine(stream, line)
+		}
+		p.lineBuf = append(p.lineBuf, r)
+		p.lineBytes = utf8.AppendRune(p.lineBytes, r)
+		return nil
+	}
+	if p.html.inBlock {
+		if r == '\n' {
+			line := strings.Clone(bytesToString(p.lineBytes))
+			p.resetLine()
+			return p.processHTMLBlockLine(stream, line)
+		}
+		p.lineBuf = append(p.lineBuf, r)
+		p.lineBytes = utf8.AppendRune(p.lineBytes, r)
+		return nil
+	}
+	if p.footnotes.open != "" {
+		if r == '\n' {
+			line := strings.Clone(bytesToString(p.lineBytes))
+			p.resetLine()
+			return p.processFootnoteLine(stream, line, true)
+		}
+		p.lineBuf = append(p.lineBuf, r)
+		p.lineBytes = utf8.AppendRune(p.lineBytes, r)
+		return nil
+	}
+	if p.setext.pending {
+		if r == '\n' {
+			line := bytesToString(p.lineBytes)
+			p.resetLine()
+			p.seenLine = true
+			return p.processSetextLine(stream, line)
+		}
+		p.lineBuf = append(p.lineBuf, r)
+		p.lineBytes = utf8.AppendRune(p.lineBytes, r)
+		if maybeSetextUnderline(bytesToString(p.lineBytes)) || p.setext.size+len(p.lineBuf) <= maxSetextHeadingRunes && !p.showsOpenInline() {
+			return nil
+		}
+		return p.releaseSetextLine(stream, bytesToString(p.lineBytes), false)
+	}
 	if p.inIndentCode {
 		if r == '\n' {
 			if !p.codeLineDecided {
@@ -326,7 +439,9 @@ func (p *liveParser) feedRune(stream Stream, r rune) error {
 			}
 		}
 		if p.lineDecided {
-			p.hardBreakPending = hasHardLineBreak(bytesToString(p.lineBytes))
+			escaped := p.takeEscapedBreak()
+			p.escapedBreak = p.escapedBreak || escaped
+			p.hardBreakPending = escaped || hasHardLineBreak(bytesToString(p.lineBytes))
 			p.immediateSpaces = p.immediateSpaces[:0]
 			if err := p.flushPendingBackticks(stream); err != nil {
 				return err
@@ -337,12 +452,26 @@ func (p *liveParser) feedRune(stream Stream, r rune) error {
 			if err := p.flushPendingNumUS(stream); err != nil {
 				return err
 			}
+			if err := p.flushPendingTildes(stream); err != nil {
+				return err
+			}
+			if err := p.flushPendingBareLink(stream); err != nil {
+				return err
+			}
+			if err := p.flushPendingBang(stream); err != nil {
+				return err
+			}
+			if err := p.flushPendingHTMLTag(stream); err != nil {
+				return err
+			}
 			p.flushPendingDelims()
 			p.lineStyled = false
 			if !p.lineSkipBreak {
 				p.pendingBreaks++
 			}
-			p.seenLine = true
+			if !p.lineHidden {
+				p.seenLine = true
+			}
 		} else {
 			if p.seenLine {
 				p.pendingBreaks++
@@ -396,8 +525,11 @@ func (p *liveParser) resetLine() {
 	p.lineEmitIdx = 0
 	p.lineIgnoreRest = false
 	p.lineSkipBreak = false
+	p.lineHidden = false
 	p.immediateSpaces = p.immediateSpaces[:0]
+	p.inline.prevRune = 0
 	p.lineHasNonSpace = false
+	p.setext.holding = false
 	p.codeLineDecided = false
 	p.codeLineIsCode = false
 	p.quoteListPrefixFirst = false
@@ -433,10 +565,23 @@ func shouldAttemptDecision(line []rune) bool {
 	return !isPotentialBlockStart(last)
 }
 
+// codeIndent returns the indent that makes a line indented code: four
+// columns past the content of the open list item.
+func (p *liveParser) codeIndent() int {
+	if len(p.listStack) == 0 {
+		return 4
+	}
+	state := p.listStack[len(p.listStack)-1]
+	return state.contentIndent + state.itemIndentExtra + 4
+}
+
 func (p *liveParser) maybeDecideLine(stream Stream, force bool) error {
 	if p.lineDecided {
 		return nil
 	}
+	if p.setext.holding && !force && len(p.lineBuf) <= maxSetextHeadingRunes && !p.showsOpenInline() {
+		return nil
+	}
 	p.prevQuoteDepth = p.quoteDepth
 	line := bytesToString(p.lineBytes)
 	if p.pendingQuoteBlank && !force && strings.TrimSpace(line) == ">" {
@@ -447,6 +592,9 @@ func (p *liveParser) maybeDecideLine(stream Stream, force bool) error {
 			if p.pendingBreaks == 0 {
 				p.pendingBreaks = 1
 			}
+			if err := p.closeLeaf(stream); err != nil {
+				return err
+			}
 			if err := p.applyPendingBreak(stream, breakSingle); err != nil {
 				return err
 			}
@@ -495,6 +643,9 @@ func (p *liveParser) maybeDecideLine(stream Stream, force bool) error {
 			if p.pendingBreaks == 0 {
 				p.pendingBreaks = 1
 			}
+			if err := p.closeLeaf(stream); err != nil {
+				return err
+			}
 			if err := p.applyPendingBreak(stream, breakSingle); err != nil {
 				return err
 			}
@@ -549,6 +700,38 @@ func (p *liveParser) maybeDecideLine(stream Stream, force bool) error {
 			return nil
 		}
 	}
+	// Definitions and HTML indented as far as code are code.
+	indent, _ := leadingIndentCount(rest)
+	code := indent >= p.codeIndent()
+	if strings.HasPrefix(trimmed, "[^") && !p.inParagraph && depth == 0 && !code {
+		if !force && maybeFootnoteDefinition(trimmed) {
+			return nil
+		}
+		if label, text, ok := parseFootnoteDefinition(trimmed); ok && force {
+			p.hideLine()
+			return p.defineFootnote(label, text)
+		}
+	}
+	if trimmed[0] == '[' && !p.inParagraph && !code {
+		if !force && maybeLinkRefDefinition(trimmed) {
+			return nil
+		}
+		if label, url, ok := parseLinkRefDefinition(trimmed); ok && force {
+			p.hideLine()
+			if err := p.defineLinkRef(label, url); err != nil {
+				return err
+			}
+			return stream.WriteToken(StreamToken{Token: Token{Kind: tokenDefinition, LinkRef: label, LinkURL: url}})
+		}
+	}
+	if trimmed[0] == '<' && p.html.policy != HTMLAsText && depth == 0 && !code {
+		if !force {
+			return nil
+		}
+		if handled, err := p.beginHTMLBlock(stream, line, trimmed); handled {
+			return err
+		}
+	}
 	if isThematicBreak(rest) {
 		if p.pendingBreaks == 0 {
 			p.pendingBreaks = 1
@@ -561,6 +744,11 @@ func (p *liveParser) maybeDecideLine(stream Stream, force bool) error {
 		p.listItemFirstLine = false
 		p.quoteLazy = false
 		p.hardBreakPending = false
+		p.clearListIfOutdented(lineIndent)
+		p.planBlocks(depth, 0, BlockInfo{}, false)
+		if err := p.syncBlocks(stream); err != nil {
+			return err
+		}
 		return stream.WriteToken(StreamToken{Token: Token{Kind: tokenThematicBreak}})
 	}
 	if isMaybeThematicBreak(rest) {
@@ -574,11 +762,17 @@ func (p *liveParser) maybeDecideLine(stream Stream, force bool) error {
 		}
 	}
 	if fence := fenceMarker(rest); fence != "" {
-		if err := p.applyPendingBreak(stream, breakDouble); err != nil {
+		info := fenceInfo(rest)
+		p.listLazy = false
+		p.listItemFirstLine = false
+		p.clearListIfOutdented(lineIndent)
+		p.planBlocks(depth, tokenCodeBlockStart, BlockInfo{Lang: fenceLang(info)}, true)
+		if err := p.applyBlockBreak(stream, breakDouble); err != nil {
 			return err
 		}
 		p.inCodeFence = true
 		p.fenceMarker = fence
+		p.codeHighlight = p.highlighter.Reset(info)
 		p.pendingCodeNL = false
 		p.enterCodeNoWrap(stream)
 		p.inParagraph = false
@@ -592,7 +786,8 @@ func (p *liveParser) maybeDecideLine(stream Stream, force bool) error {
 			p.listLazy = false
 			p.listItemFirstLine = false
 			p.clearListIfOutdented(lineIndent)
-			if err := p.applyPendingBreak(stream, breakDouble); err != nil {
+			p.planBlocks(depth, tokenHeadingStart, BlockInfo{Level: level}, true)
+			if err := p.applyBlockBreak(stream, breakDouble); err != nil {
 				return err
 			}
 			if err := p.emitPrefix(stream, depth, p.listPrefixLen); err != nil {
@@ -630,6 +825,9 @@ func (p *liveParser) maybeDecideLine(stream Stream, force bool) error {
 			return nil
 		}
 		if depth > 0 && p.inParagraph && p.pendingBreaks == 1 && !p.listLazy {
+			if err := p.closeLeaf(stream); err != nil {
+				return err
+			}
 			if err := p.applyPendingBreak(stream, breakSingle); err != nil {
 				return err
 			}
@@ -656,6 +854,9 @@ func (p *liveParser) maybeDecideLine(stream Stream, force bool) error {
 		}
 		parentOrdered := prevDepth > 0 && p.listStack[prevDepth-1].ordered
 		parentPrefixLen, state := p.updateList(leadingIndentCountBytes(line), ordered, markerRune, number, markerLen, padding)
+		state.item = p.blocks.newID()
+		state.task = taskState(content)
+		p.listStack[len(p.listStack)-1] = state
 		nested := len(p.listStack) > prevDepth && leadingIndentCountBytes(line) > prevIndent
 		mode := breakDouble
 		if p.listLazy && p.pendingBreaks == 1 && (!nested || !parentOrdered) {
@@ -664,7 +865,8 @@ func (p *liveParser) maybeDecideLine(stream Stream, force bool) error {
 		if depth > 0 && p.inParagraph && p.pendingBreaks == 1 && !p.listLazy {
 			mode = breakDouble
 		}
-		if err := p.applyPendingBreak(stream, mode); err != nil {
+		p.planBlocks(depth, tokenParagraphStart, BlockInfo{}, true)
+		if err := p.applyBlockBreak(stream, mode); err != nil {
 			return err
 		}
 		if err := p.emitPrefix(stream, depth, parentPrefixLen); err != nil {
@@ -698,15 +900,10 @@ func (p *liveParser) maybeDecideLine(stream Stream, force bool) error {
 		p.lineEmitIdx = len(p.lineBuf) - utf8.RuneCountInString(content)
 		return p.emitInlineRunes(stream, p.lineBuf[p.lineEmitIdx:])
 	}
-	indent, _ := leadingIndentCount(rest)
-	codeIndent := 4
-	if len(p.listStack) > 0 {
-		state := p.listStack[len(p.listStack)-1]
-		codeIndent = state.contentIndent + state.itemIndentExtra + 4
-	}
-	if indent >= codeIndent {
+	if indent >= p.codeIndent() {
+		p.planBlocks(depth, tokenCodeBlockStart, BlockInfo{}, true)
 		p.inIndentCode = true
-		p.indentCode = codeIndent
+		p.indentCode = p.codeIndent()
 		p.pendingCodeNL = false
 		p.enterCodeNoWrap(stream)
 		p.inParagraph = false
@@ -722,6 +919,15 @@ func (p *liveParser) maybeDecideLine(stream Stream, force bool) error {
 	if explicit && depth > 0 && lineIndent > 0 {
 		indentForList = lineIndent
 	}
+	if trimmed[0] == '|' && !p.table.skipProbe {
+		if !force {
+			return nil
+		}
+		inList := p.inListContinuation(indentForList, trimmed, explicit, lineIndent)
+		if p.beginTableHeader(depth, trimmed, inList, indentForList) {
+			return nil
+		}
+	}
 	quoteLineWithIndent := lineIndent > 0 && strings.HasPrefix(strings.TrimLeft(line, " \t"), ">")
 	if p.inListContinuation(indentForList, trimmed, explicit, lineIndent) {
 		if explicit && depth > 0 && len(p.listStack) > 0 && quoteLineWithIndent {
@@ -768,7 +974,8 @@ func (p *liveParser) maybeDecideLine(stream Stream, force bool) error {
 		if forceLineBreak && p.pendingBreaks == 0 {
 			p.pendingBreaks = 1
 		}
-		if err := p.applyPendingBreak(stream, mode); err != nil {
+		p.planBlocks(depth, tokenParagraphStart, BlockInfo{}, newParagraph)
+		if err := p.applyBlockBreak(stream, mode); err != nil {
 			return err
 		}
 		if mode != breakSpace {
@@ -839,6 +1046,9 @@ func (p *liveParser) maybeDecideLine(stream Stream, force bool) error {
 		outdentIndent = lineIndent
 	}
 	p.clearListIfOutdented(outdentIndent)
+	if p.holdSetextCandidate(depth, force) {
+		return nil
+	}
 	return p.decideParagraph(stream, depth, rest, quoteBlockStart)
 }
 
@@ -855,7 +1065,8 @@ func (p *liveParser) decideParagraph(stream Stream, depth int, rest string, bloc
 		}
 	}
 	suppressPrefix := p.inParagraph && p.hardBreakPending && depth == 0 && p.listPrefixLen == 0 && !blockStart
-	if err := p.applyPendingBreak(stream, mode); err != nil {
+	p.planBlocks(depth, tokenParagraphStart, BlockInfo{}, newParagraph)
+	if err := p.applyBlockBreak(stream, mode); err != nil {
 		return err
 	}
 	if mode != breakSpace && !suppressPrefix {
@@ -907,6 +1118,9 @@ func (p *liveParser) maybeDecideIndentCodeLine(stream Stream, force bool) error
 			if p.pendingBreaks == 0 {
 				p.pendingBreaks = 1
 			}
+			if err := p.closeLeaf(stream); err != nil {
+				return err
+			}
 			if err := p.applyPendingBreak(stream, breakSingle); err != nil {
 				return err
 			}
@@ -940,8 +1154,8 @@ func (p *liveParser) maybeDecideIndentCodeLine(stream Stream, force bool) error
 		if !force {
 			return nil
 		}
-		if p.pendingBreaks > 0 {
-			if err := p.applyPendingBreak(stream, breakDouble); err != nil {
+		if p.pendingBreaks > 0 || p.blocks.planned {
+			if err := p.applyBlockBreak(stream, breakDouble); err != nil {
 				return err
 			}
 		}
@@ -976,8 +1190,9 @@ func (p *liveParser) maybeDecideIndentCodeLine(stream Stream, force bool) error
 
 func (p *liveParser) emitInlineRunes(stream Stream, runes []rune) error {
 	for i := 0; i < len(runes); {
+		stream = p.refTarget(stream)
 		r := runes[i]
-		if r == '_' && i > 0 && i+1 < len(runes) && !p.inline.inCode && !p.inline.inLink && !p.inline.inLinkURL && !p.inline.inAutoLink {
+		if r == '_' && i > 0 && i+1 < len(runes) && !p.inline.inCode && !p.inline.inLink && !p.inline.inLinkURL && !p.inline.inAutoLink && !p.inline.inBareLink {
 			if runes[i-1] >= '0' && runes[i-1] <= '9' && runes[i+1] >= '0' && runes[i+1] <= '9' {
 				if err := p.emitInline(stream, '\u00A0'); err != nil {
 					return err
@@ -986,7 +1201,7 @@ func (p *liveParser) emitInlineRunes(stream Stream, runes []rune) error {
 				continue
 			}
 		}
-		if r == '&' && !p.inline.inCode && !p.inline.inAutoLink && !p.inline.inLinkURL {
+		if r == '&' && !p.inline.inCode && !p.inline.inAutoLink && !p.inline.inLinkURL && !p.inline.inBareLink {
 			if i+5 < len(runes) && isNBSPRunes(runes[i:i+6]) {
 				if err := p.emitInline(stream, '\u00A0'); err != nil {
 					return err
@@ -1175,11 +1390,16 @@ func (p *liveParser) processCodeFenceLine(stream Stream, line string) error {
 	if strings.HasPrefix(trim, p.fenceMarker) && strings.TrimSpace(trim[len(p.fenceMarker):]) == "" {
 		p.inCodeFence = false
 		p.fenceMarker = ""
+		p.codeHighlight = false
 		p.pendingCodeNL = false
 		p.pendingBreaks++
 		p.exitCodeNoWrap(stream)
 		return nil
 	}
+	if len(p.listStack) > 0 {
+		// Inside a list item the item's indentation is not part of the code.
+		rest = trimIndent(rest, p.listStack[len(p.listStack)-1].contentIndent)
+	}
 	return p.emitCodeLine(stream, rest)
 }
 
@@ -1196,9 +1416,49 @@ func (p *liveParser) emitCodeLine(stream Stream, line string) error {
 	if line == "" {
 		return nil
 	}
+	if p.codeHighlight {
+		return p.emitHighlightedCode(stream, line)
+	}
 	return stream.WriteToken(StreamToken{Token: Token{Text: line, Style: p.styles.CodeBlock, Kind: tokenCode, CodeBlock: true}})
 }
 
+// emitHighlightedCode writes a fenced code line as one token per highlighted
+// span.
+func (p *liveParser) emitHighlightedCode(stream Stream, line string) error {
+	p.codeSpans = p.highlighter.Line(line, p.codeSpans[:0])
+	for _, span := range p.codeSpans {
+		tok := StreamToken{Token: Token{Text: span.Text, Style: p.codeStyle(span.Class), Kind: tokenCode, CodeBlock: true}}
+		if err := stream.WriteToken(tok); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// codeStyle maps a highlight class to its theme role, falling back to the
+// code block style for roles the theme leaves empty.
+func (p *liveParser) codeStyle(class highlight.Class) Style {
+	var style Style
+	switch class {
+	case highlight.Keyword:
+		style = p.styles.CodeKeyword
+	case highlight.String:
+		style = p.styles.CodeString
+	case highlight.Comment:
+		style = p.styles.CodeComment
+	case highlight.Number:
+		style = p.styles.CodeNumber
+	case highlight.Type:
+		style = p.styles.CodeType
+	case highlight.Punctuation:
+		style = p.styles.CodePunctuation
+	}
+	if style.Prefix == "" {
+		return p.styles.CodeBlock
+	}
+	return style
+}
+
 func (p *liveParser) emitCodeRune(stream Stream, r rune) error {
 	return stream.WriteToken(StreamToken{Token: Token{Text: p.runeTokenText(r), Style: p.styles.CodeBlock, Kind: tokenCode, CodeBlock: true}})
 }
@@ -1229,6 +1489,8 @@ func (p *liveParser) updateList(indent int, ordered bool, marker rune, start int
 			next:          start,
 			contentIndent: markerLen + padding,
 			prefixLen:     markerLen + 1,
+			id:            p.blocks.newID(),
+			start:         start,
 		}
 		p.listStack = append(p.listStack, state)
 		p.listPrefixLen += state.prefixLen
@@ -1333,18 +1595,36 @@ func (s *inlineState) reset() {
 	s.pendingBackticks = 0
 	s.inEm = false
 	s.inStrong = false
+	s.inStrike = false
 	s.inLink = false
 	s.inLinkURL = false
+	s.inLinkRef = false
+	s.inImage = false
+	s.pendingBang = false
+	s.linkDepth = 0
 	s.inAutoLink = false
+	s.inHTMLTag = false
+	s.htmlQuote = 0
+	s.htmlCode = 0
+	s.htmlSup = false
+	s.htmlSub = false
+	s.htmlLink = ""
+	s.inBareLink = false
+	s.bareLinkOpen = false
+	s.skipBareLink = false
 	s.inEntity = false
 	s.pendingNumUS = false
+	s.pendingEscape = false
 	s.lastWasDigit = false
+	s.prevRune = 0
 	s.pendingDelim = 0
 	s.pendingCount = 0
 	s.pendingClose = false
+	s.pendingTildes = 0
 	s.linkText = s.linkText[:0]
 	s.linkURL = s.linkURL[:0]
 	s.autoLink = s.autoLink[:0]
+	s.bareLink = s.bareLink[:0]
 	s.entity = s.entity[:0]
 }
 
@@ -1458,6 +1738,11 @@ func fenceMarker(text string) string {
 	return ""
 }
 
+// fenceInfo returns the info string following an opening fence.
+func fenceInfo(text string) string {
+	return strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(text), "`~"))
+}
+
 func isMaybeFence(text string) bool {
 	trim := strings.TrimSpace(text)
 	if len(trim) == 0 || len(trim) >= 3 {
@@ -1475,38 +1760,36 @@ func isMaybeFence(text string) bool {
 	return true
 }
 
+// isThematicBreak reports whether text is three or more "-", "*" or "_"
+// characters, optionally separated by blanks.
 func isThematicBreak(text string) bool {
-	trim := strings.TrimSpace(text)
-	if len(trim) < 3 {
-		return false
-	}
-	ch := trim[0]
-	if ch != '-' && ch != '*' && ch != '_' {
-		return false
-	}
-	for i := 0; i < len(trim); i++ {
-		if trim[i] != ch {
-			return false
-		}
-	}
-	return true
+	ch, n := thematicBreakRun(text)
+	return ch != 0 && n >= 3
 }
 
 func isMaybeThematicBreak(text string) bool {
-	trim := strings.TrimSpace(text)
-	if len(trim) == 0 || len(trim) >= 3 {
-		return false
-	}
-	ch := trim[0]
-	if ch != '-' && ch != '*' && ch != '_' {
-		return false
-	}
-	for i := 0; i < len(trim); i++ {
-		if trim[i] != ch {
-			return false
+	ch, n := thematicBreakRun(text)
+	return ch != 0 && n < 3
+}
+
+// thematicBreakRun returns the break character of text and how often it
+// occurs, or 0 if text holds anything but that character and blanks.
+func thematicBreakRun(text string) (byte, int) {
+	var ch byte
+	n := 0
+	for i := 0; i < len(text); i++ {
+		switch c := text[i]; {
+		case c == ' ' || c == '\t' || c == '\r' || c == '\n':
+		case ch == 0 && (c == '-' || c == '*' || c == '_'):
+			ch = c
+			n++
+		case c == ch:
+			n++
+		default:
+			return 0, 0
 		}
 	}
-	return true
+	return ch, n
 }
 
 func leadingIndentCount(s string) (int, int) {
@@ -1665,6 +1948,8 @@ func (p *liveParser) orderedMarkerText(num int, marker rune) string {
 }
 
 func (p *liveParser) emitInline(stream Stream, r rune) error {
+	prev := p.inline.prevRune
+	p.inline.prevRune = r
 	if p.inline.pendingNumUS {
 		p.inline.pendingNumUS = false
 		if r >= '0' && r <= '9' && !p.inline.inCode && !p.inline.inLink && !p.inline.inLinkURL && !p.inline.inAutoLink {
@@ -1679,6 +1964,9 @@ func (p *liveParser) emitInline(stream Stream, r rune) error {
 			}
 		}
 	}
+	if p.inline.inHTMLTag {
+		return p.scanHTMLTag(stream, r)
+	}
 	if p.inline.inAutoLink {
 		switch r {
 		case '>':
@@ -1687,6 +1975,12 @@ func (p *liveParser) emitInline(stream Stream, r rune) error {
 			p.inline.inAutoLink = false
 			return p.emitAutoLink(stream, text)
 		case '\n', ' ', '\t':
+			if r != '\n' && p.html.policy != HTMLAsText && isHTMLTagPrefix(p.inline.autoLink) {
+				p.inline.inAutoLink = false
+				p.inline.inHTMLTag = true
+				p.inline.autoLink = utf8.AppendRune(p.inline.autoLink, r)
+				return nil
+			}
 			start := len(p.textArena)
 			p.textArena = append(p.textArena, '<')
 			p.textArena = append(p.textArena, p.inline.autoLink...)
@@ -1730,16 +2024,40 @@ func (p *liveParser) emitInline(stream Stream, r rune) error {
 		}
 	}
 	if p.inline.pendingCount > 0 && r != p.inline.pendingDelim {
-		p.flushPendingDelims()
+		if p.inline.pendingDelim == '_' && p.inline.delimAfterWord && isWordRune(r) && !p.inline.inCode {
+			if err := p.emitIntrawordUnderscores(stream); err != nil {
+				return err
+			}
+		} else {
+			p.flushPendingDelims()
+		}
+	}
+	if p.inline.pendingTildes > 0 && r != '~' {
+		if err := p.flushPendingTildes(stream); err != nil {
+			return err
+		}
+	}
+	if p.inline.pendingBang {
+		if r == '[' {
+			p.inline.pendingBang = false
+			p.inline.inImage = true
+			p.inline.inLink = true
+			p.inline.linkText = p.inline.linkText[:0]
+			p.inline.linkURL = p.inline.linkURL[:0]
+			return nil
+		}
+		if err := p.flushPendingBang(stream); err != nil {
+			return err
+		}
+	}
+	if p.inline.inBareLink {
+		if consumed, err := p.scanBareLink(stream, r); err != nil || consumed {
+			return err
+		}
 	}
 	if p.inline.inLink && p.inline.pendingClose && (r == ' ' || r == '\t') {
 		p.inline.pendingClose = false
-		_ = stream.WriteToken(StreamToken{Token: Token{Text: "[", Style: p.styles.Text}})
-		_ = stream.WriteToken(StreamToken{Token: Token{Text: p.bytesTokenText(p.inline.linkText), Style: p.styles.Text}})
-		_ = stream.WriteToken(StreamToken{Token: Token{Text: "]", Style: p.styles.Text}})
-		p.inline.inLink = false
-		p.inline.linkText = p.inline.linkText[:0]
-		p.inline.linkURL = p.inline.linkURL[:0]
+		p.releaseLinkText(stream)
 	}
 	if p.lineDecided {
 		if r == ' ' || r == '\t' {
@@ -1757,6 +2075,18 @@ func (p *liveParser) emitInline(stream Stream, r rune) error {
 			p.immediateSpaces = p.immediateSpaces[:0]
 		}
 	}
+	if p.footnotes.emitting > 0 && (r == footnoteAnchorRune || r == footnoteBackRune) {
+		return p.emitFootnoteMarker(stream, r)
+	}
+	if p.inline.pendingEscape {
+		p.inline.pendingEscape = false
+		if isEscapable(r) {
+			return p.emitEscaped(stream, r)
+		}
+		if err := p.emitEscaped(stream, '\\'); err != nil {
+			return err
+		}
+	}
 	if r == '`' {
 		p.inline.pendingBackticks++
 		return nil
@@ -1767,6 +2097,15 @@ func (p *liveParser) emitInline(stream Stream, r rune) error {
 		}
 		p.inline.pendingBackticks = 0
 	}
+	if r == '\\' && !p.inline.inCode {
+		p.inline.pendingEscape = true
+		return nil
+	}
+	if (r == 'h' || r == 'H' || r == 'w' || r == 'W') && !p.inline.skipBareLink && isBareLinkBoundary(prev) && !p.inline.inCode && !p.inline.inLink && !p.inline.inAutoLink && !p.inline.inEntity {
+		p.inline.inBareLink = true
+		p.inline.bareLink = utf8.AppendRune(p.inline.bareLink[:0], r)
+		return nil
+	}
 	if r == '&' && !p.inline.inCode && !p.inline.inAutoLink && !p.inline.inLinkURL {
 		p.inline.inEntity = true
 		p.inline.entity = p.inline.entity[:0]
@@ -1781,6 +2120,20 @@ func (p *liveParser) emitInline(stream Stream, r rune) error {
 	if p.inline.inCode {
 		p.inline.codeBuf = utf8.AppendRune(p.inline.codeBuf, r)
 		p.inline.lastWasDigit = false
+		return p.speculate(stream, p.runeTokenText(r), p.styles.CodeInline)
+	}
+	if p.inline.inLinkRef {
+		if r == ']' {
+			return p.emitRefLink(stream)
+		}
+		p.inline.lastWasDigit = false
+		p.inline.linkURL = utf8.AppendRune(p.inline.linkURL, r)
+		return nil
+	}
+	if r == '[' && p.inline.inLink && p.inline.pendingClose {
+		p.inline.pendingClose = false
+		p.inline.inLinkRef = true
+		p.inline.linkURL = p.inline.linkURL[:0]
 		return nil
 	}
 	switch r {
@@ -1791,9 +2144,20 @@ func (p *liveParser) emitInline(stream Stream, r rune) error {
 			} else {
 				p.inline.pendingDelim = r
 				p.inline.pendingCount = 1
+				p.inline.delimAfterWord = isWordRune(prev)
 			}
 			return nil
 		}
+	case '~':
+		if !p.inline.inCode && !p.inline.inLink {
+			p.inline.pendingTildes++
+			return nil
+		}
+	case '!':
+		if !p.inline.inCode && !p.inline.inLink {
+			p.inline.pendingBang = true
+			return nil
+		}
 	case '[':
 		if !p.inline.inCode && !p.inline.inLink {
 			p.inline.inLink = true
@@ -1801,8 +2165,18 @@ func (p *liveParser) emitInline(stream Stream, r rune) error {
 			p.inline.linkURL = p.inline.linkURL[:0]
 			return nil
 		}
+		if p.inline.inLink && !p.inline.inLinkURL && !p.inline.pendingClose && bytesToString(p.inline.linkText) == "!" {
+			p.inline.linkDepth++
+		}
 	case ']':
 		if p.inline.inLink && !p.inline.inCode {
+			if p.inline.linkDepth > 0 && !p.inline.inLinkURL {
+				p.inline.linkDepth--
+				break
+			}
+			if !p.inline.inImage && !p.inline.inLinkURL && !p.inline.pendingClose && isFootnoteRef(p.inline.linkText) {
+				return p.emitFootnoteRef(stream)
+			}
 			p.inline.pendingClose = true
 			return nil
 		}
@@ -1819,7 +2193,7 @@ func (p *liveParser) emitInline(stream Stream, r rune) error {
 	case '<':
 		if !p.inline.inCode {
 			if p.inline.inLink && !p.inline.inLinkURL && !p.inline.pendingClose && len(p.inline.linkText) == 0 {
-				_ = stream.WriteToken(StreamToken{Token: Token{Text: "[", Style: p.styles.Text}})
+				_ = stream.WriteToken(StreamToken{Token: Token{Text: p.literalLinkOpen(), Style: p.styles.Text}})
 				p.inline.inLink = false
 				p.inline.linkText = p.inline.linkText[:0]
 				p.inline.linkURL = p.inline.linkURL[:0]
@@ -1839,12 +2213,7 @@ func (p *liveParser) emitInline(stream Stream, r rune) error {
 		if p.inline.pendingClose {
 			p.inline.pendingClose = false
 			if r != '(' {
-				_ = stream.WriteToken(StreamToken{Token: Token{Text: "[", Style: p.styles.Text}})
-				_ = stream.WriteToken(StreamToken{Token: Token{Text: p.bytesTokenText(p.inline.linkText), Style: p.styles.Text}})
-				_ = stream.WriteToken(StreamToken{Token: Token{Text: "]", Style: p.styles.Text}})
-				p.inline.inLink = false
-				p.inline.linkText = p.inline.linkText[:0]
-				p.inline.linkURL = p.inline.linkURL[:0]
+				p.releaseLinkText(stream)
 			}
 		}
 		if p.inline.inLink && p.inline.inLinkURL {
@@ -1855,19 +2224,23 @@ func (p *liveParser) emitInline(stream Stream, r rune) error {
 		if p.inline.inLink {
 			p.inline.lastWasDigit = false
 			p.inline.linkText = utf8.AppendRune(p.inline.linkText, r)
-			return nil
+			style, _ := p.inlineStyle()
+			return p.speculate(stream, p.runeTokenText(r), combineStyles(style, p.styles.LinkText))
 		}
 	}
 
 	style, kind := p.inlineStyle()
 	p.inline.lastWasDigit = r >= '0' && r <= '9'
+	if p.inline.htmlSup || p.inline.htmlSub {
+		r = p.scriptRune(r)
+	}
 	return stream.WriteToken(StreamToken{Token: Token{Text: p.runeTokenText(r), Style: style, Kind: kind}})
 }
 
 func (p *liveParser) inlineStyle() (Style, tokenKind) {
 	style := p.styles.Text
 	kind := tokenText
-	if p.inline.inCode {
+	if p.inline.inCode || p.inline.htmlCode > 0 {
 		style = p.styles.CodeInline
 		kind = tokenCode
 	} else if p.inline.inEm && p.inline.inStrong {
@@ -1882,6 +2255,12 @@ func (p *liveParser) inlineStyle() (Style, tokenKind) {
 	if p.lineStyled && style.Prefix != p.lineStyle.Prefix && style.Prefix != p.styles.Text.Prefix {
 		style = combineStyles(p.lineStyle, style)
 	}
+	if p.inline.inStrike {
+		style = combineStyles(style, p.styles.Strikethrough)
+	}
+	if p.inline.htmlLink != "" {
+		style = combineStyles(style, p.styles.LinkText)
+	}
 	return style, kind
 }
 
@@ -1937,8 +2316,18 @@ func (p *liveParser) emitAutoLink(stream Stream, text string) error {
 	case isSchemeAutolink(text):
 		link = text
 	default:
-		return p.emitStyledText(stream, "<"+text+">")
+		raw := "<" + text + ">"
+		if p.html.policy != HTMLAsText {
+			if _, _, _, ok := parseHTMLTag(raw); ok {
+				return p.emitHTMLTag(stream, raw)
+			}
+		}
+		return p.emitStyledText(stream, raw)
 	}
+	return p.emitAutoLinkTokens(stream, text, link)
+}
+
+func (p *liveParser) emitAutoLinkTokens(stream Stream, text string, link string) error {
 	if p.osc8 {
 		if err := stream.WriteToken(StreamToken{Token: Token{Kind: tokenLinkStart, LinkURL: link}}); err != nil {
 			return err
@@ -2027,17 +2416,83 @@ func (p *liveParser) emitInlineBackticks(stream Stream, count int) error {
 	}
 	for i := 0; i < count; i++ {
 		p.inline.codeBuf = append(p.inline.codeBuf, '`')
+		if err := p.speculate(stream, "`", p.styles.CodeInline); err != nil {
+			return err
+		}
 	}
 	return nil
 }
 
+// speculate shows text of an open code span or link ahead of its closing
+// delimiter when the stream supports provisional output.
+func (p *liveParser) speculate(stream Stream, text string, style Style) error {
+	ps, ok := stream.(provisionalStream)
+	if !ok {
+		return nil
+	}
+	return ps.writeProvisional(StreamToken{Token: Token{Text: text, Style: style}})
+}
+
 func (p *liveParser) finalize(stream Stream) {
+	out := stream
+	stream = p.refTarget(stream)
+	if p.table.pending || p.table.active {
+		if len(p.lineBytes) > 0 {
+			line := strings.Clone(bytesToString(p.lineBytes))
+			p.resetLine()
+			_ = p.processTableLine(stream, line)
+		}
+		if p.table.pending {
+			_ = p.releaseTableHeader(stream, false)
+		} else if p.table.active {
+			_ = p.endTable(stream)
+		}
+	}
+	if p.html.inBlock {
+		if len(p.lineBytes) > 0 {
+			line := strings.Clone(bytesToString(p.lineBytes))
+			p.resetLine()
+			_ = p.processHTMLBlockLine(stream, line)
+		}
+		if p.html.inBlock {
+			p.endHTMLBlock(stream)
+		}
+	}
+	if p.footnotes.open != "" {
+		line := strings.Clone(bytesToString(p.lineBytes))
+		p.resetLine()
+		_ = p.processFootnoteLine(stream, line, false)
+		p.footnotes.open = ""
+	}
+	if p.inCodeFence && len(p.lineBuf) > 0 {
+		// The last line of a fence, or its closing fence, without a newline.
+		line := strings.Clone(strings.TrimSuffix(bytesToString(p.lineBytes), "\r"))
+		p.resetLine()
+		_ = p.processCodeFenceLine(stream, line)
+	}
+	if p.setext.pending {
+		line := bytesToString(p.lineBytes)
+		p.resetLine()
+		if level := setextUnderlineLevel(line); level > 0 {
+			header := p.setext.heading()
+			p.setext.reset()
+			_ = p.replayLine(stream, hashStringsWithSpace[level]+header)
+		} else if p.endsHeldTableHeader(line) {
+			_ = p.startHeldTable(stream, line)
+			_ = p.endTable(stream)
+		} else {
+			_ = p.releaseSetextLine(stream, line, false)
+		}
+	}
 	if len(p.lineBuf) > 0 {
 		if p.lineDecided {
 			_ = p.emitInlineRunes(stream, p.lineBuf[p.lineEmitIdx:])
 			_ = p.flushPendingBackticks(stream)
 			_ = p.flushPendingEntity(stream)
 			_ = p.flushPendingNumUS(stream)
+			_ = p.flushPendingTildes(stream)
+			_ = p.flushPendingBareLink(stream)
+			_ = p.flushPendingBang(stream)
 			p.flushPendingDelims()
 			p.lineStyled = false
 		} else {
@@ -2047,12 +2502,17 @@ func (p *liveParser) finalize(stream Stream) {
 					p.resetLine()
 				}
 			} else {
+				p.setext.skip = true
 				_ = p.maybeDecideLine(stream, true)
+				p.setext.skip = false
 				if p.lineDecided && p.lineEmitIdx < len(p.lineBuf) {
 					_ = p.emitInlineRunes(stream, p.lineBuf[p.lineEmitIdx:])
 					_ = p.flushPendingBackticks(stream)
 					_ = p.flushPendingEntity(stream)
 					_ = p.flushPendingNumUS(stream)
+					_ = p.flushPendingTildes(stream)
+					_ = p.flushPendingBareLink(stream)
+					_ = p.flushPendingBang(stream)
 					p.flushPendingDelims()
 					p.lineStyled = false
 				}
@@ -2063,6 +2523,7 @@ func (p *liveParser) finalize(stream Stream) {
 	if p.inCodeFence {
 		p.inCodeFence = false
 		p.fenceMarker = ""
+		p.codeHighlight = false
 		p.pendingCodeNL = false
 		p.exitCodeNoWrap(stream)
 	}
@@ -2070,18 +2531,51 @@ func (p *liveParser) finalize(stream Stream) {
 		p.inIndentCode = false
 		p.exitCodeNoWrap(stream)
 	}
+	p.flushOpenInline(p.refTarget(out))
+	_ = p.flushDeferredRefs()
+	_ = p.emitFootnotes(p.refTarget(out))
+	p.flushOpenInline(p.refTarget(out))
+	_ = p.closeBlocksTo(p.refTarget(out), 0)
+	_ = p.flushDeferredRefs()
+}
+
+// flushOpenInline emits unterminated code spans, links and autolinks as
+// literal text.
+func (p *liveParser) flushOpenInline(stream Stream) {
+	_ = p.flushPendingEscape(stream)
+	_ = p.flushPendingBareLink(stream)
+	_ = p.flushPendingBang(stream)
+	_ = p.flushPendingHTMLTag(stream)
+	_ = p.flushPendingBackticks(stream)
+	if p.inline.inCode {
+		// A code span left open is text, its backticks included.
+		start := len(p.textArena)
+		p.textArena = append(p.textArena, strings.Repeat("`", p.inline.codeFence)...)
+		p.textArena = append(p.textArena, p.inline.codeBuf...)
+		text := bytesToString(p.textArena[start:len(p.textArena)])
+		p.inline.inCode = false
+		p.inline.codeFence = 0
+		p.inline.codeBuf = p.inline.codeBuf[:0]
+		_ = p.emitStyledText(stream, text)
+	}
+	if p.inline.inLink && p.inline.pendingClose {
+		p.inline.pendingClose = false
+		p.releaseLinkText(stream)
+	}
 	if p.inline.inLink {
-		_ = stream.WriteToken(StreamToken{Token: Token{Text: "[", Style: p.styles.Text}})
-		_ = stream.WriteToken(StreamToken{Token: Token{Text: p.bytesTokenText(p.inline.linkText), Style: p.styles.Text}})
-		if p.inline.inLinkURL && len(p.inline.linkURL) > 0 {
+		// The source is written back as it stood.
+		_ = stream.WriteToken(StreamToken{Token: Token{Text: p.literalLinkOpen(), Style: p.styles.Text}})
+		_ = stream.WriteToken(StreamToken{Token: Token{Text: unescapeMarkdown(p.bytesTokenText(p.inline.linkText)), Style: p.styles.Text}})
+		if p.inline.inLinkURL {
 			_ = stream.WriteToken(StreamToken{Token: Token{Text: "](", Style: p.styles.Text}})
 			_ = stream.WriteToken(StreamToken{Token: Token{Text: p.bytesTokenText(p.inline.linkURL), Style: p.styles.Text}})
-			_ = stream.WriteToken(StreamToken{Token: Token{Text: ")", Style: p.styles.Text}})
-		} else {
-			_ = stream.WriteToken(StreamToken{Token: Token{Text: "]", Style: p.styles.Text}})
+		} else if p.inline.inLinkRef {
+			_ = stream.WriteToken(StreamToken{Token: Token{Text: "][", Style: p.styles.Text}})
+			_ = stream.WriteToken(StreamToken{Token: Token{Text: p.bytesTokenText(p.inline.linkURL), Style: p.styles.Text}})
 		}
 		p.inline.inLink = false
 		p.inline.inLinkURL = false
+		p.inline.inLinkRef = false
 		p.inline.linkText = p.inline.linkText[:0]
 		p.inline.linkURL = p.inline.linkURL[:0]
 	}
@@ -2122,6 +2616,160 @@ func (p *liveParser) flushPendingDelims() {
 	p.inline.pendingDelim = 0
 }
 
+// emitIntrawordUnderscores writes a pending underscore run literally, as in
+// snake_case, where it cannot open or close emphasis.
+func (p *liveParser) emitIntrawordUnderscores(stream Stream) error {
+	count := p.inline.pendingCount
+	p.inline.pendingCount = 0
+	p.inline.pendingDelim = 0
+	style, kind := p.inlineStyle()
+	for i := 0; i < count; i++ {
+		if err := stream.WriteToken(StreamToken{Token: Token{Text: "_", Style: style, Kind: kind}}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// emitEscaped writes r, which followed a backslash, as literal text. Link
+// text and reference labels keep the backslash; emitLinkText and the label
+// lookup read it again.
+func (p *liveParser) emitEscaped(stream Stream, r rune) error {
+	if p.inline.inLink && p.inline.pendingClose {
+		p.inline.pendingClose = false
+		p.releaseLinkText(stream)
+	}
+	p.inline.lastWasDigit = false
+	switch {
+	case p.inline.inLinkRef:
+		p.inline.linkURL = append(p.inline.linkURL, '\\')
+		p.inline.linkURL = utf8.AppendRune(p.inline.linkURL, r)
+		return nil
+	case p.inline.inLinkURL:
+		p.inline.linkURL = utf8.AppendRune(p.inline.linkURL, r)
+		return nil
+	case p.inline.inLink:
+		p.inline.linkText = append(p.inline.linkText, '\\')
+		p.inline.linkText = utf8.AppendRune(p.inline.linkText, r)
+		style, _ := p.inlineStyle()
+		return p.speculate(stream, p.runeTokenText(r), combineStyles(style, p.styles.LinkText))
+	}
+	style, kind := p.inlineStyle()
+	return stream.WriteToken(StreamToken{Token: Token{Text: p.runeTokenText(r), Style: style, Kind: kind}})
+}
+
+// takeEscapedBreak reports whether the line ended in a backslash, which
+// outside a link is a hard line break if the paragraph goes on. Inside a
+// link the backslash is kept.
+func (p *liveParser) takeEscapedBreak() bool {
+	if !p.inline.pendingEscape {
+		return false
+	}
+	p.inline.pendingEscape = false
+	if p.inline.inLink {
+		p.inline.linkText = append(p.inline.linkText, '\\')
+		return false
+	}
+	return true
+}
+
+// flushEscapedBreak writes the backslash of a hard break the paragraph
+// ended before.
+func (p *liveParser) flushEscapedBreak(stream Stream) error {
+	if !p.escapedBreak {
+		return nil
+	}
+	p.escapedBreak = false
+	return stream.WriteToken(StreamToken{Token: Token{Text: "\\", Style: p.styles.Text}})
+}
+
+// flushPendingEscape writes a backslash that ends the input literally.
+func (p *liveParser) flushPendingEscape(stream Stream) error {
+	if !p.inline.pendingEscape {
+		return nil
+	}
+	p.inline.pendingEscape = false
+	return p.emitEscaped(stream, '\\')
+}
+
+// releaseLinkText ends "[text]" once no destination follows it: a shortcut
+// reference to a label defined above becomes a link, anything else is
+// written literally.
+func (p *liveParser) releaseLinkText(stream Stream) {
+	open := p.literalLinkOpen()
+	text := p.bytesTokenText(p.inline.linkText)
+	p.inline.inLink = false
+	p.inline.linkText = p.inline.linkText[:0]
+	p.inline.linkURL = p.inline.linkURL[:0]
+	if url, ok := p.refs.defs[normalizeRefLabel(text)]; ok {
+		// A shortcut reference to a label defined above.
+		if open == "![" {
+			_ = p.emitImage(stream, unescapeMarkdown(text), url, text)
+			return
+		}
+		_ = p.emitLinkTo(stream, text, url, text)
+		return
+	}
+	_ = stream.WriteToken(StreamToken{Token: Token{Text: open, Style: p.styles.Text}})
+	_ = stream.WriteToken(StreamToken{Token: Token{Text: unescapeMarkdown(text), Style: p.styles.Text}})
+	_ = stream.WriteToken(StreamToken{Token: Token{Text: "]", Style: p.styles.Text}})
+}
+
+// linkHref returns the destination of a link URL without the title the
+// parser keeps after it.
+func linkHref(url string) string {
+	if i := strings.IndexAny(url, " \t"); i >= 0 {
+		return url[:i]
+	}
+	return url
+}
+
+// isEscapable reports whether a backslash before r makes r literal, which
+// holds for ASCII punctuation.
+func isEscapable(r rune) bool {
+	return r < utf8.RuneSelf && strings.ContainsRune("!\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~", r)
+}
+
+// unescapeMarkdown drops the backslash of each backslash escape in s.
+func unescapeMarkdown(s string) string {
+	if !strings.Contains(s, "\\") {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) && isEscapable(rune(s[i+1])) {
+			i++
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// flushPendingTildes toggles strikethrough for a "~~" run and emits any
+// other run of tildes literally.
+func (p *liveParser) flushPendingTildes(stream Stream) error {
+	count := p.inline.pendingTildes
+	if count == 0 {
+		return nil
+	}
+	p.inline.pendingTildes = 0
+	if count == 2 {
+		p.inline.inStrike = !p.inline.inStrike
+		return nil
+	}
+	style, kind := p.inlineStyle()
+	for i := 0; i < count; i++ {
+		if err := stream.WriteToken(StreamToken{Token: Token{Text: "~", Style: style, Kind: kind}}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (p *liveParser) flushPendingEntity(stream Stream) error {
 	if !p.inline.inEntity || len(p.inline.entity) == 0 {
 		return nil
@@ -2144,13 +2792,27 @@ func (p *liveParser) flushPendingNumUS(stream Stream) error {
 func (p *liveParser) emitLink(stream Stream) error {
 	text := p.bytesTokenText(p.inline.linkText)
 	url := p.bytesTokenText(p.inline.linkURL)
+	image := p.inline.inImage
 	p.inline.inLink = false
 	p.inline.inLinkURL = false
+	p.inline.inImage = false
+	p.inline.linkDepth = 0
 	p.inline.pendingClose = false
 	p.inline.linkText = p.inline.linkText[:0]
 	p.inline.linkURL = p.inline.linkURL[:0]
+	if image {
+		return p.emitImage(stream, unescapeMarkdown(text), url, "")
+	}
+	return p.emitLinkTo(stream, text, url, "")
+}
+
+// emitLinkTo writes a link to url; ref is the label of a reference link.
+func (p *liveParser) emitLinkTo(stream Stream, text string, url string, ref string) error {
+	if alt, src, ok := parseInlineImage(text); ok {
+		return p.emitLinkedImage(stream, unescapeMarkdown(alt), src, url, ref)
+	}
 	if p.osc8 && url != "" {
-		if err := stream.WriteToken(StreamToken{Token: Token{Kind: tokenLinkStart, LinkURL: url}}); err != nil {
+		if err := stream.WriteToken(StreamToken{Token: Token{Kind: tokenLinkStart, LinkURL: url, LinkRef: ref}}); err != nil {
 			return err
 		}
 		if err := p.emitLinkText(stream, text); err != nil {
@@ -2161,18 +2823,23 @@ func (p *liveParser) emitLink(stream Stream) error {
 	if err := p.emitLinkText(stream, text); err != nil {
 		return err
 	}
-	if url != "" {
-		if err := stream.WriteToken(StreamToken{Token: Token{Text: " (", Style: p.styles.Text}}); err != nil {
-			return err
-		}
-		if err := stream.WriteToken(StreamToken{Token: Token{Text: url, Style: p.styles.LinkURL, Kind: tokenURL}}); err != nil {
-			return err
-		}
-		if err := stream.WriteToken(StreamToken{Token: Token{Text: ")", Style: p.styles.Text}}); err != nil {
-			return err
-		}
+	return p.emitLinkURLSuffix(stream, url)
+}
+
+// emitLinkURLSuffix appends " (url)" when links cannot be hyperlinked.
+// A title after the destination is left out.
+func (p *liveParser) emitLinkURLSuffix(stream Stream, url string) error {
+	url = linkHref(url)
+	if url == "" {
+		return nil
 	}
-	return nil
+	if err := stream.WriteToken(StreamToken{Token: Token{Text: " (", Style: p.styles.Text}}); err != nil {
+		return err
+	}
+	if err := stream.WriteToken(StreamToken{Token: Token{Text: url, Style: p.styles.LinkURL, Kind: tokenURL}}); err != nil {
+		return err
+	}
+	return stream.WriteToken(StreamToken{Token: Token{Text: ")", Style: p.styles.Text}})
 }
 
 func (p *liveParser) emitLinkText(stream Stream, text string) error {
@@ -2184,7 +2851,32 @@ func (p *liveParser) emitLinkText(stream Stream, text string) error {
 		baseLine = p.lineStyle
 	}
 	outerEmph := p.emphasisStyle(baseEm, baseStrong)
+	write := func(r rune) error {
+		innerEmph := p.emphasisStyle(state.inEm, state.inStrong)
+		style := combineStyles(combineStyles(combineStyles(baseLine, outerEmph), p.styles.LinkText), innerEmph)
+		return stream.WriteToken(StreamToken{Token: Token{Text: p.runeTokenText(r), Style: style, Kind: tokenText}})
+	}
+	escaped := false
+	var prev rune
 	for _, r := range text {
+		switch {
+		case escaped:
+			escaped = false
+			if isEscapable(r) {
+				if err := write(r); err != nil {
+					return err
+				}
+				prev = r
+				continue
+			}
+			if err := write('\\'); err != nil {
+				return err
+			}
+		case r == '\\':
+			state.flushPending()
+			escaped = true
+			continue
+		}
 		if r == '*' || r == '_' {
 			if state.pendingDelim == r {
 				state.pendingCount++
@@ -2192,15 +2884,31 @@ func (p *liveParser) emitLinkText(stream Stream, text string) error {
 				state.flushPending()
 				state.pendingDelim = r
 				state.pendingCount = 1
+				state.afterWord = isWordRune(prev)
 			}
+			prev = r
 			continue
 		}
 		if state.pendingCount > 0 && r != state.pendingDelim {
-			state.flushPending()
+			if state.pendingDelim == '_' && state.afterWord && isWordRune(r) {
+				// An underscore run inside a word stays literal.
+				for ; state.pendingCount > 0; state.pendingCount-- {
+					if err := write('_'); err != nil {
+						return err
+					}
+				}
+				state.pendingDelim = 0
+			} else {
+				state.flushPending()
+			}
 		}
-		innerEmph := p.emphasisStyle(state.inEm, state.inStrong)
-		style := combineStyles(combineStyles(combineStyles(baseLine, outerEmph), p.styles.LinkText), innerEmph)
-		if err := stream.WriteToken(StreamToken{Token: Token{Text: p.runeTokenText(r), Style: style, Kind: tokenText}}); err != nil {
+		if err := write(r); err != nil {
+			return err
+		}
+		prev = r
+	}
+	if escaped {
+		if err := write('\\'); err != nil {
 			return err
 		}
 	}
@@ -2226,6 +2934,8 @@ type linkInlineState struct {
 	inStrong     bool
 	pendingDelim rune
 	pendingCount int
+	// afterWord is set when the pending run follows a letter or digit.
+	afterWord bool
 }
 
 func (s *linkInlineState) flushPending() {
@@ -2250,7 +2960,7 @@ func combineStyles(base Style, extra Style) Style {
 	if extra.Prefix == "" {
 		return base
 	}
-	return Style{Prefix: base.Prefix + extra.Prefix}
+	return base.combine(extra)
 }
 
 func (p *liveParser) quoteWrapIndent(depth int, listPrefixLen int) string {
@@ -2312,6 +3022,9 @@ func (p *liveParser) applyPendingBreak(stream Stream, mode breakMode) error {
 	}
 	switch mode {
 	case breakDouble:
+		if err := p.flushEscapedBreak(stream); err != nil {
+			return err
+		}
 		p.pendingBreaks = 0
 		p.hardBreakPending = false
 		p.postCodeBreakSingle = false
@@ -2319,12 +3032,18 @@ func (p *liveParser) applyPendingBreak(stream Stream, mode breakMode) error {
 	case breakSingle:
 		p.pendingBreaks = 0
 		p.hardBreakPending = false
+		p.escapedBreak = false
 		p.postCodeBreakSingle = false
 		return stream.WriteToken(StreamToken{Token: Token{Text: "\n", Style: Style{}, Kind: tokenText}})
 	default:
 		p.pendingBreaks = 0
 		p.hardBreakPending = false
 		p.postCodeBreakSingle = false
+		if p.inline.inLink || p.inline.inHTMLTag || p.inline.inAutoLink {
+			// Link text, tags and autolinks are buffered until they close;
+			// the space belongs after what is buffered.
+			return p.emitInline(stream, ' ')
+		}
 		style, kind := p.inlineStyle()
 		return stream.WriteToken(StreamToken{Token: Token{Text: " ", Style: style, Kind: kind}})
 	}
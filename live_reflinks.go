@@ -0,0 +1,373 @@
+package mdf
+
+import "strings"
+
+// maxDeferredRefOps bounds how much output is held back while waiting for a
+// link reference definition that appears after its first use. Once the
+// bound is reached unresolved references are emitted literally.
+const maxDeferredRefOps = 4096
+
+const maxRefLabelLen = 999
+
+type refState struct {
+	defs    map[string]string
+	pending []pendingRef
+	stream  refStream
+	scratch tokenCollector
+}
+
+type pendingRef struct {
+	// label is the normalized label and ref the label as written.
+	label   string
+	ref     string
+	literal string
+	text    []Token
+	image   bool
+	alt     string
+	// footnote marks a "[^label]" reference, resolved against the
+	// footnote definitions instead of the link ones.
+	footnote bool
+}
+
+func (r *refState) reset() {
+	clear(r.defs)
+	r.pending = r.pending[:0]
+	r.stream.reset()
+	r.scratch.tokens = r.scratch.tokens[:0]
+}
+
+type refOpKind uint8
+
+const (
+	refOpToken refOpKind = iota
+	refOpWrapIndent
+	refOpLink
+)
+
+type refOp struct {
+	kind   refOpKind
+	tok    StreamToken
+	indent string
+	ref    int
+}
+
+// refStream is a Stream that buffers output after an unresolved reference
+// link so the link can be emitted once its definition shows up.
+type refStream struct {
+	p      *liveParser
+	out    Stream
+	active bool
+	ops    []refOp
+}
+
+func (s *refStream) reset() {
+	s.out = nil
+	s.active = false
+	s.ops = s.ops[:0]
+}
+
+func (s *refStream) WriteToken(tok StreamToken) error {
+	if !s.active {
+		return s.out.WriteToken(tok)
+	}
+	tok.Text = strings.Clone(tok.Text)
+	tok.LinkURL = strings.Clone(tok.LinkURL)
+	tok.LinkRef = strings.Clone(tok.LinkRef)
+	s.ops = append(s.ops, refOp{kind: refOpToken, tok: tok})
+	if len(s.ops) >= maxDeferredRefOps {
+		return s.p.flushDeferredRefs()
+	}
+	return nil
+}
+
+func (s *refStream) Flush() error {
+	if !s.active {
+		return s.out.Flush()
+	}
+	return nil
+}
+
+func (s *refStream) Width() int {
+	return s.out.Width()
+}
+
+func (s *refStream) SetWidth(width int) {
+	s.out.SetWidth(width)
+}
+
+func (s *refStream) SetWrapIndent(indent string) {
+	if !s.active {
+		s.out.SetWrapIndent(indent)
+		return
+	}
+	s.ops = append(s.ops, refOp{kind: refOpWrapIndent, indent: strings.Clone(indent)})
+}
+
+// refTarget routes writes for the real output stream through the deferral
+// buffer while references are pending. Other streams, such as the table
+// cell collector, are returned unchanged.
+func (p *liveParser) refTarget(stream Stream) Stream {
+	if p.refs.stream.active && stream == p.refs.stream.out {
+		return &p.refs.stream
+	}
+	return stream
+}
+
+func normalizeRefLabel(label string) string {
+	return strings.ToLower(strings.Join(strings.Fields(label), " "))
+}
+
+// defineLinkRef records a definition and releases deferred output once every
+// pending reference can be resolved.
+func (p *liveParser) defineLinkRef(label string, url string) error {
+	key := normalizeRefLabel(label)
+	if key == "" {
+		return nil
+	}
+	if p.refs.defs == nil {
+		p.refs.defs = make(map[string]string)
+	}
+	if _, ok := p.refs.defs[key]; ok {
+		return nil
+	}
+	p.refs.defs[strings.Clone(key)] = strings.Clone(url)
+	return p.releaseResolvedRefs()
+}
+
+// releaseResolvedRefs flushes deferred output once every pending reference
+// has a definition.
+func (p *liveParser) releaseResolvedRefs() error {
+	if !p.refs.stream.active {
+		return nil
+	}
+	for _, ref := range p.refs.pending {
+		if !p.refDefined(ref) {
+			return nil
+		}
+	}
+	return p.flushDeferredRefs()
+}
+
+func (p *liveParser) refDefined(ref pendingRef) bool {
+	if ref.footnote {
+		_, ok := p.footnotes.defs[ref.label]
+		return ok
+	}
+	_, ok := p.refs.defs[ref.label]
+	return ok
+}
+
+// emitRefLink emits a [text][label] or [label][] link, deferring output when
+// the label has not been defined yet.
+func (p *liveParser) emitRefLink(stream Stream) error {
+	image := p.inline.inImage
+	text := p.bytesTokenText(p.inline.linkText)
+	label := p.bytesTokenText(p.inline.linkURL)
+	literal := p.literalLinkOpen() + text + "][" + label + "]"
+	if label == "" {
+		label = text
+	}
+	p.inline.inLink = false
+	p.inline.inLinkRef = false
+	p.inline.pendingClose = false
+	p.inline.linkText = p.inline.linkText[:0]
+	p.inline.linkURL = p.inline.linkURL[:0]
+	key := normalizeRefLabel(label)
+	if url, ok := p.refs.defs[key]; ok {
+		if image {
+			return p.emitImage(stream, unescapeMarkdown(text), url, label)
+		}
+		return p.emitLinkTo(stream, text, url, label)
+	}
+	if key == "" || stream == Stream(&p.table.collector) {
+		return stream.WriteToken(StreamToken{Token: Token{Text: literal, Style: p.styles.Text}})
+	}
+	p.refs.scratch.tokens = p.refs.scratch.tokens[:0]
+	if alt, src, ok := parseInlineImage(text); ok && !image {
+		// A badge: the image alone, linked once the label resolves.
+		if err := p.emitLinkedImage(&p.refs.scratch, unescapeMarkdown(alt), src, "", ""); err != nil {
+			return err
+		}
+	} else if !image {
+		if err := p.emitLinkText(&p.refs.scratch, text); err != nil {
+			return err
+		}
+	}
+	ref := pendingRef{
+		label:   key,
+		ref:     strings.Clone(label),
+		literal: strings.Clone(literal),
+		text:    append([]Token(nil), p.refs.scratch.tokens...),
+		image:   image,
+	}
+	for i := range ref.text {
+		ref.text[i].Text = strings.Clone(ref.text[i].Text)
+		ref.text[i].LinkURL = strings.Clone(ref.text[i].LinkURL)
+	}
+	if image {
+		ref.alt = strings.Clone(text)
+	}
+	p.deferRef(stream, ref)
+	return nil
+}
+
+// deferRef starts buffering output, if it is not already, and queues ref to
+// be resolved when the buffer is flushed.
+func (p *liveParser) deferRef(stream Stream, ref pendingRef) {
+	if !p.refs.stream.active {
+		if stream != Stream(&p.refs.stream) {
+			p.refs.stream.out = stream
+		}
+		p.refs.stream.active = true
+	}
+	p.refs.pending = append(p.refs.pending, ref)
+	p.refs.stream.ops = append(p.refs.stream.ops, refOp{kind: refOpLink, ref: len(p.refs.pending) - 1})
+}
+
+// flushDeferredRefs writes buffered output, resolving each pending reference
+// or falling back to its literal source.
+func (p *liveParser) flushDeferredRefs() error {
+	s := &p.refs.stream
+	if !s.active {
+		return nil
+	}
+	s.active = false
+	var firstErr error
+	for _, op := range s.ops {
+		var err error
+		switch op.kind {
+		case refOpToken:
+			err = s.out.WriteToken(op.tok)
+		case refOpWrapIndent:
+			s.out.SetWrapIndent(op.indent)
+		case refOpLink:
+			ref := p.refs.pending[op.ref]
+			if ref.footnote && p.refDefined(ref) {
+				err = p.writeFootnoteRef(s.out, ref.label)
+			} else if url, ok := p.refs.defs[ref.label]; ok && !ref.footnote {
+				err = p.writeResolvedRef(s.out, ref, url)
+			} else {
+				err = s.out.WriteToken(StreamToken{Token: Token{Text: ref.literal, Style: p.styles.Text}})
+			}
+		}
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	s.ops = s.ops[:0]
+	p.refs.pending = p.refs.pending[:0]
+	return firstErr
+}
+
+func (p *liveParser) writeResolvedRef(stream Stream, ref pendingRef, url string) error {
+	if ref.image {
+		return p.emitImage(stream, ref.alt, url, ref.ref)
+	}
+	if p.osc8 && url != "" {
+		if err := stream.WriteToken(StreamToken{Token: Token{Kind: tokenLinkStart, LinkURL: url, LinkRef: ref.ref}}); err != nil {
+			return err
+		}
+	}
+	for _, tok := range ref.text {
+		if err := stream.WriteToken(StreamToken{Token: tok}); err != nil {
+			return err
+		}
+	}
+	if p.osc8 && url != "" {
+		return stream.WriteToken(StreamToken{Token: Token{Kind: tokenLinkEnd}})
+	}
+	return p.emitLinkURLSuffix(stream, url)
+}
+
+// maybeLinkRefDefinition reports whether a partial line could still become
+// a link reference definition.
+func maybeLinkRefDefinition(line string) bool {
+	if !strings.HasPrefix(line, "[") {
+		return false
+	}
+	end := refLabelEnd(line)
+	if end == -1 {
+		return len(line) <= maxRefLabelLen+2
+	}
+	if end < 0 {
+		return false
+	}
+	return end+1 >= len(line) || line[end+1] == ':'
+}
+
+// parseLinkRefDefinition parses `[label]: url "title"` lines.
+func parseLinkRefDefinition(line string) (string, string, bool) {
+	line = strings.TrimRight(line, " \t\r")
+	if !strings.HasPrefix(line, "[") {
+		return "", "", false
+	}
+	end := refLabelEnd(line)
+	if end < 0 || end+1 >= len(line) || line[end+1] != ':' {
+		return "", "", false
+	}
+	label := line[1:end]
+	if strings.TrimSpace(label) == "" {
+		return "", "", false
+	}
+	rest := strings.TrimLeft(line[end+2:], " \t")
+	if rest == "" {
+		return "", "", false
+	}
+	var url string
+	if rest[0] == '<' {
+		gt := strings.IndexByte(rest, '>')
+		if gt < 0 {
+			return "", "", false
+		}
+		url = rest[1:gt]
+		rest = rest[gt+1:]
+	} else {
+		n := strings.IndexAny(rest, " \t")
+		if n < 0 {
+			n = len(rest)
+		}
+		url = rest[:n]
+		rest = rest[n:]
+	}
+	if rest != "" && rest[0] != ' ' && rest[0] != '\t' {
+		return "", "", false
+	}
+	rest = strings.TrimSpace(rest)
+	if rest != "" && !isLinkRefTitle(rest) {
+		return "", "", false
+	}
+	if rest != "" {
+		// The title is kept after the destination, as in inline links.
+		url += " " + rest
+	}
+	return label, url, true
+}
+
+// refLabelEnd returns the index of the closing bracket of a label, -1 when
+// the label is still open and -2 when it cannot be a label.
+func refLabelEnd(line string) int {
+	for i := 1; i < len(line) && i <= maxRefLabelLen+1; i++ {
+		switch line[i] {
+		case '\\':
+			i++
+		case '[':
+			return -2
+		case ']':
+			return i
+		}
+	}
+	return -1
+}
+
+func isLinkRefTitle(s string) bool {
+	if len(s) < 2 {
+		return false
+	}
+	switch s[0] {
+	case '"', '\'':
+		return s[len(s)-1] == s[0]
+	case '(':
+		return s[len(s)-1] == ')'
+	}
+	return false
+}
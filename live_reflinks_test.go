@@ -0,0 +1,101 @@
+package mdf
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestReferenceLinksResolveBeforeAndAfterUse(t *testing.T) {
+	cases := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{
+			name: "defined before",
+			src:  "[ref]: https://example.com\n\nSee [the site][ref].\n",
+			want: "See the site (https://example.com).\n",
+		},
+		{
+			name: "defined after",
+			src:  "See [the site][Ref] and [ref][].\n\n[ref]: <https://example.com> \"Title\"\n\nAfter.\n",
+			want: "See the site (https://example.com) and ref (https://example.com).\n\nAfter.\n",
+		},
+		{
+			name: "undefined",
+			src:  "See [text][missing] here.\n",
+			want: "See [text][missing] here.\n",
+		},
+	}
+	for _, tc := range cases {
+		out := stripANSI(renderStream(t, []byte(tc.src), 80))
+		if out != tc.want {
+			t.Fatalf("%s: got %q want %q", tc.name, out, tc.want)
+		}
+	}
+}
+
+func TestReferenceDefinitionInIndentedCode(t *testing.T) {
+	src := "para\n\n    [foo]: /url\n    code\n\n[foo]\n"
+	out := stripANSI(renderStream(t, []byte(src), 80))
+	want := "para\n\n[foo]: /url\ncode\n\n[foo]\n"
+	if out != want {
+		t.Fatalf("got %q want %q", out, want)
+	}
+}
+
+func TestReferenceLinksEmitLinkTokens(t *testing.T) {
+	src := "Read [the docs][docs] now.\n\n[docs]: https://example.com/docs\n"
+	stream := &captureStream{}
+	err := Parse(ParseRequest{
+		Reader:  strings.NewReader(src),
+		Stream:  stream,
+		Theme:   DefaultTheme(),
+		Options: []RenderOption{WithOSC8(true)},
+	})
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	var kinds []tokenKind
+	var text strings.Builder
+	for _, tok := range stream.tokens {
+		switch tok.Kind {
+		case tokenLinkStart:
+			if tok.LinkURL != "https://example.com/docs" {
+				t.Fatalf("unexpected link url %q", tok.LinkURL)
+			}
+			kinds = append(kinds, tok.Kind)
+		case tokenLinkEnd:
+			kinds = append(kinds, tok.Kind)
+		default:
+			text.WriteString(tok.Text)
+		}
+	}
+	if len(kinds) != 2 || kinds[0] != tokenLinkStart || kinds[1] != tokenLinkEnd {
+		t.Fatalf("expected link start/end tokens, got %v", kinds)
+	}
+	if strings.Contains(text.String(), "[docs]") {
+		t.Fatalf("definition leaked into output: %q", text.String())
+	}
+}
+
+func TestReferenceLinksDeferralIsBounded(t *testing.T) {
+	var src strings.Builder
+	src.WriteString("Start [text][late].\n\n")
+	for src.Len() < maxDeferredRefOps*2 {
+		src.WriteString("filler paragraph text\n\n")
+	}
+	src.WriteString("[late]: https://example.com\n")
+	out := stripANSI(renderStream(t, []byte(src.String()), 80))
+	if !strings.HasPrefix(out, "Start [text][late].\n") {
+		t.Fatalf("expected literal fallback once the bound is hit, got %q", out[:40])
+	}
+}
+
+func TestReferenceLinksStreamByteByByte(t *testing.T) {
+	src := "A [x][r] b.\n\n| c |\n| - |\n| [y][r] |\n\n[r]: https://example.com\n"
+	want := assertByteByByteMatches(t, src, 40)
+	if !strings.Contains(stripANSI(want), "x (https://example.com)") {
+		t.Fatalf("expected resolved link, got %q", stripANSI(want))
+	}
+}
@@ -0,0 +1,249 @@
+package mdf
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// maxSetextHeadingRunes bounds how much of a paragraph is held back while
+// waiting for a possible setext underline. Longer paragraphs stream
+// immediately.
+const maxSetextHeadingRunes = 200
+
+type setextState struct {
+	holding bool
+	pending bool
+	skip    bool
+	// buf holds the paragraph lines held so far, each ending at its offset
+	// in ends; size counts their runes. next holds the line that ended the
+	// candidate while they are replayed. The buffers are reused.
+	buf  []byte
+	ends []int
+	size int
+	next []byte
+}
+
+func (s *setextState) reset() {
+	s.holding = false
+	s.pending = false
+	s.skip = false
+	s.buf = s.buf[:0]
+	s.ends = s.ends[:0]
+	s.size = 0
+}
+
+// hold adds a copy of a paragraph line to the candidate.
+func (s *setextState) hold(line string) {
+	s.buf = append(s.buf, line...)
+	s.ends = append(s.ends, len(s.buf))
+	s.size += utf8.RuneCountInString(line)
+}
+
+// heading returns the text of the held lines as one heading line.
+func (s *setextState) heading() string {
+	var b strings.Builder
+	start := 0
+	for i, end := range s.ends {
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(strings.TrimSpace(bytesToString(s.buf[start:end])))
+		start = end
+	}
+	return b.String()
+}
+
+// holdSetextCandidate keeps a top-level paragraph buffered, line by line,
+// until a line shows whether it is a setext underline. It reports whether
+// the line was held.
+func (p *liveParser) holdSetextCandidate(depth int, force bool) bool {
+	if p.setext.skip || p.table.skipProbe || p.inParagraph || depth != 0 || len(p.listStack) != 0 || p.showsOpenInline() {
+		return false
+	}
+	if len(p.lineBuf) > maxSetextHeadingRunes {
+		return false
+	}
+	if !force {
+		p.setext.holding = true
+		return true
+	}
+	p.setext.holding = false
+	p.setext.pending = true
+	p.setext.hold(bytesToString(p.lineBytes))
+	p.lineDecided = true
+	p.lineIgnoreRest = true
+	p.lineSkipBreak = true
+	return true
+}
+
+// showsOpenInline reports whether the line opens a code span or link that
+// speculative output shows as it arrives. Holding the line for an underline
+// would hide it, so it is not held, and an underline after it is text.
+func (p *liveParser) showsOpenInline() bool {
+	return p.speculative && strings.ContainsAny(bytesToString(p.lineBytes), "`[")
+}
+
+// processSetextLine resolves a held candidate once the following line is
+// complete: an underline turns the held lines into a heading, another line
+// of the paragraph is held with them while they stay short, and anything
+// else releases them as a paragraph. line may alias the line buffer.
+func (p *liveParser) processSetextLine(stream Stream, line string) error {
+	if level := setextUnderlineLevel(line); level > 0 {
+		header := p.setext.heading()
+		p.setext.reset()
+		return p.replayFullLine(stream, hashStringsWithSpace[level]+header)
+	}
+	if p.endsHeldTableHeader(line) {
+		return p.startHeldTable(stream, line)
+	}
+	if continuesParagraph(line) && p.setext.size+utf8.RuneCountInString(line) <= maxSetextHeadingRunes {
+		p.setext.hold(line)
+		return nil
+	}
+	return p.releaseSetextLine(stream, line, true)
+}
+
+// lastHeld returns the last held line and the offset it starts at.
+func (s *setextState) lastHeld() (string, int) {
+	n := len(s.ends)
+	start := 0
+	if n > 1 {
+		start = s.ends[n-2]
+	}
+	return bytesToString(s.buf[start:s.ends[n-1]]), start
+}
+
+// endsHeldTableHeader reports whether line is the delimiter row of a table
+// whose header row, one that need not start with a pipe, is the last held
+// line.
+func (p *liveParser) endsHeldTableHeader(line string) bool {
+	header, _ := p.setext.lastHeld()
+	if !strings.Contains(header, "|") {
+		return false
+	}
+	_, ok := parseTableDelimiterRow(strings.TrimSpace(line), len(splitTableRow(header)))
+	return ok
+}
+
+// startHeldTable releases the lines held before the header row of a table
+// and goes on with the table from its delimiter row, line, which may alias
+// the line buffer the release reuses.
+func (p *liveParser) startHeldTable(stream Stream, line string) error {
+	p.setext.next = append(p.setext.next[:0], line...)
+	header, start := p.setext.lastHeld()
+	header = strings.Clone(header)
+	p.setext.buf = p.setext.buf[:start]
+	p.setext.ends = p.setext.ends[:len(p.setext.ends)-1]
+	if err := p.releaseSetextHeader(stream); err != nil {
+		return err
+	}
+	indent, _ := leadingIndentCount(header)
+	p.holdTableHeader(0, header, splitTableRow(header), false, indent)
+	return p.processTableLine(stream, bytesToString(p.setext.next))
+}
+
+// releaseSetextLine releases the held lines and replays line, which ended
+// the candidate, after them; full replays its newline too. line may alias
+// the line buffer, which the replay reuses, so it is copied first.
+func (p *liveParser) releaseSetextLine(stream Stream, line string, full bool) error {
+	p.setext.next = append(p.setext.next[:0], line...)
+	if err := p.releaseSetextHeader(stream); err != nil {
+		return err
+	}
+	next := bytesToString(p.setext.next)
+	if full {
+		return p.replayFullLine(stream, next)
+	}
+	return p.replayLine(stream, next)
+}
+
+// releaseSetextHeader replays the held lines as an ordinary paragraph.
+func (p *liveParser) releaseSetextHeader(stream Stream) error {
+	buf, ends := p.setext.buf, p.setext.ends
+	p.setext.reset()
+	p.setext.skip = true
+	defer func() { p.setext.skip = false }()
+	start := 0
+	for _, end := range ends {
+		if err := p.replayFullLine(stream, bytesToString(buf[start:end])); err != nil {
+			return err
+		}
+		start = end
+	}
+	return nil
+}
+
+// continuesParagraph reports whether line is plain text that continues a
+// paragraph. Lines that may start a block, a table row among them, are
+// not, and end the candidate; the parser then decides them as usual.
+func continuesParagraph(line string) bool {
+	if strings.TrimSpace(line) == "" || strings.HasPrefix(strings.TrimLeft(line, " \t"), "|") {
+		return false
+	}
+	if indent, _ := leadingIndentCount(line); indent > 3 {
+		return true
+	}
+	trim := strings.TrimLeft(line, " \t")
+	if _, _, ok := parseHeading(trim); ok || strings.Trim(trim, "#") == "" && len(trim) <= 6 {
+		return false
+	}
+	if _, _, _, _, _, _, ok := parseListMarker(trim); ok {
+		return false
+	}
+	switch {
+	case trim[0] == '>' || trim[0] == '<':
+		return false
+	case fenceMarker(trim) != "" || isThematicBreak(trim):
+		return false
+	case strings.HasPrefix(trim, "[^"):
+		return false
+	}
+	return true
+}
+
+// setextUnderlineLevel returns 1 for a "===" underline, 2 for "---" and 0
+// otherwise.
+func setextUnderlineLevel(line string) int {
+	line = strings.TrimRight(line, " \t\r")
+	if indent, _ := leadingIndentCount(line); indent > 3 {
+		return 0
+	}
+	line = strings.TrimLeft(line, " \t")
+	if line == "" {
+		return 0
+	}
+	marker := line[0]
+	if marker != '=' && marker != '-' {
+		return 0
+	}
+	for i := 1; i < len(line); i++ {
+		if line[i] != marker {
+			return 0
+		}
+	}
+	if marker == '=' {
+		return 1
+	}
+	return 2
+}
+
+// maybeSetextUnderline reports whether a partial line could still become a
+// setext underline.
+func maybeSetextUnderline(line string) bool {
+	if indent, _ := leadingIndentCount(line); indent > 3 {
+		return false
+	}
+	line = strings.TrimLeft(line, " \t")
+	if line == "" {
+		return true
+	}
+	marker := line[0]
+	if marker != '=' && marker != '-' {
+		return false
+	}
+	i := 1
+	for i < len(line) && line[i] == marker {
+		i++
+	}
+	return strings.TrimRight(line[i:], " \t\r") == ""
+}
@@ -0,0 +1,60 @@
+package mdf
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSetextHeadingsMatchATX(t *testing.T) {
+	got := renderStream(t, []byte("Title\n=====\n\nSection\n---\n\nBody text.\n"), 0)
+	want := renderStream(t, []byte("# Title\n\n## Section\n\nBody text.\n"), 0)
+	if got != want {
+		t.Fatalf("setext mismatch\n---want---\n%q\n---got---\n%q", want, got)
+	}
+}
+
+func TestSetextHeadingOverSeveralLines(t *testing.T) {
+	cases := []struct {
+		src  string
+		want string
+	}{
+		{src: "Title over\ntwo lines\n=====\n\nBody.\n", want: "# Title over two lines\n\nBody.\n"},
+		{src: "One\ntwo  \nthree\n---\n", want: "## One two three\n"},
+	}
+	for _, tc := range cases {
+		got := renderStream(t, []byte(tc.src), 0)
+		want := renderStream(t, []byte(tc.want), 0)
+		if got != want {
+			t.Fatalf("render %q:\n got %q\nwant %q", tc.src, got, want)
+		}
+	}
+}
+
+func TestSetextCandidateReleasedAsParagraph(t *testing.T) {
+	cases := []struct {
+		src  string
+		want string
+	}{
+		{src: "First line\nsecond line\n", want: "First line second line\n"},
+		{src: "Para\n-- x\n", want: "Para -- x\n"},
+		{src: "Para\n- item\n", want: "Para\n\n- item\n"},
+		{src: "Para\n=== x\n", want: "Para === x\n"},
+		{src: "Para", want: "Para\n"},
+		{src: "One\ntwo\nthree\n\nfour\n", want: "One two three\n\nfour\n"},
+		{src: "One\ntwo\n- item\n", want: "One two\n\n- item\n"},
+	}
+	for _, tc := range cases {
+		out := stripANSI(renderStream(t, []byte(tc.src), 80))
+		if out != tc.want {
+			t.Fatalf("render %q: got %q want %q", tc.src, out, tc.want)
+		}
+	}
+}
+
+func TestSetextHeadingStreamsByteByByte(t *testing.T) {
+	src := "Intro line\nmore intro\n\nHeading\nover two\n=======\ntext after\n"
+	want := assertByteByByteMatches(t, src, 40)
+	if !strings.Contains(stripANSI(want), "# Heading over two\n") {
+		t.Fatalf("expected setext heading, got %q", stripANSI(want))
+	}
+}
@@ -0,0 +1,607 @@
+package mdf
+
+import (
+	"strings"
+
+	"github.com/muesli/reflow/ansi"
+)
+
+// maxTableBufferedRows bounds how many body rows are held back to measure
+// column widths. Rows beyond the limit stream out with the widths fixed.
+const maxTableBufferedRows = 64
+
+type tableState struct {
+	pending   bool
+	active    bool
+	fixed     bool
+	skipProbe bool
+	// plain is set when the columns do not fit the width even one rune
+	// wide; rows are then written without borders and wrap as text.
+	plain       bool
+	depth       int
+	prevDepth   int
+	inList      bool
+	indent      int
+	listPrefix  int
+	lines       int
+	header      string
+	headerCells []string
+	aligns      []CellAlign
+	rows        [][]string
+	widths      []int
+	collector   tokenCollector
+}
+
+func (t *tableState) reset() {
+	t.pending = false
+	t.active = false
+	t.fixed = false
+	t.skipProbe = false
+	t.plain = false
+	t.depth = 0
+	t.prevDepth = 0
+	t.inList = false
+	t.indent = 0
+	t.listPrefix = 0
+	t.lines = 0
+	t.header = ""
+	t.headerCells = nil
+	t.aligns = t.aligns[:0]
+	t.rows = t.rows[:0]
+	t.widths = t.widths[:0]
+	t.collector.tokens = t.collector.tokens[:0]
+}
+
+// tokenCollector is a Stream that records tokens so inline content can be
+// measured before it is laid out.
+type tokenCollector struct {
+	tokens []Token
+}
+
+func (c *tokenCollector) WriteToken(tok StreamToken) error {
+	c.tokens = append(c.tokens, tok.Token)
+	return nil
+}
+
+func (c *tokenCollector) Flush() error {
+	return nil
+}
+
+func (c *tokenCollector) Width() int {
+	return 0
+}
+
+func (c *tokenCollector) SetWidth(int) {}
+
+func (c *tokenCollector) SetWrapIndent(string) {}
+
+type tableGlyph struct {
+	text  string
+	style Style
+	kind  tokenKind
+	link  string
+	width int
+}
+
+// beginTableHeader holds back a candidate header row until the next line
+// shows whether it is a delimiter row. Only rows that start with a pipe are
+// held here so ordinary paragraphs keep streaming without a line of latency;
+// a header row without one is found among the paragraph lines held for a
+// setext underline.
+func (p *liveParser) beginTableHeader(depth int, rest string, inList bool, indent int) bool {
+	cells := splitTableRow(rest)
+	if len(cells) == 0 {
+		return false
+	}
+	p.holdTableHeader(depth, strings.Clone(bytesToString(p.lineBytes)), cells, inList, indent)
+	p.lineDecided = true
+	p.lineIgnoreRest = true
+	p.lineSkipBreak = true
+	return true
+}
+
+// holdTableHeader makes header, split into cells, the pending header row.
+func (p *liveParser) holdTableHeader(depth int, header string, cells []string, inList bool, indent int) {
+	p.table.pending = true
+	p.table.depth = depth
+	p.table.prevDepth = p.prevQuoteDepth
+	p.table.inList = inList
+	p.table.indent = indent
+	p.table.header = header
+	p.table.headerCells = cells
+}
+
+func (p *liveParser) processTableLine(stream Stream, line string) error {
+	line = strings.TrimSuffix(line, "\r")
+	depth, rest, explicit := parseQuotePrefix(line)
+	if !explicit {
+		depth = 0
+	}
+	trimmed := strings.TrimSpace(rest)
+	if p.table.pending {
+		if depth == p.table.depth {
+			if aligns, ok := parseTableDelimiterRow(trimmed, len(p.table.headerCells)); ok {
+				p.table.pending = false
+				p.table.active = true
+				p.table.aligns = append(p.table.aligns[:0], aligns...)
+				return nil
+			}
+		}
+		if err := p.releaseTableHeader(stream, true); err != nil {
+			return err
+		}
+		return p.replayFullLine(stream, line)
+	}
+	if depth != p.table.depth || trimmed == "" || !strings.Contains(trimmed, "|") {
+		if err := p.endTable(stream); err != nil {
+			return err
+		}
+		return p.replayFullLine(stream, line)
+	}
+	cells := splitTableRow(trimmed)
+	if p.table.fixed {
+		return p.emitTableRow(stream, cells, false)
+	}
+	p.table.rows = append(p.table.rows, cells)
+	if len(p.table.rows) >= maxTableBufferedRows {
+		return p.commitTable(stream)
+	}
+	return nil
+}
+
+// releaseTableHeader replays a held header row as ordinary text once it is
+// clear that no delimiter row follows.
+func (p *liveParser) releaseTableHeader(stream Stream, newline bool) error {
+	header := p.table.header
+	p.quoteDepth = p.table.prevDepth
+	p.table.reset()
+	p.table.skipProbe = true
+	var err error
+	if newline {
+		err = p.replayFullLine(stream, header)
+	} else {
+		err = p.replayLine(stream, header)
+	}
+	p.table.skipProbe = false
+	return err
+}
+
+func (p *liveParser) replayFullLine(stream Stream, line string) error {
+	if err := p.replayLine(stream, line); err != nil {
+		return err
+	}
+	return p.feedRune(stream, '\n')
+}
+
+func (p *liveParser) commitTable(stream Stream) error {
+	if p.table.fixed {
+		return nil
+	}
+	if !p.table.inList {
+		p.listLazy = false
+		p.listItemFirstLine = false
+		p.clearListIfOutdented(p.table.indent)
+	}
+	p.table.listPrefix = p.listPrefixLen
+	p.layoutTable(stream)
+	p.table.fixed = true
+	p.inParagraph = false
+	p.resetInline()
+	p.planBlocks(p.table.depth, tokenTableStart, BlockInfo{}, true)
+	if err := p.applyBlockBreak(stream, breakDouble); err != nil {
+		return err
+	}
+	if err := p.emitTableRule(stream, "┌", "┬", "┐"); err != nil {
+		return err
+	}
+	if err := p.emitTableRow(stream, p.table.headerCells, true); err != nil {
+		return err
+	}
+	if err := p.emitTableRule(stream, "├", "┼", "┤"); err != nil {
+		return err
+	}
+	for _, row := range p.table.rows {
+		if err := p.emitTableRow(stream, row, false); err != nil {
+			return err
+		}
+	}
+	p.table.rows = p.table.rows[:0]
+	return nil
+}
+
+func (p *liveParser) endTable(stream Stream) error {
+	if err := p.commitTable(stream); err != nil {
+		return err
+	}
+	err := p.emitTableRule(stream, "└", "┴", "┘")
+	p.table.reset()
+	p.pendingBreaks++
+	return err
+}
+
+func (p *liveParser) layoutTable(stream Stream) {
+	n := len(p.table.aligns)
+	widths := p.table.widths[:0]
+	for i := 0; i < n; i++ {
+		widths = append(widths, 1)
+	}
+	measure := func(cells []string, header bool) {
+		for i := 0; i < n && i < len(cells); i++ {
+			if w := tableGlyphsWidth(p.tableCellGlyphs(cells[i], header)); w > widths[i] {
+				widths[i] = w
+			}
+		}
+	}
+	measure(p.table.headerCells, true)
+	for _, row := range p.table.rows {
+		measure(row, false)
+	}
+	if stream.Width() > 0 {
+		avail := stream.Width() - p.table.depth*2 - p.table.listPrefix - (3*n + 1)
+		if avail < n {
+			p.table.plain = true
+			p.table.widths = widths
+			return
+		}
+		total := 0
+		for _, w := range widths {
+			total += w
+		}
+		for total > avail {
+			widest := 0
+			for i := 1; i < n; i++ {
+				if widths[i] > widths[widest] {
+					widest = i
+				}
+			}
+			widths[widest]--
+			total--
+		}
+	}
+	p.table.widths = widths
+}
+
+func (p *liveParser) emitTableRule(stream Stream, left, mid, right string) error {
+	if p.table.plain {
+		return nil
+	}
+	if err := p.beginTableLine(stream); err != nil {
+		return err
+	}
+	var b strings.Builder
+	b.WriteString(left)
+	for i, w := range p.table.widths {
+		if i > 0 {
+			b.WriteString(mid)
+		}
+		b.WriteString(strings.Repeat("─", w+2))
+	}
+	b.WriteString(right)
+	return stream.WriteToken(StreamToken{Token: Token{Text: b.String(), Style: p.styles.ThematicBreak}})
+}
+
+func (p *liveParser) emitTableRow(stream Stream, cells []string, header bool) error {
+	if p.table.plain {
+		return p.emitPlainTableRow(stream, cells, header)
+	}
+	n := len(p.table.widths)
+	wrapped := make([][][]tableGlyph, n)
+	height := 1
+	for i := 0; i < n; i++ {
+		cell := ""
+		if i < len(cells) {
+			cell = cells[i]
+		}
+		wrapped[i] = wrapTableGlyphs(p.tableCellGlyphs(cell, header), p.table.widths[i])
+		if len(wrapped[i]) > height {
+			height = len(wrapped[i])
+		}
+	}
+	for line := 0; line < height; line++ {
+		if err := p.beginTableLine(stream); err != nil {
+			return err
+		}
+		if line == 0 {
+			if err := stream.WriteToken(StreamToken{Token: Token{Kind: tokenTableRowStart, Block: BlockInfo{Header: header}}}); err != nil {
+				return err
+			}
+		}
+		if err := stream.WriteToken(StreamToken{Token: Token{Text: "│", Style: p.styles.ThematicBreak}}); err != nil {
+			return err
+		}
+		for i := 0; i < n; i++ {
+			var glyphs []tableGlyph
+			if line < len(wrapped[i]) {
+				glyphs = wrapped[i][line]
+			}
+			pad := p.table.widths[i] - tableGlyphsWidth(glyphs)
+			if pad < 0 {
+				pad = 0
+			}
+			left := 0
+			switch p.table.aligns[i] {
+			case AlignCenter:
+				left = pad / 2
+			case AlignRight:
+				left = pad
+			}
+			if err := stream.WriteToken(StreamToken{Token: Token{Text: p.spaces(left + 1), Style: p.styles.Text}}); err != nil {
+				return err
+			}
+			cell := BlockInfo{Header: header, Column: i, Align: p.table.aligns[i]}
+			if err := stream.WriteToken(StreamToken{Token: Token{Kind: tokenTableCellStart, Block: cell}}); err != nil {
+				return err
+			}
+			if err := p.emitTableGlyphs(stream, glyphs); err != nil {
+				return err
+			}
+			if err := stream.WriteToken(StreamToken{Token: Token{Kind: tokenTableCellEnd, Block: cell}}); err != nil {
+				return err
+			}
+			if err := stream.WriteToken(StreamToken{Token: Token{Text: p.spaces(pad - left + 1), Style: p.styles.Text}}); err != nil {
+				return err
+			}
+			if err := stream.WriteToken(StreamToken{Token: Token{Text: "│", Style: p.styles.ThematicBreak}}); err != nil {
+				return err
+			}
+		}
+	}
+	return stream.WriteToken(StreamToken{Token: Token{Kind: tokenTableRowEnd, Block: BlockInfo{Header: header}}})
+}
+
+// emitPlainTableRow writes a row on one line, its cells apart by a bar, for
+// the stream to wrap like text.
+func (p *liveParser) emitPlainTableRow(stream Stream, cells []string, header bool) error {
+	if err := p.beginTableLine(stream); err != nil {
+		return err
+	}
+	if err := stream.WriteToken(StreamToken{Token: Token{Kind: tokenTableRowStart, Block: BlockInfo{Header: header}}}); err != nil {
+		return err
+	}
+	for i := range p.table.widths {
+		if i > 0 {
+			for _, text := range [...]string{" ", "│", " "} {
+				style := p.styles.Text
+				if text == "│" {
+					style = p.styles.ThematicBreak
+				}
+				if err := stream.WriteToken(StreamToken{Token: Token{Text: text, Style: style}}); err != nil {
+					return err
+				}
+			}
+		}
+		cell := ""
+		if i < len(cells) {
+			cell = cells[i]
+		}
+		info := BlockInfo{Header: header, Column: i, Align: p.table.aligns[i]}
+		if err := stream.WriteToken(StreamToken{Token: Token{Kind: tokenTableCellStart, Block: info}}); err != nil {
+			return err
+		}
+		if err := p.emitTableGlyphs(stream, wrapTableGlyphs(p.tableCellGlyphs(cell, header), 0)[0]); err != nil {
+			return err
+		}
+		if err := stream.WriteToken(StreamToken{Token: Token{Kind: tokenTableCellEnd, Block: info}}); err != nil {
+			return err
+		}
+	}
+	return stream.WriteToken(StreamToken{Token: Token{Kind: tokenTableRowEnd, Block: BlockInfo{Header: header}}})
+}
+
+func (p *liveParser) beginTableLine(stream Stream) error {
+	if p.table.lines > 0 {
+		if err := stream.WriteToken(StreamToken{Token: Token{Text: "\n", Style: Style{}, Kind: tokenText}}); err != nil {
+			return err
+		}
+	}
+	p.table.lines++
+	return p.emitPrefix(stream, p.table.depth, p.table.listPrefix)
+}
+
+func (p *liveParser) emitTableGlyphs(stream Stream, glyphs []tableGlyph) error {
+	link := ""
+	for _, g := range glyphs {
+		if g.link != link {
+			if link != "" {
+				if err := stream.WriteToken(StreamToken{Token: Token{Kind: tokenLinkEnd}}); err != nil {
+					return err
+				}
+			}
+			if g.link != "" {
+				if err := stream.WriteToken(StreamToken{Token: Token{Kind: tokenLinkStart, LinkURL: g.link}}); err != nil {
+					return err
+				}
+			}
+			link = g.link
+		}
+		if err := stream.WriteToken(StreamToken{Token: Token{Text: g.text, Style: g.style, Kind: g.kind}}); err != nil {
+			return err
+		}
+	}
+	if link != "" {
+		return stream.WriteToken(StreamToken{Token: Token{Kind: tokenLinkEnd}})
+	}
+	return nil
+}
+
+// tableCellGlyphs runs a cell through the inline scanner and returns its
+// styled runes so the cell can be measured and wrapped.
+func (p *liveParser) tableCellGlyphs(cell string, header bool) []tableGlyph {
+	c := &p.table.collector
+	c.tokens = c.tokens[:0]
+	savedDecided := p.lineDecided
+	savedEmitIdx := p.lineEmitIdx
+	savedStyle := p.lineStyle
+	savedStyled := p.lineStyled
+	p.resetInline()
+	p.immediateSpaces = p.immediateSpaces[:0]
+	p.lineDecided = true
+	p.lineStyled = header
+	p.lineStyle = p.styles.Strong
+	_ = p.emitInlineRunes(c, []rune(cell))
+	_ = p.flushPendingBackticks(c)
+	_ = p.flushPendingEntity(c)
+	_ = p.flushPendingNumUS(c)
+	_ = p.flushPendingTildes(c)
+	_ = p.flushPendingBareLink(c)
+	_ = p.flushPendingBang(c)
+	_ = p.flushPendingHTMLTag(c)
+	p.flushPendingDelims()
+	p.flushOpenInline(c)
+	p.immediateSpaces = p.immediateSpaces[:0]
+	p.resetInline()
+	p.lineDecided = savedDecided
+	p.lineEmitIdx = savedEmitIdx
+	p.lineStyle = savedStyle
+	p.lineStyled = savedStyled
+
+	var glyphs []tableGlyph
+	link := ""
+	for _, tok := range c.tokens {
+		switch tok.Kind {
+		case tokenLinkStart:
+			link = tok.LinkURL
+			continue
+		case tokenLinkEnd:
+			link = ""
+			continue
+		}
+		for _, r := range tok.Text {
+			text := p.runeTokenText(r)
+			if r == ' ' {
+				text = " "
+			}
+			glyphs = append(glyphs, tableGlyph{
+				text:  text,
+				style: tok.Style,
+				kind:  tok.Kind,
+				link:  link,
+				width: ansi.PrintableRuneWidth(text),
+			})
+		}
+	}
+	return glyphs
+}
+
+func tableGlyphsWidth(glyphs []tableGlyph) int {
+	width := 0
+	for _, g := range glyphs {
+		width += g.width
+	}
+	return width
+}
+
+// wrapTableGlyphs collapses whitespace and greedily wraps a cell to width.
+// Words wider than the column are split. A width of zero disables wrapping.
+func wrapTableGlyphs(glyphs []tableGlyph, width int) [][]tableGlyph {
+	var lines [][]tableGlyph
+	var line []tableGlyph
+	lineWidth := 0
+	var space *tableGlyph
+	for i := 0; i < len(glyphs); {
+		if glyphs[i].text == " " || glyphs[i].text == "\t" {
+			if space == nil {
+				space = &glyphs[i]
+			}
+			i++
+			continue
+		}
+		end := i
+		wordWidth := 0
+		for end < len(glyphs) && glyphs[end].text != " " && glyphs[end].text != "\t" {
+			wordWidth += glyphs[end].width
+			end++
+		}
+		word := glyphs[i:end]
+		i = end
+		if len(line) > 0 {
+			if width <= 0 || lineWidth+1+wordWidth <= width {
+				sp := *space
+				sp.text = " "
+				sp.width = 1
+				line = append(line, sp)
+				line = append(line, word...)
+				lineWidth += 1 + wordWidth
+				space = nil
+				continue
+			}
+			lines = append(lines, line)
+			line = nil
+			lineWidth = 0
+		}
+		space = nil
+		for width > 0 && wordWidth > width {
+			split := 0
+			splitWidth := 0
+			for split < len(word) && (split == 0 || splitWidth+word[split].width <= width) {
+				splitWidth += word[split].width
+				split++
+			}
+			lines = append(lines, word[:split])
+			word = word[split:]
+			wordWidth -= splitWidth
+		}
+		line = append(line, word...)
+		lineWidth = wordWidth
+	}
+	if len(line) > 0 || len(lines) == 0 {
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// splitTableRow splits a pipe table row into trimmed cells, honouring
+// backslash-escaped pipes.
+func splitTableRow(row string) []string {
+	row = strings.TrimSpace(row)
+	if strings.HasPrefix(row, "|") {
+		row = row[1:]
+	}
+	if strings.HasSuffix(row, "|") && !strings.HasSuffix(row, "\\|") {
+		row = row[:len(row)-1]
+	}
+	var cells []string
+	var b strings.Builder
+	for i := 0; i < len(row); i++ {
+		switch {
+		case row[i] == '\\' && i+1 < len(row) && row[i+1] == '|':
+			b.WriteByte('|')
+			i++
+		case row[i] == '|':
+			cells = append(cells, strings.TrimSpace(b.String()))
+			b.Reset()
+		default:
+			b.WriteByte(row[i])
+		}
+	}
+	cells = append(cells, strings.TrimSpace(b.String()))
+	return cells
+}
+
+func parseTableDelimiterRow(row string, columns int) ([]CellAlign, bool) {
+	if !strings.Contains(row, "-") {
+		return nil, false
+	}
+	cells := splitTableRow(row)
+	if len(cells) != columns {
+		return nil, false
+	}
+	aligns := make([]CellAlign, len(cells))
+	for i, cell := range cells {
+		left := strings.HasPrefix(cell, ":")
+		right := strings.HasSuffix(cell, ":")
+		dashes := strings.TrimSuffix(strings.TrimPrefix(cell, ":"), ":")
+		if dashes == "" || strings.Trim(dashes, "-") != "" {
+			return nil, false
+		}
+		switch {
+		case left && right:
+			aligns[i] = AlignCenter
+		case right:
+			aligns[i] = AlignRight
+		case left:
+			aligns[i] = AlignLeft
+		}
+	}
+	return aligns, true
+}
@@ -0,0 +1,89 @@
+package mdf
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/muesli/reflow/ansi"
+)
+
+func TestTableAlignmentAndBorders(t *testing.T) {
+	src := strings.Join([]string{
+		"| Left | Center | Right |",
+		"| :--- | :----: | ----: |",
+		"| a | b | c |",
+	}, "\n")
+	out := stripANSI(renderStream(t, []byte(src), 0))
+	want := strings.Join([]string{
+		"┌──────┬────────┬───────┐",
+		"│ Left │ Center │ Right │",
+		"├──────┼────────┼───────┤",
+		"│ a    │   b    │     c │",
+		"└──────┴────────┴───────┘",
+	}, "\n") + "\n"
+	if out != want {
+		t.Fatalf("table mismatch\n---want---\n%s---got---\n%s", want, out)
+	}
+}
+
+func TestTableWrapsCellsWithinWidth(t *testing.T) {
+	src := strings.Join([]string{
+		"| Col A | Col B |",
+		"| --- | --- |",
+		"| Longer cell that should wrap at narrower widths | B3 |",
+	}, "\n")
+	width := 30
+	out := stripANSI(renderStream(t, []byte(src), width))
+	for _, line := range strings.Split(strings.TrimSuffix(out, "\n"), "\n") {
+		if w := ansi.PrintableRuneWidth(line); w > width {
+			t.Fatalf("line exceeds width %d (%d): %q", width, w, line)
+		}
+		if !strings.HasPrefix(line, "│") && !strings.HasPrefix(line, "┌") && !strings.HasPrefix(line, "├") && !strings.HasPrefix(line, "└") {
+			t.Fatalf("unexpected table line %q", line)
+		}
+	}
+	if !strings.Contains(out, "│ Longer cell that   │ B3    │") {
+		t.Fatalf("missing wrapped first row line:\n%s", out)
+	}
+}
+
+func TestTableHeaderWithoutDelimiterIsParagraph(t *testing.T) {
+	src := "| not | a table |\nplain line\n"
+	out := stripANSI(renderStream(t, []byte(src), 0))
+	if out != "| not | a table | plain line\n" {
+		t.Fatalf("unexpected output %q", out)
+	}
+}
+
+func TestTableStreamsByteByByte(t *testing.T) {
+	assertByteByByteMatches(t, "| a | b |\n|---|---|\n| 1 | 2 |\n\nafter\n", 40)
+}
+
+func TestTableTooNarrowForBorders(t *testing.T) {
+	src := "| a | b | c | d | e | f |\n|---|---|---|---|---|---|\n| 1 | 2 | 3 | 4 | 5 | 6 |\n\nafter\n"
+	out := stripANSI(renderStream(t, []byte(src), 10))
+	want := "a │ b │ c\n│ d │ e │\nf\n1 │ 2 │ 3\n│ 4 │ 5 │\n6\n\nafter\n"
+	if out != want {
+		t.Fatalf("got %q want %q", out, want)
+	}
+}
+
+func TestTableWithoutLeadingPipe(t *testing.T) {
+	cases := []struct {
+		src  string
+		want string
+	}{
+		{src: "a | b\n--|--\n1 | 2\n", want: "┌───┬───┐\n│ a │ b │\n├───┼───┤\n│ 1 │ 2 │\n└───┴───┘\n"},
+		{src: "Intro\nline.\na | b\n:-|-:\n1 | 2\n", want: "Intro line.\n\n┌───┬───┐\n│ a │ b │\n├───┼───┤\n│ 1 │ 2 │\n└───┴───┘\n"},
+		{src: "a | b\n--|--", want: "┌───┬───┐\n│ a │ b │\n├───┼───┤\n└───┴───┘\n"},
+		{src: "a | b\nnot a row\n", want: "a | b not a row\n"},
+		{src: "a | b\n--|--|--\n", want: "a | b --|--|--\n"},
+	}
+	for _, tc := range cases {
+		out := stripANSI(renderStream(t, []byte(tc.src), 0))
+		if out != tc.want {
+			t.Fatalf("render %q:\n got %q\nwant %q", tc.src, out, tc.want)
+		}
+	}
+	assertByteByByteMatches(t, "Intro\na | b\n--|--\n1 | 2\n\nafter\n", 40)
+}
@@ -0,0 +1,59 @@
+package man
+
+import "pkt.systems/mdf"
+
+// Config holds man page settings. The header fields are used where the
+// front matter of the document does not set them.
+type Config struct {
+	// Title is the page name, such as "mdf".
+	Title string
+	// Section is the manual section, such as "1".
+	Section string
+	Date    string
+	// Source is the project or version the page belongs to, shown at the
+	// bottom left; Manual is the manual's title, shown at the top centre.
+	Source     string
+	Manual     string
+	HTMLPolicy mdf.HTMLPolicy
+}
+
+// DefaultConfig returns a baseline configuration.
+func DefaultConfig() Config {
+	return Config{Section: "1"}
+}
+
+func applyConfig(dst *Config, src Config) {
+	if src.Title != "" {
+		dst.Title = src.Title
+	}
+	if src.Section != "" {
+		dst.Section = src.Section
+	}
+	if src.Date != "" {
+		dst.Date = src.Date
+	}
+	if src.Source != "" {
+		dst.Source = src.Source
+	}
+	if src.Manual != "" {
+		dst.Manual = src.Manual
+	}
+	if src.HTMLPolicy != mdf.HTMLAsText {
+		dst.HTMLPolicy = src.HTMLPolicy
+	}
+}
+
+// applyFrontMatter sets the header fields the front matter names.
+func applyFrontMatter(dst *Config, fields map[string]string) {
+	for key, field := range map[string]*string{
+		"title":   &dst.Title,
+		"section": &dst.Section,
+		"date":    &dst.Date,
+		"source":  &dst.Source,
+		"manual":  &dst.Manual,
+	} {
+		if v := fields[key]; v != "" {
+			*field = v
+		}
+	}
+}
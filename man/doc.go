@@ -0,0 +1,33 @@
+// Package man renders Markdown to man(7) roff using the mdf streaming
+// parser, so command documentation written in Markdown can be installed
+// as a man page.
+//
+// Headings of level 1 become .SH sections and level 2 .SS subsections;
+// deeper ones are bold paragraphs. Code blocks are written between .EX and
+// .EE, list items as tagged .IP paragraphs, block quotes and nested lists
+// inside .RS and .RE, and links as .UR/.UE (.MT/.ME for mailto links). A
+// bullet item that starts with code or bold text followed by a colon, the
+// usual way options are listed, becomes a .TP paragraph with that text as
+// its tag. Tables are drawn as the terminal renderer draws them, in no-fill
+// mode.
+//
+// The .TH header takes its title, section, date, source and manual from the
+// document's front matter, falling back to the Config:
+//
+//	---
+//	title: mdf
+//	section: 1
+//	date: 2024-05-01
+//	---
+//
+// Example:
+//
+//	err := man.Render(man.RenderRequest{
+//		Reader: f,
+//		Writer: out,
+//		Config: man.Config{Title: "mdf", Source: "mdf 1.2"},
+//	})
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+package man
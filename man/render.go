@@ -0,0 +1,89 @@
+package man
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"pkt.systems/mdf"
+	"pkt.systems/mdf/internal/role"
+)
+
+// RenderRequest contains inputs for man page rendering.
+type RenderRequest struct {
+	Reader io.Reader
+	Writer io.Writer
+	Config Config
+}
+
+// Render writes Markdown as a man(7) page. The .TH header takes its fields
+// from the front matter, falling back to Config.
+func Render(req RenderRequest) error {
+	return RenderContext(context.Background(), req)
+}
+
+// RenderContext is Render with cancellation. The input is read in full
+// first; once ctx is done it returns ctx.Err(), leaving the output
+// unfinished.
+func RenderContext(ctx context.Context, req RenderRequest) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if req.Reader == nil {
+		return fmt.Errorf("man render: reader is nil")
+	}
+	if req.Writer == nil {
+		return fmt.Errorf("man render: writer is nil")
+	}
+	src, err := io.ReadAll(req.Reader)
+	if err != nil {
+		return fmt.Errorf("man render: %w", err)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	front, body := mdf.SplitFrontMatter(src)
+	cfg := DefaultConfig()
+	applyConfig(&cfg, req.Config)
+	applyFrontMatter(&cfg, mdf.FrontMatterFields(front))
+	if _, err := io.WriteString(req.Writer, header(cfg)); err != nil {
+		return fmt.Errorf("man render: %w", err)
+	}
+	if err := mdf.ParseContext(ctx, mdf.ParseRequest{
+		Reader:  bytes.NewReader(body),
+		Stream:  NewStream(req.Writer),
+		Theme:   role.Theme(),
+		Options: []mdf.RenderOption{mdf.WithOSC8(true), mdf.WithHTMLPolicy(cfg.HTMLPolicy)},
+	}); err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil && err == ctxErr {
+			return err
+		}
+		return fmt.Errorf("man render: %w", err)
+	}
+	return nil
+}
+
+// ParseTheme returns the theme a Stream expects the parser to run with.
+// Pass it as the Theme of an mdf.ParseRequest that feeds a Stream, with
+// mdf.WithOSC8(true) among its Options.
+func ParseTheme() mdf.Theme {
+	return role.Theme()
+}
+
+// header returns the .TH line of a page. The title is upper case, as is
+// usual for man pages.
+func header(cfg Config) string {
+	args := []string{strings.ToUpper(cfg.Title), cfg.Section, cfg.Date, cfg.Source, cfg.Manual}
+	for len(args) > 2 && args[len(args)-1] == "" {
+		args = args[:len(args)-1]
+	}
+	var b strings.Builder
+	b.WriteString(".TH")
+	for _, arg := range args {
+		b.WriteString(` "` + strings.NewReplacer(`\`, `\e`, `"`, `\(dq`).Replace(arg) + `"`)
+	}
+	b.WriteByte('\n')
+	return b.String()
+}
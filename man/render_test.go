@@ -0,0 +1,153 @@
+package man
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+func renderString(t *testing.T, src string, cfg Config) string {
+	t.Helper()
+	var out bytes.Buffer
+	if err := Render(RenderRequest{Reader: strings.NewReader(src), Writer: &out, Config: cfg}); err != nil {
+		t.Fatalf("render: %v", err)
+	}
+	return out.String()
+}
+
+func TestRenderHeader(t *testing.T) {
+	src := "---\ntitle: mdf\ndate: 2026-01-02\nmanual: \"User \\\"Commands\\\"\"\n---\n# Name\n"
+	got := renderString(t, src, Config{Title: "other", Section: "7", Source: "mdf 1.0"})
+	want := ".TH \"MDF\" \"7\" \"2026-01-02\" \"mdf 1.0\" \"User \\(dqCommands\\(dq\"\n.SH\nNAME\n"
+	if got != want {
+		t.Fatalf("got %q\nwant %q", got, want)
+	}
+	if got := renderString(t, "", Config{Title: "x"}); got != ".TH \"X\" \"1\"\n" {
+		t.Fatalf("empty input gave %q", got)
+	}
+}
+
+func TestRenderBlocks(t *testing.T) {
+	src := strings.Join([]string{
+		"# Synopsis",
+		"",
+		"Run *mdf* with **flags**.",
+		"",
+		"## Files",
+		"",
+		"```",
+		".hidden",
+		"",
+		"```",
+		"",
+		"> quoted",
+		"",
+		"---",
+		"",
+	}, "\n")
+	got := renderString(t, src, Config{})
+	want := strings.Join([]string{
+		`.TH "" "1"`,
+		".SH",
+		"SYNOPSIS",
+		".PP",
+		`Run \f[I]mdf\f[R] with \f[B]flags\f[R].`,
+		".SS",
+		"Files",
+		".PP",
+		".RS 4",
+		".EX",
+		`\&.hidden`,
+		".EE",
+		".RE",
+		".RS",
+		".PP",
+		"quoted",
+		".RE",
+		".PP",
+		".ce",
+		"* * *",
+		"",
+	}, "\n")
+	if got != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestRenderLists(t *testing.T) {
+	src := strings.Join([]string{
+		"- `-w`, `--write`: write in place",
+		"- plain",
+		"  - nested",
+		"",
+		"9. nine",
+		"10. ten",
+		"",
+		"- [x] done",
+		"",
+	}, "\n")
+	got := renderString(t, src, Config{})
+	want := strings.Join([]string{
+		`.TH "" "1"`,
+		".TP",
+		`\f[CR]\-w\f[R], \f[CR]\-\-write\f[R]`,
+		"write in place",
+		`.IP \(bu 2`,
+		"plain",
+		".RS",
+		`.IP \(bu 2`,
+		"nested",
+		".RE",
+		".IP 9. 4",
+		"nine",
+		".IP 10. 4",
+		"ten",
+		".IP [x] 4",
+		"done",
+		"",
+	}, "\n")
+	if got != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestRenderLinksAndEscapes(t *testing.T) {
+	src := "See [the docs](https://x.y \"T\"), <https://a.b> or <me@x.y>.\n" +
+		"A flag-name, C:\\dir and a note[^n].  \n'quoted\n\n[^n]: The note.\n"
+	got := renderString(t, src, Config{})
+	want := strings.Join([]string{
+		`.TH "" "1"`,
+		".PP",
+		"See",
+		".UR https://x.y",
+		"the docs",
+		`.UE \&,`,
+		".UR https://a.b",
+		".UE",
+		"or",
+		".MT me@x.y",
+		`.ME \&.`,
+		`A flag\-name, C:\edir and a note[1].`,
+		".br",
+		`\&'quoted`,
+		".SS",
+		"Footnotes",
+		".IP 1. 4",
+		"The note.",
+		"",
+	}, "\n")
+	if got != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestRenderContextCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	var out bytes.Buffer
+	err := RenderContext(ctx, RenderRequest{Reader: strings.NewReader("hi\n"), Writer: &out})
+	if err != context.Canceled {
+		t.Fatalf("err = %v, want context.Canceled", err)
+	}
+}
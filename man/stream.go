@@ -0,0 +1,707 @@
+package man
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"pkt.systems/mdf"
+	"pkt.systems/mdf/internal/role"
+)
+
+// Stream is an mdf.Stream that writes man(7) roff. Each block is written
+// when it ends. The parser must run with ParseTheme and with OSC 8 links
+// on; otherwise fonts and link destinations are lost.
+//
+// Stream does not write the .TH header; Render adds it.
+type Stream struct {
+	w   io.Writer
+	buf []byte
+	err error
+
+	containers []container
+	lists      []list
+
+	leaf  leafKind
+	level int
+	// lineStart is set at the start of each line of a block, where the
+	// parser draws quote bars, list markers and indentation.
+	lineStart bool
+	// task is set until the parser's "[x]" of a task item has been dropped.
+	task mdf.TaskState
+	// segs holds the content of the open paragraph or heading.
+	segs []segment
+	link linkKind
+	// linkAt is the index in segs of the open link's start.
+	linkAt int
+	code   codeState
+	table  tableState
+}
+
+type leafKind uint8
+
+const (
+	leafNone leafKind = iota
+	leafParagraph
+	leafHeading
+	leafCode
+)
+
+type linkKind uint8
+
+const (
+	linkNone linkKind = iota
+	// linkURL is written with .UR or .MT.
+	linkURL
+	// linkPlain is an internal link, of which only the text is written.
+	linkPlain
+	// linkSkip drops the text of footnote references and back links.
+	linkSkip
+)
+
+// container is a block quote or a list item.
+type container struct {
+	quote bool
+	// tag and width are the arguments of the item's .IP; tagged is set once
+	// it has been written.
+	tag    string
+	width  int
+	tagged bool
+	// option is set for the items of a bullet list, which may become .TP
+	// paragraphs.
+	option bool
+}
+
+type list struct {
+	ordered bool
+	next    int
+	// nested is set for a list inside an item, which is inset with .RS.
+	nested bool
+}
+
+type codeState struct {
+	// inset is set when the block is inset with .RS.
+	inset bool
+	line  strings.Builder
+	// blank counts the empty lines held until more code follows.
+	blank int
+}
+
+type tableState struct {
+	open      bool
+	lineStart bool
+	// closing is set once the bottom rule has been drawn.
+	closing bool
+	font    string
+}
+
+type segKind uint8
+
+const (
+	segText segKind = iota
+	segBreak
+	segLinkStart
+	segLinkEnd
+)
+
+// segment is a run of paragraph content in one font, a hard line break, or
+// the start (with its URL) or end of a link.
+type segment struct {
+	kind segKind
+	font string
+	text string
+}
+
+// NewStream returns a Stream writing to w.
+func NewStream(w io.Writer) *Stream {
+	return &Stream{w: w}
+}
+
+// Width reports 0: the formatter fills lines, and tables are not shrunk.
+func (s *Stream) Width() int { return 0 }
+
+// SetWidth is a no-op.
+func (s *Stream) SetWidth(int) {}
+
+// SetWrapIndent is a no-op.
+func (s *Stream) SetWrapIndent(string) {}
+
+// WriteToken adds tok, writing the blocks it completes.
+func (s *Stream) WriteToken(tok mdf.StreamToken) error {
+	if s.err != nil {
+		return s.err
+	}
+	s.buf = s.buf[:0]
+	s.token(tok)
+	return s.write()
+}
+
+// Flush writes the blocks left open.
+func (s *Stream) Flush() error {
+	if s.err != nil {
+		return s.err
+	}
+	s.buf = s.buf[:0]
+	s.endLeaf()
+	s.endTable()
+	for len(s.containers) > 0 {
+		s.popContainer()
+	}
+	s.lists = s.lists[:0]
+	return s.write()
+}
+
+func (s *Stream) write() error {
+	if len(s.buf) == 0 {
+		return nil
+	}
+	if _, err := s.w.Write(s.buf); err != nil {
+		s.err = fmt.Errorf("man: %w", err)
+	}
+	return s.err
+}
+
+func (s *Stream) token(tok mdf.StreamToken) {
+	set := role.Of(tok.Style)
+	if s.table.open && s.tableToken(tok, set) {
+		return
+	}
+	if mdf.IsBlockEvent(tok.Kind) {
+		s.block(tok)
+		return
+	}
+	switch s.leaf {
+	case leafCode:
+		s.codeToken(tok)
+	case leafParagraph, leafHeading:
+		s.inlineToken(tok, set)
+	default:
+		switch {
+		case tok.Kind == mdf.TokenThematicBreak:
+			s.startBlock()
+			s.line(".ce")
+			s.line("* * *")
+		case tok.Kind == mdf.TokenText && set.Has(role.ThematicBreak) && strings.HasPrefix(tok.Text, "┌"):
+			s.startBlock()
+			s.line(".nf")
+			s.table = tableState{open: true, font: "R"}
+			s.tableToken(tok, set)
+		}
+	}
+}
+
+func (s *Stream) block(tok mdf.StreamToken) {
+	info := tok.Block
+	switch tok.Kind {
+	case mdf.TokenHeadingStart:
+		s.startLeaf(leafHeading)
+		s.level = info.Level
+	case mdf.TokenParagraphStart:
+		s.startLeaf(leafParagraph)
+	case mdf.TokenCodeBlockStart:
+		s.startLeaf(leafCode)
+		if s.inItem() {
+			s.startBlock()
+		} else {
+			s.line(".PP")
+			s.line(".RS 4")
+			s.code.inset = true
+		}
+		s.line(".EX")
+	case mdf.TokenHeadingEnd, mdf.TokenParagraphEnd, mdf.TokenCodeBlockEnd:
+		s.endLeaf()
+	case mdf.TokenListStart:
+		s.endLeaf()
+		l := list{ordered: info.Ordered, next: info.Start}
+		if s.inItem() {
+			s.tagItem()
+			s.line(".RS")
+			l.nested = true
+		}
+		s.lists = append(s.lists, l)
+	case mdf.TokenListEnd:
+		s.endLeaf()
+		if n := len(s.lists); n > 0 {
+			if s.lists[n-1].nested {
+				s.line(".RE")
+			}
+			s.lists = s.lists[:n-1]
+		}
+	case mdf.TokenListItemStart:
+		s.endLeaf()
+		c := container{tag: `\(bu`, width: 2}
+		if n := len(s.lists); n > 0 {
+			l := &s.lists[n-1]
+			if l.ordered {
+				c.tag = strconv.Itoa(l.next) + "."
+				c.width = max(len(c.tag)+1, 4)
+				l.next++
+			} else {
+				c.option = info.Task == mdf.TaskNone
+			}
+		}
+		switch info.Task {
+		case mdf.TaskUnchecked:
+			c.tag, c.width = "[ ]", 4
+		case mdf.TaskChecked:
+			c.tag, c.width = "[x]", 4
+		}
+		s.containers = append(s.containers, c)
+		s.task = info.Task
+	case mdf.TokenBlockquoteStart:
+		s.endLeaf()
+		s.tagItem()
+		s.line(".RS")
+		s.containers = append(s.containers, container{quote: true})
+	case mdf.TokenListItemEnd, mdf.TokenBlockquoteEnd:
+		s.endLeaf()
+		s.popContainer()
+	}
+}
+
+func (s *Stream) startLeaf(kind leafKind) {
+	s.endLeaf()
+	s.leaf = kind
+	s.lineStart = true
+}
+
+func (s *Stream) endLeaf() {
+	if s.leaf == leafNone {
+		return
+	}
+	switch s.leaf {
+	case leafParagraph:
+		if len(s.segs) == 0 {
+			break
+		}
+		if c := s.item(); c != nil && c.option && !c.tagged {
+			if term, desc, ok := splitOption(s.segs); ok {
+				c.tagged = true
+				s.line(".TP")
+				s.writeInline(term, false, true)
+				s.writeInline(desc, false, false)
+				break
+			}
+		}
+		s.startBlock()
+		s.writeInline(s.segs, false, false)
+	case leafHeading:
+		switch s.level {
+		case 1:
+			s.line(".SH")
+			s.writeInline(s.segs, true, true)
+		case 2:
+			s.line(".SS")
+			s.writeInline(s.segs, false, true)
+		default:
+			for i := range s.segs {
+				s.segs[i].font = boldFont(s.segs[i].font)
+			}
+			s.startBlock()
+			s.writeInline(s.segs, false, false)
+		}
+	case leafCode:
+		if s.code.line.Len() > 0 {
+			s.codeLine()
+		}
+		s.line(".EE")
+		if s.code.inset {
+			s.line(".RE")
+		}
+		s.code = codeState{}
+	}
+	s.leaf = leafNone
+	s.task = mdf.TaskNone
+	s.segs = s.segs[:0]
+	s.link = linkNone
+}
+
+// startBlock writes the macro that starts a paragraph-like block: the
+// tagged .IP of a list item's first block, a plain .IP for its later
+// blocks, and .PP elsewhere.
+func (s *Stream) startBlock() {
+	c := s.item()
+	switch {
+	case c == nil:
+		s.line(".PP")
+	case !c.tagged:
+		s.tagItem()
+	default:
+		s.line(".IP")
+	}
+}
+
+// tagItem writes the .IP of the innermost list item if nothing of the item
+// has been written yet.
+func (s *Stream) tagItem() {
+	if c := s.item(); c != nil && !c.tagged {
+		c.tagged = true
+		s.line(".IP " + c.tag + " " + strconv.Itoa(c.width))
+	}
+}
+
+// item returns the innermost container if it is a list item.
+func (s *Stream) item() *container {
+	if n := len(s.containers); n > 0 && !s.containers[n-1].quote {
+		return &s.containers[n-1]
+	}
+	return nil
+}
+
+func (s *Stream) inItem() bool {
+	return s.item() != nil
+}
+
+func (s *Stream) popContainer() {
+	n := len(s.containers)
+	if n == 0 {
+		return
+	}
+	if s.containers[n-1].quote {
+		s.line(".RE")
+	} else {
+		// An empty item still shows its tag.
+		s.tagItem()
+	}
+	s.containers = s.containers[:n-1]
+}
+
+// inlineToken adds a token of a paragraph or heading.
+func (s *Stream) inlineToken(tok mdf.StreamToken, set role.Set) {
+	switch tok.Kind {
+	case mdf.TokenAnchor:
+		return
+	case mdf.TokenLinkStart:
+		s.startLink(tok.LinkURL)
+		return
+	case mdf.TokenLinkEnd:
+		s.endLink()
+		return
+	}
+	if tok.Kind == mdf.TokenText && strings.Trim(tok.Text, "\n") == "" {
+		s.segs = append(s.segs, segment{kind: segBreak})
+		s.lineStart = true
+		return
+	}
+	if s.lineStart && tok.Kind == mdf.TokenText {
+		if decoration(tok.Text, set) {
+			return
+		}
+		if s.task != mdf.TaskNone {
+			if strings.Contains(tok.Text, "]") {
+				s.task = mdf.TaskNone
+			}
+			return
+		}
+	}
+	s.lineStart = false
+	if s.link == linkSkip {
+		return
+	}
+	text := tok.Text
+	if tok.Kind == mdf.TokenImage {
+		text = "[image: " + tok.Text + "]"
+	}
+	s.addText(text, fontOf(set, tok.Kind == mdf.TokenCode))
+}
+
+func (s *Stream) addText(text, font string) {
+	if n := len(s.segs); n > 0 && s.segs[n-1].kind == segText && s.segs[n-1].font == font {
+		s.segs[n-1].text += text
+		return
+	}
+	s.segs = append(s.segs, segment{font: font, text: text})
+}
+
+func (s *Stream) startLink(url string) {
+	switch {
+	case strings.HasPrefix(url, "#fnref-"):
+		s.link = linkSkip
+	case strings.HasPrefix(url, "#fn-"):
+		s.addText("["+strings.TrimPrefix(url, "#fn-")+"]", "R")
+		s.link = linkSkip
+	case strings.HasPrefix(url, "#"):
+		s.link = linkPlain
+	default:
+		if i := strings.IndexAny(url, " \t"); i >= 0 {
+			url = url[:i]
+		}
+		s.link = linkURL
+		s.linkAt = len(s.segs)
+		s.segs = append(s.segs, segment{kind: segLinkStart, text: url})
+	}
+}
+
+func (s *Stream) endLink() {
+	if s.link == linkURL {
+		url := s.segs[s.linkAt].text
+		var text strings.Builder
+		for _, seg := range s.segs[s.linkAt+1:] {
+			text.WriteString(seg.text)
+		}
+		if t := text.String(); t == url || "mailto:"+t == url {
+			// An autolink: the macro shows the address itself.
+			s.segs = s.segs[:s.linkAt+1]
+		}
+		s.segs = append(s.segs, segment{kind: segLinkEnd})
+	}
+	s.link = linkNone
+}
+
+// decoration reports whether text is part of what the parser draws at the
+// start of a line: quote bars, list markers, indentation and heading marks.
+func decoration(text string, set role.Set) bool {
+	if set.Has(role.Quote) || set.Has(role.ListMarker) || strings.TrimSpace(text) == "" {
+		return true
+	}
+	return set.Has(role.Heading) && strings.Trim(text, "# ") == "" && strings.HasSuffix(text, " ")
+}
+
+// codeToken adds a token of a code block; the decoration the parser draws
+// before each line is dropped.
+func (s *Stream) codeToken(tok mdf.StreamToken) {
+	switch {
+	case tok.Kind == mdf.TokenCode && tok.CodeBlock:
+		s.code.line.WriteString(tok.Text)
+	case tok.Kind == mdf.TokenText && strings.Trim(tok.Text, "\n") == "":
+		for range strings.Count(tok.Text, "\n") {
+			s.codeLine()
+		}
+	}
+}
+
+// codeLine writes the current line of code. Empty lines are held so that
+// the block does not end in them.
+func (s *Stream) codeLine() {
+	c := &s.code
+	if c.line.Len() == 0 {
+		c.blank++
+		return
+	}
+	for ; c.blank > 0; c.blank-- {
+		s.buf = append(s.buf, '\n')
+	}
+	s.line(escapeLine(c.line.String()))
+	c.line.Reset()
+}
+
+// tableToken writes a token of a table the parser draws as box text. It
+// reports false for the token after the table, which ends it.
+func (s *Stream) tableToken(tok mdf.StreamToken, set role.Set) bool {
+	t := &s.table
+	newline := tok.Kind == mdf.TokenText && tok.Text != "" && strings.Trim(tok.Text, "\n") == ""
+	if t.closing {
+		s.endTable()
+		return newline
+	}
+	switch {
+	case newline:
+		s.buf = append(s.buf, '\n')
+		t.lineStart = true
+		return true
+	case t.lineStart && !set.Has(role.ThematicBreak) && decoration(tok.Text, set):
+		return true
+	case tok.Kind == mdf.TokenLinkStart, tok.Kind == mdf.TokenLinkEnd, tok.Kind == mdf.TokenAnchor:
+		return true
+	}
+	text := tok.Text
+	if tok.Kind == mdf.TokenImage {
+		text = "[image: " + text + "]"
+	}
+	if font := fontOf(set, tok.Kind == mdf.TokenCode); font != t.font && strings.TrimSpace(text) != "" {
+		s.buf = append(s.buf, `\f[`+font+`]`...)
+		t.font = font
+	}
+	if t.lineStart && (strings.HasPrefix(text, ".") || strings.HasPrefix(text, "'")) {
+		s.buf = append(s.buf, `\&`...)
+	}
+	s.buf = append(s.buf, escape(text)...)
+	t.lineStart = false
+	if set.Has(role.ThematicBreak) && strings.HasPrefix(text, "└") {
+		t.closing = true
+	}
+	return true
+}
+
+func (s *Stream) endTable() {
+	t := &s.table
+	if !t.open {
+		return
+	}
+	if t.font != "R" {
+		s.buf = append(s.buf, `\f[R]`...)
+	}
+	if !t.lineStart {
+		s.buf = append(s.buf, '\n')
+	}
+	s.line(".fi")
+	*t = tableState{}
+}
+
+// writeInline writes paragraph content as text lines, starting a line for
+// each link macro and hard break. A flat line, as a heading needs, leaves
+// links and breaks out.
+func (s *Stream) writeInline(segs []segment, upper, flat bool) {
+	var line strings.Builder
+	font := "R"
+	// Each line ends in the regular font, so that macro lines need none.
+	flush := func() {
+		if font != "R" {
+			line.WriteString(`\f[R]`)
+			font = "R"
+		}
+		text := strings.TrimSpace(line.String())
+		line.Reset()
+		if text == "" || text == `\f[R]` {
+			return
+		}
+		if text[0] == '.' || text[0] == '\'' {
+			text = `\&` + text
+		}
+		s.line(text)
+	}
+	for i := 0; i < len(segs); i++ {
+		seg := segs[i]
+		switch seg.kind {
+		case segText:
+			if seg.font != font {
+				line.WriteString(`\f[` + seg.font + `]`)
+				font = seg.font
+			}
+			text := seg.text
+			if upper {
+				text = strings.ToUpper(text)
+			}
+			line.WriteString(escape(text))
+		case segBreak:
+			if flat {
+				line.WriteByte(' ')
+				continue
+			}
+			flush()
+			s.line(".br")
+		case segLinkStart:
+			if flat {
+				continue
+			}
+			flush()
+			if addr, ok := strings.CutPrefix(seg.text, "mailto:"); ok {
+				s.line(".MT " + escapeArg(addr))
+			} else {
+				s.line(".UR " + escapeArg(seg.text))
+			}
+		case segLinkEnd:
+			if flat {
+				continue
+			}
+			start := segs[:i]
+			macro := ".UE"
+			for j := len(start) - 1; j >= 0; j-- {
+				if start[j].kind == segLinkStart {
+					if strings.HasPrefix(start[j].text, "mailto:") {
+						macro = ".ME"
+					}
+					break
+				}
+			}
+			flush()
+			// Punctuation right after the link is the macro's argument,
+			// so that no space is put before it.
+			if i+1 < len(segs) && segs[i+1].kind == segText {
+				next := &segs[i+1]
+				n := strings.IndexAny(next.text, " \t")
+				if n < 0 {
+					n = len(next.text)
+				}
+				if n > 0 {
+					macro += ` \&` + escape(next.text[:n])
+					next.text = next.text[n:]
+				}
+			}
+			s.line(macro)
+		}
+	}
+	flush()
+}
+
+// splitOption splits the first paragraph of an item listing an option,
+// such as "`-w`, `--write`: write in place", into its term and its
+// description. The term must be code or bold text.
+func splitOption(segs []segment) (term, desc []segment, ok bool) {
+	marked := false
+	for i, seg := range segs {
+		if seg.kind != segText {
+			return nil, nil, false
+		}
+		if seg.font == "CR" || seg.font == "B" {
+			marked = true
+			continue
+		}
+		j := strings.IndexByte(seg.text, ':')
+		if j < 0 || !marked || strings.Trim(seg.text[:j], " ,/|") != "" {
+			if strings.Trim(seg.text, " ,/|") == "" && j < 0 {
+				continue
+			}
+			return nil, nil, false
+		}
+		desc = append([]segment{{font: seg.font, text: seg.text[j+1:]}}, segs[i+1:]...)
+		return segs[:i], desc, true
+	}
+	return nil, nil, false
+}
+
+func (s *Stream) line(text string) {
+	s.buf = append(s.buf, text...)
+	s.buf = append(s.buf, '\n')
+}
+
+// fontOf returns the font of a token: R, I, B, BI or, for code, CR.
+func fontOf(set role.Set, code bool) string {
+	strong := set.Has(role.Strong) || set.Has(role.EmphasisStrong)
+	em := set.Has(role.Emphasis) || set.Has(role.EmphasisStrong)
+	switch {
+	case code:
+		return "CR"
+	case strong && em:
+		return "BI"
+	case strong:
+		return "B"
+	case em:
+		return "I"
+	}
+	return "R"
+}
+
+func boldFont(font string) string {
+	switch font {
+	case "R":
+		return "B"
+	case "I":
+		return "BI"
+	}
+	return font
+}
+
+var escaper = strings.NewReplacer(`\`, `\e`, "-", `\-`, "\u00a0", `\~`)
+
+// escape escapes text for roff: backslashes, hyphen-minus signs, which
+// would otherwise print as hyphens, and no-break spaces.
+func escape(text string) string {
+	return escaper.Replace(text)
+}
+
+// escapeLine escapes a line of no-fill text so that it cannot be read as a
+// request.
+func escapeLine(text string) string {
+	text = escape(text)
+	if strings.HasPrefix(text, ".") || strings.HasPrefix(text, "'") {
+		text = `\&` + text
+	}
+	return text
+}
+
+// escapeArg escapes a macro argument, which ends at a space.
+func escapeArg(text string) string {
+	return strings.NewReplacer(`\`, `\e`, " ", `\ `, `"`, `\(dq`).Replace(text)
+}
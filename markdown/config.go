@@ -0,0 +1,24 @@
+package markdown
+
+// Config holds Markdown formatting settings.
+type Config struct {
+	// Width is the column paragraphs are wrapped at, container prefixes
+	// included.
+	Width int
+	// NoWrap keeps each paragraph on one line, apart from its hard breaks.
+	NoWrap bool
+}
+
+// DefaultConfig returns a baseline configuration.
+func DefaultConfig() Config {
+	return Config{Width: 80}
+}
+
+func applyConfig(dst *Config, src Config) {
+	if src.Width > 0 {
+		dst.Width = src.Width
+	}
+	if src.NoWrap {
+		dst.NoWrap = src.NoWrap
+	}
+}
@@ -0,0 +1,29 @@
+// Package markdown formats Markdown using the mdf streaming parser.
+//
+// The output is the same document written in one canonical style:
+// paragraphs are wrapped at the configured width, setext headings become
+// ATX headings, bullets become "-", ordered lists are numbered from their
+// start with ".", nested content is indented to its list marker, tables are
+// padded pipe tables, and emphasis uses "*", "**" and "~~". Text that would
+// read as markup is backslash-escaped and hard breaks end in two spaces.
+// Reference links keep their labels and link reference definitions stay
+// where they were, consecutive ones grouped together. Code blocks are
+// written byte for byte inside backtick fences, raw HTML blocks and tags
+// are written as they came, and front matter is kept as it is. Footnotes are numbered in order of reference and their
+// definitions move to the end, as the parser resolves them.
+//
+// Example:
+//
+//	err := markdown.Render(markdown.RenderRequest{
+//		Reader: strings.NewReader("Title\n=====\n\n* one\n* two\n"),
+//		Writer: os.Stdout,
+//		Config: markdown.Config{Width: 72},
+//	})
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//
+// Formatting is idempotent: formatting the output again gives the same
+// bytes. Stream is the underlying mdf.Stream, for callers driving mdf.Parse
+// themselves with ParseTheme.
+package markdown
@@ -0,0 +1,185 @@
+package markdown
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"pkt.systems/mdf"
+	"pkt.systems/mdf/internal/role"
+)
+
+// Equivalent reports whether a and b parse to the same document: the same
+// blocks, text, styles and link targets, however they are laid out. It
+// tells whether formatting a document kept all of it.
+func Equivalent(a, b []byte) (bool, error) {
+	frontA, bodyA := mdf.SplitFrontMatter(a)
+	frontB, bodyB := mdf.SplitFrontMatter(b)
+	if !bytes.Equal(bytes.TrimSpace(frontA), bytes.TrimSpace(frontB)) {
+		return false, nil
+	}
+	tokA, err := documentTokens(bodyA)
+	if err != nil {
+		return false, err
+	}
+	tokB, err := documentTokens(bodyB)
+	if err != nil {
+		return false, err
+	}
+	if len(tokA) != len(tokB) {
+		return false, nil
+	}
+	for i := range tokA {
+		if tokA[i] != tokB[i] {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// docToken is a token with what layout changes taken out: the style is
+// reduced to its roles and whitespace to one space or line break.
+type docToken struct {
+	tok   mdf.Token
+	roles role.Set
+}
+
+func documentTokens(src []byte) ([]docToken, error) {
+	var c collector
+	if err := mdf.Parse(mdf.ParseRequest{
+		Reader:  bytes.NewReader(src),
+		Stream:  &c,
+		Theme:   role.Theme(),
+		Options: parseOptions(),
+	}); err != nil {
+		return nil, fmt.Errorf("markdown equivalent: %w", err)
+	}
+	c.trim()
+	return c.tokens, nil
+}
+
+// collector is an mdf.Stream that keeps the tokens that make up a
+// document. Decoration is dropped, runs of text of one style are joined
+// and the lines of a code block are joined into one token.
+type collector struct {
+	tokens []docToken
+	code   bool
+}
+
+func (c *collector) Width() int           { return 0 }
+func (c *collector) SetWidth(int)         {}
+func (c *collector) SetWrapIndent(string) {}
+func (c *collector) Flush() error         { return nil }
+func (c *collector) WriteToken(tok mdf.StreamToken) error {
+	t := tok.Token
+	t.Style = mdf.Style{}
+	set := role.Of(tok.Style)
+	switch {
+	case mdf.IsBlockEvent(t.Kind):
+		c.trim()
+		switch t.Kind {
+		case mdf.TokenCodeBlockStart:
+			c.code = true
+		case mdf.TokenCodeBlockEnd:
+			c.code = false
+		}
+		c.add(docToken{tok: t})
+		return nil
+	case c.code:
+		// Highlighting styles and the indentation drawn before each line
+		// depend on the layout; only the code and its line breaks count.
+		if t.Kind == mdf.TokenText {
+			t.Text = strings.Repeat("\n", strings.Count(t.Text, "\n"))
+		}
+		c.join(docToken{tok: mdf.Token{Kind: mdf.TokenCode, CodeBlock: true, Text: t.Text}})
+		return nil
+	case t.Kind != mdf.TokenText:
+		t.Text = strings.Clone(t.Text)
+		t.LinkURL = strings.Clone(t.LinkURL)
+		t.LinkRef = strings.Clone(t.LinkRef)
+		c.add(docToken{tok: t, roles: set})
+		return nil
+	case set.Has(role.Quote) || set.Has(role.ListMarker):
+		return nil
+	}
+	if strings.TrimSpace(t.Text) == "" {
+		// The style of the space between two words depends on where the
+		// delimiters around them were put.
+		if n := len(c.tokens); n > 0 && c.tokens[n-1].tok.Kind == mdf.TokenText {
+			set = c.tokens[n-1].roles
+		}
+	}
+	c.join(docToken{tok: mdf.Token{Text: t.Text}, roles: set})
+	return nil
+}
+
+func (c *collector) add(t docToken) {
+	c.tokens = append(c.tokens, t)
+}
+
+// join adds t to the last token when both are text of the same style or
+// both are block code.
+func (c *collector) join(t docToken) {
+	if n := len(c.tokens); n > 0 {
+		last := &c.tokens[n-1]
+		if last.tok.Kind == t.tok.Kind && last.roles == t.roles && last.tok.CodeBlock == t.tok.CodeBlock &&
+			(t.tok.Kind == mdf.TokenText || t.tok.CodeBlock) {
+			last.tok.Text += strings.Clone(t.tok.Text)
+			return
+		}
+	}
+	t.tok.Text = strings.Clone(t.tok.Text)
+	c.add(t)
+}
+
+// trim normalizes the whitespace of the text since the last block event,
+// dropping it at either end of the block.
+func (c *collector) trim() {
+	start := len(c.tokens)
+	for start > 0 && !mdf.IsBlockEvent(c.tokens[start-1].tok.Kind) {
+		start--
+	}
+	out := c.tokens[:start]
+	for i, t := range c.tokens[start:] {
+		switch {
+		case t.tok.CodeBlock:
+			t.tok.Text = strings.TrimRight(t.tok.Text, "\n")
+		case t.tok.Kind == mdf.TokenText:
+			t.tok.Text = collapseSpace(t.tok.Text)
+			if i == 0 {
+				t.tok.Text = strings.TrimLeft(t.tok.Text, " \n")
+			}
+			if start+i == len(c.tokens)-1 {
+				t.tok.Text = strings.TrimRight(t.tok.Text, " \n")
+			}
+			if t.tok.Text == "" {
+				continue
+			}
+		}
+		out = append(out, t)
+	}
+	c.tokens = out
+}
+
+// collapseSpace turns each run of whitespace into a line break when it
+// holds one and into a space otherwise.
+func collapseSpace(s string) string {
+	var b strings.Builder
+	space := ""
+	for _, r := range s {
+		switch r {
+		case '\n':
+			space = "\n"
+		case ' ', '\t':
+			if space == "" {
+				space = " "
+			}
+		default:
+			b.WriteString(space)
+			space = ""
+			b.WriteRune(r)
+		}
+	}
+	b.WriteString(space)
+	return b.String()
+}
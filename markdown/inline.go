@@ -0,0 +1,581 @@
+package markdown
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/muesli/reflow/ansi"
+	"pkt.systems/mdf"
+	"pkt.systems/mdf/internal/role"
+)
+
+// hardBreak is the word that stands for a hard line break.
+const hardBreak = "\n"
+
+// escMark is put before a character of text that may be read as markup.
+// escapeWord turns it into a backslash where the character needs one and
+// drops it elsewhere.
+const escMark = '\x00'
+
+// markupChars are the characters of text that get an escMark.
+const markupChars = "\\*_`[<~"
+
+// inline collects the content of a paragraph, heading or table cell as
+// Markdown words. Only spaces of plain text outside links separate words,
+// so code spans, links and images are never broken across lines.
+type inline struct {
+	words []string
+	word  []byte
+	// space is set when whitespace was seen since the last word character;
+	// it becomes a word break once more content follows.
+	space bool
+	// open holds the emphasis delimiters in effect, outermost first.
+	open []string
+	code []byte
+	// inCode is set while the characters of a code span are collected.
+	inCode bool
+	link   *link
+}
+
+type linkMode uint8
+
+const (
+	// linkPending is a link whose first token decides how it is written.
+	linkPending linkMode = iota
+	linkText
+	linkAuto
+	linkImage
+	// linkSkip drops the text of footnote references and back links.
+	linkSkip
+)
+
+type link struct {
+	url string
+	// ref is the label of a reference link.
+	ref  string
+	mode linkMode
+	// start is where the link text begins in the word.
+	start int
+	// depth is the number of delimiters open outside the link text.
+	depth int
+	// auto holds the text of an autolink.
+	auto strings.Builder
+}
+
+// delimiters returns the emphasis delimiters of a token, outermost first.
+// Header cells are bold already, so strong is left out of them.
+func delimiters(set role.Set, header bool) []string {
+	var want []string
+	if set.Has(role.Strikethrough) {
+		want = append(want, "~~")
+	}
+	if (set.Has(role.Strong) || set.Has(role.EmphasisStrong)) && !header {
+		want = append(want, "**")
+	}
+	if set.Has(role.Emphasis) || set.Has(role.EmphasisStrong) {
+		want = append(want, "*")
+	}
+	return want
+}
+
+// token adds an inline token.
+func (in *inline) token(tok mdf.StreamToken, set role.Set, header bool) {
+	if in.link != nil && in.link.mode == linkSkip && tok.Kind != mdf.TokenLinkEnd {
+		return
+	}
+	want := delimiters(set, header)
+	switch tok.Kind {
+	case mdf.TokenLinkStart:
+		in.startLink(tok.LinkURL, tok.LinkRef)
+	case mdf.TokenLinkEnd:
+		in.endLink()
+	case mdf.TokenImage:
+		in.image(tok.Text, tok.LinkURL, want)
+	case mdf.TokenURL:
+		in.url(tok.Text, want)
+	case mdf.TokenCode:
+		if tok.HTML {
+			in.openLink(want)
+			in.raw(tok.Text, want)
+			break
+		}
+		in.codeSpan(tok.Text, want)
+	case mdf.TokenText:
+		in.text(tok.Text, want)
+	}
+}
+
+func (in *inline) text(text string, want []string) {
+	in.openLink(want)
+	in.endCode()
+	for text != "" {
+		r, size := utf8.DecodeRuneInString(text)
+		text = text[size:]
+		switch r {
+		case '\n':
+			in.breakLine()
+		case ' ', '\t':
+			in.space = true
+		case '\u00a0':
+			in.raw("&nbsp;", want)
+		case '[', ']':
+			in.transition(want)
+			if in.link != nil && in.link.mode == linkText {
+				// Brackets in link text are always escaped.
+				in.word = append(in.word, '\\')
+			} else if r == '[' {
+				in.word = append(in.word, escMark)
+			}
+			in.word = utf8.AppendRune(in.word, r)
+		default:
+			in.transition(want)
+			if strings.ContainsRune(markupChars, r) {
+				in.word = append(in.word, escMark)
+			}
+			in.word = utf8.AppendRune(in.word, r)
+		}
+	}
+}
+
+// raw adds s without breaking it.
+func (in *inline) raw(s string, want []string) {
+	in.endCode()
+	in.transition(want)
+	in.word = append(in.word, s...)
+}
+
+// transition closes the delimiters not in want before pending whitespace,
+// breaks the word there, and opens the delimiters of want after it.
+func (in *inline) transition(want []string) {
+	keep := 0
+	for keep < len(want) && keep < len(in.open) && want[keep] == in.open[keep] {
+		keep++
+	}
+	in.closeTo(keep)
+	if in.space {
+		in.breakWord()
+	}
+	for _, d := range want[keep:] {
+		in.word = append(in.word, d...)
+		in.open = append(in.open, d)
+	}
+}
+
+func (in *inline) closeTo(depth int) {
+	for len(in.open) > depth {
+		in.word = append(in.word, in.open[len(in.open)-1]...)
+		in.open = in.open[:len(in.open)-1]
+	}
+}
+
+func (in *inline) breakWord() {
+	in.space = false
+	if in.link != nil && in.link.mode == linkText {
+		// The parser does not join the lines of link text again.
+		in.word = append(in.word, ' ')
+		return
+	}
+	if len(in.word) > 0 {
+		in.words = append(in.words, escapeWord(string(in.word)))
+		in.word = in.word[:0]
+	}
+}
+
+// breakLine adds a hard line break. Breaks before any content are dropped.
+func (in *inline) breakLine() {
+	in.endCode()
+	in.space = false
+	if len(in.word) > 0 {
+		in.words = append(in.words, escapeWord(string(in.word)))
+		in.word = in.word[:0]
+	}
+	if len(in.words) > 0 && in.words[len(in.words)-1] != hardBreak {
+		in.words = append(in.words, hardBreak)
+	}
+}
+
+func (in *inline) codeSpan(text string, want []string) {
+	if !in.inCode {
+		in.openLink(want)
+		in.transition(want)
+		in.inCode = true
+		in.code = in.code[:0]
+	}
+	in.code = append(in.code, text...)
+}
+
+// endCode writes the collected code span with a fence longer than any run
+// of backticks in it.
+func (in *inline) endCode() {
+	if !in.inCode {
+		return
+	}
+	in.inCode = false
+	code := string(in.code)
+	fence := strings.Repeat("`", longestRun(code, '`')+1)
+	pad := ""
+	if strings.HasPrefix(code, "`") || strings.HasSuffix(code, "`") ||
+		(strings.HasPrefix(code, " ") && strings.HasSuffix(code, " ") && strings.Trim(code, " ") != "") {
+		pad = " "
+	}
+	in.word = append(in.word, fence+pad+code+pad+fence...)
+}
+
+func (in *inline) startLink(url, ref string) {
+	in.endCode()
+	l := &link{url: url, ref: ref}
+	switch {
+	case strings.HasPrefix(url, "#fnref-"):
+		l.mode = linkSkip
+	case strings.HasPrefix(url, "#fn-"):
+		in.raw("[^"+strings.TrimPrefix(url, "#fn-")+"]", in.open)
+		l.mode = linkSkip
+	}
+	in.link = l
+}
+
+// openLink writes the "[" of a pending link once its text starts with a
+// token styled want. Emphasis around the whole link stays outside it.
+func (in *inline) openLink(want []string) {
+	l := in.link
+	if l == nil || l.mode != linkPending {
+		return
+	}
+	keep := 0
+	for keep < len(want) && keep < len(in.open) && want[keep] == in.open[keep] {
+		keep++
+	}
+	in.raw("[", in.open[:keep])
+	l.mode = linkText
+	l.depth = keep
+	l.start = len(in.word)
+}
+
+func (in *inline) url(text string, want []string) {
+	l := in.link
+	if l == nil {
+		in.raw(text, want)
+		return
+	}
+	if l.mode == linkPending && l.ref == "" {
+		l.mode = linkAuto
+	}
+	if l.mode != linkAuto {
+		in.text(text, want)
+		return
+	}
+	l.auto.WriteString(text)
+}
+
+// image writes an image. An image the parser links to its own source
+// takes the title from that link, or its label when it is a reference.
+func (in *inline) image(alt, src string, want []string) {
+	dest := "(" + src + ")"
+	if l := in.link; l != nil && l.mode == linkPending {
+		if href, _, _ := strings.Cut(l.url, " "); href == src {
+			l.mode = linkImage
+			dest = "(" + l.url + ")"
+			if l.ref != "" {
+				dest = "[" + l.ref + "]"
+			}
+		}
+	}
+	in.openLink(want)
+	in.raw("!["+altEscaper.Replace(alt)+"]"+dest, want)
+}
+
+// altEscaper escapes the characters that would end alt text early.
+var altEscaper = strings.NewReplacer(`\`, `\\`, "[", `\[`, "]", `\]`)
+
+func (in *inline) endLink() {
+	in.endCode()
+	l := in.link
+	if l == nil {
+		return
+	}
+	in.link = nil
+	switch l.mode {
+	case linkPending:
+		in.raw("[]"+l.destination(""), in.open)
+	case linkText:
+		in.closeTo(l.depth)
+		text := strings.ReplaceAll(string(in.word[min(l.start, len(in.word)):]), string(escMark), "")
+		in.word = append(in.word, "]"+l.destination(text)...)
+	case linkAuto:
+		text := l.auto.String()
+		switch {
+		case strings.HasPrefix(text, "www.") || (text == l.url && (strings.HasPrefix(text, "http://") || strings.HasPrefix(text, "https://"))):
+			in.raw(text, in.open)
+		case text == l.url || "mailto:"+text == l.url:
+			in.raw("<"+text+">", in.open)
+		default:
+			in.raw("["+text+"]("+l.url+")", in.open)
+		}
+	}
+}
+
+// destination returns what follows the text of a link: its label, left
+// out when it is the text, or its URL.
+func (l *link) destination(text string) string {
+	switch l.ref {
+	case "":
+		return "(" + l.url + ")"
+	case text:
+		return "[]"
+	}
+	return "[" + l.ref + "]"
+}
+
+// finish returns the words, closing what is still open. defs holds the
+// normalized labels of the definitions written so far.
+func (in *inline) finish(defs map[string]bool) []string {
+	if in.link != nil {
+		in.endLink()
+	}
+	in.endCode()
+	in.closeTo(0)
+	in.breakWord()
+	words := in.words
+	for len(words) > 0 && words[len(words)-1] == hardBreak {
+		words = words[:len(words)-1]
+	}
+	*in = inline{}
+	return escapeBrackets(words, defs)
+}
+
+// wordSep joins the words of a block while their brackets are resolved.
+const wordSep = "\x01"
+
+// escapeBrackets resolves the escMarks of "[" across the words of a block.
+// The parser reads "[" as text unless a link, reference, footnote label or
+// definition follows from it, so it is escaped only where one might.
+func escapeBrackets(words []string, defs map[string]bool) []string {
+	text := strings.Join(words, wordSep)
+	if !strings.Contains(text, string(escMark)+"[") {
+		return words
+	}
+	var b strings.Builder
+	for i := 0; i < len(text); i++ {
+		if text[i] != escMark {
+			b.WriteByte(text[i])
+			continue
+		}
+		if opensLink(text[i+2:], defs) {
+			b.WriteByte('\\')
+		}
+	}
+	return strings.Split(b.String(), wordSep)
+}
+
+// opensLink reports whether the text after a "[" could make it a link: its
+// closing bracket is followed by a destination, a label or a colon, or what
+// it encloses is a footnote label or a defined one.
+func opensLink(rest string, defs map[string]bool) bool {
+	end := -1
+	for i := 0; i < len(rest) && end < 0; i++ {
+		switch rest[i] {
+		case '\\':
+			i++
+		case ']':
+			end = i
+		}
+	}
+	if end < 0 {
+		return false
+	}
+	label := strings.NewReplacer(wordSep, " ", string(escMark), "").Replace(rest[:end])
+	if after := rest[end+1:]; after != "" && strings.ContainsRune("([:", rune(after[0])) {
+		return true
+	}
+	return strings.HasPrefix(label, "^") || defs[normalizeLabel(label)]
+}
+
+// normalizeLabel returns the form of a link label that definitions are
+// matched by.
+func normalizeLabel(label string) string {
+	return strings.ToLower(strings.Join(strings.Fields(label), " "))
+}
+
+// wrap fills lines of at most width columns with words, greedily. Lines
+// that end in a hard break get two trailing spaces. A width of 0 or less
+// leaves lines unwrapped. Lines break only before words that are safe at
+// the start of a line; the first word of the block and of each line after
+// a hard break is escaped instead.
+func wrap(words []string, width int) []string {
+	var lines []string
+	var line strings.Builder
+	col := 0
+	for _, word := range words {
+		if word == hardBreak {
+			line.WriteString("  ")
+			lines = append(lines, line.String())
+			line.Reset()
+			col = 0
+			continue
+		}
+		if line.Len() == 0 {
+			word = escapeLineStart(word)
+		}
+		w := ansi.PrintableRuneWidth(word)
+		if col > 0 {
+			if width > 0 && col+1+w > width && lineStartSafe(word) {
+				lines = append(lines, line.String())
+				line.Reset()
+				col = 0
+			} else {
+				line.WriteByte(' ')
+				col++
+			}
+		}
+		line.WriteString(word)
+		col += w
+	}
+	if line.Len() > 0 {
+		lines = append(lines, line.String())
+	}
+	return lines
+}
+
+// lineStartSafe reports whether a wrapped line may start with word without
+// it being read as the start of a block: a heading, quote, list item,
+// thematic break, setext underline, fence, HTML block or link definition.
+func lineStartSafe(word string) bool {
+	if word == "" {
+		return true
+	}
+	switch c := word[0]; {
+	case c == '>' || c == '<' || c == '|':
+		return false
+	case strings.Trim(word, "#") == "":
+		return false
+	case strings.ContainsRune("-+*=_", rune(c)) && strings.Trim(word, word[:1]) == "":
+		return false
+	case strings.HasPrefix(word, "```") || strings.HasPrefix(word, "~~~"):
+		return false
+	case c == '[' && strings.HasSuffix(word, "]:"):
+		return false
+	}
+	digits := len(word) - len(strings.TrimLeft(word, "0123456789"))
+	if digits > 0 && digits == len(word)-1 {
+		if c := word[digits]; c == '.' || c == ')' {
+			return false
+		}
+	}
+	return true
+}
+
+// escapeLineStart escapes a word that starts a line where it would be read
+// as the start of a block.
+func escapeLineStart(word string) string {
+	if lineStartSafe(word) {
+		return word
+	}
+	if digits := len(word) - len(strings.TrimLeft(word, "0123456789")); digits > 0 {
+		return word[:digits] + `\` + word[digits:]
+	}
+	return `\` + word
+}
+
+// escapeWord resolves the escMarks of a word, but for those of "[". A
+// backslash is escaped before punctuation and at the end of the word; "_"
+// unless it joins two letters or digits; "<" and "~" unless they cannot
+// open a tag, autolink or strikethrough; "*" and "`" always. "&" is left alone: the parser
+// keeps most entities as text, so they are written back as they came.
+func escapeWord(word string) string {
+	if strings.IndexByte(word, escMark) < 0 {
+		return word
+	}
+	var b strings.Builder
+	for i := 0; i < len(word); i++ {
+		if word[i] != escMark {
+			b.WriteByte(word[i])
+			continue
+		}
+		if i+1 < len(word) && word[i+1] == '[' {
+			// escapeBrackets resolves it.
+			b.WriteByte(escMark)
+		} else if i+1 < len(word) && needsEscape(word, i+1) {
+			b.WriteByte('\\')
+		}
+	}
+	return b.String()
+}
+
+// needsEscape reports whether the marked character at word[i] needs a
+// backslash.
+func needsEscape(word string, i int) bool {
+	next := unmarked(word[i+1:])
+	switch word[i] {
+	case '\\':
+		return next == "" || isEscapable(next[0])
+	case '_':
+		// The run of marked underscores around word[i] is word[start:end].
+		start, end := i-1, i+1
+		for start >= 2 && word[start-2:start] == string(escMark)+"_" {
+			start -= 2
+		}
+		for end+1 < len(word) && word[end:end+2] == string(escMark)+"_" {
+			end += 2
+		}
+		prev, _ := utf8.DecodeLastRuneInString(word[:start])
+		after, _ := utf8.DecodeRuneInString(unmarked(word[end:]))
+		return !isWordRune(prev) || !isWordRune(after)
+	case '<':
+		return next != "" && next[0] != ' '
+	case '~':
+		return strings.HasPrefix(next, "~") || strings.HasSuffix(strings.TrimSuffix(word[:i], string(escMark)), "~")
+	}
+	return true
+}
+
+// unmarked returns s without a leading escMark.
+func unmarked(s string) string {
+	return strings.TrimPrefix(s, string(escMark))
+}
+
+func isEscapable(c byte) bool {
+	return strings.IndexByte("!\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~", c) >= 0
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+func longestRun(s string, c byte) int {
+	longest, run := 0, 0
+	for i := 0; i < len(s); i++ {
+		if s[i] != c {
+			run = 0
+			continue
+		}
+		run++
+		longest = max(longest, run)
+	}
+	return longest
+}
+
+// fence returns a code fence longer than any backtick run in lines.
+func fence(lines []string) string {
+	n := 3
+	for _, line := range lines {
+		if strings.HasPrefix(strings.TrimLeft(line, " "), "```") {
+			n = max(n, longestRun(line, '`')+1)
+		}
+	}
+	return strings.Repeat("`", n)
+}
+
+// itemMarker returns the marker of the item numbered n of a list.
+func itemMarker(ordered, alt bool, n int) string {
+	switch {
+	case ordered && alt:
+		return strconv.Itoa(n) + ")"
+	case ordered:
+		return strconv.Itoa(n) + "."
+	case alt:
+		return "*"
+	}
+	return "-"
+}
@@ -0,0 +1,88 @@
+package markdown
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+
+	"pkt.systems/mdf"
+	"pkt.systems/mdf/internal/role"
+)
+
+// RenderRequest contains inputs for Markdown formatting.
+type RenderRequest struct {
+	Reader io.Reader
+	Writer io.Writer
+	Config Config
+}
+
+// Render formats Markdown. Front matter is copied unchanged.
+func Render(req RenderRequest) error {
+	return RenderContext(context.Background(), req)
+}
+
+// RenderContext is Render with cancellation. The input is read in full
+// first; once ctx is done it returns ctx.Err(), leaving the output
+// unfinished.
+func RenderContext(ctx context.Context, req RenderRequest) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if req.Reader == nil {
+		return fmt.Errorf("markdown render: reader is nil")
+	}
+	if req.Writer == nil {
+		return fmt.Errorf("markdown render: writer is nil")
+	}
+	cfg := DefaultConfig()
+	applyConfig(&cfg, req.Config)
+	src, err := io.ReadAll(req.Reader)
+	if err != nil {
+		return fmt.Errorf("markdown render: %w", err)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	width := cfg.Width
+	if cfg.NoWrap {
+		width = 0
+	}
+	st := NewStream(req.Writer, width)
+	front, body := mdf.SplitFrontMatter(src)
+	if len(front) > 0 {
+		if !bytes.HasSuffix(front, []byte("\n")) {
+			front = append(front[:len(front):len(front)], '\n')
+		}
+		if _, err := req.Writer.Write(front); err != nil {
+			return fmt.Errorf("markdown render: %w", err)
+		}
+		// The body is separated from the front matter like a block.
+		st.wrote = true
+	}
+	if err := mdf.ParseContext(ctx, mdf.ParseRequest{
+		Reader:  bytes.NewReader(body),
+		Stream:  st,
+		Theme:   role.Theme(),
+		Options: parseOptions(),
+	}); err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil && err == ctxErr {
+			return err
+		}
+		return fmt.Errorf("markdown render: %w", err)
+	}
+	return nil
+}
+
+// parseOptions returns the options the parser runs with for a Stream.
+func parseOptions() []mdf.RenderOption {
+	return []mdf.RenderOption{mdf.WithOSC8(true), mdf.WithHTMLPolicy(mdf.HTMLAsCode)}
+}
+
+// ParseTheme returns the theme a Stream expects the parser to run with.
+// Pass it as the Theme of an mdf.ParseRequest that feeds a Stream, with
+// mdf.WithOSC8(true) and mdf.WithHTMLPolicy(mdf.HTMLAsCode) among its
+// Options.
+func ParseTheme() mdf.Theme {
+	return role.Theme()
+}
@@ -0,0 +1,220 @@
+package markdown
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+func formatString(t *testing.T, src string, cfg Config) string {
+	t.Helper()
+	var out bytes.Buffer
+	if err := Render(RenderRequest{Reader: strings.NewReader(src), Writer: &out, Config: cfg}); err != nil {
+		t.Fatalf("render: %v", err)
+	}
+	return out.String()
+}
+
+func TestRenderNormalises(t *testing.T) {
+	src := strings.Join([]string{
+		"Title",
+		"=====",
+		"",
+		"Some _emphasis_, __strong__ and snake_case text that is long enough to wrap.",
+		"hard  ",
+		"break",
+		"",
+		"* one",
+		"* two",
+		"    * nested",
+		"",
+		"1) first",
+		"3) second",
+		"",
+		"> quoted",
+		"continued",
+		"",
+	}, "\n")
+	got := formatString(t, src, Config{Width: 30})
+	want := strings.Join([]string{
+		"# Title",
+		"",
+		"Some *emphasis*, **strong**",
+		"and snake_case text that is",
+		"long enough to wrap. hard  ",
+		"break",
+		"",
+		"- one",
+		"- two",
+		"  - nested",
+		"",
+		"1. first",
+		"2. second",
+		"",
+		"> quoted continued",
+		"",
+	}, "\n")
+	if got != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestRenderKeepsCodeBytes(t *testing.T) {
+	src := "~~~go\n\tx := 1  \n\n```` inside\n~~~\n\n    indented block\n\n- item\n\n  ```\n  in list\n    indented\n  ```\n"
+	got := formatString(t, src, Config{})
+	want := "`````go\n\tx := 1  \n\n```` inside\n`````\n\n```\nindented block\n```\n\n- item\n\n  ```\n  in list\n    indented\n  ```\n"
+	if got != want {
+		t.Fatalf("got %q\nwant %q", got, want)
+	}
+}
+
+func TestRenderTable(t *testing.T) {
+	src := "| left | center | right |\n|:--|:-:|--:|\n| a | b | c |\n| long cell | `x` | **y** |\n"
+	got := formatString(t, src, Config{})
+	want := "| left      | center | right |\n" +
+		"| :-------- | :----: | ----: |\n" +
+		"| a         |   b    |     c |\n" +
+		"| long cell |  `x`   | **y** |\n"
+	if got != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestRenderTableKeepsAlignment(t *testing.T) {
+	src := "| n | c |\n|--:|:-:|\n| 10 | x │ y |\n"
+	got := formatString(t, src, Config{})
+	want := "|   n |   c   |\n| --: | :---: |\n|  10 | x │ y |\n"
+	if got != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestRenderFrontMatterLinksAndNotes(t *testing.T) {
+	src := "---\ntitle: x\n---\nSee [the *docs*](https://x.y \"T\"), <https://a.b>, ![img](i.png) and a note[^n].\n\n[^n]: The note.\n"
+	got := formatString(t, src, Config{})
+	want := "---\ntitle: x\n---\n\nSee [the *docs*](https://x.y \"T\"), https://a.b, ![img](i.png) and a note[^1].\n\n[^1]: The note.\n"
+	if got != want {
+		t.Fatalf("got %q\nwant %q", got, want)
+	}
+}
+
+func TestRenderKeepsImageTitles(t *testing.T) {
+	src := "![a\\]t](a.png \"A title\") and [![b](b.png)](https://x.y 'L')\n"
+	if got := formatString(t, src, Config{}); got != src {
+		t.Fatalf("got %q\nwant %q", got, src)
+	}
+}
+
+func TestRenderKeepsEscapes(t *testing.T) {
+	src := "\\* not a list, \\*not em\\*, snake_case and \\_x\\_, a < b, \\<b>, &amp; & `c\\*`, \\[x](y), x\\~\\~y, C:\\dir\\\n\n\\# not a heading\n\n1\\. not a list\n"
+	got := formatString(t, src, Config{NoWrap: true})
+	want := "\\* not a list, \\*not em\\*, snake_case and \\_x\\_, a < b, \\<b>, &amp; & `c\\*`, \\[x](y), x\\~\\~y, C:\\dir\\\\\n\n\\# not a heading\n\n1\\. not a list\n"
+	if got != want {
+		t.Fatalf("got %q\nwant %q", got, want)
+	}
+}
+
+func TestRenderKeepsReferences(t *testing.T) {
+	src := "See [Go], [the site][go] and [go][], [WIP] and \\[go].\n\n[go]: https://go.dev \"Go\"\n[l]: logo.png\n\nA ![logo][l] [![b](b.png)][go] and [Go] again.\n"
+	want := "See [Go], [the site][go] and [go][], [WIP] and [go].\n\n[go]: https://go.dev \"Go\"\n[l]: logo.png\n\nA ![logo][l] [![b](b.png)][go] and [Go][] again.\n"
+	if got := formatString(t, src, Config{NoWrap: true}); got != want {
+		t.Fatalf("got %q\nwant %q", got, want)
+	}
+	if got := formatString(t, "[go]: https://go.dev\n\nNot \\[go].\n", Config{}); got != "[go]: https://go.dev\n\nNot \\[go].\n" {
+		t.Fatalf("escaped label after its definition: got %q", got)
+	}
+}
+
+func TestRenderKeepsIndentedCode(t *testing.T) {
+	src := "para\n\n    [server]: localhost\n    code\n"
+	want := "para\n\n```\n[server]: localhost\ncode\n```\n"
+	if got := formatString(t, src, Config{}); got != want {
+		t.Fatalf("got %q\nwant %q", got, want)
+	}
+}
+
+func TestRenderKeepsRawHTML(t *testing.T) {
+	src := "<div align=\"center\">\n  <img src=\"a.png\" width=\"300\">\n</div>\n\nText <span class=\"a b\">x</span> and <!-- c --> here.\n\n- item\n\n  <p>in item</p>\n"
+	want := strings.Replace(src, "</span> and", "</span>\nand", 1)
+	if got := formatString(t, src, Config{Width: 20}); got != want {
+		t.Fatalf("got %q\nwant %q", got, want)
+	}
+}
+
+func TestEquivalent(t *testing.T) {
+	cases := []struct {
+		a, b string
+		want bool
+	}{
+		{a: "Title\n=====\n\n* one\n* two\n", b: "# Title\n\n- one\n- two\n", want: true},
+		{a: "a *b c*\nd\n", b: "a *b* *c* d\n", want: true},
+		{a: "a  \nb\n", b: "a\\\nb\n", want: true},
+		{a: "a  \nb\n", b: "a\nb\n", want: false},
+		{a: "[a](b)\n", b: "[a](c)\n", want: false},
+		{a: "\\* x\n", b: "* x\n", want: false},
+		{a: "---\nt: 1\n---\nx\n", b: "---\nt: 2\n---\nx\n", want: false},
+	}
+	for _, tc := range cases {
+		got, err := Equivalent([]byte(tc.a), []byte(tc.b))
+		if err != nil || got != tc.want {
+			t.Errorf("Equivalent(%q, %q) = %v, %v; want %v", tc.a, tc.b, got, err, tc.want)
+		}
+	}
+}
+
+func TestRenderIdempotent(t *testing.T) {
+	src := strings.Join([]string{
+		"Heading *with* `code`",
+		"---------------------",
+		"",
+		"A paragraph with [a link that is long](https://example.com/a/long/path) and",
+		"a number 10. that must not start a line, then more words to wrap around.",
+		"",
+		"- [ ] task",
+		"- [x] done",
+		"",
+		"  > quote in item",
+		"",
+		"2. two",
+		"",
+		"   para",
+		"",
+		"* * *",
+		"",
+		"| a | b |",
+		"|---|--:|",
+		"| 1 | 2 |",
+		"",
+	}, "\n")
+	once := formatString(t, src, Config{Width: 40})
+	twice := formatString(t, once, Config{Width: 40})
+	if once != twice {
+		t.Fatalf("second pass changed the output:\n%s\n---\n%s", once, twice)
+	}
+	for _, want := range []string{"## Heading *with* `code`\n", "\n- [ ] task\n- [x] done\n\n  > quote in item\n", "\n2. two\n\n   para\n\n---\n"} {
+		if !strings.Contains(once, want) {
+			t.Errorf("missing %q in:\n%s", want, once)
+		}
+	}
+}
+
+func TestRenderNoWrap(t *testing.T) {
+	src := "one two three four five six seven\neight\n"
+	if got := formatString(t, src, Config{Width: 10, NoWrap: true}); got != "one two three four five six seven eight\n" {
+		t.Fatalf("got %q", got)
+	}
+	if got := formatString(t, "", Config{}); got != "" {
+		t.Fatalf("empty input gave %q", got)
+	}
+}
+
+func TestRenderContextCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	var out bytes.Buffer
+	err := RenderContext(ctx, RenderRequest{Reader: strings.NewReader("hi\n"), Writer: &out})
+	if err != context.Canceled {
+		t.Fatalf("err = %v, want context.Canceled", err)
+	}
+}
@@ -0,0 +1,518 @@
+package markdown
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/muesli/reflow/ansi"
+	"pkt.systems/mdf"
+	"pkt.systems/mdf/internal/role"
+	"pkt.systems/mdf/internal/table"
+)
+
+// Stream is an mdf.Stream that writes Markdown. Each block is written once
+// it ends, so a paragraph can be wrapped as a whole. The parser must run
+// with ParseTheme, with OSC 8 links on and with mdf.HTMLAsCode; otherwise
+// inline markup, link destinations and raw HTML are lost.
+type Stream struct {
+	w     io.Writer
+	width int
+	buf   []byte
+	err   error
+
+	containers []container
+	lists      []list
+	// wrote is set once a block was written; later blocks are separated
+	// from it.
+	wrote bool
+	// newlines counts the line breaks the parser drew since the last block.
+	// Inside lists a single one marks a tight list.
+	newlines   int
+	prevInItem bool
+	// ended is the list that just ended, until another block is written.
+	ended *list
+	// def is set after a link reference definition, until another block is
+	// written.
+	def bool
+	// defs holds the normalized labels of the definitions written.
+	defs map[string]bool
+
+	leaf  leafKind
+	level int
+	// lineStart is set at the start of each line of a block, where the
+	// parser draws quote bars, list markers and indentation that the
+	// container prefixes replace.
+	lineStart bool
+	// task is the state of a task item until the parser's "[x]" has been
+	// dropped.
+	task  mdf.TaskState
+	in    inline
+	code  codeBlock
+	table table.Table
+	// notes is the "Footnotes" heading the parser writes before the
+	// footnote definitions, held until the list after it shows whether it
+	// is one.
+	notes string
+}
+
+type leafKind uint8
+
+const (
+	leafNone leafKind = iota
+	leafParagraph
+	leafHeading
+	leafCode
+)
+
+// container is a block quote or list item, written as a prefix of each of
+// its lines.
+type container struct {
+	quote bool
+	// marker is written on the first line of an item and indent on the
+	// others.
+	marker string
+	indent string
+	// fresh is set until the container's first line has been written.
+	fresh bool
+}
+
+type list struct {
+	ordered bool
+	// alt selects the "*" or ")" marker, which keeps a list apart from the
+	// list of the same kind it directly follows.
+	alt  bool
+	next int
+	// notes is set for the footnote definitions.
+	notes bool
+}
+
+type codeBlock struct {
+	lang string
+	// html is set for an HTML block, written without a fence.
+	html  bool
+	lines []string
+	line  strings.Builder
+}
+
+// NewStream returns a Stream writing to w that wraps paragraphs at width
+// columns. A width of 0 or less leaves paragraphs unwrapped.
+func NewStream(w io.Writer, width int) *Stream {
+	return &Stream{w: w, width: max(width, 0)}
+}
+
+// Width reports 0, so the parser draws tables at their natural width.
+func (s *Stream) Width() int { return 0 }
+
+// SetWidth is a no-op.
+func (s *Stream) SetWidth(int) {}
+
+// SetWrapIndent is a no-op.
+func (s *Stream) SetWrapIndent(string) {}
+
+// WriteToken adds tok, writing the blocks it completes.
+func (s *Stream) WriteToken(tok mdf.StreamToken) error {
+	if s.err != nil {
+		return s.err
+	}
+	s.buf = s.buf[:0]
+	s.token(tok)
+	return s.write()
+}
+
+// Flush writes the blocks left open.
+func (s *Stream) Flush() error {
+	if s.err != nil {
+		return s.err
+	}
+	s.buf = s.buf[:0]
+	s.endLeaf()
+	s.releaseNotes()
+	if s.table.Open {
+		s.endTable()
+	}
+	for len(s.containers) > 0 {
+		s.popContainer()
+	}
+	s.lists = s.lists[:0]
+	return s.write()
+}
+
+func (s *Stream) write() error {
+	if len(s.buf) == 0 {
+		return nil
+	}
+	if _, err := s.w.Write(s.buf); err != nil {
+		s.err = fmt.Errorf("markdown: %w", err)
+	}
+	return s.err
+}
+
+func (s *Stream) token(tok mdf.StreamToken) {
+	set := role.Of(tok.Style)
+	if s.notes != "" && !s.holdsNotes(tok, set) {
+		s.releaseNotes()
+	}
+	if s.table.Open || tok.Kind == mdf.TokenTableStart {
+		if s.table.Token(tok) {
+			s.endTable()
+		}
+		return
+	}
+	if mdf.IsBlockEvent(tok.Kind) {
+		s.block(tok)
+		return
+	}
+	if tok.Kind == mdf.TokenDefinition {
+		s.endLeaf()
+		s.definition(tok)
+		return
+	}
+	switch s.leaf {
+	case leafCode:
+		s.codeToken(tok)
+	case leafParagraph, leafHeading:
+		s.inlineToken(tok, set)
+	default:
+		if tok.Kind == mdf.TokenThematicBreak {
+			s.writeBlock("---")
+			return
+		}
+		// Between blocks the parser draws only line breaks and decoration.
+		s.newlines += strings.Count(tok.Text, "\n")
+	}
+}
+
+func (s *Stream) block(tok mdf.StreamToken) {
+	info := tok.Block
+	switch tok.Kind {
+	case mdf.TokenHeadingStart:
+		s.startLeaf(leafHeading)
+		s.level = min(max(info.Level, 1), 6)
+	case mdf.TokenParagraphStart:
+		s.startLeaf(leafParagraph)
+	case mdf.TokenCodeBlockStart:
+		s.startLeaf(leafCode)
+		s.code.lang = info.Lang
+		s.code.html = info.HTML
+	case mdf.TokenHeadingEnd, mdf.TokenParagraphEnd, mdf.TokenCodeBlockEnd:
+		s.endLeaf()
+	case mdf.TokenListStart:
+		s.endLeaf()
+		l := list{ordered: info.Ordered, next: max(info.Start, 0)}
+		if prev := s.ended; prev != nil && prev.ordered == l.ordered {
+			l.alt = !prev.alt
+		}
+		s.lists = append(s.lists, l)
+		s.ended = nil
+		if n := len(s.containers); n > 0 && !s.containers[n-1].quote && !s.containers[n-1].fresh {
+			// The parser separates a nested list from the text of its item
+			// as if it were loose; keep it on the next line.
+			s.newlines = 1
+		}
+	case mdf.TokenListEnd:
+		s.endLeaf()
+		if n := len(s.lists); n > 0 {
+			ended := s.lists[n-1]
+			s.lists = s.lists[:n-1]
+			s.ended = &ended
+		}
+	case mdf.TokenListItemStart:
+		s.endLeaf()
+		marker := "-"
+		if n := len(s.lists); n > 0 {
+			l := &s.lists[n-1]
+			marker = itemMarker(l.ordered, l.alt, l.next)
+			l.next++
+		}
+		indent := strings.Repeat(" ", len(marker)+1)
+		switch info.Task {
+		case mdf.TaskUnchecked:
+			marker += " [ ]"
+		case mdf.TaskChecked:
+			marker += " [x]"
+		}
+		s.containers = append(s.containers, container{marker: marker + " ", indent: indent, fresh: true})
+		s.task = info.Task
+	case mdf.TokenBlockquoteStart:
+		s.endLeaf()
+		s.containers = append(s.containers, container{quote: true, fresh: true})
+	case mdf.TokenListItemEnd, mdf.TokenBlockquoteEnd:
+		s.endLeaf()
+		s.popContainer()
+	}
+}
+
+func (s *Stream) startLeaf(kind leafKind) {
+	s.endLeaf()
+	s.leaf = kind
+	s.lineStart = true
+}
+
+func (s *Stream) endLeaf() {
+	if s.leaf == leafNone {
+		return
+	}
+	switch s.leaf {
+	case leafParagraph:
+		words := s.in.finish(s.defs)
+		if len(words) == 0 {
+			break
+		}
+		width := 0
+		if s.width > 0 {
+			width = max(s.width-ansi.PrintableRuneWidth(s.prefix()), 1)
+		}
+		s.writeBlock(wrap(words, width)...)
+	case leafHeading:
+		words := s.in.finish(s.defs)
+		line := strings.Repeat("#", s.level)
+		for _, word := range words {
+			if word != hardBreak {
+				line += " " + word
+			}
+		}
+		if s.level == 2 && line == "## Footnotes" && len(s.containers) == 0 {
+			s.notes = line
+			break
+		}
+		s.writeBlock(line)
+	case leafCode:
+		c := &s.code
+		c.lines = append(c.lines, c.line.String())
+		lines := c.lines
+		for len(lines) > 0 && lines[len(lines)-1] == "" {
+			lines = lines[:len(lines)-1]
+		}
+		if c.html {
+			s.writeBlock(lines...)
+		} else {
+			f := fence(lines)
+			out := append([]string{f + c.lang}, lines...)
+			s.writeBlock(append(out, f)...)
+		}
+		s.code = codeBlock{}
+	}
+	s.leaf = leafNone
+	s.task = mdf.TaskNone
+}
+
+// inlineToken adds a token of a paragraph or heading.
+func (s *Stream) inlineToken(tok mdf.StreamToken, set role.Set) {
+	if tok.Kind == mdf.TokenAnchor {
+		s.anchor(tok.LinkURL)
+		return
+	}
+	if tok.Kind == mdf.TokenText && strings.Trim(tok.Text, "\n") == "" {
+		s.in.breakLine()
+		s.lineStart = true
+		return
+	}
+	if s.lineStart && tok.Kind == mdf.TokenText {
+		if decoration(tok.Text, set) {
+			return
+		}
+		if s.task != mdf.TaskNone {
+			if strings.Contains(tok.Text, "]") {
+				s.task = mdf.TaskNone
+			}
+			return
+		}
+	}
+	s.lineStart = false
+	s.in.token(tok, set, false)
+}
+
+// decoration reports whether text is part of what the parser draws at the
+// start of a line: quote bars, list markers, indentation and heading marks.
+func decoration(text string, set role.Set) bool {
+	if set.Has(role.Quote) || set.Has(role.ListMarker) || strings.TrimSpace(text) == "" {
+		return true
+	}
+	return set.Has(role.Heading) && strings.Trim(text, "# ") == "" && strings.HasSuffix(text, " ")
+}
+
+// codeToken adds a token of a code block. Code text, and the source of an
+// HTML block, is kept as it is; the decoration the parser draws before each
+// line is dropped.
+func (s *Stream) codeToken(tok mdf.StreamToken) {
+	c := &s.code
+	switch {
+	case tok.Kind == mdf.TokenCode && tok.CodeBlock:
+		c.line.WriteString(tok.Text)
+	case tok.Kind == mdf.TokenText && strings.Trim(tok.Text, "\n") == "":
+		for range strings.Count(tok.Text, "\n") {
+			c.lines = append(c.lines, c.line.String())
+			c.line.Reset()
+		}
+	}
+}
+
+// anchor switches the item of a footnote definition to a "[^N]: " marker.
+func (s *Stream) anchor(id string) {
+	n := len(s.lists)
+	if !strings.HasPrefix(id, "fn-") || n == 0 || !(s.lists[n-1].notes || s.notes != "") {
+		return
+	}
+	s.notes = ""
+	s.lists[n-1].notes = true
+	if c := &s.containers[len(s.containers)-1]; c.fresh && !c.quote {
+		c.marker = "[^" + strings.TrimPrefix(id, "fn-") + "]: "
+		c.indent = "    "
+	}
+}
+
+// holdsNotes reports whether tok may still belong to the footnote
+// definitions after a held "Footnotes" heading.
+func (s *Stream) holdsNotes(tok mdf.StreamToken, set role.Set) bool {
+	switch tok.Kind {
+	case mdf.TokenListStart:
+		return tok.Block.Ordered && len(s.lists) == 0
+	case mdf.TokenListItemStart, mdf.TokenParagraphStart:
+		return len(s.lists) == 1
+	case mdf.TokenAnchor:
+		return strings.HasPrefix(tok.LinkURL, "fn-")
+	case mdf.TokenText:
+		if s.leaf == leafNone {
+			return strings.TrimSpace(tok.Text) == ""
+		}
+		return s.leaf == leafParagraph && s.lineStart && decoration(tok.Text, set)
+	}
+	return false
+}
+
+// releaseNotes writes a held "Footnotes" heading that turned out to be an
+// ordinary one, ahead of the blocks opened since.
+func (s *Stream) releaseNotes() {
+	if s.notes == "" {
+		return
+	}
+	line := s.notes
+	s.notes = ""
+	open := s.containers
+	s.containers = nil
+	s.writeBlock(line)
+	s.containers = open
+}
+
+// definition writes a link reference definition. Definitions that follow
+// one another are kept together.
+func (s *Stream) definition(tok mdf.StreamToken) {
+	line := "[" + tok.LinkRef + "]: " + tok.LinkURL
+	if s.defs == nil {
+		s.defs = make(map[string]bool)
+	}
+	s.defs[strings.Clone(normalizeLabel(tok.LinkRef))] = true
+	if !s.def {
+		s.writeBlock(line)
+		s.def = true
+		return
+	}
+	s.buf = append(s.buf, s.prefix()...)
+	s.buf = append(s.buf, line...)
+	s.buf = append(s.buf, '\n')
+	s.newlines = 0
+}
+
+func (s *Stream) endTable() {
+	lines := tableLines(&s.table, s.defs)
+	s.table = table.Table{}
+	if len(lines) > 0 {
+		s.writeBlock(lines...)
+	}
+	s.newlines = 0
+}
+
+func (s *Stream) popContainer() {
+	n := len(s.containers)
+	if n == 0 {
+		return
+	}
+	if c := s.containers[n-1]; c.fresh && !c.quote {
+		// An empty item still needs its marker.
+		s.separate()
+		s.buf = append(s.buf, strings.TrimRight(s.prefix(), " ")...)
+		s.buf = append(s.buf, '\n')
+		s.wroteLine()
+	}
+	s.containers = s.containers[:n-1]
+}
+
+// writeBlock writes the lines of a block with the container prefix.
+func (s *Stream) writeBlock(lines ...string) {
+	s.separate()
+	for _, line := range lines {
+		if line == "" {
+			s.buf = append(s.buf, s.blankPrefix()...)
+		} else {
+			s.buf = append(s.buf, s.prefix()...)
+			s.buf = append(s.buf, line...)
+			s.wroteLine()
+		}
+		s.buf = append(s.buf, '\n')
+	}
+}
+
+// separate writes a blank line before a block unless it follows another
+// block of a tight list.
+func (s *Stream) separate() {
+	inItem := s.inItem()
+	if s.wrote && (s.newlines >= 2 || !inItem || !s.prevInItem) {
+		s.buf = append(s.buf, s.blankPrefix()...)
+		s.buf = append(s.buf, '\n')
+	}
+	s.wrote = true
+	s.newlines = 0
+	s.prevInItem = inItem
+	s.ended = nil
+	s.def = false
+}
+
+// prefix returns the container prefix of the next line.
+func (s *Stream) prefix() string {
+	var b strings.Builder
+	for _, c := range s.containers {
+		switch {
+		case c.quote:
+			b.WriteString("> ")
+		case c.fresh:
+			b.WriteString(c.marker)
+		default:
+			b.WriteString(c.indent)
+		}
+	}
+	return b.String()
+}
+
+// blankPrefix returns the prefix of a blank line. It ends before a
+// container that has no line yet, so the blank line separates the block
+// that opens it from the one before.
+func (s *Stream) blankPrefix() string {
+	var b strings.Builder
+	for _, c := range s.containers {
+		if c.fresh {
+			break
+		}
+		if c.quote {
+			b.WriteString("> ")
+		} else {
+			b.WriteString(c.indent)
+		}
+	}
+	return strings.TrimRight(b.String(), " ")
+}
+
+func (s *Stream) wroteLine() {
+	for i := range s.containers {
+		s.containers[i].fresh = false
+	}
+}
+
+func (s *Stream) inItem() bool {
+	for _, c := range s.containers {
+		if !c.quote {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,98 @@
+package markdown
+
+import (
+	"strings"
+
+	"github.com/muesli/reflow/ansi"
+	"pkt.systems/mdf"
+	"pkt.systems/mdf/internal/role"
+	"pkt.systems/mdf/internal/table"
+)
+
+// tableLines returns t as a pipe table, padded to column width. defs holds
+// the labels defined so far.
+func tableLines(t *table.Table, defs map[string]bool) []string {
+	cols := t.Columns()
+	if cols == 0 {
+		return nil
+	}
+	rows := make([][]string, len(t.Rows))
+	widths := make([]int, cols)
+	for i := range widths {
+		widths[i] = 3
+	}
+	for r, row := range t.Rows {
+		for i, tokens := range row {
+			cell := cellText(tokens, r < t.Header, defs)
+			rows[r] = append(rows[r], cell)
+			widths[i] = max(widths[i], ansi.PrintableRuneWidth(cell))
+		}
+	}
+	header := max(t.Header, 1)
+	var lines []string
+	for r, row := range rows {
+		lines = append(lines, tableLine(t, row, widths))
+		if r == header-1 {
+			lines = append(lines, delimiterRow(t, widths))
+		}
+	}
+	if len(rows) < header {
+		lines = append(lines, delimiterRow(t, widths))
+	}
+	return lines
+}
+
+// cellText returns the Markdown of a cell's tokens, with its pipes escaped.
+func cellText(tokens []mdf.StreamToken, header bool, defs map[string]bool) string {
+	var in inline
+	for _, tok := range tokens {
+		in.token(tok, role.Of(tok.Style), header)
+	}
+	return strings.ReplaceAll(strings.Join(in.finish(defs), " "), "|", `\|`)
+}
+
+func tableLine(t *table.Table, row []string, widths []int) string {
+	var b strings.Builder
+	b.WriteByte('|')
+	for i, w := range widths {
+		cell := ""
+		if i < len(row) {
+			cell = row[i]
+		}
+		pad := w - ansi.PrintableRuneWidth(cell)
+		left := 0
+		switch columnAlign(t, i) {
+		case mdf.AlignCenter:
+			left = pad / 2
+		case mdf.AlignRight:
+			left = pad
+		}
+		b.WriteString(" " + strings.Repeat(" ", left) + cell + strings.Repeat(" ", pad-left) + " |")
+	}
+	return b.String()
+}
+
+func delimiterRow(t *table.Table, widths []int) string {
+	var b strings.Builder
+	b.WriteByte('|')
+	for i, w := range widths {
+		switch columnAlign(t, i) {
+		case mdf.AlignLeft:
+			b.WriteString(" :" + strings.Repeat("-", w-1) + " |")
+		case mdf.AlignCenter:
+			b.WriteString(" :" + strings.Repeat("-", w-2) + ": |")
+		case mdf.AlignRight:
+			b.WriteString(" " + strings.Repeat("-", w-1) + ": |")
+		default:
+			b.WriteString(" " + strings.Repeat("-", w) + " |")
+		}
+	}
+	return b.String()
+}
+
+func columnAlign(t *table.Table, i int) mdf.CellAlign {
+	if i >= len(t.Aligns) {
+		return mdf.AlignNone
+	}
+	return t.Aligns[i]
+}
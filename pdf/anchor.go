@@ -0,0 +1,59 @@
+package pdf
+
+import (
+	"strings"
+
+	"pkt.systems/mdf"
+)
+
+// anchor is an internal link destination; placed reports whether its
+// position has been set.
+type anchor struct {
+	id     int
+	placed bool
+}
+
+// anchorLink returns the internal link id for name, allocating it on first
+// use so links may point forward to anchors that are placed later.
+func (s *pdfStream) anchorLink(name string) int {
+	if a, ok := s.anchors[name]; ok {
+		return a.id
+	}
+	if s.anchors == nil {
+		s.anchors = make(map[string]anchor)
+	}
+	id := s.pdf.AddLink()
+	s.anchors[name] = anchor{id: id}
+	return id
+}
+
+// writeAnchor places the named destination at the current line.
+func (s *pdfStream) writeAnchor(tok mdf.StreamToken) error {
+	if len(s.pending.atoms) > 0 {
+		s.flushWord(boundaryNone)
+	}
+	id := s.anchorLink(tok.LinkURL)
+	s.pdf.SetLink(id, s.y-s.lineHeight, s.pdf.PageNo())
+	s.anchors[tok.LinkURL] = anchor{id: id, placed: true}
+	return nil
+}
+
+// linkArea makes a rectangle clickable, pointing at an anchor for "#name"
+// targets and at the URL otherwise.
+func (s *pdfStream) linkArea(x, y, w, h float64, target string) {
+	if name, ok := strings.CutPrefix(target, "#"); ok {
+		s.pdf.Link(x, y, w, h, s.anchorLink(name))
+		return
+	}
+	s.pdf.LinkString(x, y, w, h, target)
+}
+
+// resolveAnchors points links to anchors that never appeared at the top of
+// the first page, keeping the document's link table valid.
+func (s *pdfStream) resolveAnchors() {
+	for _, a := range s.anchors {
+		if !a.placed {
+			s.pdf.SetLink(a.id, 0, 1)
+		}
+	}
+}
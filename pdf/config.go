@@ -1,5 +1,7 @@
 package pdf
 
+import "pkt.systems/mdf"
+
 // Config holds PDF rendering settings.
 type Config struct {
 	PageSize             string
@@ -28,6 +30,8 @@ type Config struct {
 	CornerImageMaxWidth  float64
 	CornerImageMaxHeight float64
 	CornerImagePadding   float64
+	HTMLPolicy           mdf.HTMLPolicy
+	ImageBaseDir         string
 }
 
 const headingFontFamily = "Heading"
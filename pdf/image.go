@@ -0,0 +1,98 @@
+package pdf
+
+import (
+	"math"
+	"os"
+	"strings"
+
+	"pkt.systems/mdf"
+	"pkt.systems/mdf/pdf/gofpdf"
+)
+
+// minImageColumnFraction is the share of the text column an inline image
+// needs on the current line before it is moved to a line of its own.
+const minImageColumnFraction = 1.0 / 3
+
+// writeImage embeds a local PNG or JPEG image, or falls back to its alt text
+// in brackets when the source cannot be embedded.
+func (s *pdfStream) writeImage(tok mdf.StreamToken) error {
+	if len(s.pending.atoms) > 0 {
+		s.flushWord(boundaryNone)
+	} else if len(s.pendingSpaces) > 0 {
+		s.emitAtoms(s.pendingSpaces)
+		s.pendingSpaces = s.pendingSpaces[:0]
+	}
+	if s.embedImage(tok.LinkURL) {
+		return nil
+	}
+	tok.Kind = tokenText
+	tok.Text = "[" + tok.Text + "]"
+	return s.WriteToken(tok)
+}
+
+// embedImage draws the image at the current position, scaled down to fit the
+// remaining text column and page. It reports whether the image was drawn.
+func (s *pdfStream) embedImage(path string) bool {
+	imageType := imageTypeForPath(path)
+	if imageType == "" || !s.pdf.Ok() {
+		return false
+	}
+	if info, err := os.Stat(path); err != nil || !info.Mode().IsRegular() {
+		return false
+	}
+	opts := gofpdf.ImageOptions{ImageType: imageType, ReadDpi: true}
+	info := s.pdf.RegisterImageOptions(path, opts)
+	if s.pdf.Error() != nil {
+		s.pdf.ClearError()
+		return false
+	}
+	width, height := info.Extent()
+	if width <= 0 || height <= 0 {
+		return false
+	}
+	if s.pendingIndent {
+		s.pendingIndent = false
+		s.emitIndent()
+	}
+	column := s.lineLimit()
+	if s.lineWidth > 0 && column-s.lineWidth < column*minImageColumnFraction {
+		s.wrapNewline()
+		if s.pendingIndent {
+			s.pendingIndent = false
+			s.emitIndent()
+		}
+	}
+	maxH := s.pageH - 2*s.cfg.Margin
+	scale := math.Min(1, math.Min((column-s.lineWidth)/width, maxH/height))
+	width *= scale
+	height *= scale
+	top := s.y - s.cfg.FontSize
+	if top+height > s.pageH-s.cfg.Margin {
+		s.y = s.pageH
+		s.newline(false)
+		if s.pendingIndent {
+			s.pendingIndent = false
+			s.emitIndent()
+		}
+		top = s.y - s.cfg.FontSize
+	}
+	if s.layers.enabled {
+		s.pdf.BeginLayer(s.layers.image)
+	}
+	link := s.currentLink
+	if strings.HasPrefix(link, "file:") || strings.HasPrefix(link, "#") {
+		link = ""
+	}
+	s.pdf.ImageOptions(path, s.x, top, width, height, false, opts, 0, link)
+	if s.layers.enabled {
+		s.pdf.EndLayer()
+	}
+	// Text following the image on the same line sits on its bottom edge.
+	if bottom := top + height + s.cfg.FontSize - s.lineHeight; bottom > s.y {
+		s.y = bottom
+	}
+	s.x += width
+	s.lineWidth += width
+	s.atLineStart = false
+	return true
+}
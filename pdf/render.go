@@ -1,6 +1,7 @@
 package pdf
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"math"
@@ -22,6 +23,15 @@ type RenderRequest struct {
 
 // Render converts Markdown to a themed PDF.
 func Render(req RenderRequest) error {
+	return RenderContext(context.Background(), req)
+}
+
+// RenderContext is Render with cancellation. Once ctx is done it stops
+// reading and returns ctx.Err() without writing a document.
+func RenderContext(ctx context.Context, req RenderRequest) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
 	if req.Reader == nil {
 		return fmt.Errorf("pdf render: reader is nil")
 	}
@@ -159,14 +169,22 @@ func Render(req RenderRequest) error {
 		pdf.SetLayerPrintState(layers.image, gofpdf.LayerUsageOn)
 	}
 	stream := newPDFStream(pdf, cfg, theme.Styles(), cols, charWidth, cornerImage, layers)
-	if err := mdf.Parse(mdf.ParseRequest{
-		Reader:  req.Reader,
-		Stream:  stream,
-		Theme:   theme,
-		Options: []mdf.RenderOption{mdf.WithOSC8(true)},
+	if err := mdf.ParseContext(ctx, mdf.ParseRequest{
+		Reader: req.Reader,
+		Stream: stream,
+		Theme:  theme,
+		Options: []mdf.RenderOption{
+			mdf.WithOSC8(true),
+			mdf.WithHTMLPolicy(cfg.HTMLPolicy),
+			mdf.WithImageBaseDir(cfg.ImageBaseDir),
+		},
 	}); err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil && err == ctxErr {
+			return err
+		}
 		return fmt.Errorf("pdf render: %w", err)
 	}
+	stream.resolveAnchors()
 	if err := pdf.Output(req.Writer); err != nil {
 		return fmt.Errorf("pdf render: output: %w", err)
 	}
@@ -252,6 +270,12 @@ func applyConfig(dst *Config, src Config) {
 	if src.CornerImagePadding > 0 {
 		dst.CornerImagePadding = src.CornerImagePadding
 	}
+	// Every policy is applied, HTMLAsText included, so that text can be
+	// chosen whatever the default is.
+	dst.HTMLPolicy = src.HTMLPolicy
+	if src.ImageBaseDir != "" {
+		dst.ImageBaseDir = src.ImageBaseDir
+	}
 }
 
 func isCoreFont(name string) bool {
@@ -2,6 +2,8 @@ package pdf
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"strings"
 	"testing"
 
@@ -197,3 +199,170 @@ func TestRenderPDFLinkTextWithSpaces(t *testing.T) {
 		t.Fatalf("expected link target in pdf output")
 	}
 }
+
+func TestRenderPDFReferenceLinks(t *testing.T) {
+	var out bytes.Buffer
+	err := Render(RenderRequest{
+		Reader: strings.NewReader("See [the example][ex].\n\n[ex]: http://example.com/ref\n"),
+		Writer: &out,
+		Theme:  mdf.DefaultTheme(),
+		Config: Config{
+			PageSize:   "A4",
+			Margin:     36,
+			FontFamily: "Courier",
+			FontSize:   12,
+			LineHeight: 1.4,
+		},
+	})
+	if err != nil {
+		t.Fatalf("render: %v", err)
+	}
+	if !bytes.Contains(out.Bytes(), []byte("http://example.com/ref")) {
+		t.Fatalf("expected reference link target in pdf output")
+	}
+}
+
+func TestRenderPDFBareLinks(t *testing.T) {
+	var out bytes.Buffer
+	err := Render(RenderRequest{
+		Reader: strings.NewReader("Docs at https://example.com/docs. Mirror: www.example.org\n"),
+		Writer: &out,
+		Theme:  mdf.DefaultTheme(),
+		Config: Config{
+			PageSize:   "A4",
+			Margin:     36,
+			FontFamily: "Courier",
+			FontSize:   12,
+			LineHeight: 1.4,
+		},
+	})
+	if err != nil {
+		t.Fatalf("render: %v", err)
+	}
+	data := out.Bytes()
+	if !bytes.Contains(data, []byte("https://example.com/docs")) {
+		t.Fatalf("expected bare url annotation in pdf output")
+	}
+	if !bytes.Contains(data, []byte("http://www.example.org")) {
+		t.Fatalf("expected www autolink annotation in pdf output")
+	}
+}
+
+func TestRenderPDFHTMLPolicy(t *testing.T) {
+	src := "Go to <a href=\"https://example.com/html\">the site</a>.\n"
+	render := func(policy mdf.HTMLPolicy) []byte {
+		var out bytes.Buffer
+		err := Render(RenderRequest{
+			Reader: strings.NewReader(src),
+			Writer: &out,
+			Theme:  mdf.DefaultTheme(),
+			Config: Config{
+				PageSize:   "A4",
+				Margin:     36,
+				FontFamily: "Courier",
+				FontSize:   12,
+				LineHeight: 1.4,
+				HTMLPolicy: policy,
+			},
+		})
+		if err != nil {
+			t.Fatalf("render: %v", err)
+		}
+		return out.Bytes()
+	}
+	if !bytes.Contains(render(mdf.HTMLInterpret), []byte("https://example.com/html")) {
+		t.Fatalf("expected interpreted anchor annotation in pdf output")
+	}
+	if bytes.Contains(render(mdf.HTMLAsText), []byte("/S /URI")) {
+		t.Fatalf("unexpected anchor annotation when html is shown as text")
+	}
+}
+
+func TestApplyConfigHTMLPolicy(t *testing.T) {
+	for _, policy := range []mdf.HTMLPolicy{mdf.HTMLAsText, mdf.HTMLStrip, mdf.HTMLAsCode, mdf.HTMLInterpret} {
+		dst := Config{HTMLPolicy: mdf.HTMLInterpret}
+		applyConfig(&dst, Config{HTMLPolicy: policy})
+		if dst.HTMLPolicy != policy {
+			t.Fatalf("policy %d applied as %d", policy, dst.HTMLPolicy)
+		}
+	}
+}
+
+func TestRenderPDFEmbedsImages(t *testing.T) {
+	render := func(src string) []byte {
+		var out bytes.Buffer
+		err := Render(RenderRequest{
+			Reader: strings.NewReader(src),
+			Writer: &out,
+			Theme:  mdf.DefaultTheme(),
+			Config: Config{
+				PageSize:     "A4",
+				Margin:       36,
+				FontFamily:   "Courier",
+				FontSize:     12,
+				LineHeight:   1.4,
+				ImageBaseDir: "testdata",
+			},
+		})
+		if err != nil {
+			t.Fatalf("render: %v", err)
+		}
+		return out.Bytes()
+	}
+	if !bytes.Contains(render("Logo: ![corner](ocg_corner.png) done\n"), []byte("/Subtype /Image")) {
+		t.Fatalf("expected embedded image in pdf output")
+	}
+	if bytes.Contains(render("![missing](nope.png) and ![remote](https://example.com/x.png)\n"), []byte("/Subtype /Image")) {
+		t.Fatalf("unexpected image for unresolvable sources")
+	}
+}
+
+func TestRenderPDFFootnoteLinks(t *testing.T) {
+	src := "Claim[^1] and a dangling link to [nowhere](#missing).\n\n[^1]: The note.\n"
+	var out bytes.Buffer
+	err := Render(RenderRequest{
+		Reader: strings.NewReader(src),
+		Writer: &out,
+		Theme:  mdf.DefaultTheme(),
+		Config: Config{
+			PageSize:   "A4",
+			Margin:     36,
+			FontFamily: "Courier",
+			FontSize:   12,
+			LineHeight: 1.4,
+		},
+	})
+	if err != nil {
+		t.Fatalf("render: %v", err)
+	}
+	if !bytes.Contains(out.Bytes(), []byte("/Dest [")) {
+		t.Fatalf("expected internal link annotations in pdf output")
+	}
+	if bytes.Contains(out.Bytes(), []byte("/URI (#")) {
+		t.Fatalf("internal link written as a URI action")
+	}
+}
+
+func TestRenderContextCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	var out bytes.Buffer
+	err := RenderContext(ctx, RenderRequest{
+		Reader: strings.NewReader("# Title\n\nBody."),
+		Writer: &out,
+		Theme:  mdf.DefaultTheme(),
+		Config: Config{
+			PageSize:   "A4",
+			Margin:     36,
+			FontFamily: "Courier",
+			FontSize:   12,
+			LineHeight: 1.4,
+		},
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if out.Len() != 0 {
+		t.Fatalf("unexpected output from a canceled render: %d bytes", out.Len())
+	}
+}
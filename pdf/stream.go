@@ -3,7 +3,6 @@ package pdf
 import (
 	"bytes"
 	"math"
-	"strconv"
 	"strings"
 	"time"
 	"unicode"
@@ -21,6 +20,11 @@ const (
 	tokenURL                     = 3
 	tokenCode                    = 4
 	tokenThematicBreak           = 5
+	tokenImage                   = 6
+	tokenAnchor                  = 7
+	tokenHeadingStart            = 8
+	tokenHeadingEnd              = 9
+	tokenListItemStart           = 14
 	headingSpaceBeforeMultiplier = 0.35
 	headingSpaceAfterMultiplier  = 1.3
 )
@@ -29,8 +33,8 @@ type pdfStream struct {
 	pdf                 *gofpdf.Fpdf
 	cfg                 Config
 	styles              mdf.Styles
-	styleCache          map[string]pdfStyle
-	printStyleCache     map[string]pdfStyle
+	styleCache          map[styleKey]pdfStyle
+	printStyleCache     map[styleKey]pdfStyle
 	width               int
 	lineWidth           float64
 	pending             wordBuffer
@@ -41,7 +45,7 @@ type pdfStream struct {
 	wrapIndentUseWidth  bool
 	wrapIndentPrefix    string
 	wrapIndentPrefixSt  mdf.Style
-	lastStylePrefix     string
+	lastStyle           mdf.Style
 	lastPDFStyle        pdfStyle
 	lastStyleSet        bool
 	lastListIndentWidth float64
@@ -60,7 +64,10 @@ type pdfStream struct {
 	pageW                 float64
 	pageH                 float64
 	currentLink           string
+	anchors               map[string]anchor
 	headingLevel          int
+	headingOpen           int
+	listItemLine          bool
 	cornerImage           *cornerImage
 	cornerImageBottom     float64
 	pageNum               int
@@ -109,8 +116,8 @@ func newPDFStream(pdf *gofpdf.Fpdf, cfg Config, styles mdf.Styles, width int, ch
 		pdf:             pdf,
 		cfg:             cfg,
 		styles:          styles,
-		styleCache:      make(map[string]pdfStyle),
-		printStyleCache: make(map[string]pdfStyle),
+		styleCache:      make(map[styleKey]pdfStyle),
+		printStyleCache: make(map[styleKey]pdfStyle),
 		width:           width,
 		pending:         wordBuffer{atoms: make([]mdf.StreamToken, 0, 64)},
 		pendingSpaces:   make([]mdf.StreamToken, 0, 16),
@@ -173,6 +180,7 @@ func (s *pdfStream) addPage() {
 	s.prefixBuf = s.prefixBuf[:0]
 	s.prefixWidth = 0
 	s.inQuoteLine = false
+	s.listItemLine = false
 	s.pendingIndent = false
 	s.lineWidth = 0
 	s.pendingHeadingBuf = s.pendingHeadingBuf[:0]
@@ -185,7 +193,7 @@ func (s *pdfStream) addPage() {
 	s.headingPending = false
 	s.headingBuf.Reset()
 	s.headingMarker = ""
-	s.lastStylePrefix = ""
+	s.lastStyle = mdf.Style{}
 	s.lastStyleSet = false
 }
 
@@ -240,6 +248,16 @@ func (s *pdfStream) WriteToken(tok mdf.StreamToken) error {
 		s.emitCodeBlockText(tok.Text, tok.Style)
 		return nil
 	}
+	if tok.Kind == tokenImage {
+		return s.writeImage(tok)
+	}
+	if tok.Kind == tokenAnchor {
+		return s.writeAnchor(tok)
+	}
+	if mdf.IsBlockEvent(tok.Kind) {
+		s.writeBlockEvent(tok)
+		return nil
+	}
 	if tok.Kind == tokenLinkStart {
 		if len(s.pending.atoms) > 0 {
 			s.flushWord(boundaryNone)
@@ -325,6 +343,26 @@ func (s *pdfStream) WriteToken(tok mdf.StreamToken) error {
 	return nil
 }
 
+// writeBlockEvent tracks the headings and list items the parser reports. A
+// heading's marker is laid out with its text once the marker is complete.
+func (s *pdfStream) writeBlockEvent(tok mdf.StreamToken) {
+	switch tok.Kind {
+	case tokenHeadingStart, tokenHeadingEnd:
+		if len(s.pending.atoms) > 0 {
+			s.flushWord(boundaryNone)
+		} else if len(s.pendingSpaces) > 0 {
+			s.emitAtoms(s.pendingSpaces)
+			s.pendingSpaces = s.pendingSpaces[:0]
+		}
+		s.headingOpen = 0
+		if tok.Kind == tokenHeadingStart {
+			s.headingOpen = tok.Block.Level
+		}
+	case tokenListItemStart:
+		s.listItemLine = true
+	}
+}
+
 func (s *pdfStream) Flush() error {
 	if len(s.nbspBuf) > 0 {
 		s.flushNBSPBuf()
@@ -873,7 +911,7 @@ func (s *pdfStream) renderHeadingBlock() {
 	s.headingPending = false
 	s.headingBuf.Reset()
 	s.headingMarker = ""
-	pstyle := s.styleForPrefix(style.Prefix, s.headingLevel)
+	pstyle := s.styleFor(style, s.headingLevel)
 	lineHeight := pstyle.size * s.cfg.LineHeight
 	s.lastHeadingLineHeight = lineHeight
 
@@ -917,12 +955,11 @@ func (s *pdfStream) renderHeadingBlock() {
 		width := 0.0
 		if s.layers.enabled {
 			width = s.drawTextLayer(s.layers.viewText, pstyle, line, false)
-			printStyle := s.styleForPrefixPrint(style.Prefix, s.headingLevel)
+			printStyle := s.styleForPrint(style, s.headingLevel)
 			_ = s.drawTextLayer(s.layers.printText, printStyle, line, false)
 		} else {
 			s.applyStyle(pstyle)
-			s.pdf.Text(s.x, s.y, line)
-			width = s.pdf.GetStringWidth(line)
+			width = s.drawText(pstyle, line)
 		}
 		s.x += width
 		s.lineWidth = width
@@ -965,11 +1002,9 @@ func (s *pdfStream) emitText(text string, style mdf.Style) {
 		if bytes.Contains(s.prefixBuf, []byte(" ")) && isLinePrefixBytes(s.prefixBuf) {
 			s.noteListIndentPrefix()
 		}
-		if s.headingLevel == 0 && isHeadingStyle(style, s.styles) {
-			linePrefix := isLinePrefixBytes(s.prefixBuf)
-			lineHasQuote := linePrefix && lineHasQuotePrefix(s.prefixBuf)
-			lineHasList := linePrefix && listPrefixDetected(s.prefixBuf)
-			if lineHasQuote || lineHasList {
+		if s.headingLevel == 0 && s.headingOpen > 0 {
+			lineHasQuote := isLinePrefixBytes(s.prefixBuf) && lineHasQuotePrefix(s.prefixBuf)
+			if lineHasQuote || s.listItemLine {
 				s.emitTextDirect(text, style)
 				if bytes.Contains(s.prefixBuf, []byte(" ")) && !isLinePrefixBytes(s.prefixBuf) {
 					s.atLineStart = false
@@ -977,23 +1012,23 @@ func (s *pdfStream) emitText(text string, style mdf.Style) {
 				return
 			}
 		}
-		if s.headingLevel == 0 && isHeadingStyle(style, s.styles) {
+		if s.headingLevel == 0 && s.headingOpen > 0 {
 			if len(s.pendingHeadingBuf) > 0 && text != "#" && text != " " {
 				buf := string(s.pendingHeadingBuf)
 				s.pendingHeadingBuf = s.pendingHeadingBuf[:0]
 				s.emitTextDirect(buf, style)
 			}
-			if text == "#" || text == " " || len(s.pendingHeadingBuf) > 0 {
+			if text == "#" || len(s.pendingHeadingBuf) > 0 {
 				s.pendingHeadingBuf = append(s.pendingHeadingBuf, text...)
 				if text == " " {
-					if level := headingLevelFromPrefixBuf(s.pendingHeadingBuf, style, s.styles); level > 0 {
-						s.headingLevel = level
+					if len(s.pendingHeadingBuf) == s.headingOpen+1 {
+						s.headingLevel = s.headingOpen
 						s.wrapIndent = indentSpaces(len(s.pendingHeadingBuf))
 						s.wrapIndentUseWidth = true
 						s.wrapIndentWidth = s.charWidth * float64(textColumns(s.wrapIndent))
 						s.wrapIndentPrefix = ""
 						s.wrapIndentPrefixSt = mdf.Style{}
-						pstyle := s.styleForPrefix(style.Prefix, s.headingLevel)
+						pstyle := s.styleFor(style, s.headingLevel)
 						s.lastHeadingLineHeight = pstyle.size * s.cfg.LineHeight
 						s.headingPending = true
 						s.headingStyle = style
@@ -1075,7 +1110,7 @@ func (s *pdfStream) pageBreak() {
 	s.headingPending = false
 	s.headingBuf.Reset()
 	s.headingMarker = ""
-	s.lastStylePrefix = ""
+	s.lastStyle = mdf.Style{}
 	s.lastStyleSet = false
 	s.addPage()
 }
@@ -1090,7 +1125,7 @@ func isOnlyNewline(text string) bool {
 }
 
 func (s *pdfStream) measureText(text string, style mdf.Style) float64 {
-	pstyle := s.styleForPrefix(style.Prefix, s.headingLevel)
+	pstyle := s.styleFor(style, s.headingLevel)
 	s.applyStyle(pstyle)
 	return s.pdf.GetStringWidth(text)
 }
@@ -1100,10 +1135,9 @@ func (s *pdfStream) drawTextLayer(layerID int, style pdfStyle, text string, link
 		s.pdf.BeginLayer(layerID)
 	}
 	s.applyStyle(style)
-	s.pdf.Text(s.x, s.y, text)
-	width := s.pdf.GetStringWidth(text)
+	width := s.drawText(style, text)
 	if link && s.currentLink != "" && width > 0 {
-		s.pdf.LinkString(s.x, s.y-style.size, width, style.size*1.1, s.currentLink)
+		s.linkArea(s.x, s.y-style.size, width, style.size*1.1, s.currentLink)
 	}
 	if s.layers.enabled {
 		s.pdf.EndLayer()
@@ -1111,11 +1145,23 @@ func (s *pdfStream) drawTextLayer(layerID int, style pdfStyle, text string, link
 	return width
 }
 
+// drawText draws text at the cursor over its background colour, if any, and
+// returns its width.
+func (s *pdfStream) drawText(style pdfStyle, text string) float64 {
+	width := s.pdf.GetStringWidth(text)
+	if style.background && width > 0 {
+		s.pdf.SetFillColor(style.bg[0], style.bg[1], style.bg[2])
+		s.pdf.Rect(s.x, s.y-style.size*0.8, width, style.size*1.05, "F")
+	}
+	s.pdf.Text(s.x, s.y, text)
+	return width
+}
+
 func (s *pdfStream) availableCols(limit float64, style mdf.Style) int {
 	if limit <= 0 {
 		return 0
 	}
-	pstyle := s.styleForPrefix(style.Prefix, s.headingLevel)
+	pstyle := s.styleFor(style, s.headingLevel)
 	s.applyStyle(pstyle)
 	charWidth := s.pdf.GetStringWidth("M")
 	if charWidth <= 0 {
@@ -1128,7 +1174,7 @@ func (s *pdfStream) emitTextDirect(text string, style mdf.Style) {
 	if strings.ContainsRune(text, '\u00A0') {
 		text = strings.ReplaceAll(text, "\u00A0", " ")
 	}
-	pstyle := s.styleForPrefix(style.Prefix, s.headingLevel)
+	pstyle := s.styleFor(style, s.headingLevel)
 	if s.headingLevel > 0 {
 		s.lastHeadingSize = pstyle.size
 		s.lastHeadingLineHeight = pstyle.size * s.cfg.LineHeight
@@ -1136,7 +1182,7 @@ func (s *pdfStream) emitTextDirect(text string, style mdf.Style) {
 	}
 	if s.layers.enabled {
 		width := s.drawTextLayer(s.layers.viewText, pstyle, text, s.currentLink != "")
-		printStyle := s.styleForPrefixPrint(style.Prefix, s.headingLevel)
+		printStyle := s.styleForPrint(style, s.headingLevel)
 		_ = s.drawTextLayer(s.layers.printText, printStyle, text, false)
 		if s.atLineStart && isLinePrefixBytes(s.prefixBuf) {
 			s.prefixWidth += width
@@ -1150,23 +1196,22 @@ func (s *pdfStream) emitTextDirect(text string, style mdf.Style) {
 		if s.headingLevel > 0 && s.lastHeadingLineHeight > 0 {
 			s.lineHeight = s.lastHeadingLineHeight
 		}
-		if isHeadingStyle(style, s.styles) {
+		if s.headingOpen > 0 {
 			s.lineHadHeading = true
 			s.lastHeadingLineHeight = pstyle.size * s.cfg.LineHeight
 		}
-		s.lastStylePrefix = style.Prefix
+		s.lastStyle = style
 		s.lastPDFStyle = pstyle
 		s.lastStyleSet = true
 		return
 	}
 	s.applyStyle(pstyle)
-	s.pdf.Text(s.x, s.y, text)
-	width := s.pdf.GetStringWidth(text)
+	width := s.drawText(pstyle, text)
 	if s.atLineStart && isLinePrefixBytes(s.prefixBuf) {
 		s.prefixWidth += width
 	}
 	if s.currentLink != "" && width > 0 {
-		s.pdf.LinkString(s.x, s.y-pstyle.size, width, pstyle.size*1.1, s.currentLink)
+		s.linkArea(s.x, s.y-pstyle.size, width, pstyle.size*1.1, s.currentLink)
 	}
 	s.x += width
 	s.lineWidth += width
@@ -1177,11 +1222,11 @@ func (s *pdfStream) emitTextDirect(text string, style mdf.Style) {
 	if s.headingLevel > 0 && s.lastHeadingLineHeight > 0 {
 		s.lineHeight = s.lastHeadingLineHeight
 	}
-	if isHeadingStyle(style, s.styles) {
+	if s.headingOpen > 0 {
 		s.lineHadHeading = true
 		s.lastHeadingLineHeight = pstyle.size * s.cfg.LineHeight
 	}
-	s.lastStylePrefix = style.Prefix
+	s.lastStyle = style
 	s.lastPDFStyle = pstyle
 	s.lastStyleSet = true
 }
@@ -1196,15 +1241,6 @@ func (s *pdfStream) lineHasOnlyPrefix() bool {
 	return false
 }
 
-func isHeadingStyle(style mdf.Style, styles mdf.Styles) bool {
-	for i := 0; i < len(styles.Heading); i++ {
-		if style.Prefix == styles.Heading[i].Prefix {
-			return true
-		}
-	}
-	return false
-}
-
 func lineHasQuotePrefix(buf []byte) bool {
 	if !isLinePrefixBytes(buf) {
 		return false
@@ -1227,7 +1263,7 @@ func (s *pdfStream) noteListIndentPrefix() {
 		s.listIndentActive = false
 		return
 	}
-	if !listPrefixDetected(s.prefixBuf) {
+	if !s.listItemLine {
 		s.lastListIndentWidth = 0
 		s.lastListIndentCols = 0
 		s.listIndentActive = false
@@ -1238,20 +1274,22 @@ func (s *pdfStream) noteListIndentPrefix() {
 	s.listIndentActive = true
 }
 
-func (s *pdfStream) styleForPrefix(prefix string, headingLevel int) pdfStyle {
-	key := prefix
-	if headingLevel > 0 {
-		key = prefix + "#h" + strconv.Itoa(headingLevel)
-		if s.inQuoteLine {
-			key += "#q"
-		}
-	}
+// styleKey identifies a cached pdfStyle.
+type styleKey struct {
+	style        mdf.Style
+	headingLevel int
+	quote        bool
+}
+
+func (s *pdfStream) styleFor(st mdf.Style, headingLevel int) pdfStyle {
+	key := styleKey{style: st, headingLevel: headingLevel, quote: headingLevel > 0 && s.inQuoteLine}
 	if cached, ok := s.styleCache[key]; ok {
 		return cached
 	}
-	attrs := parseANSIPrefix(prefix, s.cfg.TextRGB)
+	attrs := styleAttrs(st, s.cfg.TextRGB)
 	if s.cfg.IgnoreColors {
 		attrs.color = s.cfg.TextRGB
+		attrs.backgroundSet = false
 	}
 	forceBold := headingLevel > 0
 	allowBoldItalic := s.cfg.HeadingFont == "" && (s.cfg.BoldItalicFont != "" || len(s.cfg.BoldItalicFontBytes) > 0)
@@ -1276,23 +1314,19 @@ func (s *pdfStream) styleForPrefix(prefix string, headingLevel int) pdfStyle {
 		r:          color[0],
 		g:          color[1],
 		b:          color[2],
+		background: attrs.backgroundSet,
+		bg:         attrs.background,
 	}
 	s.styleCache[key] = style
 	return style
 }
 
-func (s *pdfStream) styleForPrefixPrint(prefix string, headingLevel int) pdfStyle {
-	key := prefix
-	if headingLevel > 0 {
-		key = prefix + "#p" + strconv.Itoa(headingLevel)
-		if s.inQuoteLine {
-			key += "#q"
-		}
-	}
+func (s *pdfStream) styleForPrint(st mdf.Style, headingLevel int) pdfStyle {
+	key := styleKey{style: st, headingLevel: headingLevel, quote: headingLevel > 0 && s.inQuoteLine}
 	if cached, ok := s.printStyleCache[key]; ok {
 		return cached
 	}
-	attrs := parseANSIPrefix(prefix, [3]int{0, 0, 0})
+	attrs := styleAttrs(st, [3]int{0, 0, 0})
 	attrs.color = [3]int{0, 0, 0}
 	forceBold := headingLevel > 0
 	allowBoldItalic := s.cfg.HeadingFont == "" && (s.cfg.BoldItalicFont != "" || len(s.cfg.BoldItalicFontBytes) > 0)
@@ -1371,13 +1405,14 @@ func (s *pdfStream) newline(resetStyle bool) {
 		s.prefixBuf = s.prefixBuf[:0]
 		s.prefixWidth = 0
 		s.inQuoteLine = false
+		s.listItemLine = false
 		s.pendingIndent = false
 		s.headingLevel = 0
 		s.pendingHeadingBuf = s.pendingHeadingBuf[:0]
 		s.headingSpacingApplied = false
 		s.lastHeadingLineHeight = 0
 		s.lineHadHeading = false
-		s.lastStylePrefix = ""
+		s.lastStyle = mdf.Style{}
 		s.lastStyleSet = false
 		return
 	}
@@ -1389,10 +1424,10 @@ func (s *pdfStream) emitIndent() {
 		return
 	}
 	if s.wrapIndentPrefix != "" {
-		pstyle := s.styleForPrefix(s.wrapIndentPrefixSt.Prefix, 0)
+		pstyle := s.styleFor(s.wrapIndentPrefixSt, 0)
 		width := s.drawTextLayer(s.layers.viewText, pstyle, s.wrapIndentPrefix, false)
 		if s.layers.enabled {
-			printStyle := s.styleForPrefixPrint(s.wrapIndentPrefixSt.Prefix, 0)
+			printStyle := s.styleForPrint(s.wrapIndentPrefixSt, 0)
 			_ = s.drawTextLayer(s.layers.printText, printStyle, s.wrapIndentPrefix, false)
 		}
 		s.x += width
@@ -1403,7 +1438,7 @@ func (s *pdfStream) emitIndent() {
 	if s.lastStyleSet {
 		width := s.drawTextLayer(s.layers.viewText, s.lastPDFStyle, s.wrapIndent, false)
 		if s.layers.enabled {
-			printStyle := s.styleForPrefixPrint(s.lastStylePrefix, s.headingLevel)
+			printStyle := s.styleForPrint(s.lastStyle, s.headingLevel)
 			_ = s.drawTextLayer(s.layers.printText, printStyle, s.wrapIndent, false)
 		}
 		s.x += width
@@ -1514,32 +1549,6 @@ func (s *pdfStream) maybeSetWrapIndent() {
 	}
 }
 
-func headingLevelFromPrefixBuf(buf []byte, style mdf.Style, styles mdf.Styles) int {
-	space := bytes.IndexByte(buf, ' ')
-	if space == -1 || space == 0 {
-		return 0
-	}
-	i := 0
-	for i < space && (buf[i] == ' ' || buf[i] == '\t') {
-		i++
-	}
-	if i >= space {
-		return 0
-	}
-	level := 0
-	for i < space && buf[i] == '#' {
-		level++
-		i++
-	}
-	if level < 1 || level > 6 || i != space {
-		return 0
-	}
-	if style.Prefix != styles.Heading[level-1].Prefix {
-		return 0
-	}
-	return level
-}
-
 func isLinePrefixBytes(buf []byte) bool {
 	trim := bytes.TrimLeft(buf, " \t")
 	trim = bytes.TrimRight(trim, " ")
@@ -1602,35 +1611,6 @@ func isPrefixToken(tok []byte) bool {
 	return false
 }
 
-func listPrefixDetected(prefix []byte) bool {
-	trim := bytes.TrimSpace(prefix)
-	if len(trim) == 0 {
-		return false
-	}
-	fields := bytes.Fields(trim)
-	if len(fields) == 0 {
-		return false
-	}
-	for _, field := range fields {
-		if len(field) == 1 {
-			switch field[0] {
-			case '-', '+', '*':
-				return true
-			}
-		}
-		if field[0] >= '0' && field[0] <= '9' {
-			i := 0
-			for i < len(field) && field[i] >= '0' && field[i] <= '9' {
-				i++
-			}
-			if i < len(field) && (field[i] == '.' || field[i] == ')') && i+1 == len(field) {
-				return true
-			}
-		}
-	}
-	return false
-}
-
 func indentSpaces(count int) string {
 	if count <= 0 {
 		return ""
@@ -1,7 +1,10 @@
 package pdf
 
 import (
+	"bytes"
 	"math"
+	"regexp"
+	"strings"
 	"testing"
 
 	"pkt.systems/mdf"
@@ -63,19 +66,37 @@ func TestPageBreakResetsState(t *testing.T) {
 	}
 }
 
-func TestHeadingLevelFromPrefixBuf(t *testing.T) {
+func TestHeadingLevelFromBlockEvent(t *testing.T) {
 	styles := mdf.DefaultTheme().Styles()
-	style := styles.Heading[0]
-	buf := []byte("# ")
-	if got := headingLevelFromPrefixBuf(buf, style, styles); got != 1 {
-		t.Fatalf("unexpected heading level: got %d want %d", got, 1)
+	pdf := gofpdf.New("P", "pt", "A4", "")
+	stream := newPDFStream(pdf, DefaultConfig(), styles, 80, 7, nil, pdfLayers{})
+	stream.emitText("#", styles.Text)
+	stream.emitText(" ", styles.Text)
+	if stream.headingLevel != 0 || stream.headingPending {
+		t.Fatalf("expected no heading without a heading event")
+	}
+
+	stream = newPDFStream(pdf, DefaultConfig(), styles, 80, 7, nil, pdfLayers{})
+	startHeading(t, stream, 3)
+	stream.emitText("#", styles.Heading[2])
+	stream.emitText("#", styles.Heading[2])
+	stream.emitText("#", styles.Heading[2])
+	stream.emitText(" ", styles.Heading[2])
+	if stream.headingLevel != 3 || !stream.headingPending {
+		t.Fatalf("unexpected heading level: got %d want %d", stream.headingLevel, 3)
+	}
+	if err := stream.WriteToken(mdf.StreamToken{Token: mdf.Token{Kind: mdf.TokenHeadingEnd, Block: mdf.BlockInfo{Level: 3}}}); err != nil {
+		t.Fatalf("write heading end: %v", err)
 	}
-	buf = []byte("### ")
-	if got := headingLevelFromPrefixBuf(buf, styles.Heading[2], styles); got != 3 {
-		t.Fatalf("unexpected heading level: got %d want %d", got, 3)
+	if stream.headingOpen != 0 {
+		t.Fatalf("expected heading end to close the heading")
 	}
-	if got := headingLevelFromPrefixBuf([]byte("# "), styles.Text, styles); got != 0 {
-		t.Fatalf("expected no heading for text style")
+}
+
+func startHeading(t *testing.T, stream *pdfStream, level int) {
+	t.Helper()
+	if err := stream.WriteToken(mdf.StreamToken{Token: mdf.Token{Kind: mdf.TokenHeadingStart, Block: mdf.BlockInfo{Level: level}}}); err != nil {
+		t.Fatalf("write heading start: %v", err)
 	}
 }
 
@@ -108,6 +129,7 @@ func TestHeadingPendingCollectsMarkerAndText(t *testing.T) {
 	cfg.FontSize = 12
 	pdf := gofpdf.New("P", "pt", "A4", "")
 	stream := newPDFStream(pdf, cfg, theme.Styles(), 80, 7, nil, pdfLayers{})
+	startHeading(t, stream, 3)
 	style := theme.Styles().Heading[2]
 
 	stream.emitText("#", style)
@@ -140,6 +162,7 @@ func TestHeadingInQuoteListDoesNotTriggerHeadingMode(t *testing.T) {
 	stream.inQuoteLine = true
 	stream.prefixBuf = append(stream.prefixBuf, []byte("> ")...)
 
+	startHeading(t, stream, 2)
 	style := theme.Styles().Heading[1]
 	stream.emitText("#", style)
 	stream.emitText("#", style)
@@ -160,6 +183,7 @@ func TestHeadingWrapIndentPinsToMarker(t *testing.T) {
 	pdf.SetFont(cfg.FontFamily, "", cfg.FontSize)
 	charWidth := pdf.GetStringWidth("M")
 	stream := newPDFStream(pdf, cfg, theme.Styles(), 80, charWidth, nil, pdfLayers{})
+	startHeading(t, stream, 3)
 	style := theme.Styles().Heading[2]
 
 	stream.emitText("#", style)
@@ -396,7 +420,7 @@ func TestHeadingInQuoteUsesBodyFontSize(t *testing.T) {
 	stream := newPDFStream(pdf, cfg, theme.Styles(), 80, charWidth, nil, pdfLayers{})
 	stream.inQuoteLine = true
 
-	style := stream.styleForPrefix(theme.Styles().Heading[1].Prefix, 2)
+	style := stream.styleFor(theme.Styles().Heading[1], 2)
 	if style.fontFamily != cfg.FontFamily {
 		t.Fatalf("expected heading in quote to use body font family, got %q", style.fontFamily)
 	}
@@ -404,3 +428,36 @@ func TestHeadingInQuoteUsesBodyFontSize(t *testing.T) {
 		t.Fatalf("expected heading in quote to use body font size, got %v", style.size)
 	}
 }
+
+var fillColorRE = regexp.MustCompile(`[0-9.]+ [0-9.]+ [0-9.]+ rg`)
+
+func TestCodeBlockHighlightColors(t *testing.T) {
+	render := func(info string) map[string]bool {
+		pdf := gofpdf.New("P", "pt", "A4", "")
+		pdf.SetCompression(false)
+		cfg := DefaultConfig()
+		cfg.FontFamily = "Courier"
+		stream := newPDFStream(pdf, cfg, mdf.DefaultTheme().Styles(), 80, 7, nil, pdfLayers{})
+		err := mdf.Parse(mdf.ParseRequest{
+			Reader: strings.NewReader("```" + info + "\nfunc f() string { return \"s\" } // c\n```\n"),
+			Stream: stream,
+			Theme:  mdf.DefaultTheme(),
+		})
+		if err != nil {
+			t.Fatalf("parse: %v", err)
+		}
+		var out bytes.Buffer
+		if err := pdf.Output(&out); err != nil {
+			t.Fatalf("output: %v", err)
+		}
+		colors := make(map[string]bool)
+		for _, m := range fillColorRE.FindAll(out.Bytes(), -1) {
+			colors[string(m)] = true
+		}
+		return colors
+	}
+	plain, highlighted := render("text"), render("go")
+	if len(highlighted) <= len(plain) {
+		t.Fatalf("expected highlighted code to use more fill colors: plain=%d highlighted=%d", len(plain), len(highlighted))
+	}
+}
@@ -3,6 +3,8 @@ package pdf
 import (
 	"strconv"
 	"strings"
+
+	"pkt.systems/mdf"
 )
 
 type pdfStyle struct {
@@ -12,14 +14,48 @@ type pdfStyle struct {
 	r          int
 	g          int
 	b          int
+	background bool
+	bg         [3]int
 }
 
 type ansiAttrs struct {
-	bold      bool
-	italic    bool
-	underline bool
-	colorSet  bool
-	color     [3]int
+	bold          bool
+	italic        bool
+	underline     bool
+	strike        bool
+	colorSet      bool
+	color         [3]int
+	backgroundSet bool
+	background    [3]int
+}
+
+// styleAttrs returns the attributes drawn for st. Dim and reverse have no
+// PDF equivalent. A style carrying only an ANSI prefix is parsed from it.
+func styleAttrs(st mdf.Style, defaultColor [3]int) ansiAttrs {
+	if st == (mdf.Style{Prefix: st.Prefix}) {
+		return parseANSIPrefix(st.Prefix, defaultColor)
+	}
+	attrs := ansiAttrs{
+		bold:      st.Bold,
+		italic:    st.Italic,
+		underline: st.Underline,
+		strike:    st.Strikethrough,
+	}
+	attrs.color, attrs.colorSet = colorRGB(st.FG, defaultColor)
+	attrs.background, attrs.backgroundSet = colorRGB(st.BG, [3]int{})
+	return attrs
+}
+
+// colorRGB returns the components of c, or fallback for the default colour.
+// Indexed colours use the PDF palette.
+func colorRGB(c mdf.Color, fallback [3]int) ([3]int, bool) {
+	switch c.Kind {
+	case mdf.ColorIndexed:
+		return xtermColor(int(c.Index)), true
+	case mdf.ColorRGB:
+		return [3]int{int(c.R), int(c.G), int(c.B)}, true
+	}
+	return fallback, false
 }
 
 func parseANSIPrefix(prefix string, defaultColor [3]int) ansiAttrs {
@@ -51,6 +87,7 @@ func parseANSIPrefix(prefix string, defaultColor [3]int) ansiAttrs {
 				attrs.bold = false
 				attrs.italic = false
 				attrs.underline = false
+				attrs.strike = false
 				attrs.colorSet = false
 				attrs.color = defaultColor
 			case n == 1:
@@ -59,6 +96,8 @@ func parseANSIPrefix(prefix string, defaultColor [3]int) ansiAttrs {
 				attrs.italic = true
 			case n == 4:
 				attrs.underline = true
+			case n == 9:
+				attrs.strike = true
 			case n >= 30 && n <= 37:
 				attrs.color = ansiColor(n - 30)
 				attrs.colorSet = true
@@ -73,6 +112,12 @@ func parseANSIPrefix(prefix string, defaultColor [3]int) ansiAttrs {
 						attrs.colorSet = true
 					}
 					i += 2
+				} else if i+4 < len(codes) && codes[i+1] == "2" {
+					if rgb, ok := parseRGB(codes[i+2 : i+5]); ok {
+						attrs.color = rgb
+						attrs.colorSet = true
+					}
+					i += 4
 				}
 			}
 		}
@@ -80,6 +125,18 @@ func parseANSIPrefix(prefix string, defaultColor [3]int) ansiAttrs {
 	return attrs
 }
 
+func parseRGB(codes []string) ([3]int, bool) {
+	var rgb [3]int
+	for i, code := range codes {
+		v, err := strconv.Atoi(code)
+		if err != nil || v < 0 || v > 255 {
+			return rgb, false
+		}
+		rgb[i] = v
+	}
+	return rgb, true
+}
+
 func ansiColor(idx int) [3]int {
 	colors := [16][3]int{
 		{0, 0, 0},
@@ -150,5 +207,8 @@ func styleToFontStyle(attrs ansiAttrs, forceBold bool, allowBoldItalic bool) str
 	if attrs.underline {
 		b.WriteByte('U')
 	}
+	if attrs.strike {
+		b.WriteByte('S')
+	}
 	return b.String()
 }
@@ -23,6 +23,52 @@ func TestParseANSIPrefix(t *testing.T) {
 	}
 }
 
+func TestParseANSIPrefixTruecolor(t *testing.T) {
+	attrs := parseANSIPrefix("\x1b[1;38;2;136;192;208m", [3]int{1, 2, 3})
+	if !attrs.bold || !attrs.colorSet {
+		t.Fatalf("expected bold with a colour, got %+v", attrs)
+	}
+	if want := [3]int{136, 192, 208}; attrs.color != want {
+		t.Fatalf("unexpected color: %+v", attrs.color)
+	}
+}
+
+func TestStyleAttrsStructured(t *testing.T) {
+	attrs := styleAttrs(mdf.Style{
+		Prefix: "\x1b[3;38;2;10;20;30;48;5;4m",
+		Italic: true,
+		FG:     mdf.RGBColor(10, 20, 30),
+		BG:     mdf.IndexedColor(4),
+	}, [3]int{1, 2, 3})
+	if !attrs.italic || attrs.bold {
+		t.Fatalf("unexpected attributes: %+v", attrs)
+	}
+	if attrs.color != [3]int{10, 20, 30} || !attrs.colorSet {
+		t.Fatalf("unexpected color: %+v", attrs.color)
+	}
+	if attrs.background != ansiColor(4) || !attrs.backgroundSet {
+		t.Fatalf("unexpected background: %+v", attrs.background)
+	}
+	plain := styleAttrs(mdf.Style{}, [3]int{1, 2, 3})
+	if plain.colorSet || plain.backgroundSet || plain.color != [3]int{1, 2, 3} {
+		t.Fatalf("expected default colours, got %+v", plain)
+	}
+}
+
+func TestParseANSIPrefixStrikethrough(t *testing.T) {
+	attrs := parseANSIPrefix("\x1b[9m\x1b[34m", [3]int{1, 2, 3})
+	if !attrs.strike {
+		t.Fatalf("expected strikethrough")
+	}
+	if got := styleToFontStyle(attrs, false, false); got != "S" {
+		t.Fatalf("expected strikeout font style, got %q", got)
+	}
+	attrs = parseANSIPrefix("\x1b[9;0m", [3]int{1, 2, 3})
+	if attrs.strike {
+		t.Fatalf("expected reset to clear strikethrough")
+	}
+}
+
 func TestStyleToFontStyle(t *testing.T) {
 	attrs := ansiAttrs{bold: true, italic: true}
 	got := styleToFontStyle(attrs, false, false)
@@ -41,22 +87,22 @@ func TestHeadingScaleApplied(t *testing.T) {
 	s := &pdfStream{
 		cfg:        cfg,
 		styles:     styles,
-		styleCache: make(map[string]pdfStyle),
+		styleCache: make(map[styleKey]pdfStyle),
 	}
-	h1 := s.styleForPrefix(styles.Heading[0].Prefix, 1)
+	h1 := s.styleFor(styles.Heading[0], 1)
 	if h1.size != cfg.FontSize*cfg.HeadingScale[0] {
 		t.Fatalf("unexpected h1 size: got %v want %v", h1.size, cfg.FontSize*cfg.HeadingScale[0])
 	}
 	if h1.fontFamily != cfg.FontFamily {
 		t.Fatalf("unexpected h1 family: got %q want %q", h1.fontFamily, cfg.FontFamily)
 	}
-	h4 := s.styleForPrefix(styles.Heading[3].Prefix, 4)
+	h4 := s.styleFor(styles.Heading[3], 4)
 	if h4.size != cfg.FontSize*cfg.HeadingScale[3] {
 		t.Fatalf("unexpected h4 size: got %v want %v", h4.size, cfg.FontSize*cfg.HeadingScale[3])
 	}
 	cfg.HeadingFont = "/tmp/heading.ttf"
 	s.cfg = cfg
-	h2 := s.styleForPrefix(styles.Heading[1].Prefix, 2)
+	h2 := s.styleFor(styles.Heading[1], 2)
 	if h2.fontFamily != headingFontFamily {
 		t.Fatalf("unexpected heading font family: got %q want %q", h2.fontFamily, headingFontFamily)
 	}
@@ -0,0 +1,68 @@
+package png
+
+import "pkt.systems/mdf"
+
+// Config holds PNG rendering settings. Sizes are in pixels before Scale is
+// applied.
+type Config struct {
+	// Width is the terminal width in columns the document is rendered at.
+	Width    int
+	FontSize float64
+	// CellWidth and CellHeight default to the advance of the font and 1.4
+	// times the font size.
+	CellWidth  int
+	CellHeight int
+	// Scale multiplies every size, so 2 gives a sharp image for high
+	// density displays.
+	Scale   float64
+	Padding int
+	// BackgroundRGB and TextRGB default to colours suiting the theme: light
+	// for built-in light themes, dark otherwise.
+	BackgroundRGB [3]int
+	TextRGB       [3]int
+	HTMLPolicy    mdf.HTMLPolicy
+	ImageBaseDir  string
+}
+
+// DefaultConfig returns a baseline configuration.
+func DefaultConfig() Config {
+	return Config{
+		Width:    80,
+		FontSize: 14,
+		Scale:    1,
+		Padding:  16,
+	}
+}
+
+func applyConfig(dst *Config, src Config) {
+	if src.Width > 0 {
+		dst.Width = src.Width
+	}
+	if src.FontSize > 0 {
+		dst.FontSize = src.FontSize
+	}
+	if src.CellWidth > 0 {
+		dst.CellWidth = src.CellWidth
+	}
+	if src.CellHeight > 0 {
+		dst.CellHeight = src.CellHeight
+	}
+	if src.Scale > 0 {
+		dst.Scale = src.Scale
+	}
+	if src.Padding > 0 {
+		dst.Padding = src.Padding
+	}
+	if src.BackgroundRGB != [3]int{} {
+		dst.BackgroundRGB = src.BackgroundRGB
+	}
+	if src.TextRGB != [3]int{} {
+		dst.TextRGB = src.TextRGB
+	}
+	if src.HTMLPolicy != mdf.HTMLAsText {
+		dst.HTMLPolicy = src.HTMLPolicy
+	}
+	if src.ImageBaseDir != "" {
+		dst.ImageBaseDir = src.ImageBaseDir
+	}
+}
@@ -0,0 +1,20 @@
+// Package png renders Markdown to a PNG image of the terminal output.
+//
+// The document is rendered by the mdf ANSI renderer at Config.Width
+// columns, and the result is rasterised on a cell grid with the Hack fonts
+// embedded in the pdf package, so no external tools or system fonts are
+// needed. SGR colours and attributes are drawn as the terminal would show
+// them.
+//
+// Example:
+//
+//	err := png.Render(png.RenderRequest{
+//		Reader: strings.NewReader("# Report\n\nHello PNG.\n"),
+//		Writer: outFile,
+//		Theme:  mdf.DefaultTheme(),
+//		Config: png.Config{Width: 80, Scale: 2},
+//	})
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+package png
@@ -0,0 +1,95 @@
+package png
+
+import (
+	"fmt"
+	"sync"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+	"pkt.systems/mdf/pdf"
+)
+
+var (
+	fontsOnce sync.Once
+	fonts     [4]*opentype.Font
+	fontsErr  error
+)
+
+// loadFonts parses the embedded Hack fonts once, in the order regular,
+// bold, italic, bold italic.
+func loadFonts() ([4]*opentype.Font, error) {
+	fontsOnce.Do(func() {
+		regular, bold, italic, boldItalic, err := pdf.EmbeddedHackFonts()
+		if err != nil {
+			fontsErr = err
+			return
+		}
+		for i, data := range [][]byte{regular, bold, italic, boldItalic} {
+			if fonts[i], err = opentype.Parse(data); err != nil {
+				fontsErr = fmt.Errorf("parse font: %w", err)
+				return
+			}
+		}
+	})
+	return fonts, fontsErr
+}
+
+// faces holds the four styles of the font at one size.
+type faces [4]font.Face
+
+func newFaces(size float64) (faces, error) {
+	fts, err := loadFonts()
+	if err != nil {
+		return faces{}, err
+	}
+	var f faces
+	for i, ft := range fts {
+		face, err := opentype.NewFace(ft, &opentype.FaceOptions{Size: size, DPI: 72, Hinting: font.HintingFull})
+		if err != nil {
+			f.Close()
+			return faces{}, fmt.Errorf("font face: %w", err)
+		}
+		f[i] = face
+	}
+	return f, nil
+}
+
+func (f faces) pick(bold, italic bool) font.Face {
+	i := 0
+	if bold {
+		i |= 1
+	}
+	if italic {
+		i |= 2
+	}
+	return f[i]
+}
+
+// advance returns the unhinted width of a cell in the regular font at size
+// pixels.
+func advance(size float64) (float64, error) {
+	fts, err := loadFonts()
+	if err != nil {
+		return 0, err
+	}
+	var buf sfnt.Buffer
+	idx, err := fts[0].GlyphIndex(&buf, 'M')
+	if err != nil {
+		return 0, fmt.Errorf("font advance: %w", err)
+	}
+	adv, err := fts[0].GlyphAdvance(&buf, idx, fixed.Int26_6(size*64), font.HintingNone)
+	if err != nil {
+		return 0, fmt.Errorf("font advance: %w", err)
+	}
+	return float64(adv) / 64, nil
+}
+
+func (f faces) Close() {
+	for _, face := range f {
+		if face != nil {
+			face.Close()
+		}
+	}
+}
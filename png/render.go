@@ -0,0 +1,191 @@
+package png
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	imagepng "image/png"
+	"io"
+	"math"
+
+	"github.com/muesli/reflow/ansi"
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+	"pkt.systems/mdf"
+	"pkt.systems/mdf/internal/screen"
+)
+
+// RenderRequest contains inputs for PNG rendering.
+type RenderRequest struct {
+	Reader io.Reader
+	Writer io.Writer
+	Theme  mdf.Theme
+	Config Config
+}
+
+// Render converts Markdown to a PNG image of what the terminal renderer
+// shows at Config.Width columns.
+func Render(req RenderRequest) error {
+	return RenderContext(context.Background(), req)
+}
+
+// RenderContext is Render with cancellation. Once ctx is done it stops
+// reading and returns ctx.Err() without writing an image.
+func RenderContext(ctx context.Context, req RenderRequest) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if req.Reader == nil {
+		return fmt.Errorf("png render: reader is nil")
+	}
+	if req.Writer == nil {
+		return fmt.Errorf("png render: writer is nil")
+	}
+	cfg := DefaultConfig()
+	applyConfig(&cfg, req.Config)
+	theme := req.Theme
+	if theme == nil {
+		theme = mdf.DefaultTheme()
+	}
+	if cfg.BackgroundRGB == [3]int{} && cfg.TextRGB == [3]int{} {
+		cfg.BackgroundRGB, cfg.TextRGB = screen.Colors(theme)
+	}
+	fc, err := newFaces(cfg.FontSize * cfg.Scale)
+	if err != nil {
+		return fmt.Errorf("png render: %w", err)
+	}
+	defer fc.Close()
+	g, err := newGrid(cfg)
+	if err != nil {
+		return fmt.Errorf("png render: %w", err)
+	}
+	sc, err := screen.Render(ctx, req.Reader, theme, cfg.Width,
+		mdf.WithHTMLPolicy(cfg.HTMLPolicy),
+		mdf.WithImageBaseDir(cfg.ImageBaseDir))
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil && err == ctxErr {
+			return err
+		}
+		return fmt.Errorf("png render: %w", err)
+	}
+	var b bytes.Buffer
+	if err := imagepng.Encode(&b, rasterize(sc, fc, g, cfg)); err != nil {
+		return fmt.Errorf("png render: encode: %w", err)
+	}
+	if _, err := req.Writer.Write(b.Bytes()); err != nil {
+		return fmt.Errorf("png render: output: %w", err)
+	}
+	return nil
+}
+
+// grid is the pixel geometry of a render.
+type grid struct {
+	cell, row, padding, line int
+}
+
+func newGrid(cfg Config) (grid, error) {
+	g := grid{
+		cell:    scaled(cfg.CellWidth, cfg.Scale),
+		row:     scaled(cfg.CellHeight, cfg.Scale),
+		padding: scaled(cfg.Padding, cfg.Scale),
+		line:    max(1, int(math.Round(cfg.Scale))),
+	}
+	// Derived sizes are rounded before scaling so that a scaled image is
+	// the unscaled one enlarged.
+	if g.cell == 0 {
+		adv, err := advance(cfg.FontSize)
+		if err != nil {
+			return grid{}, err
+		}
+		g.cell = scaled(max(1, int(math.Round(adv))), cfg.Scale)
+	}
+	if g.row == 0 {
+		g.row = scaled(int(math.Ceil(cfg.FontSize*1.4)), cfg.Scale)
+	}
+	return g, nil
+}
+
+func scaled(v int, scale float64) int {
+	return int(math.Round(float64(v) * scale))
+}
+
+func rasterize(sc screen.Screen, fc faces, g grid, cfg Config) *image.RGBA {
+	cols := max(cfg.Width, sc.Cols)
+	img := image.NewRGBA(image.Rect(0, 0, 2*g.padding+cols*g.cell, 2*g.padding+len(sc.Lines)*g.row))
+	bg, fg := rgba(cfg.BackgroundRGB), rgba(cfg.TextRGB)
+	fill(img, img.Bounds(), bg)
+	// The baseline sits so the text is centred in its row.
+	m := fc[0].Metrics()
+	baseline := (g.row-(m.Ascent+m.Descent).Ceil())/2 + m.Ascent.Ceil()
+	for i, line := range sc.Lines {
+		y := g.padding + i*g.row
+		for _, s := range line {
+			x := g.padding + s.Col*g.cell
+			textColor, spanBG := spanColors(s.Style, fg, bg)
+			if spanBG != bg {
+				fill(img, image.Rect(x, y, x+s.Width*g.cell, y+g.row), spanBG)
+			}
+			if s.Style.Dim {
+				textColor = mix(textColor, spanBG, 0.3)
+			}
+			d := font.Drawer{Dst: img, Src: image.NewUniform(textColor), Face: fc.pick(s.Style.Bold, s.Style.Italic)}
+			col := x
+			for _, r := range s.Text {
+				if r != ' ' {
+					d.Dot = fixed.P(col, y+baseline)
+					d.DrawString(string(r))
+				}
+				col += ansi.PrintableRuneWidth(string(r)) * g.cell
+			}
+			if s.Style.Underline {
+				fill(img, image.Rect(x, y+baseline+g.line, x+s.Width*g.cell, y+baseline+2*g.line), textColor)
+			}
+			if s.Style.Strikethrough {
+				mid := y + baseline - m.XHeight.Ceil()/2
+				fill(img, image.Rect(x, mid, x+s.Width*g.cell, mid+g.line), textColor)
+			}
+		}
+	}
+	return img
+}
+
+// spanColors returns the text and background colour of a span. Reverse
+// video swaps them.
+func spanColors(st mdf.Style, fg, bg color.RGBA) (text, back color.RGBA) {
+	text, back = colorOr(st.FG, fg), colorOr(st.BG, bg)
+	if st.Reverse {
+		text, back = back, text
+	}
+	return text, back
+}
+
+func colorOr(c mdf.Color, fallback color.RGBA) color.RGBA {
+	r, g, b, ok := c.RGB()
+	if !ok {
+		return fallback
+	}
+	return color.RGBA{r, g, b, 0xff}
+}
+
+func rgba(rgb [3]int) color.RGBA {
+	return color.RGBA{clamp(rgb[0]), clamp(rgb[1]), clamp(rgb[2]), 0xff}
+}
+
+func clamp(v int) uint8 {
+	return uint8(min(max(v, 0), 255))
+}
+
+// mix returns c moved toward to by the fraction t.
+func mix(c, to color.RGBA, t float64) color.RGBA {
+	lerp := func(a, b uint8) uint8 {
+		return uint8(math.Round(float64(a) + (float64(b)-float64(a))*t))
+	}
+	return color.RGBA{lerp(c.R, to.R), lerp(c.G, to.G), lerp(c.B, to.B), 0xff}
+}
+
+func fill(img *image.RGBA, r image.Rectangle, c color.RGBA) {
+	draw.Draw(img, r, image.NewUniform(c), image.Point{}, draw.Src)
+}
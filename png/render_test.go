@@ -0,0 +1,78 @@
+package png
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	imagepng "image/png"
+	"strings"
+	"testing"
+
+	"pkt.systems/mdf"
+)
+
+func renderImage(t *testing.T, src string, theme mdf.Theme, cfg Config) image.Image {
+	t.Helper()
+	var out bytes.Buffer
+	if err := Render(RenderRequest{Reader: strings.NewReader(src), Writer: &out, Theme: theme, Config: cfg}); err != nil {
+		t.Fatalf("render: %v", err)
+	}
+	img, err := imagepng.Decode(&out)
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	return img
+}
+
+func rgbAt(img image.Image, x, y int) [3]int {
+	c := color.RGBAModel.Convert(img.At(x, y)).(color.RGBA)
+	return [3]int{int(c.R), int(c.G), int(c.B)}
+}
+
+func TestRenderPNGGeometry(t *testing.T) {
+	img := renderImage(t, "# Title\n\nhello\n", mdf.DefaultTheme(), Config{Width: 20, CellWidth: 10, CellHeight: 20, Padding: 4})
+	if got, want := img.Bounds().Size(), image.Pt(2*4+20*10, 2*4+3*20); got != want {
+		t.Fatalf("size = %v, want %v", got, want)
+	}
+	if got := rgbAt(img, 0, 0); got != [3]int{0, 0, 0} {
+		t.Fatalf("background = %v, want black", got)
+	}
+	// The heading is drawn in the theme's green.
+	green := false
+	for y := 4; y < 24; y++ {
+		for x := 4; x < 24; x++ {
+			if rgbAt(img, x, y) == [3]int{0, 0xcd, 0} {
+				green = true
+			}
+		}
+	}
+	if !green {
+		t.Fatal("heading colour not drawn")
+	}
+}
+
+func TestRenderPNGScaleAndLightTheme(t *testing.T) {
+	theme, _ := mdf.ThemeByName("github-light")
+	one := renderImage(t, "hi\n", theme, Config{Width: 10})
+	two := renderImage(t, "hi\n", theme, Config{Width: 10, Scale: 2})
+	if got, want := two.Bounds().Dx(), 2*one.Bounds().Dx(); got != want {
+		t.Fatalf("scaled width = %d, want %d", got, want)
+	}
+	if got := rgbAt(two, 0, 0); got != [3]int{250, 250, 250} {
+		t.Fatalf("background = %v, want light", got)
+	}
+}
+
+func TestRenderPNGCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	var out bytes.Buffer
+	err := RenderContext(ctx, RenderRequest{Reader: strings.NewReader("hi\n"), Writer: &out})
+	if err != context.Canceled {
+		t.Fatalf("err = %v, want context.Canceled", err)
+	}
+	if out.Len() != 0 {
+		t.Fatalf("wrote %d bytes after cancellation", out.Len())
+	}
+}
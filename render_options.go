@@ -4,10 +4,29 @@ package mdf
 type RenderOption func(*renderConfig)
 
 type renderConfig struct {
-	osc8     bool
-	softWrap bool
+	osc8       bool
+	softWrap   bool
+	htmlPolicy HTMLPolicy
+	// speculative shows open code spans and links before they resolve.
+	speculative bool
+
+	imageBaseDir string
 }
 
+// HTMLPolicy controls how raw HTML in Markdown is rendered.
+type HTMLPolicy uint8
+
+const (
+	// HTMLAsText renders raw HTML as ordinary paragraph text.
+	HTMLAsText HTMLPolicy = iota
+	// HTMLStrip drops HTML blocks, comments and tags, keeping inline text.
+	HTMLStrip
+	// HTMLAsCode renders raw HTML styled as code.
+	HTMLAsCode
+	// HTMLInterpret renders a safe subset of tags and strips the rest.
+	HTMLInterpret
+)
+
 // WithOSC8 enables or disables OSC 8 hyperlinks.
 func WithOSC8(enabled bool) RenderOption {
 	return func(cfg *renderConfig) {
@@ -21,3 +40,31 @@ func WithSoftWrap(enabled bool) RenderOption {
 		cfg.softWrap = enabled
 	}
 }
+
+// WithHTMLPolicy selects how raw HTML is rendered.
+func WithHTMLPolicy(policy HTMLPolicy) RenderOption {
+	return func(cfg *renderConfig) {
+		cfg.htmlPolicy = policy
+	}
+}
+
+// WithImageBaseDir resolves relative image sources against dir, typically the
+// directory of the Markdown file being rendered.
+func WithImageBaseDir(dir string) RenderOption {
+	return func(cfg *renderConfig) {
+		cfg.imageBaseDir = dir
+	}
+}
+
+// WithSpeculativeInline shows the text of a code span or link as it arrives,
+// before the closing delimiter confirms it, and repaints it with cursor
+// movement once the construct resolves, whether styled or literal. Emphasis
+// needs no speculation since the rune after a delimiter run decides it. A
+// paragraph line holding a code span or link is shown at once rather than
+// held for a setext underline, so such a line does not make a heading. Only
+// use it when the output is a terminal.
+func WithSpeculativeInline(enabled bool) RenderOption {
+	return func(cfg *renderConfig) {
+		cfg.speculative = enabled
+	}
+}
@@ -6,6 +6,8 @@ import (
 	"regexp"
 	"strings"
 	"testing"
+
+	"pkt.systems/mdf/internal/palette"
 )
 
 func TestHeadingsIncludeMarkers(t *testing.T) {
@@ -102,6 +104,96 @@ func TestBlankLineBetweenListAndHeading(t *testing.T) {
 	}
 }
 
+func TestIntrawordUnderscoresStayLiteral(t *testing.T) {
+	src := []byte("call snake_case_name or __init__ with _em_\n")
+	out := renderStream(t, src, 0)
+	if got := stripANSI(out); got != "call snake_case_name or init with em\n" {
+		t.Fatalf("unexpected text %q", got)
+	}
+	if !strings.Contains(out, palette.PaletteDefault.Emphasis+"e") {
+		t.Fatalf("expected emphasis for _em_, got %q", out)
+	}
+}
+
+func TestIntrawordUnderscoresStayLiteralInLinkText(t *testing.T) {
+	out := stripANSI(renderStream(t, []byte("see [snake_case_name](http://e.com)\n"), 0))
+	if out != "see snake_case_name (http://e.com)\n" {
+		t.Fatalf("unexpected text %q", out)
+	}
+}
+
+func TestBackslashEscapes(t *testing.T) {
+	cases := []struct {
+		src  string
+		want string
+	}{
+		{src: "\\* not a list\n", want: "* not a list\n"},
+		{src: "a \\*star\\* and \\_under\\_\n", want: "a *star* and _under_\n"},
+		{src: "\\[not a link\\](x) and \\`tick\\`\n", want: "[not a link](x) and `tick`\n"},
+		{src: "a \\q b \\\\ c\n", want: "a \\q b \\ c\n"},
+		{src: "`a\\*b`\n", want: "a\\*b\n"},
+		{src: "[a\\]b](http://e.com)\n", want: "a]b (http://e.com)\n"},
+		{src: "line\\\nbreak\n", want: "line\nbreak\n"},
+		{src: "end\\", want: "end\\\n"},
+		{src: "C:\\dir\\\n\nnext\n", want: "C:\\dir\\\n\nnext\n"},
+	}
+	for _, tc := range cases {
+		out := stripANSI(renderStream(t, []byte(tc.src), 0))
+		if out != tc.want {
+			t.Fatalf("render %q: got %q want %q", tc.src, out, tc.want)
+		}
+	}
+	out := renderStream(t, []byte("\\*\\*not strong\\*\\*\n"), 0)
+	if strings.Contains(out, palette.PaletteDefault.Strong) {
+		t.Fatalf("escaped delimiters opened strong text: %q", out)
+	}
+}
+
+func TestOpenBracketsEndWithTheirParagraph(t *testing.T) {
+	cases := []struct {
+		src  string
+		want string
+	}{
+		{src: "a [foo\n\nnext\n", want: "a [foo\n\nnext\n"},
+		{src: "a [foo](bar\n\nnext\n", want: "a [foo](bar\n\nnext\n"},
+		{src: "a <foo\n\nnext\n", want: "a <foo\n\nnext\n"},
+		{src: "a [ref]\n# h\n", want: "a [ref]\n\n# h\n"},
+		{src: "a `` b\n\nnext\n", want: "a `` b\n\nnext\n"},
+		{src: "a `open", want: "a `open\n"},
+	}
+	for _, tc := range cases {
+		out := stripANSI(renderStream(t, []byte(tc.src), 0))
+		if out != tc.want {
+			t.Fatalf("render %q: got %q want %q", tc.src, out, tc.want)
+		}
+	}
+}
+
+func TestShortcutReferenceLinks(t *testing.T) {
+	src := []byte("[Go]: https://go.dev\n\nSee [Go] and [go][] but not [Rust].\n")
+	out := stripANSI(renderStream(t, src, 0))
+	want := "See Go (https://go.dev) and go (https://go.dev) but not [Rust].\n"
+	if out != want {
+		t.Fatalf("got %q want %q", out, want)
+	}
+}
+
+func TestSoftBreakInsideBrackets(t *testing.T) {
+	src := []byte("see [Effective\nGo][x] and <span\nclass=\"n\">\n")
+	out := stripANSI(renderStream(t, src, 0))
+	if out != "see [Effective Go][x] and <span class=\"n\">\n" {
+		t.Fatalf("unexpected text %q", out)
+	}
+}
+
+func TestFencedCodeInListItemDropsItemIndent(t *testing.T) {
+	src := []byte("- a\n\n  ```\n  if x {\n      y\n  }\n  ```\n")
+	out := stripANSI(renderStream(t, src, 0))
+	if out != "- a\n\n  if x {\n      y\n  }\n" {
+		t.Fatalf("unexpected text %q", out)
+	}
+}
+
 func TestAgentsNestedListIndentation(t *testing.T) {
 	src := readAgents(t)
 	out := stripANSI(renderStream(t, src, 60))
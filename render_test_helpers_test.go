@@ -2,6 +2,7 @@ package mdf
 
 import (
 	"os"
+	"strings"
 	"testing"
 )
 
@@ -10,6 +11,28 @@ func renderStream(t *testing.T, src []byte, width int) string {
 	return renderStreamWithOptions(t, src, width, WithOSC8(false))
 }
 
+// assertByteByByteMatches renders src one byte per read and fails unless the
+// output matches rendering it whole. It returns the whole-input output.
+func assertByteByByteMatches(t *testing.T, src string, width int, opts ...RenderOption) string {
+	t.Helper()
+	want := renderStreamWithOptions(t, []byte(src), width, opts...)
+	var out strings.Builder
+	err := Render(RenderRequest{
+		Reader:  &oneByteReader{data: []byte(src)},
+		Writer:  &out,
+		Width:   width,
+		Theme:   DefaultTheme(),
+		Options: opts,
+	})
+	if err != nil {
+		t.Fatalf("stream live: %v", err)
+	}
+	if out.String() != want {
+		t.Fatalf("chunked output mismatch\n---want---\n%q\n---got---\n%q", want, out.String())
+	}
+	return want
+}
+
 func readAgents(t *testing.T) []byte {
 	t.Helper()
 	data, err := os.ReadFile("testdata/agents.md")
@@ -22,6 +22,7 @@ type StreamRenderer struct {
 	w                 io.Writer
 	width             int
 	osc8              bool
+	internalLink      bool
 	softWrap          bool
 	lineWidth         int
 	style             string
@@ -50,6 +51,9 @@ type StreamRenderer struct {
 
 	wordRunes  []rune
 	carryRunes []rune
+
+	spec    speculation
+	counter screenCounter
 }
 
 // NewStreamRenderer creates a streaming renderer.
@@ -67,7 +71,7 @@ func NewStreamRenderer(w io.Writer, width int, opts ...RenderOption) *StreamRend
 
 // Reset clears stream state for reuse with a new writer or width.
 func (s *StreamRenderer) Reset(w io.Writer, width int) {
-	cfg := renderConfig{osc8: s.osc8, softWrap: s.softWrap}
+	cfg := renderConfig{osc8: s.osc8, softWrap: s.softWrap, speculative: s.spec.enabled}
 	s.resetWithConfig(w, width, cfg)
 }
 
@@ -76,6 +80,7 @@ func (s *StreamRenderer) resetWithConfig(w io.Writer, width int, cfg renderConfi
 	s.w = w
 	s.width = width
 	s.osc8 = cfg.osc8
+	s.internalLink = false
 	s.softWrap = cfg.softWrap
 	s.lineWidth = 0
 	s.style = ""
@@ -94,6 +99,11 @@ func (s *StreamRenderer) resetWithConfig(w io.Writer, width int, cfg renderConfi
 	s.codeFlushPending = false
 	s.nbspBuf = s.nbspBufArr[:0]
 	s.punctQuotePending = false
+	s.spec.reset(cfg.speculative)
+	if cfg.speculative {
+		s.counter = screenCounter{w: w}
+		s.w = &s.counter
+	}
 }
 
 func (s *StreamRenderer) initBuffers() {
@@ -137,16 +147,27 @@ func (s *StreamRenderer) Width() int {
 
 // SetWidth updates the wrap width.
 func (s *StreamRenderer) SetWidth(width int) {
+	_ = s.rewindSpeculation()
 	s.width = width
 }
 
 // SetWrapIndent updates the wrap indentation for continued lines.
 func (s *StreamRenderer) SetWrapIndent(indent string) {
+	_ = s.rewindSpeculation()
 	s.setWrapIndent(indent)
 }
 
 // WriteToken writes a single inference token, honoring its delay.
 func (s *StreamRenderer) WriteToken(tok StreamToken) error {
+	if s.spec.active && writesOutput(tok) {
+		if err := s.rewindSpeculation(); err != nil {
+			return err
+		}
+	}
+	return s.writeToken(tok)
+}
+
+func (s *StreamRenderer) writeToken(tok StreamToken) error {
 	if tok.Kind == tokenLinkStart || tok.Kind == tokenLinkEnd {
 		return s.writeLinkToken(tok)
 	}
@@ -159,6 +180,10 @@ func (s *StreamRenderer) WriteToken(tok StreamToken) error {
 		}
 		return nil
 	}
+	if tok.Kind == tokenImage {
+		tok.Text = imageMarker + tok.Text
+		tok.Kind = tokenText
+	}
 	if tok.Text == "" {
 		return nil
 	}
@@ -225,6 +250,9 @@ func (s *StreamRenderer) WriteToken(tok StreamToken) error {
 
 // Flush resets the style at the end of a stream.
 func (s *StreamRenderer) Flush() error {
+	if err := s.rewindSpeculation(); err != nil {
+		return err
+	}
 	if len(s.nbspBuf) > 0 {
 		s.flushNBSPBuf()
 	}
@@ -693,12 +721,21 @@ func (s *StreamRenderer) writeLinkToken(tok StreamToken) error {
 	if !s.osc8 {
 		return nil
 	}
+	// Links to in-document anchors have no target a terminal can open.
+	if tok.Kind == tokenLinkStart && strings.HasPrefix(tok.LinkURL, "#") {
+		s.internalLink = true
+		return nil
+	}
+	if tok.Kind == tokenLinkEnd && s.internalLink {
+		s.internalLink = false
+		return nil
+	}
 	if tok.Delay > 0 {
 		time.Sleep(tok.Delay)
 	}
 	if tok.Kind == tokenLinkStart {
 		if tok.LinkURL != "" {
-			_, err := io.WriteString(s.w, osc8Start+tok.LinkURL+"\x1b\\")
+			_, err := io.WriteString(s.w, osc8Start+linkHref(tok.LinkURL)+"\x1b\\")
 			return err
 		}
 		return nil
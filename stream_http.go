@@ -47,7 +47,7 @@ func HTTPRender(ctx context.Context, req HTTPRenderRequest) error {
 	if resp.StatusCode < 200 || resp.StatusCode > 299 {
 		return fmt.Errorf("stream http: status %s", resp.Status)
 	}
-	return Render(RenderRequest{
+	return RenderContext(ctx, RenderRequest{
 		Reader:  resp.Body,
 		Writer:  req.Writer,
 		Width:   req.Width,
@@ -2,9 +2,11 @@ package mdf
 
 import (
 	"bufio"
+	"context"
 	"fmt"
 	"io"
 	"sync"
+	"time"
 	"unicode/utf8"
 )
 
@@ -51,6 +53,13 @@ type ParseRequest struct {
 
 // Render renders Markdown from a stream.
 func Render(req RenderRequest) error {
+	return RenderContext(context.Background(), req)
+}
+
+// RenderContext is Render with cancellation. Once ctx is done it stops
+// reading, flushes what has been parsed, resets the terminal style and
+// returns ctx.Err().
+func RenderContext(ctx context.Context, req RenderRequest) error {
 	if req.Reader == nil {
 		return fmt.Errorf("render: reader is nil")
 	}
@@ -68,7 +77,7 @@ func Render(req RenderRequest) error {
 	configPool.Put(cfg)
 	stream := streamRendererPool.Get().(*StreamRenderer)
 	stream.resetWithConfig(req.Writer, req.Width, cfgVal)
-	err := Parse(ParseRequest{
+	err := ParseContext(ctx, ParseRequest{
 		Reader:  req.Reader,
 		Stream:  stream,
 		Theme:   req.Theme,
@@ -81,6 +90,18 @@ func Render(req RenderRequest) error {
 
 // Parse parses Markdown from a stream and writes tokens to a sink.
 func Parse(req ParseRequest) error {
+	return ParseContext(context.Background(), req)
+}
+
+// ParseContext is Parse with cancellation. Once ctx is done it stops reading,
+// finalizes the partial document, flushes the stream and returns ctx.Err().
+// Reads run in their own goroutine, so a Read blocked on a reader without
+// deadline support, such as a terminal, does not delay cancellation; input
+// that arrives after ctx is done is dropped.
+func ParseContext(ctx context.Context, req ParseRequest) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
 	if req.Reader == nil {
 		return fmt.Errorf("parse: reader is nil")
 	}
@@ -102,54 +123,32 @@ func Parse(req ParseRequest) error {
 	}
 	parser := parserPool.Get().(*liveParser)
 	reader := readerPool.Get().(*bufio.Reader)
-	parser.Reset(theme, cfgVal.osc8)
-	reader.Reset(req.Reader)
+	parser.resetWithConfig(theme, cfgVal)
+	if ctx.Done() != nil {
+		reader.Reset(&ctxReader{ctx: ctx, r: req.Reader})
+	} else {
+		reader.Reset(req.Reader)
+	}
 	buf := parser.readBufArr[:]
-	var tailBuf [utf8.UTFMax]byte
-	tailLen := 0
-	var cleanBuf [4096]byte
+	feed := feeder{parser: parser, stream: req.Stream}
 	var retErr error
+	var canceled error
+	stopInterrupt := interruptRead(ctx, req.Reader)
+	defer stopInterrupt()
 	for {
+		if err := ctx.Err(); err != nil {
+			canceled = err
+			break
+		}
 		n, err := reader.Read(buf[:])
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			canceled = ctxErr
+			break
+		}
 		if n > 0 {
-			chunk := buf[:n]
-			if tailLen > 0 {
-				need := utf8.UTFMax - tailLen
-				if need > len(chunk) {
-					need = len(chunk)
-				}
-				var smallBuf [utf8.UTFMax * 2]byte
-				combined := smallBuf[:tailLen+need]
-				copy(combined, tailBuf[:tailLen])
-				copy(combined[tailLen:], chunk[:need])
-				var smallOut [utf8.UTFMax * 2]byte
-				clean, rest := sanitizeBytes(smallOut[:], combined)
-				if len(clean) > 0 {
-					filtered := parser.frontMatter.process(clean)
-					if len(filtered) > 0 {
-						err := parser.feedBytes(req.Stream, filtered)
-						if err != nil {
-							retErr = fmt.Errorf("parse: %w", err)
-							goto done
-						}
-					}
-				}
-				tailLen = copy(tailBuf[:], rest)
-				chunk = chunk[need:]
-			}
-			if len(chunk) > 0 {
-				clean, rest := sanitizeBytes(cleanBuf[:len(chunk)], chunk)
-				if len(clean) > 0 {
-					filtered := parser.frontMatter.process(clean)
-					if len(filtered) > 0 {
-						err := parser.feedBytes(req.Stream, filtered)
-						if err != nil {
-							retErr = fmt.Errorf("parse: %w", err)
-							goto done
-						}
-					}
-				}
-				tailLen = copy(tailBuf[:], rest)
+			if err := feed.write(buf[:n]); err != nil {
+				retErr = err
+				goto done
 			}
 		}
 		if err != nil {
@@ -160,22 +159,99 @@ func Parse(req ParseRequest) error {
 			goto done
 		}
 	}
-	if trailing := parser.frontMatter.finish(); len(trailing) > 0 {
-		if err := parser.feedBytes(req.Stream, trailing); err != nil {
-			retErr = fmt.Errorf("parse: %w", err)
-			goto done
-		}
-	}
-	parser.finalize(req.Stream)
-	if err := req.Stream.Flush(); err != nil {
+	if err := feed.finish(); err != nil {
 		retErr = err
 	}
+	if canceled != nil {
+		retErr = canceled
+	}
 done:
 	parserPool.Put(parser)
 	readerPool.Put(reader)
 	return retErr
 }
 
+// resetWithConfig prepares a pooled parser for a new document.
+func (p *liveParser) resetWithConfig(theme Theme, cfg renderConfig) {
+	p.Reset(theme, cfg.osc8)
+	p.html.policy = cfg.htmlPolicy
+	p.imageBaseDir = cfg.imageBaseDir
+	p.speculative = cfg.speculative
+}
+
+// feeder runs raw input through the validator and the front matter filter
+// into a parser. Input may be split anywhere, including inside a UTF-8
+// sequence.
+type feeder struct {
+	parser  *liveParser
+	stream  Stream
+	tail    [utf8.UTFMax]byte
+	tailLen int
+	clean   [4096]byte
+}
+
+// write feeds data to the parser.
+func (f *feeder) write(data []byte) error {
+	for len(data) > 0 {
+		chunk := data
+		if len(chunk) > len(f.clean) {
+			chunk = chunk[:len(f.clean)]
+		}
+		data = data[len(chunk):]
+		if f.tailLen > 0 {
+			need := utf8.UTFMax - f.tailLen
+			if need > len(chunk) {
+				need = len(chunk)
+			}
+			var smallBuf [utf8.UTFMax * 2]byte
+			combined := smallBuf[:f.tailLen+need]
+			copy(combined, f.tail[:f.tailLen])
+			copy(combined[f.tailLen:], chunk[:need])
+			var smallOut [utf8.UTFMax * 2]byte
+			clean, rest := sanitizeBytes(smallOut[:], combined)
+			if err := f.feed(clean); err != nil {
+				return err
+			}
+			f.tailLen = copy(f.tail[:], rest)
+			chunk = chunk[need:]
+		}
+		if len(chunk) > 0 {
+			clean, rest := sanitizeBytes(f.clean[:len(chunk)], chunk)
+			if err := f.feed(clean); err != nil {
+				return err
+			}
+			f.tailLen = copy(f.tail[:], rest)
+		}
+	}
+	return nil
+}
+
+func (f *feeder) feed(clean []byte) error {
+	if len(clean) == 0 {
+		return nil
+	}
+	filtered := f.parser.frontMatter.process(clean)
+	if len(filtered) == 0 {
+		return nil
+	}
+	if err := f.parser.feedBytes(f.stream, filtered); err != nil {
+		return fmt.Errorf("parse: %w", err)
+	}
+	return nil
+}
+
+// finish feeds any front matter held back at the end of input, finalizes the
+// document and flushes the stream.
+func (f *feeder) finish() error {
+	if trailing := f.parser.frontMatter.finish(); len(trailing) > 0 {
+		if err := f.parser.feedBytes(f.stream, trailing); err != nil {
+			return fmt.Errorf("parse: %w", err)
+		}
+	}
+	f.parser.finalize(f.stream)
+	return f.stream.Flush()
+}
+
 func (p *liveParser) feedBytes(stream Stream, data []byte) error {
 	for len(data) > 0 {
 		r, size := utf8.DecodeRune(data)
@@ -194,3 +270,67 @@ func (p *liveParser) feedBytes(stream Stream, data []byte) error {
 	}
 	return nil
 }
+
+// readDeadliner is implemented by readers whose blocking reads can be
+// interrupted, such as *os.File pipes and net.Conn.
+type readDeadliner interface {
+	SetReadDeadline(t time.Time) error
+}
+
+// interruptRead unblocks a pending Read on r once ctx is done. The returned
+// function stops watching ctx and clears any deadline it set.
+func interruptRead(ctx context.Context, r io.Reader) func() {
+	d, ok := r.(readDeadliner)
+	if !ok || ctx.Done() == nil {
+		return func() {}
+	}
+	fired := make(chan struct{})
+	stop := context.AfterFunc(ctx, func() {
+		_ = d.SetReadDeadline(time.Unix(1, 0))
+		close(fired)
+	})
+	return func() {
+		if !stop() {
+			<-fired
+			_ = d.SetReadDeadline(time.Time{})
+		}
+	}
+}
+
+type readResult struct {
+	n   int
+	err error
+}
+
+// ctxReader runs each Read on r in a goroutine and returns ctx.Err() as soon
+// as ctx is done, leaving the blocked Read behind. Reads go through its own
+// buffer so an abandoned Read never writes into the caller's.
+type ctxReader struct {
+	ctx     context.Context
+	r       io.Reader
+	buf     []byte
+	results chan readResult
+}
+
+func (c *ctxReader) Read(p []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+	if cap(c.buf) < len(p) {
+		c.buf = make([]byte, len(p))
+	}
+	if c.results == nil {
+		c.results = make(chan readResult, 1)
+	}
+	buf := c.buf[:len(p)]
+	go func() {
+		n, err := c.r.Read(buf)
+		c.results <- readResult{n: n, err: err}
+	}()
+	select {
+	case <-c.ctx.Done():
+		return 0, c.ctx.Err()
+	case res := <-c.results:
+		return copy(p, buf[:res.n]), res.err
+	}
+}
@@ -2,12 +2,15 @@ package mdf
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"regexp"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/muesli/reflow/ansi"
 )
@@ -34,7 +37,7 @@ func (c *captureStream) SetWidth(int) {}
 func (c *captureStream) SetWrapIndent(string) {}
 
 func TestLiveParserEmitsThematicBreakToken(t *testing.T) {
-	src := "one\n---\ntwo\n"
+	src := "one\n\n---\ntwo\n"
 	stream := &captureStream{}
 	err := Parse(ParseRequest{
 		Reader: strings.NewReader(src),
@@ -667,3 +670,122 @@ func TestRenderOneByteChunks(t *testing.T) {
 		t.Fatalf("stream live: %v", err)
 	}
 }
+
+func TestStrikethroughStyle(t *testing.T) {
+	src := "This has ~~struck text~~ and ~one~ tilde.\n"
+	stream := &captureStream{}
+	err := Parse(ParseRequest{
+		Reader: strings.NewReader(src),
+		Stream: stream,
+		Theme:  DefaultTheme(),
+	})
+	if err != nil {
+		t.Fatalf("stream parse live: %v", err)
+	}
+	strike := DefaultTheme().Styles().Strikethrough.Prefix
+	var struck, plain strings.Builder
+	for _, tok := range stream.tokens {
+		if strings.Contains(tok.Style.Prefix, strike) {
+			struck.WriteString(tok.Text)
+		} else {
+			plain.WriteString(tok.Text)
+		}
+	}
+	if struck.String() != "struck text" {
+		t.Fatalf("unexpected struck text %q", struck.String())
+	}
+	if plain.String() != "This has  and ~one~ tilde." {
+		t.Fatalf("unexpected plain text %q", plain.String())
+	}
+}
+
+func TestRenderContextCanceledMidStream(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("pipe: %v", err)
+	}
+	defer r.Close()
+	defer w.Close()
+	if _, err := w.WriteString("# Title\n\nSome **bold"); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	var out bytes.Buffer
+	done := make(chan error, 1)
+	go func() {
+		done <- RenderContext(ctx, RenderRequest{
+			Reader: r,
+			Writer: &out,
+			Width:  80,
+			Theme:  DefaultTheme(),
+		})
+	}()
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+	select {
+	case err = <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("render did not return after cancellation")
+	}
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	plain := stripANSI(out.String())
+	if !strings.Contains(plain, "Title") || !strings.Contains(plain, "Some bold") {
+		t.Fatalf("partial document not flushed: %q", plain)
+	}
+	if !strings.HasSuffix(strings.TrimRight(out.String(), "\n"), ansiReset) {
+		t.Fatalf("expected style reset at end of output: %q", out.String())
+	}
+}
+
+func TestRenderContextCanceledWhileReadBlocks(t *testing.T) {
+	// An io.Pipe has no read deadlines, like a terminal on stdin.
+	r, w := io.Pipe()
+	defer w.Close()
+	go func() {
+		_, _ = w.Write([]byte("# Title\n\nSome **bold"))
+	}()
+	ctx, cancel := context.WithCancel(context.Background())
+	var out bytes.Buffer
+	done := make(chan error, 1)
+	go func() {
+		done <- RenderContext(ctx, RenderRequest{
+			Reader: r,
+			Writer: &out,
+			Width:  80,
+			Theme:  DefaultTheme(),
+		})
+	}()
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+	var err error
+	select {
+	case err = <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("render did not return after cancellation")
+	}
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if plain := stripANSI(out.String()); !strings.Contains(plain, "Some bold") {
+		t.Fatalf("partial document not flushed: %q", plain)
+	}
+}
+
+func TestParseContextAlreadyCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	stream := &captureStream{}
+	err := ParseContext(ctx, ParseRequest{
+		Reader: strings.NewReader("never read\n"),
+		Stream: stream,
+		Theme:  DefaultTheme(),
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if len(stream.tokens) != 0 {
+		t.Fatalf("unexpected tokens from a canceled parse: %+v", stream.tokens)
+	}
+}
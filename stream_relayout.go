@@ -0,0 +1,198 @@
+package mdf
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/muesli/reflow/ansi"
+)
+
+// RelayoutWriter is a push-style renderer, like NewWriter, that keeps the
+// source of the document it renders so the output can be laid out again
+// when the terminal is resized. Resize may be called while another
+// goroutine writes.
+type RelayoutWriter struct {
+	mu     sync.Mutex
+	w      io.Writer
+	theme  Theme
+	opts   []RenderOption
+	width  int
+	src    []byte
+	target *switchWriter
+	inner  *streamWriter
+	// scrolled is set once the start of the document has left the screen;
+	// later resizes then redraw the whole screen.
+	scrolled bool
+	closed   bool
+}
+
+// NewRelayoutWriter returns a RelayoutWriter rendering to the terminal w
+// at width columns.
+func NewRelayoutWriter(w io.Writer, width int, theme Theme, opts ...RenderOption) *RelayoutWriter {
+	r := &RelayoutWriter{w: w, theme: theme, opts: opts, width: width}
+	r.target = &switchWriter{w: w}
+	r.inner = NewWriter(r.target, width, theme, opts...).(*streamWriter)
+	return r
+}
+
+// Write renders p and appends it to the kept source.
+func (r *RelayoutWriter) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return 0, fmt.Errorf("relayout: write after close")
+	}
+	n, err := r.inner.Write(p)
+	r.src = append(r.src, p[:n]...)
+	return n, err
+}
+
+// Close finalizes the document and drops the kept source. Resizes after
+// Close are ignored.
+func (r *RelayoutWriter) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return nil
+	}
+	r.closed = true
+	r.src = nil
+	return r.inner.Close()
+}
+
+// Resize lays the document out again for a terminal of width columns and
+// height rows. The output written so far is erased with cursor movement
+// and redrawn at the new width; once the document no longer fits on the
+// screen, the screen is cleared and the end of the document redrawn
+// instead, leaving the scrollback as it was.
+//
+// Terminals that reflow long lines on resize are assumed, so the rows the
+// old output takes up are counted at the new width.
+func (r *RelayoutWriter) Resize(width, height int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed || width <= 0 || height <= 0 || width == r.width {
+		return nil
+	}
+	if r.inner.stream != nil {
+		// Take back provisional output first, so the screen holds exactly
+		// what a render of the source shows.
+		if err := r.inner.stream.rewindSpeculation(); err != nil {
+			return fmt.Errorf("relayout: %w", err)
+		}
+	}
+	old, err := r.render(r.width)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	target := &switchWriter{w: &buf}
+	inner := NewWriter(target, width, r.theme, r.opts...).(*streamWriter)
+	if err := inner.replay(r.src); err != nil {
+		_ = inner.Close()
+		return err
+	}
+	var screen bytes.Buffer
+	if rows := screenRows(old, width); !r.scrolled && rows <= height {
+		screen.WriteByte('\r')
+		if rows > 1 {
+			fmt.Fprintf(&screen, "\x1b[%dA", rows-1)
+		}
+		screen.WriteString("\x1b[J")
+		screen.Write(buf.Bytes())
+	} else {
+		r.scrolled = true
+		screen.WriteString("\x1b[H\x1b[2J")
+		screen.WriteString(screenTail(buf.String(), width, height))
+	}
+	if _, err := r.w.Write(screen.Bytes()); err != nil {
+		_ = inner.Close()
+		return fmt.Errorf("relayout: %w", err)
+	}
+	target.w = r.w
+	r.target.w = io.Discard
+	_ = r.inner.Close()
+	r.target = target
+	r.inner = inner
+	r.width = width
+	return nil
+}
+
+// render returns the output of the kept source at width, as written before
+// the document is finalized.
+func (r *RelayoutWriter) render(width int) (string, error) {
+	var buf bytes.Buffer
+	target := &switchWriter{w: &buf}
+	inner := NewWriter(target, width, r.theme, r.opts...).(*streamWriter)
+	err := inner.replay(r.src)
+	target.w = io.Discard
+	_ = inner.Close()
+	return buf.String(), err
+}
+
+// switchWriter forwards to a writer that can be swapped.
+type switchWriter struct {
+	w io.Writer
+}
+
+func (s *switchWriter) Write(p []byte) (int, error) {
+	return s.w.Write(p)
+}
+
+// screenRows returns the terminal rows output takes up at width columns,
+// counting the row the cursor is left on.
+func screenRows(output string, width int) int {
+	rows := 0
+	for _, line := range strings.Split(output, "\n") {
+		rows += lineRows(line, width)
+	}
+	return rows
+}
+
+func lineRows(line string, width int) int {
+	w := visibleWidth(line)
+	if w <= width {
+		return 1
+	}
+	return (w + width - 1) / width
+}
+
+// screenTail returns the end of output that fits in height rows.
+func screenTail(output string, width int, height int) string {
+	lines := strings.Split(output, "\n")
+	start := len(lines)
+	rows := 0
+	for start > 0 {
+		rows += lineRows(lines[start-1], width)
+		if rows > height {
+			break
+		}
+		start--
+	}
+	return strings.Join(lines[start:], "\n")
+}
+
+// visibleWidth returns the display width of a rendered line, skipping SGR
+// and OSC 8 sequences.
+func visibleWidth(line string) int {
+	width := 0
+	for {
+		i := strings.Index(line, "\x1b]")
+		if i < 0 {
+			return width + ansi.PrintableRuneWidth(line)
+		}
+		width += ansi.PrintableRuneWidth(line[:i])
+		line = line[i+2:]
+		end := strings.IndexAny(line, "\x07\x1b")
+		if end < 0 {
+			return width
+		}
+		if line[end] == '\x1b' {
+			end++
+		}
+		line = line[min(end+1, len(line)):]
+	}
+}
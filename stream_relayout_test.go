@@ -0,0 +1,71 @@
+package mdf
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+const relayoutSample = "# Relayout\n\nA paragraph that is long enough to wrap differently at forty and at twenty columns.\n\n- one item\n- another item with more words in it\n"
+
+func TestRelayoutWriterRedrawsAtNewWidth(t *testing.T) {
+	var out bytes.Buffer
+	w := NewRelayoutWriter(&out, 40, DefaultTheme(), WithOSC8(false))
+	split := strings.Index(relayoutSample, "- one")
+	if _, err := w.Write([]byte(relayoutSample[:split])); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	before := out.Len()
+	rows := screenRows(out.String(), 20)
+	if err := w.Resize(20, 50); err != nil {
+		t.Fatalf("resize: %v", err)
+	}
+	redraw := out.String()[before:]
+	prefix := fmt.Sprintf("\r\x1b[%dA\x1b[J", rows-1)
+	if !strings.HasPrefix(redraw, prefix) {
+		t.Fatalf("expected redraw to start with %q, got %q", prefix, redraw)
+	}
+	if _, err := w.Write([]byte(relayoutSample[split:])); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	want := renderStream(t, []byte(relayoutSample), 20)
+	if got := out.String()[before+len(prefix):]; got != want {
+		t.Fatalf("relayout output differs from a render at the new width:\n got %q\nwant %q", got, want)
+	}
+}
+
+func TestRelayoutWriterRedrawsScreenTail(t *testing.T) {
+	var out bytes.Buffer
+	w := NewRelayoutWriter(&out, 40, DefaultTheme(), WithOSC8(false))
+	if _, err := w.Write([]byte(relayoutSample)); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	before := out.Len()
+	if err := w.Resize(20, 4); err != nil {
+		t.Fatalf("resize: %v", err)
+	}
+	redraw, ok := strings.CutPrefix(out.String()[before:], "\x1b[H\x1b[2J")
+	if !ok {
+		t.Fatalf("expected a full screen redraw, got %q", out.String()[before:])
+	}
+	if rows := screenRows(redraw, 20); rows > 4 {
+		t.Fatalf("redraw takes %d rows, want at most 4: %q", rows, redraw)
+	}
+	if !strings.HasSuffix(stripANSI(redraw), "more words in") {
+		t.Fatalf("expected the end of the document, got %q", redraw)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+}
+
+func TestVisibleWidthSkipsEscapes(t *testing.T) {
+	line := "\x1b[1mab\x1b[0m \x1b]8;;http://example.com\x1b\\link\x1b]8;;\x1b\\"
+	if got := visibleWidth(line); got != 7 {
+		t.Fatalf("visibleWidth = %d, want 7", got)
+	}
+}
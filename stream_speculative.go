@@ -0,0 +1,165 @@
+package mdf
+
+import (
+	"bytes"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// provisionalStream is implemented by streams that can show output which the
+// next token written normally takes back.
+type provisionalStream interface {
+	writeProvisional(tok StreamToken) error
+}
+
+// speculation is the state of provisional output on a StreamRenderer. The
+// renderer is saved when provisional output starts; the next token that
+// writes output restores it, moves the cursor back to where the provisional
+// output began and erases to the end of the screen before going on.
+type speculation struct {
+	enabled bool
+	active  bool
+	saved   *StreamRenderer
+	// row and col are the screen position, as counted by screenCounter,
+	// where the provisional output began.
+	row int
+	col int
+}
+
+func (sp *speculation) reset(enabled bool) {
+	sp.enabled = enabled
+	sp.active = false
+	sp.row = 0
+	sp.col = 0
+}
+
+// screenCounter tracks the rows written and the column of the cursor.
+type screenCounter struct {
+	w   io.Writer
+	row int
+	col int
+}
+
+func (c *screenCounter) Write(p []byte) (int, error) {
+	if i := bytes.LastIndexByte(p, '\n'); i >= 0 {
+		c.row += bytes.Count(p, []byte{'\n'})
+		c.col = visibleWidth(string(p[i+1:]))
+	} else {
+		c.col += visibleWidth(string(p))
+	}
+	return c.w.Write(p)
+}
+
+// writesOutput reports whether tok changes what is on screen.
+func writesOutput(tok StreamToken) bool {
+	switch tok.Kind {
+	case tokenLinkStart, tokenLinkEnd, tokenThematicBreak, tokenImage:
+		return true
+	}
+	return tok.Text != ""
+}
+
+// writeProvisional shows tok at once, flushing the word it belongs to.
+func (s *StreamRenderer) writeProvisional(tok StreamToken) error {
+	if !s.spec.enabled {
+		return nil
+	}
+	if !s.spec.active {
+		s.saveSpeculation()
+	}
+	if err := s.writeToken(tok); err != nil {
+		return err
+	}
+	if len(s.pending.atoms) > 0 {
+		s.flushWord(boundaryNone)
+	}
+	return nil
+}
+
+func (s *StreamRenderer) saveSpeculation() {
+	sp := &s.spec
+	if sp.saved == nil {
+		sp.saved = &StreamRenderer{}
+	}
+	saved := sp.saved
+	*saved = *s
+	saved.spec = speculation{}
+	saved.pending.atoms = cloneTokens(s.pending.atoms)
+	saved.pendingSpaces = cloneTokens(s.pendingSpaces)
+	saved.prefixBuf = bytes.Clone(s.prefixBuf)
+	saved.wordScratch = bytes.Clone(s.wordScratch)
+	saved.indentArena = bytes.Clone(s.indentArena)
+	saved.wordRunes = slices.Clone(s.wordRunes)
+	saved.carryRunes = slices.Clone(s.carryRunes)
+	saved.nbspBuf = slices.Clone(s.nbspBuf)
+	for i := range saved.nbspBuf {
+		saved.nbspBuf[i].StreamToken = cloneToken(saved.nbspBuf[i].StreamToken)
+	}
+	saved.wrapIndent = strings.Clone(s.wrapIndent)
+	sp.row = s.counter.row
+	sp.col = s.counter.col
+	sp.active = true
+}
+
+// rewindSpeculation erases the provisional output and restores the renderer
+// to where it began.
+func (s *StreamRenderer) rewindSpeculation() error {
+	sp := s.spec
+	if !sp.active {
+		return nil
+	}
+	var seq []byte
+	if s.style != "" {
+		seq = append(seq, ansiReset...)
+	}
+	seq = append(seq, '\r')
+	if up := s.counter.row - sp.row; up > 0 {
+		seq = append(seq, "\x1b["...)
+		seq = strconv.AppendInt(seq, int64(up), 10)
+		seq = append(seq, 'A')
+	}
+	if sp.col > 0 {
+		seq = append(seq, "\x1b["...)
+		seq = strconv.AppendInt(seq, int64(sp.col), 10)
+		seq = append(seq, 'C')
+	}
+	seq = append(seq, "\x1b[J"...)
+	out := s.counter.w
+	saved := sp.saved
+	*s = *saved
+	s.pending.atoms = append(s.pendingAtomsBuf[:0], saved.pending.atoms...)
+	s.pendingSpaces = append(s.pendingSpacesBuf[:0], saved.pendingSpaces...)
+	s.prefixBuf = append(s.prefixBufArr[:0], saved.prefixBuf...)
+	s.wordScratch = append(s.wordScratchArr[:0], saved.wordScratch...)
+	s.indentArena = append(s.indentArenaArr[:0], saved.indentArena...)
+	s.wordRunes = append(s.wordRunesArr[:0], saved.wordRunes...)
+	s.carryRunes = append(s.carryRunesArr[:0], saved.carryRunes...)
+	s.nbspBuf = append(s.nbspBufArr[:0], saved.nbspBuf...)
+	s.spec = sp
+	s.spec.active = false
+	s.counter = screenCounter{w: out, row: sp.row, col: sp.col}
+	s.w = &s.counter
+	if s.style != "" {
+		seq = append(seq, s.style...)
+	}
+	_, err := out.Write(seq)
+	return err
+}
+
+func cloneTokens(tokens []StreamToken) []StreamToken {
+	out := make([]StreamToken, len(tokens))
+	for i, tok := range tokens {
+		out[i] = cloneToken(tok)
+	}
+	return out
+}
+
+// cloneToken copies the strings of tok, which may point into parser buffers
+// that are reused while the provisional output is shown.
+func cloneToken(tok StreamToken) StreamToken {
+	tok.Text = strings.Clone(tok.Text)
+	tok.LinkURL = strings.Clone(tok.LinkURL)
+	return tok
+}
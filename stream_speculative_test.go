@@ -0,0 +1,188 @@
+package mdf
+
+import (
+	"bytes"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// screen is a minimal terminal for the cursor movement the speculative
+// renderer uses. Each cell keeps the SGR sequences in effect when it was
+// written.
+type screen struct {
+	rows     [][]screenCell
+	row, col int
+	style    string
+}
+
+type screenCell struct {
+	r     rune
+	style string
+}
+
+func (s *screen) write(t *testing.T, out string) {
+	t.Helper()
+	for i := 0; i < len(out); {
+		switch c := out[i]; {
+		case c == '\x1b':
+			j := i + 2
+			for j < len(out) && (out[j] < 0x40 || out[j] > 0x7e) {
+				j++
+			}
+			if i+1 >= len(out) || out[i+1] != '[' || j >= len(out) {
+				t.Fatalf("unexpected escape in %q", out[i:])
+			}
+			arg := out[i+2 : j]
+			n, _ := strconv.Atoi(arg)
+			switch out[j] {
+			case 'm':
+				if arg == "0" || arg == "" {
+					s.style = ""
+				} else {
+					s.style += out[i : j+1]
+				}
+			case 'A':
+				s.row -= n
+			case 'C':
+				s.col += n
+			case 'J':
+				s.grow()
+				s.rows[s.row] = s.rows[s.row][:min(s.col, len(s.rows[s.row]))]
+				s.rows = s.rows[:s.row+1]
+			default:
+				t.Fatalf("unexpected escape %q", out[i:j+1])
+			}
+			i = j + 1
+		case c == '\r':
+			s.col = 0
+			i++
+		case c == '\n':
+			s.row++
+			s.col = 0
+			i++
+		default:
+			r, size := utf8.DecodeRuneInString(out[i:])
+			s.grow()
+			line := s.rows[s.row]
+			for len(line) <= s.col {
+				line = append(line, screenCell{r: ' '})
+			}
+			line[s.col] = screenCell{r: r, style: s.style}
+			s.rows[s.row] = line
+			s.col++
+			i += size
+		}
+	}
+}
+
+func (s *screen) grow() {
+	for len(s.rows) <= s.row {
+		s.rows = append(s.rows, nil)
+	}
+}
+
+func (s *screen) String() string {
+	var b strings.Builder
+	for i, line := range s.rows {
+		if i > 0 {
+			b.WriteByte('\n')
+		}
+		for _, c := range line {
+			b.WriteString(c.style)
+			b.WriteRune(c.r)
+		}
+	}
+	return b.String()
+}
+
+func renderScreen(t *testing.T, src string, speculative bool, observe func(src string, out string)) string {
+	t.Helper()
+	var out bytes.Buffer
+	w := NewWriter(&out, 20, DefaultTheme(), WithOSC8(false), WithSpeculativeInline(speculative))
+	for i := 0; i < len(src); i++ {
+		if _, err := w.Write([]byte{src[i]}); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+		if observe != nil {
+			observe(src[:i+1], out.String())
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	var scr screen
+	scr.write(t, out.String())
+	return scr.String()
+}
+
+func TestSpeculativeInlineRepaintsToFinalOutput(t *testing.T) {
+	cases := []string{
+		"Intro\nrun `go test ./... -run Speculative` before you push.\n",
+		"Intro\nsee [the project documentation](https://example.com/docs) for details.\n",
+		"Intro\nnot a link: [just brackets] and `unclosed code\n",
+		"Intro\na **bold** word, [a link](http://e.com) and `code` on one line.\n\n- item with `a long code span that wraps`\n",
+	}
+	for _, name := range []string{"OBAF.md", "centaur.md", "eg.md"} {
+		data, err := os.ReadFile("testdata/" + name)
+		if err != nil {
+			t.Fatalf("read %s: %v", name, err)
+		}
+		cases = append(cases, string(data))
+	}
+	for _, src := range cases {
+		want := renderScreen(t, src, false, nil)
+		if got := renderScreen(t, src, true, nil); got != want {
+			t.Fatalf("speculative screen differs for %q:\n got %q\nwant %q", src, got, want)
+		}
+	}
+}
+
+func TestSpeculativeInlineShowsOpenCodeSpan(t *testing.T) {
+	src := "Intro\nrun `go test` now.\n"
+	shown := false
+	renderScreen(t, src, true, func(written string, out string) {
+		if written == "Intro\nrun `go te" && strings.Contains(stripANSI(out), "go te") {
+			shown = true
+		}
+	})
+	if !shown {
+		t.Fatalf("expected the open code span to be shown before it closes")
+	}
+}
+
+func TestSpeculativeInlineShowsOpenSpanInFirstLine(t *testing.T) {
+	cases := []struct {
+		src  string
+		open string
+		want string
+	}{
+		{src: "Intro\n\nrun `go test` now.\n", open: "Intro\n\nrun `go te", want: "go te"},
+		{src: "Intro\n\nrun [the tests](x) now.\n", open: "Intro\n\nrun [the te", want: "the te"},
+	}
+	for _, tc := range cases {
+		shown := false
+		renderScreen(t, tc.src, true, func(written string, out string) {
+			if written == tc.open && strings.Contains(stripANSI(out), tc.want) {
+				shown = true
+			}
+		})
+		if !shown {
+			t.Fatalf("%q: expected the open span to be shown before the line ends", tc.src)
+		}
+	}
+}
+
+func TestSpeculativeInlineRepaintsAcrossWrappedLines(t *testing.T) {
+	src := "Intro\nthen [a link whose text wraps over several lines](http://example.com/) ends.\n"
+	var last string
+	got := renderScreen(t, src, true, func(_ string, out string) { last = out })
+	if !strings.Contains(last, "A\x1b[") {
+		t.Fatalf("expected the repaint to move the cursor up: %q", last)
+	}
+	if want := renderScreen(t, src, false, nil); got != want {
+		t.Fatalf("speculative screen differs:\n got %q\nwant %q", got, want)
+	}
+}
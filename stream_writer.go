@@ -0,0 +1,132 @@
+package mdf
+
+import (
+	"fmt"
+	"io"
+)
+
+// parseWriter is the io.WriteCloser returned by NewStreamWriter.
+type parseWriter struct {
+	parser *liveParser
+	feed   feeder
+	err    error
+	closed bool
+}
+
+// streamWriter is the io.WriteCloser returned by NewWriter.
+type streamWriter struct {
+	parseWriter
+	stream *StreamRenderer
+}
+
+// NewWriter returns a renderer that is fed by writes instead of reading a
+// stream: each Write parses its bytes and renders them to w, and Close
+// finalizes the document. Writes may split the input anywhere, which suits
+// handlers that receive model output as a series of deltas. The writer is
+// not safe for concurrent use.
+func NewWriter(w io.Writer, width int, theme Theme, opts ...RenderOption) io.WriteCloser {
+	if w == nil {
+		return &streamWriter{parseWriter: parseWriter{err: fmt.Errorf("writer: writer is nil")}}
+	}
+	cfg := writerConfig(opts)
+	stream := streamRendererPool.Get().(*StreamRenderer)
+	stream.resetWithConfig(w, width, cfg)
+	sw := &streamWriter{stream: stream}
+	sw.start(stream, theme, cfg)
+	return sw
+}
+
+// NewStreamWriter is NewWriter for any Stream: each Write parses its bytes
+// into tokens for stream, and Close finalizes the document and flushes
+// stream.
+func NewStreamWriter(stream Stream, theme Theme, opts ...RenderOption) io.WriteCloser {
+	if stream == nil {
+		return &parseWriter{err: fmt.Errorf("writer: stream is nil")}
+	}
+	pw := &parseWriter{}
+	pw.start(stream, theme, writerConfig(opts))
+	return pw
+}
+
+func writerConfig(opts []RenderOption) renderConfig {
+	cfg := configPool.Get().(*renderConfig)
+	*cfg = renderConfig{}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(cfg)
+		}
+	}
+	cfgVal := *cfg
+	configPool.Put(cfg)
+	return cfgVal
+}
+
+func (s *parseWriter) start(stream Stream, theme Theme, cfg renderConfig) {
+	if theme == nil {
+		theme = DefaultTheme()
+	}
+	parser := parserPool.Get().(*liveParser)
+	parser.resetWithConfig(theme, cfg)
+	s.parser = parser
+	s.feed.parser = parser
+	s.feed.stream = stream
+}
+
+// Write parses p. After an error every later Write returns it again.
+func (s *parseWriter) Write(p []byte) (int, error) {
+	if s.err != nil {
+		return 0, s.err
+	}
+	if s.closed {
+		return 0, fmt.Errorf("writer: write after close")
+	}
+	if err := s.feed.write(p); err != nil {
+		s.err = err
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Close finalizes the document and flushes the stream. Closing twice is a
+// no-op.
+func (s *parseWriter) Close() error {
+	if s.closed || s.parser == nil {
+		s.closed = true
+		return nil
+	}
+	s.closed = true
+	var err error
+	if s.err == nil {
+		err = s.feed.finish()
+	}
+	parserPool.Put(s.parser)
+	s.parser = nil
+	s.feed = feeder{}
+	return err
+}
+
+// replay feeds src with provisional output turned off, for rebuilding the
+// output of a document already shown.
+func (s *streamWriter) replay(src []byte) error {
+	if s.stream == nil {
+		_, err := s.Write(src)
+		return err
+	}
+	enabled := s.stream.spec.enabled
+	s.stream.spec.enabled = false
+	_, err := s.Write(src)
+	s.stream.spec.enabled = enabled
+	return err
+}
+
+// Close finalizes the document, flushes the partial line and resets the
+// terminal style. Closing twice is a no-op.
+func (s *streamWriter) Close() error {
+	err := s.parseWriter.Close()
+	if s.stream != nil {
+		s.stream.Reset(io.Discard, 0)
+		streamRendererPool.Put(s.stream)
+		s.stream = nil
+	}
+	return err
+}
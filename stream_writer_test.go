@@ -0,0 +1,58 @@
+package mdf
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestNewWriterMatchesRender(t *testing.T) {
+	for _, name := range []string{"OBAF.md", "centaur.md", "frontmatter1.md", "eg.md"} {
+		src, err := os.ReadFile("testdata/" + name)
+		if err != nil {
+			t.Fatalf("read %s: %v", name, err)
+		}
+		want := renderStream(t, src, 60)
+		for _, size := range []int{1, 3, 7, 5000} {
+			var out bytes.Buffer
+			w := NewWriter(&out, 60, DefaultTheme(), WithOSC8(false))
+			for rest := src; len(rest) > 0; {
+				n := min(size, len(rest))
+				if written, err := w.Write(rest[:n]); err != nil || written != n {
+					t.Fatalf("%s: write %d bytes: %d, %v", name, n, written, err)
+				}
+				rest = rest[n:]
+			}
+			if err := w.Close(); err != nil {
+				t.Fatalf("%s: close: %v", name, err)
+			}
+			if out.String() != want {
+				t.Fatalf("%s: output with %d byte writes differs from Render", name, size)
+			}
+		}
+	}
+}
+
+func TestNewWriterClose(t *testing.T) {
+	var out bytes.Buffer
+	w := NewWriter(&out, 80, DefaultTheme())
+	if _, err := w.Write([]byte("Some **bold")); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	if !strings.Contains(stripANSI(out.String()), "Some bold") {
+		t.Fatalf("partial line not flushed on close: %q", out.String())
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("second close: %v", err)
+	}
+	if _, err := w.Write([]byte("more")); err == nil {
+		t.Fatalf("expected error for write after close")
+	}
+	if _, err := NewWriter(nil, 80, nil).Write([]byte("x")); err == nil {
+		t.Fatalf("expected error for nil writer")
+	}
+}
@@ -0,0 +1,229 @@
+package mdf
+
+import (
+	"strconv"
+	"strings"
+)
+
+// ColorKind says how a Color is given.
+type ColorKind uint8
+
+const (
+	// ColorDefault is the terminal's default colour.
+	ColorDefault ColorKind = iota
+	// ColorIndexed is an entry of the xterm 256-colour palette; entries 0
+	// to 15 are the ANSI colours.
+	ColorIndexed
+	// ColorRGB is a 24-bit colour.
+	ColorRGB
+)
+
+// Color is a foreground or background colour. The zero Color is the
+// terminal's default.
+type Color struct {
+	Kind    ColorKind
+	Index   uint8
+	R, G, B uint8
+}
+
+// IndexedColor returns entry n of the xterm 256-colour palette.
+func IndexedColor(n uint8) Color {
+	return Color{Kind: ColorIndexed, Index: n}
+}
+
+// RGBColor returns a 24-bit colour.
+func RGBColor(r, g, b uint8) Color {
+	return Color{Kind: ColorRGB, R: r, G: g, B: b}
+}
+
+// RGB returns the components of c. Indexed colours use xterm's default
+// palette; ok is false for the default colour.
+func (c Color) RGB() (r, g, b uint8, ok bool) {
+	switch c.Kind {
+	case ColorRGB:
+		return c.R, c.G, c.B, true
+	case ColorIndexed:
+		rgb := xterm256RGB(int(c.Index))
+		return uint8(rgb[0]), uint8(rgb[1]), uint8(rgb[2]), true
+	}
+	return 0, 0, 0, false
+}
+
+// appendSGR appends the SGR parameters selecting c, base being 30 for the
+// foreground and 40 for the background.
+func (c Color) appendSGR(codes []string, base int) []string {
+	switch c.Kind {
+	case ColorIndexed:
+		switch {
+		case c.Index < 8:
+			return append(codes, strconv.Itoa(base+int(c.Index)))
+		case c.Index < 16:
+			return append(codes, strconv.Itoa(base+60+int(c.Index)-8))
+		}
+		return append(codes, strconv.Itoa(base+8), "5", strconv.Itoa(int(c.Index)))
+	case ColorRGB:
+		return append(codes, strconv.Itoa(base+8), "2", strconv.Itoa(int(c.R)), strconv.Itoa(int(c.G)), strconv.Itoa(int(c.B)))
+	}
+	return codes
+}
+
+// Style describes how text is drawn: colours and attributes, and Prefix,
+// the ANSI sequence the terminal renderer writes for them.
+//
+// Styles of built-in themes and of LoadTheme carry both. NewTheme fills in
+// whichever side a style leaves empty: Prefix from the other fields with
+// SGR, or the other fields from Prefix with ParseStyle.
+type Style struct {
+	Prefix        string
+	FG            Color
+	BG            Color
+	Bold          bool
+	Dim           bool
+	Italic        bool
+	Underline     bool
+	Reverse       bool
+	Strikethrough bool
+}
+
+// ParseStyle returns the style an ANSI prefix selects, keeping prefix as its
+// Prefix. Codes other than colours and the Style attributes are ignored.
+func ParseStyle(prefix string) Style {
+	s := Style{Prefix: prefix}
+	for prefix != "" {
+		start := strings.Index(prefix, "\x1b[")
+		if start < 0 {
+			break
+		}
+		end := strings.IndexByte(prefix[start:], 'm')
+		if end < 0 {
+			break
+		}
+		codes := strings.Split(prefix[start+2:start+end], ";")
+		prefix = prefix[start+end+1:]
+		for i := 0; i < len(codes); i++ {
+			n, err := strconv.Atoi(codes[i])
+			if err != nil {
+				continue
+			}
+			switch {
+			case n == 0:
+				s = Style{Prefix: s.Prefix}
+			case n == 1:
+				s.Bold = true
+			case n == 2:
+				s.Dim = true
+			case n == 3:
+				s.Italic = true
+			case n == 4:
+				s.Underline = true
+			case n == 7:
+				s.Reverse = true
+			case n == 9:
+				s.Strikethrough = true
+			case n == 22:
+				s.Bold, s.Dim = false, false
+			case n == 23:
+				s.Italic = false
+			case n == 24:
+				s.Underline = false
+			case n == 27:
+				s.Reverse = false
+			case n == 29:
+				s.Strikethrough = false
+			case n >= 30 && n <= 37:
+				s.FG = IndexedColor(uint8(n - 30))
+			case n >= 90 && n <= 97:
+				s.FG = IndexedColor(uint8(n - 90 + 8))
+			case n == 39:
+				s.FG = Color{}
+			case n >= 40 && n <= 47:
+				s.BG = IndexedColor(uint8(n - 40))
+			case n >= 100 && n <= 107:
+				s.BG = IndexedColor(uint8(n - 100 + 8))
+			case n == 49:
+				s.BG = Color{}
+			case n == 38 || n == 48:
+				rgb, index, used, ok := parseExtendedColor(codes[i+1:])
+				i += used
+				if !ok {
+					continue
+				}
+				c := RGBColor(uint8(rgb[0]), uint8(rgb[1]), uint8(rgb[2]))
+				if index >= 0 {
+					c = IndexedColor(uint8(index))
+				}
+				if n == 38 {
+					s.FG = c
+				} else {
+					s.BG = c
+				}
+			}
+		}
+	}
+	return s
+}
+
+// SGR returns the ANSI prefix for the colours and attributes of s, ignoring
+// its Prefix.
+func (s Style) SGR() string {
+	var codes []string
+	for _, a := range []struct {
+		set  bool
+		code string
+	}{
+		{s.Bold, "1"}, {s.Dim, "2"}, {s.Italic, "3"}, {s.Underline, "4"}, {s.Reverse, "7"}, {s.Strikethrough, "9"},
+	} {
+		if a.set {
+			codes = append(codes, a.code)
+		}
+	}
+	codes = s.FG.appendSGR(codes, 30)
+	codes = s.BG.appendSGR(codes, 40)
+	if len(codes) == 0 {
+		return ""
+	}
+	return "\x1b[" + strings.Join(codes, ";") + "m"
+}
+
+// plain reports whether s sets no colour or attribute.
+func (s Style) plain() bool {
+	s.Prefix = ""
+	return s == Style{}
+}
+
+// combine returns s drawn over by extra: extra's colours win where set and
+// the attributes of both apply.
+func (s Style) combine(extra Style) Style {
+	out := Style{
+		Prefix:        s.Prefix + extra.Prefix,
+		FG:            s.FG,
+		BG:            s.BG,
+		Bold:          s.Bold || extra.Bold,
+		Dim:           s.Dim || extra.Dim,
+		Italic:        s.Italic || extra.Italic,
+		Underline:     s.Underline || extra.Underline,
+		Reverse:       s.Reverse || extra.Reverse,
+		Strikethrough: s.Strikethrough || extra.Strikethrough,
+	}
+	if extra.FG.Kind != ColorDefault {
+		out.FG = extra.FG
+	}
+	if extra.BG.Kind != ColorDefault {
+		out.BG = extra.BG
+	}
+	return out
+}
+
+// normalize keeps Prefix and the fields in step. Set fields win: unless
+// they are what Prefix already encodes, Prefix is regenerated from them, so
+// editing a field of a theme's style takes effect in ANSI output too. A
+// style with only a Prefix gets its fields parsed.
+func (s Style) normalize() Style {
+	switch {
+	case s.plain() && s.Prefix != "":
+		s = ParseStyle(s.Prefix)
+	case !s.plain() && ParseStyle(s.Prefix) != s:
+		s.Prefix = s.SGR()
+	}
+	return s
+}
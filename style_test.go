@@ -0,0 +1,109 @@
+package mdf
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseStyle(t *testing.T) {
+	got := ParseStyle("\x1b[3m\x1b[1;38;5;219;48;2;40;42;54m")
+	want := Style{
+		Prefix: "\x1b[3m\x1b[1;38;5;219;48;2;40;42;54m",
+		FG:     IndexedColor(219),
+		BG:     RGBColor(40, 42, 54),
+		Bold:   true,
+		Italic: true,
+	}
+	if got != want {
+		t.Fatalf("ParseStyle = %+v, want %+v", got, want)
+	}
+	if got := ParseStyle("\x1b[1;91;0;4;7m"); got.Bold || got.FG.Kind != ColorDefault || !got.Underline || !got.Reverse {
+		t.Fatalf("expected reset to clear earlier codes, got %+v", got)
+	}
+}
+
+func TestStyleSGR(t *testing.T) {
+	cases := []struct {
+		style Style
+		want  string
+	}{
+		{Style{}, ""},
+		{Style{Bold: true, FG: IndexedColor(2)}, "\x1b[1;32m"},
+		{Style{FG: IndexedColor(9), BG: IndexedColor(4)}, "\x1b[91;44m"},
+		{Style{Dim: true, Strikethrough: true, FG: IndexedColor(219)}, "\x1b[2;9;38;5;219m"},
+		{Style{Reverse: true, BG: RGBColor(1, 2, 3)}, "\x1b[7;48;2;1;2;3m"},
+	}
+	for _, tc := range cases {
+		if got := tc.style.SGR(); got != tc.want {
+			t.Fatalf("SGR(%+v) = %q, want %q", tc.style, got, tc.want)
+		}
+		if tc.want != "" {
+			if back := ParseStyle(tc.want); back.SGR() != tc.want {
+				t.Fatalf("ParseStyle(%q).SGR() = %q", tc.want, back.SGR())
+			}
+		}
+	}
+}
+
+func TestNewThemeFillsStyles(t *testing.T) {
+	theme := NewTheme("mixed", Styles{
+		Text:   Style{Prefix: "\x1b[38;2;1;2;3m"},
+		Strong: Style{Bold: true, FG: RGBColor(255, 0, 0)},
+	})
+	styles := theme.Styles()
+	if styles.Text.FG != RGBColor(1, 2, 3) {
+		t.Fatalf("expected text colour parsed from prefix, got %+v", styles.Text)
+	}
+	if styles.Strong.Prefix != "\x1b[1;38;2;255;0;0m" {
+		t.Fatalf("expected strong prefix generated, got %q", styles.Strong.Prefix)
+	}
+	if styles.Quote != (Style{}) {
+		t.Fatalf("expected empty quote style, got %+v", styles.Quote)
+	}
+}
+
+func TestNewThemeEditedFieldsReplacePrefix(t *testing.T) {
+	styles := DefaultTheme().Styles()
+	if styles.Heading[0].Prefix == "" {
+		t.Fatalf("expected default heading prefix")
+	}
+	strong := styles.Strong
+	styles.Heading[0].FG = RGBColor(1, 2, 3)
+	edited := NewTheme("edited", styles).Styles()
+	if got := edited.Heading[0]; got.Prefix != got.SGR() || !strings.Contains(got.Prefix, "38;2;1;2;3") {
+		t.Fatalf("expected prefix regenerated from fields, got %q", got.Prefix)
+	}
+	if edited.Strong != strong {
+		t.Fatalf("unedited style changed: %+v, want %+v", edited.Strong, strong)
+	}
+	var out strings.Builder
+	err := Render(RenderRequest{Reader: strings.NewReader("# Title\n"), Writer: &out, Width: 80, Theme: NewTheme("edited", styles)})
+	if err != nil {
+		t.Fatalf("render: %v", err)
+	}
+	if !strings.Contains(out.String(), "38;2;1;2;3") {
+		t.Fatalf("expected edited colour in output: %q", out.String())
+	}
+}
+
+func TestBuiltinThemesCarryFields(t *testing.T) {
+	for _, name := range AvailableThemes() {
+		theme, _ := ThemeByName(name)
+		styles := theme.Styles()
+		for _, n := range namedStyles(&styles) {
+			if n.style.Prefix != "" && n.style.plain() {
+				t.Fatalf("%s: %s has prefix %q but no fields", name, n.key, n.style.Prefix)
+			}
+		}
+	}
+}
+
+func TestCombineStyles(t *testing.T) {
+	base := Style{Prefix: "\x1b[1;31m", Bold: true, FG: IndexedColor(1), BG: IndexedColor(0)}
+	extra := Style{Prefix: "\x1b[4;34m", Underline: true, FG: IndexedColor(4)}
+	got := combineStyles(base, extra)
+	want := Style{Prefix: "\x1b[1;31m\x1b[4;34m", Bold: true, Underline: true, FG: IndexedColor(4), BG: IndexedColor(0)}
+	if got != want {
+		t.Fatalf("combineStyles = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,70 @@
+package svg
+
+import "pkt.systems/mdf"
+
+// Config holds SVG rendering settings.
+type Config struct {
+	// Width is the terminal width in columns the document is rendered at.
+	Width      int
+	FontFamily string
+	// FontSize is in pixels; a cell is 0.6 of it wide.
+	FontSize   float64
+	LineHeight float64
+	Padding    float64
+	// Window draws a window frame with a title bar around the output.
+	Window bool
+	Title  string
+	// BackgroundRGB and TextRGB default to colours suiting the theme: light
+	// for built-in light themes, dark otherwise.
+	BackgroundRGB [3]int
+	TextRGB       [3]int
+	HTMLPolicy    mdf.HTMLPolicy
+	ImageBaseDir  string
+}
+
+// DefaultConfig returns a baseline configuration.
+func DefaultConfig() Config {
+	return Config{
+		Width:      80,
+		FontFamily: "'Hack', 'DejaVu Sans Mono', Menlo, Consolas, monospace",
+		FontSize:   14,
+		LineHeight: 1.4,
+		Padding:    16,
+	}
+}
+
+func applyConfig(dst *Config, src Config) {
+	if src.Width > 0 {
+		dst.Width = src.Width
+	}
+	if src.FontFamily != "" {
+		dst.FontFamily = src.FontFamily
+	}
+	if src.FontSize > 0 {
+		dst.FontSize = src.FontSize
+	}
+	if src.LineHeight > 0 {
+		dst.LineHeight = src.LineHeight
+	}
+	if src.Padding > 0 {
+		dst.Padding = src.Padding
+	}
+	if src.Window {
+		dst.Window = src.Window
+	}
+	if src.Title != "" {
+		dst.Title = src.Title
+	}
+	if src.BackgroundRGB != [3]int{} {
+		dst.BackgroundRGB = src.BackgroundRGB
+	}
+	if src.TextRGB != [3]int{} {
+		dst.TextRGB = src.TextRGB
+	}
+	if src.HTMLPolicy != mdf.HTMLAsText {
+		dst.HTMLPolicy = src.HTMLPolicy
+	}
+	if src.ImageBaseDir != "" {
+		dst.ImageBaseDir = src.ImageBaseDir
+	}
+}
@@ -0,0 +1,19 @@
+// Package svg renders Markdown to an SVG image of the terminal output.
+//
+// The document is rendered by the mdf ANSI renderer at Config.Width
+// columns, and the result is drawn on a monospace grid: SGR colours and
+// attributes become styled text, OSC 8 hyperlinks become links, and an
+// optional window frame surrounds it.
+//
+// Example:
+//
+//	err := svg.Render(svg.RenderRequest{
+//		Reader: strings.NewReader("# Report\n\nHello SVG.\n"),
+//		Writer: outFile,
+//		Theme:  mdf.DefaultTheme(),
+//		Config: svg.Config{Width: 80, Window: true, Title: "report.md"},
+//	})
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+package svg
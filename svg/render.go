@@ -0,0 +1,195 @@
+package svg
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"pkt.systems/mdf"
+	"pkt.systems/mdf/internal/screen"
+)
+
+// RenderRequest contains inputs for SVG rendering.
+type RenderRequest struct {
+	Reader io.Reader
+	Writer io.Writer
+	Theme  mdf.Theme
+	Config Config
+}
+
+// Render converts Markdown to an SVG image of what the terminal renderer
+// shows at Config.Width columns.
+func Render(req RenderRequest) error {
+	return RenderContext(context.Background(), req)
+}
+
+// RenderContext is Render with cancellation. Once ctx is done it stops
+// reading and returns ctx.Err() without writing an image.
+func RenderContext(ctx context.Context, req RenderRequest) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if req.Reader == nil {
+		return fmt.Errorf("svg render: reader is nil")
+	}
+	if req.Writer == nil {
+		return fmt.Errorf("svg render: writer is nil")
+	}
+	cfg := DefaultConfig()
+	applyConfig(&cfg, req.Config)
+	if cfg.FontFamily == "" || cfg.FontSize <= 0 || cfg.LineHeight <= 0 {
+		return fmt.Errorf("svg render: invalid font configuration")
+	}
+	theme := req.Theme
+	if theme == nil {
+		theme = mdf.DefaultTheme()
+	}
+	if cfg.BackgroundRGB == [3]int{} && cfg.TextRGB == [3]int{} {
+		cfg.BackgroundRGB, cfg.TextRGB = screen.Colors(theme)
+	}
+	sc, err := screen.Render(ctx, req.Reader, theme, cfg.Width,
+		mdf.WithHTMLPolicy(cfg.HTMLPolicy),
+		mdf.WithImageBaseDir(cfg.ImageBaseDir))
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil && err == ctxErr {
+			return err
+		}
+		return fmt.Errorf("svg render: %w", err)
+	}
+	var b bytes.Buffer
+	writeSVG(&b, sc, cfg)
+	if _, err := req.Writer.Write(b.Bytes()); err != nil {
+		return fmt.Errorf("svg render: output: %w", err)
+	}
+	return nil
+}
+
+// Window frame measurements, in pixels.
+const (
+	titleBarHeight = 32
+	buttonRadius   = 6
+	cornerRadius   = 8
+)
+
+var buttonColors = []string{"#ff5f56", "#ffbd2e", "#27c93f"}
+
+func writeSVG(b *bytes.Buffer, sc screen.Screen, cfg Config) {
+	cell := cfg.FontSize * 0.6
+	row := cfg.FontSize * cfg.LineHeight
+	cols := max(cfg.Width, sc.Cols)
+	top := cfg.Padding
+	if cfg.Window {
+		top += titleBarHeight
+	}
+	width := 2*cfg.Padding + float64(cols)*cell
+	height := top + float64(len(sc.Lines))*row + cfg.Padding
+	fmt.Fprintf(b, `<svg xmlns="http://www.w3.org/2000/svg" width="%s" height="%s" viewBox="0 0 %s %s">`+"\n",
+		num(width), num(height), num(width), num(height))
+	bg, fg := hexRGB(cfg.BackgroundRGB), hexRGB(cfg.TextRGB)
+	if cfg.Window {
+		fmt.Fprintf(b, `<rect width="100%%" height="100%%" rx="%d" fill="%s"/>`+"\n", cornerRadius, bg)
+		for i, color := range buttonColors {
+			fmt.Fprintf(b, `<circle cx="%s" cy="%s" r="%d" fill="%s"/>`+"\n",
+				num(cfg.Padding+buttonRadius+float64(i)*20), num(titleBarHeight/2+4), buttonRadius, color)
+		}
+		if cfg.Title != "" {
+			fmt.Fprintf(b, `<text x="%s" y="%s" fill="%s" fill-opacity="0.6" font-family="%s" font-size="%s" text-anchor="middle">%s</text>`+"\n",
+				num(width/2), num(titleBarHeight/2+8), fg, escape(cfg.FontFamily), num(cfg.FontSize), escape(cfg.Title))
+		}
+	} else {
+		fmt.Fprintf(b, `<rect width="100%%" height="100%%" fill="%s"/>`+"\n", bg)
+	}
+	fmt.Fprintf(b, `<g font-family="%s" font-size="%s" fill="%s" xml:space="preserve">`+"\n",
+		escape(cfg.FontFamily), num(cfg.FontSize), fg)
+	// The baseline sits so the text is centred in its row.
+	baseline := (row-cfg.FontSize)/2 + cfg.FontSize*0.8
+	for i, line := range sc.Lines {
+		y := top + float64(i)*row
+		for _, s := range line {
+			x := cfg.Padding + float64(s.Col)*cell
+			w := float64(s.Width) * cell
+			textColor, fill := spanColors(s.Style, fg, bg)
+			if fill != "" {
+				fmt.Fprintf(b, `<rect x="%s" y="%s" width="%s" height="%s" fill="%s"/>`+"\n",
+					num(x), num(y), num(w), num(row), fill)
+			}
+			if strings.TrimSpace(s.Text) == "" && !s.Style.Underline && !s.Style.Strikethrough {
+				continue
+			}
+			if s.Link != "" {
+				fmt.Fprintf(b, `<a href="%s">`, escape(s.Link))
+			}
+			fmt.Fprintf(b, `<text x="%s" y="%s" textLength="%s" lengthAdjust="spacingAndGlyphs"%s>%s</text>`,
+				num(x), num(y+baseline), num(w), textAttrs(s.Style, textColor, fg), escape(s.Text))
+			if s.Link != "" {
+				b.WriteString("</a>")
+			}
+			b.WriteByte('\n')
+		}
+	}
+	b.WriteString("</g>\n</svg>\n")
+}
+
+// spanColors returns the text colour of a span and its background fill, ""
+// if it has none. Reverse video swaps them.
+func spanColors(st mdf.Style, fg, bg string) (text, fill string) {
+	text, fill = colorValue(st.FG, fg), colorValue(st.BG, "")
+	if st.Reverse {
+		if fill == "" {
+			fill = bg
+		}
+		text, fill = fill, text
+	}
+	return text, fill
+}
+
+func textAttrs(st mdf.Style, color, fg string) string {
+	var b strings.Builder
+	if color != fg {
+		b.WriteString(` fill="` + color + `"`)
+	}
+	if st.Bold {
+		b.WriteString(` font-weight="bold"`)
+	}
+	if st.Italic {
+		b.WriteString(` font-style="italic"`)
+	}
+	switch {
+	case st.Underline && st.Strikethrough:
+		b.WriteString(` text-decoration="underline line-through"`)
+	case st.Underline:
+		b.WriteString(` text-decoration="underline"`)
+	case st.Strikethrough:
+		b.WriteString(` text-decoration="line-through"`)
+	}
+	if st.Dim {
+		b.WriteString(` fill-opacity="0.7"`)
+	}
+	return b.String()
+}
+
+func colorValue(c mdf.Color, fallback string) string {
+	r, g, b, ok := c.RGB()
+	if !ok {
+		return fallback
+	}
+	return fmt.Sprintf("#%02x%02x%02x", r, g, b)
+}
+
+func hexRGB(rgb [3]int) string {
+	return fmt.Sprintf("#%02x%02x%02x", min(max(rgb[0], 0), 255), min(max(rgb[1], 0), 255), min(max(rgb[2], 0), 255))
+}
+
+// num formats a coordinate with at most two decimals.
+func num(v float64) string {
+	return strconv.FormatFloat(float64(int64(v*100+0.5))/100, 'f', -1, 64)
+}
+
+var escaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;")
+
+func escape(s string) string {
+	return escaper.Replace(s)
+}
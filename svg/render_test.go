@@ -0,0 +1,84 @@
+package svg
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"pkt.systems/mdf"
+)
+
+func renderString(t *testing.T, src string, theme mdf.Theme, cfg Config) string {
+	t.Helper()
+	var out bytes.Buffer
+	if err := Render(RenderRequest{Reader: strings.NewReader(src), Writer: &out, Theme: theme, Config: cfg}); err != nil {
+		t.Fatalf("render: %v", err)
+	}
+	return out.String()
+}
+
+func checkXML(t *testing.T, doc string) {
+	t.Helper()
+	dec := xml.NewDecoder(strings.NewReader(doc))
+	for {
+		if _, err := dec.Token(); err != nil {
+			if errors.Is(err, io.EOF) {
+				return
+			}
+			t.Fatalf("invalid XML: %v\n%s", err, doc)
+		}
+	}
+}
+
+func TestRenderSVG(t *testing.T) {
+	src := "# Title\n\nSee [docs](https://example.com) & <more>.\n"
+	got := renderString(t, src, mdf.DefaultTheme(), Config{Width: 40})
+	checkXML(t, got)
+	for _, want := range []string{
+		`<svg xmlns="http://www.w3.org/2000/svg" width="368" height="`,
+		`<rect width="100%" height="100%" fill="#000000"/>`,
+		`fill="#00cd00" font-weight="bold"># </text>`,
+		`<a href="https://example.com"><text `,
+		`&amp; &lt;more&gt;.</text>`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("missing %q in:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "<circle") {
+		t.Fatal("window frame drawn without Config.Window")
+	}
+}
+
+func TestRenderSVGWindowAndLightTheme(t *testing.T) {
+	theme, _ := mdf.ThemeByName("github-light")
+	got := renderString(t, "hi\n", theme, Config{Window: true, Title: "doc.md"})
+	checkXML(t, got)
+	for _, want := range []string{
+		`rx="8" fill="#fafafa"/>`,
+		`<circle cx="22" cy="20" r="6" fill="#ff5f56"/>`,
+		`text-anchor="middle">doc.md</text>`,
+		`<text x="16" y="62" textLength="16.8" lengthAdjust="spacingAndGlyphs">hi</text>`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("missing %q in:\n%s", want, got)
+		}
+	}
+}
+
+func TestRenderSVGCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	var out bytes.Buffer
+	err := RenderContext(ctx, RenderRequest{Reader: strings.NewReader("hi\n"), Writer: &out})
+	if err != context.Canceled {
+		t.Fatalf("err = %v, want context.Canceled", err)
+	}
+	if out.Len() != 0 {
+		t.Fatalf("wrote %d bytes after cancellation", out.Len())
+	}
+}
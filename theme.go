@@ -7,11 +7,6 @@ import (
 	"pkt.systems/mdf/internal/palette"
 )
 
-// Style describes a terminal style as an ANSI prefix sequence.
-type Style struct {
-	Prefix string
-}
-
 // Styles groups the semantic styles used by the renderer.
 type Styles struct {
 	Text           Style
@@ -26,6 +21,16 @@ type Styles struct {
 	LinkText       Style
 	LinkURL        Style
 	ThematicBreak  Style
+	Strikethrough  Style
+	Image          Style
+	// Syntax highlighting roles for fenced code blocks. An empty role falls
+	// back to CodeBlock.
+	CodeKeyword     Style
+	CodeString      Style
+	CodeComment     Style
+	CodeNumber      Style
+	CodeType        Style
+	CodePunctuation Style
 }
 
 // Theme provides named styles for Markdown rendering.
@@ -42,8 +47,12 @@ type theme struct {
 func (t theme) Name() string   { return t.name }
 func (t theme) Styles() Styles { return t.styles }
 
-// NewTheme returns a Theme from a Styles definition.
+// NewTheme returns a Theme from a Styles definition. Styles that set only
+// Prefix, or only colours and attributes, have the other side filled in.
 func NewTheme(name string, styles Styles) Theme {
+	for _, s := range styleFields(&styles) {
+		*s = s.normalize()
+	}
 	return theme{name: name, styles: styles}
 }
 
@@ -54,23 +63,31 @@ func style(prefixes ...string) Style {
 			b.WriteString(p)
 		}
 	}
-	return Style{Prefix: b.String()}
+	return ParseStyle(b.String())
 }
 
 func stylesFromPalette(p palette.Palette) Styles {
 	return Styles{
-		Text:           style(p.Text),
-		Heading:        [6]Style{style(p.H1), style(p.H2), style(p.H3), style(p.H4), style(p.H5), style(p.H6)},
-		Emphasis:       style(palette.Italic, p.Emphasis),
-		Strong:         style(palette.Bold, p.Strong),
-		EmphasisStrong: style(palette.Bold, palette.Italic, p.EmphasisStrong),
-		CodeInline:     style(p.CodeInline),
-		CodeBlock:      style(p.CodeBlock),
-		Quote:          style(p.Quote),
-		ListMarker:     style(p.ListMarker),
-		LinkText:       style(palette.Underline, p.LinkText),
-		LinkURL:        style(p.LinkURL),
-		ThematicBreak:  style(p.ThematicBreak),
+		Text:            style(p.Text),
+		Heading:         [6]Style{style(p.H1), style(p.H2), style(p.H3), style(p.H4), style(p.H5), style(p.H6)},
+		Emphasis:        style(palette.Italic, p.Emphasis),
+		Strong:          style(palette.Bold, p.Strong),
+		EmphasisStrong:  style(palette.Bold, palette.Italic, p.EmphasisStrong),
+		CodeInline:      style(p.CodeInline),
+		CodeBlock:       style(p.CodeBlock),
+		Quote:           style(p.Quote),
+		ListMarker:      style(p.ListMarker),
+		LinkText:        style(palette.Underline, p.LinkText),
+		LinkURL:         style(p.LinkURL),
+		ThematicBreak:   style(p.ThematicBreak),
+		Strikethrough:   style(palette.Strike, p.Strikethrough),
+		Image:           style(palette.Italic, p.LinkText),
+		CodeKeyword:     style(p.CodeKeyword),
+		CodeString:      style(p.CodeString),
+		CodeComment:     style(palette.Italic, p.CodeComment),
+		CodeNumber:      style(p.CodeNumber),
+		CodeType:        style(p.CodeType),
+		CodePunctuation: style(p.CodePunctuation),
 	}
 }
 
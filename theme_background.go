@@ -0,0 +1,162 @@
+package mdf
+
+import (
+	"bytes"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Background is the brightness of a terminal's background.
+type Background uint8
+
+const (
+	// BackgroundUnknown means the background could not be determined.
+	BackgroundUnknown Background = iota
+	// BackgroundDark is a dark background.
+	BackgroundDark
+	// BackgroundLight is a light background.
+	BackgroundLight
+)
+
+// themeVariant says which background a built-in theme is meant for and
+// which built-in theme to use on the other one.
+type themeVariant struct {
+	light       bool
+	counterpart string
+}
+
+// builtinVariants pairs every built-in theme with a theme for the other
+// kind of background. Themes without a sibling of their own are paired
+// with the closest light theme.
+var builtinVariants = map[string]themeVariant{
+	"default":             {counterpart: "one-light"},
+	"outrun-electric":     {counterpart: "one-light"},
+	"iosvkem":             {counterpart: "one-light"},
+	"gruvbox":             {counterpart: "gruvbox-light"},
+	"dracula":             {counterpart: "one-light"},
+	"nord":                {counterpart: "github-light"},
+	"tokyo-night":         {counterpart: "github-light"},
+	"solarized-nightfall": {counterpart: "solarized-light"},
+	"catppuccin-mocha":    {counterpart: "github-light"},
+	"gruvbox-light":       {light: true, counterpart: "gruvbox"},
+	"monokai-vibrant":     {counterpart: "one-light"},
+	"one-dark-aurora":     {counterpart: "one-light"},
+	"synthwave-84":        {counterpart: "rose-pine-dawn"},
+	"kanagawa":            {counterpart: "papercolor-light"},
+	"rose-pine":           {counterpart: "rose-pine-dawn"},
+	"rose-pine-dawn":      {light: true, counterpart: "rose-pine"},
+	"everforest":          {counterpart: "everforest-light"},
+	"everforest-light":    {light: true, counterpart: "everforest"},
+	"night-owl":           {counterpart: "github-light"},
+	"ayu-mirage":          {counterpart: "ayu-light"},
+	"ayu-light":           {light: true, counterpart: "ayu-mirage"},
+	"one-light":           {light: true, counterpart: "one-dark"},
+	"one-dark":            {counterpart: "one-light"},
+	"solarized-light":     {light: true, counterpart: "solarized-dark"},
+	"solarized-dark":      {counterpart: "solarized-light"},
+	"github-light":        {light: true, counterpart: "github-dark"},
+	"github-dark":         {counterpart: "github-light"},
+	"papercolor-light":    {light: true, counterpart: "papercolor-dark"},
+	"papercolor-dark":     {counterpart: "papercolor-light"},
+	"oceanic-next":        {counterpart: "solarized-light"},
+	"horizon":             {counterpart: "rose-pine-dawn"},
+	"palenight":           {counterpart: "one-light"},
+}
+
+// ThemeForBackground returns the built-in theme t or its counterpart,
+// whichever is meant for bg. Themes that are not built in and an unknown
+// background return t.
+func ThemeForBackground(t Theme, bg Background) Theme {
+	if t == nil {
+		t = DefaultTheme()
+	}
+	v, ok := builtinVariants[t.Name()]
+	if !ok || bg == BackgroundUnknown || v.light == (bg == BackgroundLight) {
+		return t
+	}
+	if other, ok := builtinThemes[v.counterpart]; ok {
+		return other
+	}
+	return t
+}
+
+// ThemeBackground returns the background the built-in theme t is meant for,
+// or BackgroundUnknown for themes that are not built in.
+func ThemeBackground(t Theme) Background {
+	if t == nil {
+		t = DefaultTheme()
+	}
+	v, ok := builtinVariants[t.Name()]
+	switch {
+	case !ok:
+		return BackgroundUnknown
+	case v.light:
+		return BackgroundLight
+	}
+	return BackgroundDark
+}
+
+// DetectBackground reports whether the terminal has a dark or light
+// background. It asks the controlling terminal for its background colour
+// with OSC 11, waiting at most timeout for the answer, and falls back to
+// COLORFGBG.
+func DetectBackground(timeout time.Duration) Background {
+	if bg := queryBackground(timeout); bg != BackgroundUnknown {
+		return bg
+	}
+	return backgroundFromEnv()
+}
+
+// backgroundFromEnv reads COLORFGBG, "fg;bg" or "fg;default;bg" in ANSI
+// colour numbers, as set by rxvt and others.
+func backgroundFromEnv() Background {
+	value := os.Getenv("COLORFGBG")
+	if value == "" {
+		return BackgroundUnknown
+	}
+	fields := strings.Split(value, ";")
+	n, err := strconv.Atoi(fields[len(fields)-1])
+	switch {
+	case err != nil || n < 0 || n > 15:
+		return BackgroundUnknown
+	case n <= 6 || n == 8:
+		return BackgroundDark
+	}
+	return BackgroundLight
+}
+
+// parseBackgroundReply reads the background colour from an OSC 11 reply,
+// "\x1b]11;rgb:RRRR/GGGG/BBBB" ended by BEL or ST, with one to four hex
+// digits per component.
+func parseBackgroundReply(reply []byte) Background {
+	i := bytes.Index(reply, []byte("\x1b]11;rgb:"))
+	if i < 0 {
+		return BackgroundUnknown
+	}
+	reply = reply[i+len("\x1b]11;rgb:"):]
+	end := bytes.IndexAny(reply, "\x07\x1b")
+	if end < 0 {
+		return BackgroundUnknown
+	}
+	parts := strings.Split(string(reply[:end]), "/")
+	if len(parts) != 3 {
+		return BackgroundUnknown
+	}
+	var rgb [3]float64
+	for i, part := range parts {
+		if len(part) == 0 || len(part) > 4 {
+			return BackgroundUnknown
+		}
+		v, err := strconv.ParseUint(part, 16, 16)
+		if err != nil {
+			return BackgroundUnknown
+		}
+		rgb[i] = float64(v) / float64(uint64(1)<<(4*len(part))-1)
+	}
+	if 0.2126*rgb[0]+0.7152*rgb[1]+0.0722*rgb[2] > 0.5 {
+		return BackgroundLight
+	}
+	return BackgroundDark
+}
@@ -0,0 +1,10 @@
+//go:build !unix
+
+package mdf
+
+import "time"
+
+// queryBackground is not supported on this platform.
+func queryBackground(time.Duration) Background {
+	return BackgroundUnknown
+}
@@ -0,0 +1,91 @@
+package mdf
+
+import "testing"
+
+func TestBuiltinVariantsPairEveryTheme(t *testing.T) {
+	if len(builtinVariants) != len(builtinThemes) {
+		t.Fatalf("builtinVariants has %d themes, builtinThemes %d", len(builtinVariants), len(builtinThemes))
+	}
+	for name, v := range builtinVariants {
+		if _, ok := builtinThemes[name]; !ok {
+			t.Fatalf("variant declared for unknown theme %q", name)
+		}
+		other, ok := builtinVariants[v.counterpart]
+		if !ok {
+			t.Fatalf("%s: unknown counterpart %q", name, v.counterpart)
+		}
+		if other.light == v.light {
+			t.Fatalf("%s: counterpart %q is for the same background", name, v.counterpart)
+		}
+	}
+}
+
+func TestThemeForBackground(t *testing.T) {
+	gruvbox, _ := ThemeByName("gruvbox")
+	light, _ := ThemeByName("gruvbox-light")
+	cases := []struct {
+		theme Theme
+		bg    Background
+		want  string
+	}{
+		{gruvbox, BackgroundLight, "gruvbox-light"},
+		{gruvbox, BackgroundDark, "gruvbox"},
+		{light, BackgroundDark, "gruvbox"},
+		{light, BackgroundUnknown, "gruvbox-light"},
+		{NewTheme("custom", Styles{}), BackgroundLight, "custom"},
+	}
+	for _, tc := range cases {
+		if got := ThemeForBackground(tc.theme, tc.bg).Name(); got != tc.want {
+			t.Fatalf("ThemeForBackground(%s, %d) = %s, want %s", tc.theme.Name(), tc.bg, got, tc.want)
+		}
+	}
+}
+
+func TestThemeBackground(t *testing.T) {
+	gruvbox, _ := ThemeByName("gruvbox")
+	light, _ := ThemeByName("gruvbox-light")
+	if got := ThemeBackground(gruvbox); got != BackgroundDark {
+		t.Fatalf("ThemeBackground(gruvbox) = %d, want dark", got)
+	}
+	if got := ThemeBackground(light); got != BackgroundLight {
+		t.Fatalf("ThemeBackground(gruvbox-light) = %d, want light", got)
+	}
+	if got := ThemeBackground(NewTheme("custom", Styles{})); got != BackgroundUnknown {
+		t.Fatalf("ThemeBackground(custom) = %d, want unknown", got)
+	}
+}
+
+func TestParseBackgroundReply(t *testing.T) {
+	cases := map[string]Background{
+		"\x1b]11;rgb:ffff/ffff/ffff\x1b\\\x1b[?62;22c": BackgroundLight,
+		"\x1b]11;rgb:2828/2828/2828\x07":               BackgroundDark,
+		"\x1b]11;rgb:fd/f6/e3\x07":                     BackgroundLight,
+		"\x1b]11;rgb:0/2/3\x1b\\":                      BackgroundDark,
+		"\x1b[?62;22c":                                 BackgroundUnknown,
+		"\x1b]11;rgb:ffff/ffff\x07":                    BackgroundUnknown,
+		"\x1b]11;rgb:ffff/ffff/ffff":                   BackgroundUnknown,
+	}
+	for reply, want := range cases {
+		if got := parseBackgroundReply([]byte(reply)); got != want {
+			t.Fatalf("parseBackgroundReply(%q) = %d, want %d", reply, got, want)
+		}
+	}
+}
+
+func TestBackgroundFromEnv(t *testing.T) {
+	cases := map[string]Background{
+		"15;0":         BackgroundDark,
+		"0;15":         BackgroundLight,
+		"0;default;7":  BackgroundLight,
+		"7;8":          BackgroundDark,
+		"":             BackgroundUnknown,
+		"15;default":   BackgroundUnknown,
+		"15;something": BackgroundUnknown,
+	}
+	for value, want := range cases {
+		t.Setenv("COLORFGBG", value)
+		if got := backgroundFromEnv(); got != want {
+			t.Fatalf("COLORFGBG=%q: got %d, want %d", value, got, want)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+//go:build unix
+
+package mdf
+
+import (
+	"bytes"
+	"os"
+	"time"
+
+	"golang.org/x/term"
+)
+
+// queryBackground asks the controlling terminal for its background colour.
+// The OSC 11 query is followed by a primary device attributes query, which
+// every terminal answers, so terminals that ignore OSC 11 are detected
+// without waiting for the timeout.
+func queryBackground(timeout time.Duration) Background {
+	if timeout <= 0 {
+		return BackgroundUnknown
+	}
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return BackgroundUnknown
+	}
+	defer func() { _ = tty.Close() }()
+	// Fd would switch the file to blocking mode and disable deadlines.
+	conn, err := tty.SyscallConn()
+	if err != nil {
+		return BackgroundUnknown
+	}
+	fd := -1
+	_ = conn.Control(func(f uintptr) { fd = int(f) })
+	state, err := term.MakeRaw(fd)
+	if err != nil {
+		return BackgroundUnknown
+	}
+	defer func() { _ = term.Restore(fd, state) }()
+	if err := tty.SetReadDeadline(time.Now().Add(timeout)); err != nil {
+		return BackgroundUnknown
+	}
+	if _, err := tty.WriteString("\x1b]11;?\x1b\\\x1b[c"); err != nil {
+		return BackgroundUnknown
+	}
+	var reply []byte
+	var buf [256]byte
+	for {
+		n, err := tty.Read(buf[:])
+		reply = append(reply, buf[:n]...)
+		if err != nil || deviceAttributesReply(reply) || len(reply) > 1024 {
+			break
+		}
+	}
+	return parseBackgroundReply(reply)
+}
+
+// deviceAttributesReply reports whether reply holds the answer to the
+// primary device attributes query, "\x1b[?...c".
+func deviceAttributesReply(reply []byte) bool {
+	i := bytes.Index(reply, []byte("\x1b[?"))
+	return i >= 0 && bytes.IndexByte(reply[i:], 'c') >= 0
+}
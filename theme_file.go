@@ -0,0 +1,542 @@
+package mdf
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// namedStyle is a style of a theme as named in theme files.
+type namedStyle struct {
+	key   string
+	style *Style
+}
+
+// namedStyles returns the styles of s under their theme file names, in the
+// order ExportTheme writes them.
+func namedStyles(s *Styles) []namedStyle {
+	return []namedStyle{
+		{"text", &s.Text},
+		{"heading1", &s.Heading[0]},
+		{"heading2", &s.Heading[1]},
+		{"heading3", &s.Heading[2]},
+		{"heading4", &s.Heading[3]},
+		{"heading5", &s.Heading[4]},
+		{"heading6", &s.Heading[5]},
+		{"emphasis", &s.Emphasis},
+		{"strong", &s.Strong},
+		{"emphasis_strong", &s.EmphasisStrong},
+		{"code_inline", &s.CodeInline},
+		{"code_block", &s.CodeBlock},
+		{"quote", &s.Quote},
+		{"list_marker", &s.ListMarker},
+		{"link_text", &s.LinkText},
+		{"link_url", &s.LinkURL},
+		{"thematic_break", &s.ThematicBreak},
+		{"strikethrough", &s.Strikethrough},
+		{"image", &s.Image},
+		{"code_keyword", &s.CodeKeyword},
+		{"code_string", &s.CodeString},
+		{"code_comment", &s.CodeComment},
+		{"code_number", &s.CodeNumber},
+		{"code_type", &s.CodeType},
+		{"code_punctuation", &s.CodePunctuation},
+	}
+}
+
+// colorNames are the names of the ANSI colours 0 to 7; "bright-" names 8
+// to 15.
+var colorNames = [8]string{"black", "red", "green", "yellow", "blue", "magenta", "cyan", "white"}
+
+// namedAttr is a style attribute as named in theme files.
+type namedAttr struct {
+	name string
+	set  *bool
+}
+
+// namedAttrs returns the attributes of s, in the order ExportTheme writes
+// them.
+func namedAttrs(s *Style) []namedAttr {
+	return []namedAttr{
+		{"bold", &s.Bold},
+		{"dim", &s.Dim},
+		{"italic", &s.Italic},
+		{"underline", &s.Underline},
+		{"reverse", &s.Reverse},
+		{"strikethrough", &s.Strikethrough},
+	}
+}
+
+// LoadTheme reads a theme file in TOML or JSON; a document starting with
+// "{" is JSON. The top level may set name and extends, the name of a
+// built-in theme to start from. Every other key is a table naming a style
+// (text, heading1 to heading6, emphasis, strong, emphasis_strong,
+// code_inline, code_block, quote, list_marker, link_text, link_url,
+// thematic_break, strikethrough, image, code_keyword, code_string,
+// code_comment, code_number, code_type, code_punctuation) with optional
+// keys:
+//
+//   - fg and bg: a colour as "#rrggbb", "#rgb", an xterm palette index
+//     from 0 to 255, one of the names black, red, green, yellow, blue,
+//     magenta, cyan and white, optionally prefixed with "bright-", or
+//     "default"
+//   - attrs: attribute words from bold, dim, italic, underline, reverse and
+//     strikethrough, as an array or a space-separated string
+//
+// A style table replaces the base theme's style as a whole.
+//
+//	name = "my-nord"
+//	extends = "nord"
+//
+//	[heading1]
+//	fg = "#88c0d0"
+//	attrs = ["bold", "underline"]
+func LoadTheme(r io.Reader) (Theme, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("theme: %w", err)
+	}
+	data = bytes.TrimPrefix(data, []byte("\ufeff"))
+	var doc map[string]any
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		if err := json.Unmarshal(trimmed, &doc); err != nil {
+			return nil, fmt.Errorf("theme: %w", err)
+		}
+	} else if doc, err = parseThemeTOML(data); err != nil {
+		return nil, fmt.Errorf("theme: %w", err)
+	}
+	t, err := themeFromDoc(doc)
+	if err != nil {
+		return nil, fmt.Errorf("theme: %w", err)
+	}
+	return t, nil
+}
+
+func themeFromDoc(doc map[string]any) (Theme, error) {
+	name, styles := "custom", Styles{}
+	if v, ok := doc["extends"]; ok {
+		base, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("extends: expected a theme name")
+		}
+		t, ok := ThemeByName(base)
+		if !ok {
+			return nil, fmt.Errorf("unknown base theme %q", base)
+		}
+		name, styles = t.Name(), t.Styles()
+	}
+	if v, ok := doc["name"]; ok {
+		s, ok := v.(string)
+		if !ok || s == "" {
+			return nil, fmt.Errorf("name: expected a string")
+		}
+		name = s
+	}
+	named := namedStyles(&styles)
+	for key, value := range doc {
+		if key == "name" || key == "extends" {
+			continue
+		}
+		var target *Style
+		for _, n := range named {
+			if n.key == key {
+				target = n.style
+				break
+			}
+		}
+		if target == nil {
+			return nil, fmt.Errorf("unknown style %q", key)
+		}
+		table, ok := value.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("%s: expected a table", key)
+		}
+		st, err := styleFromTable(table)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", key, err)
+		}
+		*target = st
+	}
+	return NewTheme(name, styles), nil
+}
+
+// styleFromTable returns the style a style table describes.
+func styleFromTable(table map[string]any) (Style, error) {
+	var st Style
+	for key := range table {
+		switch key {
+		case "fg", "bg", "attrs":
+		default:
+			return st, fmt.Errorf("unknown key %q", key)
+		}
+	}
+	if v, ok := table["attrs"]; ok {
+		words, err := attrWords(v)
+		if err != nil {
+			return st, err
+		}
+		for _, a := range namedAttrs(&st) {
+			if words[a.name] {
+				*a.set = true
+				delete(words, a.name)
+			}
+		}
+		for word := range words {
+			return st, fmt.Errorf("unknown attribute %q", word)
+		}
+	}
+	for _, c := range []struct {
+		key   string
+		color *Color
+	}{{"fg", &st.FG}, {"bg", &st.BG}} {
+		v, ok := table[c.key]
+		if !ok {
+			continue
+		}
+		color, err := parseColorValue(v)
+		if err != nil {
+			return st, fmt.Errorf("%s: %w", c.key, err)
+		}
+		*c.color = color
+	}
+	st.Prefix = st.SGR()
+	return st, nil
+}
+
+func attrWords(v any) (map[string]bool, error) {
+	var list []string
+	switch v := v.(type) {
+	case string:
+		list = strings.FieldsFunc(v, func(r rune) bool { return r == ' ' || r == ',' })
+	case []any:
+		for _, item := range v {
+			s, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("attrs: expected words")
+			}
+			list = append(list, s)
+		}
+	default:
+		return nil, fmt.Errorf("attrs: expected words")
+	}
+	words := make(map[string]bool, len(list))
+	for _, word := range list {
+		word = strings.ToLower(strings.TrimSpace(word))
+		switch word {
+		case "faint":
+			word = "dim"
+		case "strike":
+			word = "strikethrough"
+		}
+		words[word] = true
+	}
+	return words, nil
+}
+
+// parseColorValue parses a colour of a theme file.
+func parseColorValue(v any) (Color, error) {
+	switch v := v.(type) {
+	case int64:
+		return indexedColorValue(v)
+	case float64:
+		if v != math.Trunc(v) {
+			return Color{}, fmt.Errorf("invalid colour %v", v)
+		}
+		return indexedColorValue(int64(v))
+	case string:
+		name := strings.ToLower(strings.TrimSpace(v))
+		if strings.HasPrefix(name, "#") {
+			rgb, ok := parseHexColor(name[1:])
+			if !ok {
+				return Color{}, fmt.Errorf("invalid colour %q", v)
+			}
+			return RGBColor(rgb[0], rgb[1], rgb[2]), nil
+		}
+		if n, err := strconv.ParseInt(name, 10, 64); err == nil {
+			return indexedColorValue(n)
+		}
+		if name == "default" {
+			return Color{}, nil
+		}
+		if name == "gray" || name == "grey" {
+			name = "bright-black"
+		}
+		offset := 0
+		for _, p := range []string{"bright-", "bright_", "bright"} {
+			if strings.HasPrefix(name, p) {
+				name, offset = name[len(p):], 8
+				break
+			}
+		}
+		for i, c := range colorNames {
+			if c == name {
+				return IndexedColor(uint8(offset + i)), nil
+			}
+		}
+		return Color{}, fmt.Errorf("invalid colour %q", v)
+	}
+	return Color{}, fmt.Errorf("invalid colour %v", v)
+}
+
+func indexedColorValue(n int64) (Color, error) {
+	if n < 0 || n > 255 {
+		return Color{}, fmt.Errorf("colour index %d out of range", n)
+	}
+	return IndexedColor(uint8(n)), nil
+}
+
+func parseHexColor(hex string) ([3]uint8, bool) {
+	var rgb [3]uint8
+	if len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+	if len(hex) != 6 {
+		return rgb, false
+	}
+	for i := range rgb {
+		v, err := strconv.ParseUint(hex[i*2:i*2+2], 16, 8)
+		if err != nil {
+			return rgb, false
+		}
+		rgb[i] = uint8(v)
+	}
+	return rgb, true
+}
+
+// ExportTheme writes t as a TOML theme file that LoadTheme reads back to
+// the same styles. Every style is written, so the file does not depend on
+// a base theme.
+func ExportTheme(w io.Writer, t Theme) error {
+	if t == nil {
+		t = DefaultTheme()
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "name = %s\n", strconv.Quote(t.Name()))
+	styles := t.Styles()
+	for _, n := range namedStyles(&styles) {
+		fmt.Fprintf(&b, "\n[%s]\n", n.key)
+		st := n.style.normalize()
+		if st.FG.Kind != ColorDefault {
+			fmt.Fprintf(&b, "fg = %s\n", colorValue(st.FG))
+		}
+		if st.BG.Kind != ColorDefault {
+			fmt.Fprintf(&b, "bg = %s\n", colorValue(st.BG))
+		}
+		var attrs []string
+		for _, a := range namedAttrs(&st) {
+			if *a.set {
+				attrs = append(attrs, strconv.Quote(a.name))
+			}
+		}
+		if len(attrs) > 0 {
+			fmt.Fprintf(&b, "attrs = [%s]\n", strings.Join(attrs, ", "))
+		}
+	}
+	if _, err := io.WriteString(w, b.String()); err != nil {
+		return fmt.Errorf("theme: %w", err)
+	}
+	return nil
+}
+
+// colorValue formats c as a TOML theme file value.
+func colorValue(c Color) string {
+	if c.Kind == ColorRGB {
+		return strconv.Quote(fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B))
+	}
+	switch {
+	case c.Index < 8:
+		return strconv.Quote(colorNames[c.Index])
+	case c.Index < 16:
+		return strconv.Quote("bright-" + colorNames[c.Index-8])
+	}
+	return strconv.Itoa(int(c.Index))
+}
+
+// parseThemeTOML parses the subset of TOML theme files use: top-level keys
+// and tables of keys whose values are strings, integers or arrays of them,
+// which may span lines.
+func parseThemeTOML(data []byte) (map[string]any, error) {
+	doc := map[string]any{}
+	table := doc
+	lines := strings.Split(string(data), "\n")
+	i := 0
+	more := func() (string, bool) {
+		if i+1 >= len(lines) {
+			return "", false
+		}
+		i++
+		return strings.TrimSpace(strings.TrimSuffix(lines[i], "\r")), true
+	}
+	for ; i < len(lines); i++ {
+		line := strings.TrimSpace(strings.TrimSuffix(lines[i], "\r"))
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		if line[0] == '[' {
+			end := strings.IndexByte(line, ']')
+			if end < 0 || !tomlTrailing(line[end+1:]) {
+				return nil, fmt.Errorf("line %d: invalid table header", i+1)
+			}
+			name := strings.TrimSpace(line[1:end])
+			if !tomlBareKey(name) {
+				return nil, fmt.Errorf("line %d: invalid table name %q", i+1, name)
+			}
+			if _, ok := doc[name]; ok {
+				return nil, fmt.Errorf("line %d: duplicate table %q", i+1, name)
+			}
+			table = map[string]any{}
+			doc[name] = table
+			continue
+		}
+		key, rest, ok := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !ok || !tomlBareKey(key) {
+			return nil, fmt.Errorf("line %d: expected key = value", i+1)
+		}
+		value, rest, err := parseTOMLValue(strings.TrimSpace(rest), more)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", i+1, err)
+		}
+		if !tomlTrailing(rest) {
+			return nil, fmt.Errorf("line %d: unexpected %q", i+1, strings.TrimSpace(rest))
+		}
+		if _, ok := table[key]; ok {
+			return nil, fmt.Errorf("line %d: duplicate key %q", i+1, key)
+		}
+		table[key] = value
+	}
+	return doc, nil
+}
+
+func tomlBareKey(key string) bool {
+	if key == "" {
+		return false
+	}
+	for i := 0; i < len(key); i++ {
+		c := key[i]
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '-') {
+			return false
+		}
+	}
+	return true
+}
+
+// tomlTrailing reports whether rest holds only space and a comment.
+func tomlTrailing(rest string) bool {
+	rest = strings.TrimSpace(rest)
+	return rest == "" || rest[0] == '#'
+}
+
+// parseTOMLValue parses the value at the start of s and returns the rest.
+// An array that is still open at the end of s goes on with the lines more
+// returns.
+func parseTOMLValue(s string, more func() (string, bool)) (any, string, error) {
+	if s == "" {
+		return nil, "", fmt.Errorf("missing value")
+	}
+	switch s[0] {
+	case '"':
+		return parseTOMLString(s)
+	case '\'':
+		end := strings.IndexByte(s[1:], '\'')
+		if end < 0 {
+			return nil, "", fmt.Errorf("unterminated string")
+		}
+		return s[1 : end+1], s[end+2:], nil
+	case '[':
+		var items []any
+		// next skips space, comments and line ends up to the next token.
+		next := func(s string) (string, error) {
+			for s = strings.TrimSpace(s); s == "" || s[0] == '#'; s = strings.TrimSpace(s) {
+				var ok bool
+				if s, ok = more(); !ok {
+					return "", fmt.Errorf("unterminated array")
+				}
+			}
+			return s, nil
+		}
+		s, err := next(s[1:])
+		if err != nil {
+			return nil, "", err
+		}
+		for s[0] != ']' {
+			item, rest, err := parseTOMLValue(s, more)
+			if err != nil {
+				return nil, "", err
+			}
+			items = append(items, item)
+			if s, err = next(rest); err != nil {
+				return nil, "", err
+			}
+			switch s[0] {
+			case ',':
+				if s, err = next(s[1:]); err != nil {
+					return nil, "", err
+				}
+			case ']':
+			default:
+				return nil, "", fmt.Errorf("expected , or ] in array")
+			}
+		}
+		return items, s[1:], nil
+	}
+	end := strings.IndexAny(s, " \t,]#")
+	if end < 0 {
+		end = len(s)
+	}
+	n, err := strconv.ParseInt(strings.ReplaceAll(s[:end], "_", ""), 10, 64)
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid value %q", s[:end])
+	}
+	return n, s[end:], nil
+}
+
+func parseTOMLString(s string) (any, string, error) {
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		c := s[i]
+		switch c {
+		case '"':
+			return b.String(), s[i+1:], nil
+		case '\\':
+			i++
+			if i >= len(s) {
+				return nil, "", fmt.Errorf("unterminated string")
+			}
+			switch s[i] {
+			case '"', '\\':
+				b.WriteByte(s[i])
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			case 'r':
+				b.WriteByte('\r')
+			case 'u', 'U':
+				size := 4
+				if s[i] == 'U' {
+					size = 8
+				}
+				if i+size >= len(s) {
+					return nil, "", fmt.Errorf("invalid escape")
+				}
+				r, err := strconv.ParseUint(s[i+1:i+1+size], 16, 32)
+				if err != nil || !utf8.ValidRune(rune(r)) {
+					return nil, "", fmt.Errorf("invalid escape")
+				}
+				b.WriteRune(rune(r))
+				i += size
+			default:
+				return nil, "", fmt.Errorf("invalid escape \\%c", s[i])
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return nil, "", fmt.Errorf("unterminated string")
+}
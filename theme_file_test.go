@@ -0,0 +1,116 @@
+package mdf
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLoadThemeTOML(t *testing.T) {
+	src := `# a nord variant
+name = "my-nord"
+extends = "nord"
+
+[heading1]
+fg = "#88c0d0"  # frost
+attrs = ["bold", "underline"]
+
+[code_inline]
+fg = 219
+bg = "bright-black"
+
+[quote]
+attrs = "italic dim"
+
+[strong]
+attrs = [
+  "bold",   # heavy
+  "underline",
+]
+`
+	theme, err := LoadTheme(strings.NewReader(src))
+	if err != nil {
+		t.Fatalf("LoadTheme: %v", err)
+	}
+	if theme.Name() != "my-nord" {
+		t.Fatalf("name = %q", theme.Name())
+	}
+	styles := theme.Styles()
+	cases := map[string]string{
+		"heading1":    "\x1b[1;4;38;2;136;192;208m",
+		"code_inline": "\x1b[38;5;219;100m",
+		"quote":       "\x1b[2;3m",
+		"strong":      "\x1b[1;4m",
+	}
+	for _, n := range namedStyles(&styles) {
+		if want, ok := cases[n.key]; ok && n.style.Prefix != want {
+			t.Fatalf("%s = %q, want %q", n.key, n.style.Prefix, want)
+		}
+	}
+	nord, _ := ThemeByName("nord")
+	if styles.LinkText != nord.Styles().LinkText {
+		t.Fatalf("expected link_text to come from nord")
+	}
+}
+
+func TestLoadThemeJSONMatchesTOML(t *testing.T) {
+	fromTOML, err := LoadTheme(strings.NewReader("extends = 'gruvbox'\n[strong]\nfg = \"#fb4\"\nattrs = [\"bold\"]\n"))
+	if err != nil {
+		t.Fatalf("LoadTheme toml: %v", err)
+	}
+	fromJSON, err := LoadTheme(strings.NewReader(`{"extends": "gruvbox", "strong": {"fg": "#fb4", "attrs": ["bold"]}}`))
+	if err != nil {
+		t.Fatalf("LoadTheme json: %v", err)
+	}
+	if fromTOML.Styles() != fromJSON.Styles() {
+		t.Fatalf("expected TOML and JSON themes to match")
+	}
+	if got := fromJSON.Styles().Strong.Prefix; got != "\x1b[1;38;2;255;187;68m" {
+		t.Fatalf("strong = %q", got)
+	}
+}
+
+func TestExportThemeRoundTrip(t *testing.T) {
+	for _, name := range AvailableThemes() {
+		theme, _ := ThemeByName(name)
+		var first strings.Builder
+		if err := ExportTheme(&first, theme); err != nil {
+			t.Fatalf("%s: ExportTheme: %v", name, err)
+		}
+		loaded, err := LoadTheme(strings.NewReader(first.String()))
+		if err != nil {
+			t.Fatalf("%s: LoadTheme: %v\n%s", name, err, first.String())
+		}
+		if loaded.Name() != name {
+			t.Fatalf("%s: loaded name %q", name, loaded.Name())
+		}
+		var second strings.Builder
+		if err := ExportTheme(&second, loaded); err != nil {
+			t.Fatalf("%s: ExportTheme: %v", name, err)
+		}
+		if first.String() != second.String() {
+			t.Fatalf("%s: export does not round-trip:\n%s\n---\n%s", name, first.String(), second.String())
+		}
+	}
+}
+
+func TestLoadThemeErrors(t *testing.T) {
+	cases := map[string]string{
+		"extends = \"nope\"\n":                "unknown base theme",
+		"[headline]\nfg = \"red\"\n":          "unknown style",
+		"[text]\nfg = \"reddish\"\n":          "invalid colour",
+		"[text]\nfg = 300\n":                  "out of range",
+		"[text]\nattrs = \"blink\"\n":         "unknown attribute",
+		"[text]\ncolour = \"red\"\n":          "unknown key",
+		"[text]\nfg = \"red\n":                "line 2: unterminated string",
+		"[text]\n[text]\n":                    "duplicate table",
+		"[text]\nattrs = [\n\"bold\",\n":      "unterminated array",
+		"[text]\nattrs = [\"bold\"\n\"x\"]\n": "expected , or ]",
+		"{\"text\": {\"fg\": \"#12345\"}}":    "invalid colour",
+	}
+	for src, want := range cases {
+		_, err := LoadTheme(strings.NewReader(src))
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Fatalf("LoadTheme(%q) error = %v, want %q", src, err, want)
+		}
+	}
+}
@@ -41,3 +41,23 @@ func TestThemeByNameExpanded(t *testing.T) {
 		}
 	}
 }
+
+func TestThemesDefineCodeRoles(t *testing.T) {
+	for _, name := range AvailableThemes() {
+		theme, _ := ThemeByName(name)
+		s := theme.Styles()
+		roles := map[string]Style{
+			"keyword":     s.CodeKeyword,
+			"string":      s.CodeString,
+			"comment":     s.CodeComment,
+			"number":      s.CodeNumber,
+			"type":        s.CodeType,
+			"punctuation": s.CodePunctuation,
+		}
+		for role, style := range roles {
+			if style.Prefix == "" {
+				t.Fatalf("theme %q leaves the code %s role empty", name, role)
+			}
+		}
+	}
+}
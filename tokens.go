@@ -2,13 +2,72 @@ package mdf
 
 // Token is a text segment with a style applied.
 type Token struct {
-	Text      string
-	Style     Style
-	Kind      tokenKind
-	LinkURL   string
+	Text    string
+	Style   Style
+	Kind    tokenKind
+	LinkURL string
+	// LinkRef is the label a reference link names, as written, on its link
+	// start and on its definition. A shortcut or collapsed reference names
+	// its own text.
+	LinkRef   string
 	CodeBlock bool
+	// HTML is set on an inline tag or comment passed on as code, as it was
+	// written.
+	HTML bool
+	// Block holds the attributes of block start and end events.
+	Block BlockInfo
 }
 
+// BlockInfo carries the attributes of a block event. The start and end
+// events of a block carry the same values; fields that do not apply to the
+// block are zero.
+type BlockInfo struct {
+	// Level is the heading level (1-6) or the blockquote nesting depth.
+	Level int
+	// Ordered reports whether a list is numbered, and Start is the number
+	// of its first item.
+	Ordered bool
+	Start   int
+	// Task is the checkbox state of a list item.
+	Task TaskState
+	// Lang is the first word of a fenced code block's info string.
+	Lang string
+	// HTML reports whether a code block is an HTML block passed on as code.
+	HTML bool
+	// Header reports whether a table row or cell belongs to the header.
+	Header bool
+	// Column is the zero-based column of a table cell and Align the
+	// alignment the delimiter row gives that column.
+	Column int
+	Align  CellAlign
+}
+
+// CellAlign is the alignment of a table column.
+type CellAlign uint8
+
+const (
+	// AlignNone marks a column whose delimiter has no colon, as "---".
+	AlignNone CellAlign = iota
+	// AlignLeft marks a ":--" column.
+	AlignLeft
+	// AlignCenter marks a ":-:" column.
+	AlignCenter
+	// AlignRight marks a "--:" column.
+	AlignRight
+)
+
+// TaskState is the checkbox state of a task list item.
+type TaskState uint8
+
+const (
+	// TaskNone marks an ordinary list item.
+	TaskNone TaskState = iota
+	// TaskUnchecked marks an unchecked "[ ]" item.
+	TaskUnchecked
+	// TaskChecked marks a checked "[x]" item.
+	TaskChecked
+)
+
 type tokenKind uint8
 
 // TokenKind is the exported alias of tokenKind for tooling and reference renderers.
@@ -21,6 +80,28 @@ const (
 	tokenURL
 	tokenCode
 	tokenThematicBreak
+	tokenImage
+	tokenAnchor
+	// Block events come in start/end pairs; each end kind is its start + 1.
+	tokenHeadingStart
+	tokenHeadingEnd
+	tokenParagraphStart
+	tokenParagraphEnd
+	tokenListStart
+	tokenListEnd
+	tokenListItemStart
+	tokenListItemEnd
+	tokenBlockquoteStart
+	tokenBlockquoteEnd
+	tokenCodeBlockStart
+	tokenCodeBlockEnd
+	tokenTableStart
+	tokenTableEnd
+	tokenTableRowStart
+	tokenTableRowEnd
+	tokenTableCellStart
+	tokenTableCellEnd
+	tokenDefinition
 )
 
 const (
@@ -36,4 +117,60 @@ const (
 	TokenCode tokenKind = tokenCode
 	// TokenThematicBreak represents a thematic break token.
 	TokenThematicBreak tokenKind = tokenThematicBreak
+	// TokenImage represents an image; Text holds the alt text and LinkURL the source.
+	TokenImage tokenKind = tokenImage
+	// TokenAnchor marks a link destination named by LinkURL. Links whose URL is
+	// "#" followed by that name point at it.
+	TokenAnchor tokenKind = tokenAnchor
+	// TokenHeadingStart opens a heading; Block.Level holds its level.
+	TokenHeadingStart tokenKind = tokenHeadingStart
+	// TokenHeadingEnd closes a heading.
+	TokenHeadingEnd tokenKind = tokenHeadingEnd
+	// TokenParagraphStart opens a paragraph.
+	TokenParagraphStart tokenKind = tokenParagraphStart
+	// TokenParagraphEnd closes a paragraph.
+	TokenParagraphEnd tokenKind = tokenParagraphEnd
+	// TokenListStart opens a list; Block.Ordered and Block.Start describe it.
+	TokenListStart tokenKind = tokenListStart
+	// TokenListEnd closes a list.
+	TokenListEnd tokenKind = tokenListEnd
+	// TokenListItemStart opens a list item; Block.Task holds its checkbox state.
+	TokenListItemStart tokenKind = tokenListItemStart
+	// TokenListItemEnd closes a list item.
+	TokenListItemEnd tokenKind = tokenListItemEnd
+	// TokenBlockquoteStart opens a blockquote; Block.Level holds its depth.
+	TokenBlockquoteStart tokenKind = tokenBlockquoteStart
+	// TokenBlockquoteEnd closes a blockquote.
+	TokenBlockquoteEnd tokenKind = tokenBlockquoteEnd
+	// TokenCodeBlockStart opens a fenced or indented code block; Block.Lang
+	// holds the fence language, if any.
+	TokenCodeBlockStart tokenKind = tokenCodeBlockStart
+	// TokenCodeBlockEnd closes a code block.
+	TokenCodeBlockEnd tokenKind = tokenCodeBlockEnd
+	// TokenTableStart opens a table. The parser still draws the table as
+	// text; its rules and bars lie outside the cells.
+	TokenTableStart tokenKind = tokenTableStart
+	// TokenTableEnd closes a table.
+	TokenTableEnd tokenKind = tokenTableEnd
+	// TokenTableRowStart opens a table row; Block.Header marks the header
+	// row.
+	TokenTableRowStart tokenKind = tokenTableRowStart
+	// TokenTableRowEnd closes a table row.
+	TokenTableRowEnd tokenKind = tokenTableRowEnd
+	// TokenTableCellStart opens the text of a cell; Block.Column,
+	// Block.Align and Block.Header describe it. A row wrapped over several
+	// lines reports each cell once per line, with the same column.
+	TokenTableCellStart tokenKind = tokenTableCellStart
+	// TokenTableCellEnd closes a cell.
+	TokenTableCellEnd tokenKind = tokenTableCellEnd
+	// TokenDefinition marks a link reference definition where it stands;
+	// LinkRef holds its label and LinkURL its destination and title. It
+	// carries no text.
+	TokenDefinition tokenKind = tokenDefinition
 )
+
+// IsBlockEvent reports whether k is a block start or end event. Block events
+// carry no text; they bracket the tokens of the block they describe.
+func IsBlockEvent(k TokenKind) bool {
+	return k >= tokenHeadingStart && k <= tokenTableCellEnd
+}